@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// agentTokenEnv sets the agent's bearer token when -token or -agent-token is not given
+const agentTokenEnv = "SITE_INFO_AGENT_TOKEN"
+
+// ttfbAgent measures TTFB from its region for scans running elsewhere
+type ttfbAgent struct {
+	region  string
+	scanner *siteinfo.Scanner
+	allow   func(url string) error
+}
+
+// handler returns the agent's routes, behind the bearer token when one is set
+func (a *ttfbAgent) handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /ttfb", a.measure)
+	return bearerAuth(token, mux)
+}
+
+// measure takes the TTFB samples of the URL in the request body and answers with them
+func (a *ttfbAgent) measure(w http.ResponseWriter, r *http.Request) {
+	var req siteinfo.AgentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if !strings.HasPrefix(req.URL, "http://") && !strings.HasPrefix(req.URL, "https://") {
+		writeAPIError(w, http.StatusBadRequest, `give the "url" of a site`)
+		return
+	}
+	if err := a.allow(req.URL); err != nil {
+		writeAPIError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	ttfbs, err := a.scanner.SampleTTFB(r.Context(), req.URL)
+	if err != nil {
+		writeAPIJSON(w, http.StatusBadGateway, siteinfo.AgentResponse{Region: a.region, Error: err.Error()})
+		return
+	}
+	answer := siteinfo.AgentResponse{Region: a.region, TTFBs: make([]float64, len(ttfbs))}
+	for i, ttfb := range ttfbs {
+		answer.TTFBs[i] = siteinfo.Milliseconds(ttfb)
+	}
+	writeAPIJSON(w, http.StatusOK, answer)
+}
+
+// runAgent runs the agent command: a small HTTP server, deployed in another region, that
+// measures TTFB for scans given it in -agents, so latency is reported as visitors there see it
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:9090", "address to listen on")
+	region := fs.String("region", "", "name of the region the agent measures from, e.g. eu-west, used in the report's column headers")
+	token := fs.String("token", os.Getenv(agentTokenEnv), "bearer token scans must send in the Authorization header (or set "+agentTokenEnv+")")
+	scan := registerScanFlags(fs)
+	registerErrorFormat(fs)
+	registerLogFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	if *region == "" {
+		fail(codeAgent, errors.New("-region is required"))
+	}
+	opts, closeAudit, err := scan.options()
+	if err != nil {
+		fail(codeScannerConfig, err)
+	}
+	defer closeAudit()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fail(codeAgent, err)
+	}
+	agent := &ttfbAgent{region: *region, scanner: siteinfo.New(opts), allow: opts.AllowTarget}
+	server := &http.Server{Handler: agent.handler(*token), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if *token == "" {
+		slog.Warn("No -token set; anyone who can reach the agent can have it send requests")
+	}
+	slog.Info("Serving the TTFB agent", "region", *region, "url", "http://"+listener.Addr().String())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fail(codeAgent, err)
+	}
+	slog.Info("Agent stopped")
+}
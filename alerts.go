@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// Kinds of alert raised by the daemon
+const (
+	alertDown         = "site_down"
+	alertRecovered    = "site_recovered"
+	alertSSLInvalid   = "ssl_invalid"
+	alertSSLExpiring  = "ssl_expiring"
+	alertRenewal      = "renewal_overdue"
+	alertRenewed      = "certificate_renewed"
+	alertOutdated     = "version_outdated"
+	alertNowSupported = "version_supported"
+)
+
+// alert is a change in a monitored site worth telling someone about
+type alert struct {
+	RaisedAt time.Time `json:"raised_at"`
+	Group    string    `json:"group"`
+	URL      string    `json:"url"`
+	Kind     string    `json:"kind"`
+	Detail   string    `json:"detail"`
+}
+
+// siteState is what the daemon remembers about a site between runs, so alerts are raised when
+// a problem first appears rather than on every run
+type siteState struct {
+	down        bool
+	sslInvalid  bool
+	sslExpiring bool
+	renewalDue  bool
+	outdated    map[string]string
+}
+
+// monitoredStatuses are the support statuses watched for components reaching end of life
+// and exported as metrics, with the label identifying them in metrics
+var monitoredStatuses = []struct {
+	name  string
+	label string
+	value func(info *siteinfo.SiteInfo) (status, version string)
+}{
+	{"PHP", "php", func(info *siteinfo.SiteInfo) (string, string) { return info.PHPStatus, info.PHPVersion }},
+	{"MySQL", "mysql", func(info *siteinfo.SiteInfo) (string, string) { return info.MySQLStatus, info.MySQLVersion }},
+	{"Web server", "web_server", func(info *siteinfo.SiteInfo) (string, string) {
+		return info.WebServerStatus, strings.TrimSpace(info.WebServer + " " + info.WebServerVersion)
+	}},
+	{"WordPress", "wordpress", func(info *siteinfo.SiteInfo) (string, string) { return info.WordPressStatus, info.WordPressVersion }},
+}
+
+// monitor holds the last known state of each site of a group and turns each run's results
+// into alerts
+type monitor struct {
+	group string
+	sites map[string]*siteState
+}
+
+// newMonitor returns a monitor with no history, so problems found by the first run are alerted
+func newMonitor(group string) *monitor {
+	return &monitor{group: group, sites: map[string]*siteState{}}
+}
+
+// state returns the site's remembered state
+func (m *monitor) state(url string) *siteState {
+	state, ok := m.sites[url]
+	if !ok {
+		state = &siteState{outdated: map[string]string{}}
+		m.sites[url] = state
+	}
+	return state
+}
+
+// newAlert returns an alert of the group raised now
+func (m *monitor) newAlert(url, kind, detail string) alert {
+	return alert{RaisedAt: time.Now(), Group: m.group, URL: url, Kind: kind, Detail: detail}
+}
+
+// availability records whether the site answered and returns the alert for a site going down
+// or coming back up
+func (m *monitor) availability(url string, down bool, reason string) []alert {
+	state := m.state(url)
+	wasDown := state.down
+	state.down = down
+	switch {
+	case down && !wasDown:
+		return []alert{m.newAlert(url, alertDown, reason)}
+	case !down && wasDown:
+		return []alert{m.newAlert(url, alertRecovered, "")}
+	}
+	return nil
+}
+
+// scanned records a full scan of the site and returns the alerts for the site going down or
+// coming back up, certificates that became invalid, started expiring or were not renewed in
+// time, and components that reached or left end of life. A site answering with a server
+// error is down, as for uptime checks.
+func (m *monitor) scanned(info *siteinfo.SiteInfo) []alert {
+	alerts := m.availability(info.URL, info.ScanStatus == siteinfo.ScanServerError, info.ScanError)
+	state := m.state(info.URL)
+
+	sslInvalid := !info.SSLValid
+	if sslInvalid && !state.sslInvalid {
+		detail := "certificate did not verify"
+		if info.SSLExpired {
+			detail = "certificate has expired"
+		}
+		alerts = append(alerts, m.newAlert(info.URL, alertSSLInvalid, detail))
+	}
+	state.sslInvalid = sslInvalid
+
+	sslExpiring := info.Certificate != nil && info.Certificate.ExpiringSoon && !info.SSLExpired
+	if sslExpiring && !state.sslExpiring {
+		alerts = append(alerts, m.newAlert(info.URL, alertSSLExpiring,
+			fmt.Sprintf("certificate expires in %d days, on %s", info.Certificate.DaysUntilExpiry, info.Certificate.NotAfter.Format(time.DateOnly))))
+	}
+	state.sslExpiring = sslExpiring
+
+	// A Let's Encrypt certificate not renewed after 60 days is the first sign of a failing ACME
+	// client; the alert is resolved once a fresh certificate is served
+	renewalDue := info.Certificate != nil && info.Certificate.RenewalOverdue
+	switch {
+	case renewalDue && !state.renewalDue:
+		alerts = append(alerts, m.newAlert(info.URL, alertRenewal,
+			fmt.Sprintf("Let's Encrypt certificate issued %s has not been renewed", info.Certificate.NotBefore.Format(time.DateOnly))))
+	case !renewalDue && state.renewalDue && info.Certificate != nil:
+		alerts = append(alerts, m.newAlert(info.URL, alertRenewed, ""))
+	}
+	if info.Certificate != nil {
+		state.renewalDue = renewalDue
+	}
+
+	for _, component := range monitoredStatuses {
+		status, version := component.value(info)
+		_, wasOutdated := state.outdated[component.name]
+		switch {
+		case status == "Outdated" && !wasOutdated:
+			state.outdated[component.name] = version
+			alerts = append(alerts, m.newAlert(info.URL, alertOutdated, fmt.Sprintf("%s %s has reached end of life", component.name, version)))
+		case status == "Supported" && wasOutdated:
+			delete(state.outdated, component.name)
+			alerts = append(alerts, m.newAlert(info.URL, alertNowSupported, fmt.Sprintf("%s %s is supported", component.name, version)))
+		}
+	}
+	return alerts
+}
+
+// alertLog prints alerts and appends them to a JSON Lines file shared by every group
+type alertLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openAlertLog opens the alerts file for appending, creating it if needed
+func openAlertLog(filePath string) (*alertLog, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &alertLog{file: file}, nil
+}
+
+// raise logs and records the alerts
+func (l *alertLog) raise(alerts []alert) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, a := range alerts {
+		slog.Warn("Alert", "group", a.Group, "kind", a.Kind, "url", a.URL, "detail", a.Detail)
+		data, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		if _, err := l.file.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the alerts file
+func (l *alertLog) Close() error {
+	return l.file.Close()
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// benchBarWidth is the width of the longest bar of the latency histogram
+const benchBarWidth = 40
+
+// renderBench prints the benchmark's throughput, error rate, latency percentiles and a
+// histogram of the latencies
+func renderBench(w io.Writer, result *siteinfo.BenchResult) {
+	fmt.Fprintf(w, "Benchmarked %s with %d workers for %.1fs\n\n", result.URL, result.Concurrency, result.Duration.Seconds())
+	fmt.Fprintf(w, "Requests:     %d (%.1f/s)\n", result.Requests, result.RequestsPerSecond)
+	fmt.Fprintf(w, "Errors:       %d (%.2f%%)\n", result.Errors, result.ErrorRate*100)
+	fmt.Fprintf(w, "Cache hits:   %d\n", result.CacheHits)
+	codes := make([]string, 0, len(result.StatusCodes))
+	for _, code := range slices.Sorted(maps.Keys(result.StatusCodes)) {
+		codes = append(codes, fmt.Sprintf("%d: %d", code, result.StatusCodes[code]))
+	}
+	fmt.Fprintf(w, "Status codes: %s\n", strings.Join(codes, ", "))
+	if result.Requests == result.Errors && len(result.StatusCodes) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\nLatency (ms): mean %.1f, min %.1f, p50 %.1f, p90 %.1f, p99 %.1f, max %.1f\n",
+		siteinfo.Milliseconds(result.Mean), siteinfo.Milliseconds(result.Min), siteinfo.Milliseconds(result.P50),
+		siteinfo.Milliseconds(result.P90), siteinfo.Milliseconds(result.P99), siteinfo.Milliseconds(result.Max))
+	fmt.Fprintf(w, "Mean TTFB:    %.1fms\n\n", siteinfo.Milliseconds(result.MeanTTFB))
+
+	most := 0
+	for _, bucket := range result.Histogram {
+		most = max(most, bucket.Count)
+	}
+	for i, bucket := range result.Histogram {
+		// The last bucket holds everything slower than the bucket before it
+		label := fmt.Sprintf("<= %6.0fms", siteinfo.Milliseconds(bucket.UpperBound))
+		if bucket.UpperBound == 0 {
+			label = fmt.Sprintf(" > %6.0fms", siteinfo.Milliseconds(result.Histogram[i-1].UpperBound))
+		}
+		bar := strings.Repeat("#", bucket.Count*benchBarWidth/max(most, 1))
+		fmt.Fprintf(w, "%s  %-*s %d\n", label, benchBarWidth, bar, bucket.Count)
+	}
+}
+
+// runBench runs the bench command: a short load test of one URL from concurrent workers,
+// to check caching changes hold up under load right after a scan flags them
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	duration := fs.Duration("duration", 10*time.Second, "how long to send requests for")
+	jsonOutput := fs.Bool("json", false, "print the results as JSON")
+	scan := registerScanFlags(fs)
+	registerErrorFormat(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: site-info-fetcher bench [flags] <url>\n\nThe -concurrency flag sets the number of workers sending requests.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 || *duration <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	opts, closeAudit, err := scan.options()
+	if err != nil {
+		fail(codeScannerConfig, err)
+	}
+	defer closeAudit()
+	opts.Logger = nil
+	scanner := siteinfo.New(opts)
+
+	// Ctrl+C ends the run early and reports the requests made so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := scanner.Bench(ctx, fs.Arg(0), opts.Concurrency, *duration)
+	if err != nil {
+		fail(codeTargetRefused, err)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+		return
+	}
+	renderBench(os.Stdout, result)
+}
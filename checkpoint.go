@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// checkpoint persists each site's result to a state file as soon as it is scanned, one JSON
+// object per line, so an interrupted run can be resumed without rescanning those sites
+type checkpoint struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openCheckpoint opens the state file, keeping the results already in it when resuming and
+// starting it afresh otherwise
+func openCheckpoint(filePath string, resume bool) (*checkpoint, error) {
+	flags := os.O_CREATE | os.O_RDWR | os.O_APPEND
+	if !resume {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(filePath, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	// End a line truncated by a killed run, so the next result starts on a line of its own
+	if stat, err := file.Stat(); err == nil && stat.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, stat.Size()-1); err == nil && last[0] != '\n' {
+			file.Write([]byte{'\n'})
+		}
+	}
+	return &checkpoint{file: file}, nil
+}
+
+// record appends a successfully scanned site to the state file. Failed sites are not
+// recorded, so they are retried on resume.
+func (c *checkpoint) record(url string, info *siteinfo.SiteInfo, err error) {
+	if err != nil {
+		return
+	}
+	line, err := json.Marshal(info)
+	if err != nil {
+		slog.Error("Error saving checkpoint", "url", url, "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		slog.Error("Error saving checkpoint", "url", url, "error", err)
+	}
+}
+
+// close closes the state file
+func (c *checkpoint) close() error {
+	return c.file.Close()
+}
+
+// loadCheckpoint returns the sites completed by an earlier run, keyed by URL. A missing state
+// file means nothing was completed. A truncated last line, left by a run killed mid-write, is
+// ignored.
+func loadCheckpoint(filePath string) (map[string]*siteinfo.SiteInfo, error) {
+	completed := map[string]*siteinfo.SiteInfo{}
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return completed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var info siteinfo.SiteInfo
+		if err := json.Unmarshal(scanner.Bytes(), &info); err != nil {
+			continue
+		}
+		completed[info.URL] = &info
+	}
+	return completed, scanner.Err()
+}
+
+// mergeCompleted returns the results of the earlier run and of this one in input order
+func mergeCompleted(urls []string, completed map[string]*siteinfo.SiteInfo, scanned []*siteinfo.SiteInfo) []*siteinfo.SiteInfo {
+	for _, info := range scanned {
+		completed[info.URL] = info
+	}
+	var siteInfos []*siteinfo.SiteInfo
+	for _, url := range urls {
+		if info, ok := completed[url]; ok {
+			siteInfos = append(siteInfos, info)
+		}
+	}
+	return siteInfos
+}
@@ -0,0 +1,12 @@
+package main
+
+import "strings"
+
+// isCompetitorMarker reports whether an input column value marks the site as a competitor
+func isCompetitorMarker(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "competitor", "yes", "y", "true", "1", "x":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is a scanning profile loaded from a YAML file. Every setting mirrors a
+// command-line flag; flags given on the command line take precedence.
+type config struct {
+	Timeout        string             `yaml:"timeout"`
+	Retries        int                `yaml:"retries"`
+	Concurrency    int                `yaml:"concurrency"`
+	Format         string             `yaml:"format"`
+	Columns        []string           `yaml:"columns"`
+	FailOn         []string           `yaml:"fail_on"`
+	HealthWeights  map[string]float64 `yaml:"health_weights"`
+	LogLevel       string             `yaml:"log_level"`
+	LogFormat      string             `yaml:"log_format"`
+	UserAgent      string             `yaml:"user_agent"`
+	RequestProfile string             `yaml:"request_profile"`
+	Checks         map[string]bool    `yaml:"checks"`
+}
+
+// loadConfig reads a scanning profile from a YAML file
+func loadConfig(filePath string) (*config, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+	}
+	return &cfg, nil
+}
+
+// apply sets each flag that was not given on the command line to its value from the config.
+// Detection toggles map to the matching -check-<name> flag, e.g. checks.error_page to -check-error-page.
+func (cfg *config) apply(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	values := map[string]string{}
+	if cfg.Timeout != "" {
+		values["timeout"] = cfg.Timeout
+	}
+	if cfg.Retries != 0 {
+		values["retries"] = fmt.Sprint(cfg.Retries)
+	}
+	if cfg.Concurrency != 0 {
+		values["concurrency"] = fmt.Sprint(cfg.Concurrency)
+	}
+	if cfg.Format != "" {
+		values["format"] = cfg.Format
+	}
+	if len(cfg.Columns) > 0 {
+		values["columns"] = strings.Join(cfg.Columns, ",")
+	}
+	if len(cfg.FailOn) > 0 {
+		values["fail-on"] = strings.Join(cfg.FailOn, ",")
+	}
+	if len(cfg.HealthWeights) > 0 {
+		var weights []string
+		for area, weight := range cfg.HealthWeights {
+			weights = append(weights, fmt.Sprintf("%s=%g", area, weight))
+		}
+		values["health-weights"] = strings.Join(weights, ",")
+	}
+	if cfg.LogLevel != "" {
+		values["log-level"] = cfg.LogLevel
+	}
+	if cfg.LogFormat != "" {
+		values["log-format"] = cfg.LogFormat
+	}
+	if cfg.UserAgent != "" {
+		values["user-agent"] = cfg.UserAgent
+	}
+	if cfg.RequestProfile != "" {
+		values["request-profile"] = cfg.RequestProfile
+	}
+	for name, enabled := range cfg.Checks {
+		values["check-"+strings.ReplaceAll(name, "_", "-")] = fmt.Sprint(enabled)
+	}
+
+	for name, value := range values {
+		if explicit[name] {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown setting: there is no -%s flag", name)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %q for -%s: %w", value, name, err)
+		}
+	}
+	return nil
+}
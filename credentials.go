@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+	"gopkg.in/yaml.v3"
+)
+
+// loadCredentials reads a YAML file of credentials for password-protected sites, keyed by
+// hostname or site URL, and returns them keyed by hostname
+func loadCredentials(filePath string) (map[string]*siteinfo.Credentials, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]*siteinfo.Credentials
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+	}
+	credentials := map[string]*siteinfo.Credentials{}
+	for site, creds := range entries {
+		credentials[hostname(site)] = creds
+	}
+	return credentials, nil
+}
+
+// recordCredentials returns the credentials given in the record's auth, cookie and header
+// columns, or nil if there are none
+func recordCredentials(record []string, columns inputColumns) *siteinfo.Credentials {
+	field := func(column int) string {
+		if column < 0 || column >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[column])
+	}
+
+	creds := &siteinfo.Credentials{}
+	if auth := field(columns.auth); auth != "" {
+		creds.Username, creds.Password, _ = strings.Cut(auth, ":")
+	}
+	if cookies := field(columns.cookie); cookies != "" {
+		parsed, err := http.ParseCookie(cookies)
+		if err == nil {
+			creds.Cookies = map[string]string{}
+			for _, cookie := range parsed {
+				creds.Cookies[cookie.Name] = cookie.Value
+			}
+		}
+	}
+	if headers := field(columns.header); headers != "" {
+		creds.Headers = map[string]string{}
+		for _, header := range strings.Split(headers, ";") {
+			name, value, ok := strings.Cut(header, ":")
+			if ok && strings.TrimSpace(name) != "" {
+				creds.Headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	if creds.Username == "" && len(creds.Cookies) == 0 && len(creds.Headers) == 0 {
+		return nil
+	}
+	return creds
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+	"gopkg.in/yaml.v3"
+)
+
+// daemonConfig is the schedule file read by the daemon command
+type daemonConfig struct {
+	OutputDir string      `yaml:"output_dir"`
+	Alerts    string      `yaml:"alerts"`
+	DB        string      `yaml:"db"`
+	Notify    notifySinks `yaml:"notify"`
+	Groups    []siteGroup `yaml:"groups"`
+	// StatusPage is a directory the public status page is regenerated in after every run
+	StatusPage  string `yaml:"status_page"`
+	StatusTitle string `yaml:"status_title"`
+}
+
+// siteGroup is a set of sites checked on the same cron schedule. Scan groups run the full
+// scan and write a report per run; uptime groups run a lightweight availability check.
+type siteGroup struct {
+	Name       string   `yaml:"name"`
+	Schedule   string   `yaml:"schedule"`
+	Mode       string   `yaml:"mode"`
+	Input      string   `yaml:"input"`
+	Column     int      `yaml:"column"`
+	ColumnName string   `yaml:"column_name"`
+	Sheet      string   `yaml:"sheet"`
+	URLs       []string `yaml:"urls"`
+	Config     string   `yaml:"config"`
+	Format     string   `yaml:"format"`
+}
+
+// daemonGroup is a site group ready to run
+type daemonGroup struct {
+	siteGroup
+	schedule *schedule
+	opts     siteinfo.Options
+	monitor  *monitor
+	close    func()
+}
+
+// loadDaemonConfig reads and validates the daemon schedule file
+func loadDaemonConfig(filePath string) (*daemonConfig, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var cfg daemonConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+	}
+	if len(cfg.Groups) == 0 {
+		return nil, errors.New("no site groups defined")
+	}
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = "."
+	}
+	if cfg.Alerts == "" {
+		cfg.Alerts = filepath.Join(cfg.OutputDir, "alerts.jsonl")
+	}
+	if err := cfg.Notify.validate(); err != nil {
+		return nil, fmt.Errorf("notify: %w", err)
+	}
+	return &cfg, nil
+}
+
+// prepare validates the group and builds its scanner options from the group's scanning profile
+func (g siteGroup) prepare() (*daemonGroup, error) {
+	if g.Name == "" {
+		return nil, errors.New("site group without a name")
+	}
+	if g.Input == "" && len(g.URLs) == 0 {
+		return nil, fmt.Errorf("group %s: no input file or urls", g.Name)
+	}
+	switch g.Mode {
+	case "":
+		g.Mode = "scan"
+	case "scan", "uptime":
+	default:
+		return nil, fmt.Errorf("group %s: unknown mode %q (expected scan or uptime)", g.Name, g.Mode)
+	}
+	sched, err := parseSchedule(g.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("group %s: %w", g.Name, err)
+	}
+
+	fs := flag.NewFlagSet(g.Name, flag.ContinueOnError)
+	scan := registerScanFlags(fs)
+	format := fs.String("format", "csv", "output format: "+strings.Join(report.Formats(), ", "))
+	if g.Config != "" {
+		cfg, err := loadConfig(g.Config)
+		if err == nil {
+			err = cfg.apply(fs)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", g.Name, err)
+		}
+	}
+	if g.Format == "" {
+		g.Format = *format
+	}
+	if !report.HasFormat(g.Format) {
+		return nil, fmt.Errorf("group %s: unsupported output format: %s", g.Name, g.Format)
+	}
+	opts, closeAudit, err := scan.options()
+	if err != nil {
+		return nil, fmt.Errorf("group %s: %w", g.Name, err)
+	}
+	opts.Logger = opts.Logger.With("group", g.Name)
+	return &daemonGroup{siteGroup: g, schedule: sched, opts: opts, monitor: newMonitor(g.Name), close: closeAudit}, nil
+}
+
+// urls returns the group's sites, re-reading the input file so edits apply to the next run,
+// without those the group's target filters refuse
+func (g *daemonGroup) urls() ([]string, error) {
+	urls := append([]string{}, g.URLs...)
+	if g.Input != "" {
+		columns := urlColumn(g.Column)
+		columns.urlName, columns.sheet = g.ColumnName, g.Sheet
+		in, err := readInput(g.Input, columns)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, in.urls...)
+	}
+	return slices.DeleteFunc(urls, func(url string) bool {
+		err := g.opts.AllowTarget(url)
+		if err != nil {
+			g.opts.Logger.Info("Skipping site", "url", url, "reason", err)
+		}
+		return err != nil
+	}), nil
+}
+
+// daemon is what the site groups share: the schedule file's settings, the alert log and the
+// latest results exported as metrics
+type daemon struct {
+	cfg     *daemonConfig
+	alerts  *alertLog
+	metrics *metrics
+
+	statusMu sync.Mutex
+}
+
+// run performs one scheduled run of the group, writing its results to the output directory
+// and the database, updating the metrics and raising alerts for the changes since the
+// previous run. The run's alerts are posted to the notification sinks together.
+func (g *daemonGroup) run(ctx context.Context, d *daemon) error {
+	urls, err := g.urls()
+	if err != nil {
+		return err
+	}
+
+	var raised []alert
+	raise := func(alerts []alert) error {
+		raised = append(raised, alerts...)
+		return d.alerts.raise(alerts)
+	}
+	defer func() {
+		if len(raised) > 0 && d.cfg.Notify.enabled() {
+			if err := d.cfg.Notify.send(alertSummary(g.Name, raised)); err != nil {
+				slog.Error("Error sending notification", "group", g.Name, "error", err)
+			}
+		}
+	}()
+
+	if g.Mode == "uptime" {
+		scanner := siteinfo.New(g.opts)
+		file, err := os.OpenFile(filepath.Join(d.cfg.OutputDir, g.Name+"_uptime.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		encoder := json.NewEncoder(file)
+		for _, url := range urls {
+			uptime := scanner.CheckUptime(ctx, url)
+			if ctx.Err() != nil {
+				return nil
+			}
+			reason := uptime.Error
+			if reason == "" {
+				reason = fmt.Sprintf("HTTP %d", uptime.StatusCode)
+			}
+			d.metrics.recordUptime(g.Name, uptime)
+			if err := raise(g.monitor.availability(url, !uptime.Up, reason)); err != nil {
+				return err
+			}
+			if err := encoder.Encode(uptime); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// A new scanner per run refreshes the endoflife.date data in long-running daemons
+	scanner := siteinfo.New(g.opts)
+
+	siteInfos, errs := scanner.ScanAll(ctx, urls)
+	if ctx.Err() != nil {
+		return nil
+	}
+	for _, err := range errs {
+		slog.Error("Error fetching site info", "group", g.Name, "error", err)
+		var scanErr *siteinfo.ScanError
+		if !errors.As(err, &scanErr) {
+			continue
+		}
+		d.metrics.recordFailure(g.Name, scanErr.URL)
+		if err := raise(g.monitor.availability(scanErr.URL, true, scanErr.Err.Error())); err != nil {
+			return err
+		}
+	}
+	for _, info := range siteInfos {
+		d.metrics.recordScan(g.Name, info)
+		if err := raise(g.monitor.scanned(info)); err != nil {
+			return err
+		}
+	}
+
+	outputFilePath := filepath.Join(d.cfg.OutputDir, fmt.Sprintf("%s_%s.%s", g.Name, time.Now().Format("20060102_150405"), g.Format))
+	if err := report.Write(g.Format, outputFilePath, withFailures(urls, siteInfos, errs)); err != nil {
+		return err
+	}
+	slog.Info("Site information written", "group", g.Name, "path", outputFilePath)
+	if d.cfg.DB != "" {
+		return report.Write("sqlite", d.cfg.DB, siteInfos)
+	}
+	return nil
+}
+
+// loop runs the group each time its schedule fires until ctx is cancelled. A run that
+// overruns the next scheduled time delays it rather than overlapping.
+func (g *daemonGroup) loop(ctx context.Context, d *daemon) {
+	for {
+		next := g.schedule.next(time.Now())
+		if next.IsZero() {
+			slog.Warn("Schedule never fires; group disabled", "group", g.Name, "schedule", g.Schedule)
+			return
+		}
+		slog.Info("Next run scheduled", "group", g.Name, "at", next.Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		if err := g.run(ctx, d); err != nil {
+			slog.Error("Run failed", "group", g.Name, "error", err)
+		}
+		d.updateStatusPage()
+	}
+}
+
+// runDaemon runs the daemon command: it scans each site group on its own cron schedule
+// until interrupted
+func runDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	schedulePath := fs.String("schedule", "", "path to the YAML file defining site groups and their schedules")
+	metricsAddr := fs.String("metrics-addr", "", "serve the latest results as Prometheus metrics on /metrics at this address, e.g. :9090")
+	registerErrorFormat(fs)
+	registerLogFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+	if *schedulePath == "" {
+		fail(codeSchedule, errors.New("the daemon command requires -schedule"))
+	}
+
+	cfg, err := loadDaemonConfig(*schedulePath)
+	if err != nil {
+		fail(codeSchedule, err)
+	}
+	var groups []*daemonGroup
+	for _, group := range cfg.Groups {
+		prepared, err := group.prepare()
+		if err != nil {
+			fail(codeSchedule, err)
+		}
+		defer prepared.close()
+		groups = append(groups, prepared)
+	}
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		fail(codeOutputDir, err)
+	}
+	alerts, err := openAlertLog(cfg.Alerts)
+	if err != nil {
+		fail(codeOutputDir, err)
+	}
+	defer alerts.Close()
+	d := &daemon{cfg: cfg, alerts: alerts, metrics: newMetrics()}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *metricsAddr != "" {
+		listener, err := net.Listen("tcp", *metricsAddr)
+		if err != nil {
+			fail(codeMetrics, err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", d.metrics)
+		server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go server.Serve(listener)
+		defer server.Close()
+		slog.Info("Serving metrics", "url", "http://"+listener.Addr().String()+"/metrics")
+	}
+
+	var wg sync.WaitGroup
+	for _, group := range groups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			group.loop(ctx, d)
+		}()
+	}
+	wg.Wait()
+	slog.Info("Daemon stopped")
+}
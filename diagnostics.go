@@ -0,0 +1,158 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+)
+
+// Diagnostic codes of the errors that stop a run. The codes are stable, so scripts can match
+// them and users can look them up whatever the wording of the message.
+const (
+	codeConfig         = "E101"
+	codeOutputSpec     = "E102"
+	codeOutputConflict = "E103"
+	codeOutputFormat   = "E104"
+	codeTargetFilter   = "E105"
+	codeScannerConfig  = "E106"
+	codeResume         = "E107"
+	codeSchedule       = "E108"
+	codeUnknownCheck   = "E109"
+	codeMetrics        = "E110"
+	codeServe          = "E111"
+	codeWebhook        = "E112"
+	codeMaxAge         = "E113"
+	codeTemplate       = "E114"
+	codeColumns        = "E115"
+	codeSample         = "E116"
+	codeGoogleSheet    = "E117"
+	codeFailOn         = "E118"
+	codeTUI            = "E119"
+	codeWeb            = "E120"
+	codeAgent          = "E121"
+	codeTargetRefused  = "E122"
+	codeInput          = "E201"
+	codeCheckpoint     = "E202"
+	codeReportRead     = "E203"
+	codeHistory        = "E204"
+	codeWriteReport    = "E301"
+	codeEncrypt        = "E302"
+	codeOutputDir      = "E303"
+	codeRetest         = "E401"
+	codeGate           = "E501"
+	codeInterrupted    = "E502"
+)
+
+// errorFormatEnv sets the error format when -error-format is not given
+const errorFormatEnv = "SITE_INFO_ERROR_FORMAT"
+
+// diagnosticInfo describes an error code: what went wrong, how to fix it and the exit status
+type diagnosticInfo struct {
+	message string
+	fix     string
+	exit    int
+}
+
+// diagnostics is the catalogue of user-facing errors
+var diagnostics = map[string]diagnosticInfo{
+	codeConfig:         {"Could not load the scanning profile", "Check the YAML syntax and that every setting matches a flag; see site-info.example.yaml.", 2},
+	codeOutputSpec:     {"Invalid -out value", "Use format=path, e.g. -out json=report.json, with one of the formats " + strings.Join(report.Formats(), ", ") + ".", 2},
+	codeOutputConflict: {"-output and -out cannot be combined", "Name every output with -out, e.g. -out csv=report.csv -out json=report.json.", 2},
+	codeOutputFormat:   {"Unsupported output format", "Use one of the formats " + strings.Join(report.Formats(), ", ") + ".", 2},
+	codeTargetFilter:   {"Could not load the target filters", "Check the -include and -exclude patterns, and that the -blocklist file exists.", 2},
+	codeScannerConfig:  {"Could not configure the scanner", "Check the values of -include, -exclude, -blocklist, -proxy, -tor-proxy, -resolver, -credentials, -fingerprint-rules, -detect-rules, -geoip-asn-db, -geoip-country-db, -vuln-feed, -audit-log and -otlp-endpoint.", 2},
+	codeResume:         {"-resume requires the -checkpoint file of the interrupted run", "Run again with the same -checkpoint file as the interrupted run.", 2},
+	codeSchedule:       {"Could not load the schedule file", "Check the -schedule path, the YAML syntax and each group's cron expression; see daemon.example.yaml.", 2},
+	codeUnknownCheck:   {"Unknown check", "Run with -h to list the available checks.", 2},
+	codeMetrics:        {"Could not serve the metrics", "Check that the -metrics-addr address is valid and its port is free.", 2},
+	codeServe:          {"Could not start the API server", "Check that the -addr address is valid and its port is free.", 2},
+	codeWebhook:        {"Invalid webhook URL", "Give the full URL of the endpoint to -webhook, -notify-slack or -notify-teams, e.g. -webhook https://hooks.example.com/site-info.", 2},
+	codeMaxAge:         {"-max-age requires the -db results database", "Give the -db database the earlier runs wrote their results to, e.g. -db scans.db -max-age 7d.", 2},
+	codeTemplate:       {"Could not load the output template", "Check the -template path and its text/template syntax; see Custom output templates in the README.", 2},
+	codeColumns:        {"Unknown -columns field", "Name columns by their CSV header in lower case with spaces and punctuation replaced by underscores, e.g. -columns url,wordpress_version,ssl_valid.", 2},
+	codeSample:         {"Invalid -sample size", "Give a number of sites, e.g. -sample 500, or a percentage of the input, e.g. -sample 5%.", 2},
+	codeGoogleSheet:    {"The gsheet format needs the Google Sheet to write to", "Give the spreadsheet's URL to -output, e.g. -format gsheet -output https://docs.google.com/spreadsheets/d/<id>/edit, and share it with the service account.", 2},
+	codeFailOn:         {"Invalid -fail-on condition", "Use a condition such as outdated-php, ssl-expired, ssl-expiring, vulnerable or scan-failed, or a threshold such as ttfb>1500ms, page-weight>3000000 or cert-days<14.", 2},
+	codeTUI:            {"Could not start the terminal UI", "Run -tui in an interactive terminal on Linux, macOS or BSD, without redirecting its input or output.", 2},
+	codeWeb:            {"Could not serve the dashboard", "Check that the -web address is valid, e.g. :8080 or localhost:8080, and its port is free.", 2},
+	codeAgent:          {"Could not start the TTFB agent", "Give the agent's region with -region, and check that the -addr address is valid and its port is free.", 2},
+	codeTargetRefused:  {"Refused to scan the site", "Check the -include, -exclude and -blocklist rules, or give a site they allow.", 2},
+	codeInput:          {"Could not read the input file", "Check the -input path and that the column numbers or -column-name given exist in the file.", 1},
+	codeCheckpoint:     {"Could not use the checkpoint file", "Check that the -checkpoint file's directory exists and is writable.", 1},
+	codeReportRead:     {"Could not read the report", "Check the path and that the file is a CSV or JSON report written by this tool.", 1},
+	codeHistory:        {"Could not read the scan history", "Check the -db path, and that the site was scanned with -db and its URL is written as in the input.", 1},
+	codeWriteReport:    {"Could not write the report", "Check that the output directory exists and is writable, and that the disk is not full.", 1},
+	codeEncrypt:        {"Could not encrypt the report", "Check the -encrypt-key or SITE_INFO_ENCRYPT_KEY value and that the report directory is writable.", 1},
+	codeOutputDir:      {"Could not create the output directory", "Check the schedule file's output_dir and its permissions.", 1},
+	codeRetest:         {"The retest failed", "Check that the site is reachable from this machine, or raise -timeout.", 1},
+	codeGate:           {"Sites break the -fail-on conditions", "Fix the sites listed above, or relax the -fail-on conditions.", 3},
+	codeInterrupted:    {"The scan was interrupted", "The sites scanned so far were written. Run with -checkpoint, then again with -resume, to scan only the remaining sites.", 130},
+}
+
+// errorFormat is how diagnostics are printed: text, or json for scripts
+var errorFormat = cmp.Or(os.Getenv(errorFormatEnv), "text")
+
+// registerErrorFormat adds the -error-format flag to the flag set
+func registerErrorFormat(fs *flag.FlagSet) {
+	fs.Func("error-format", "print errors as text or as json, with a code and a suggested fix (or set "+errorFormatEnv+")", func(value string) error {
+		if value != "text" && value != "json" {
+			return fmt.Errorf("use text or json")
+		}
+		errorFormat = value
+		return nil
+	})
+}
+
+// diagnostic is a user-facing error with its code and suggested fix
+type diagnostic struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// exitHooks release what a command opened, such as the audit log and the trace exporter, when
+// fail exits before the command's deferred calls can run
+var exitHooks []func()
+
+// onExit registers the cleanup to run if fail exits, and returns it wrapped to run at most
+// once, so it can also be deferred for a normal return
+func onExit(cleanup func()) func() {
+	once := sync.OnceFunc(cleanup)
+	exitHooks = append(exitHooks, once)
+	return once
+}
+
+// fail prints the diagnostic for the code to stderr, with the error that caused it as detail,
+// runs the exit hooks, latest first, and exits with the code's status
+func fail(code string, err error) {
+	info := diagnostics[code]
+	d := diagnostic{Code: code, Message: info.message, Fix: info.fix}
+	if err != nil {
+		d.Detail = err.Error()
+	}
+
+	if errorFormat == "json" {
+		data, _ := json.Marshal(d)
+		fmt.Fprintln(os.Stderr, string(data))
+	} else {
+		line := fmt.Sprintf("Error %s: %s", d.Code, d.Message)
+		if d.Detail != "" {
+			line += ": " + d.Detail
+		}
+		fmt.Fprintln(os.Stderr, line)
+		if d.Fix != "" {
+			fmt.Fprintf(os.Stderr, "  Fix: %s\n", d.Fix)
+		}
+	}
+	for i := len(exitHooks) - 1; i >= 0; i-- {
+		exitHooks[i]()
+	}
+	os.Exit(info.exit)
+}
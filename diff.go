@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+)
+
+// runDiff compares two earlier reports and prints or writes the changes between them
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	ttfbThreshold := fs.Float64("ttfb-threshold", 100, "report average TTFB changes of at least this many milliseconds")
+	outputPath := fs.String("output", "", "write the change report to this file instead of printing it")
+	format := fs.String("format", "csv", "change report format: csv or json")
+	registerErrorFormat(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: site-info-fetcher diff [flags] <before> <after>\n\nCompares two CSV or JSON reports.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "csv" && *format != "json" {
+		fail(codeOutputFormat, errors.New(*format))
+	}
+
+	before, err := report.LoadScan(fs.Arg(0))
+	if err != nil {
+		fail(codeReportRead, err)
+	}
+	after, err := report.LoadScan(fs.Arg(1))
+	if err != nil {
+		fail(codeReportRead, err)
+	}
+	diff := report.DiffScans(before, after, *ttfbThreshold)
+
+	if *outputPath != "" {
+		if err := report.WriteDiff(*outputPath, *format, diff); err != nil {
+			fail(codeWriteReport, err)
+		}
+		fmt.Printf("%d changes written to %s\n", len(diff.Changes), *outputPath)
+		return
+	}
+
+	if len(diff.Changes) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+	for _, change := range diff.Changes {
+		line := change.URL + ": " + strings.ReplaceAll(change.Kind, "_", " ")
+		if change.Field != "" {
+			line += fmt.Sprintf(" %s %q -> %q", change.Field, change.Before, change.After)
+		}
+		if change.Detail != "" {
+			line += " (" + change.Detail + ")"
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// stringList is a flag that can be repeated to collect several values
+type stringList []string
+
+// String returns the collected values
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set adds a value
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// hostPattern matches hostnames with either a glob (*.example.com) or a regular
+// expression wrapped in slashes (/^staging\./)
+type hostPattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+// parseHostPattern parses a glob or /regex/ pattern
+func parseHostPattern(pattern string) (hostPattern, error) {
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return hostPattern{}, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		return hostPattern{regex: re}, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return hostPattern{}, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+	return hostPattern{glob: strings.ToLower(pattern)}, nil
+}
+
+// match reports whether the hostname matches the pattern
+func (p hostPattern) match(host string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(host)
+	}
+	ok, _ := path.Match(p.glob, host)
+	return ok
+}
+
+// targetFilter decides which input URLs may be scanned
+type targetFilter struct {
+	include []hostPattern
+	exclude []hostPattern
+	blocked map[string]bool
+}
+
+// newTargetFilter builds a filter from include and exclude patterns and an optional blocklist file
+func newTargetFilter(include, exclude []string, blocklistPath string) (*targetFilter, error) {
+	f := &targetFilter{blocked: map[string]bool{}}
+	for _, pattern := range include {
+		p, err := parseHostPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, p)
+	}
+	for _, pattern := range exclude {
+		p, err := parseHostPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, p)
+	}
+	if blocklistPath != "" {
+		if err := f.loadBlocklist(blocklistPath); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// loadBlocklist reads domains never to touch, one per line, with # comments
+func (f *targetFilter) loadBlocklist(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if domain := strings.ToLower(strings.TrimSpace(line)); domain != "" {
+			f.blocked[strings.TrimSuffix(domain, ".")] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// hostname returns the lowercase hostname of an input URL
+func hostname(rawURL string) string {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// allow reports whether the URL may be scanned, and if not, why
+func (f *targetFilter) allow(rawURL string) (bool, string) {
+	host := hostname(rawURL)
+
+	// The blocklist covers each listed domain and all of its subdomains
+	for domain := host; domain != ""; {
+		if f.blocked[domain] {
+			return false, "blocklisted domain " + domain
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+
+	for _, p := range f.exclude {
+		if p.match(host) {
+			return false, "excluded by pattern"
+		}
+	}
+	if len(f.include) == 0 {
+		return true, ""
+	}
+	for _, p := range f.include {
+		if p.match(host) {
+			return true, ""
+		}
+	}
+	return false, "not matched by any include pattern"
+}
+
+// check returns why the URL may not be scanned, or nil when it may
+func (f *targetFilter) check(rawURL string) error {
+	if ok, reason := f.allow(rawURL); !ok {
+		return fmt.Errorf("%s is not a permitted target: %s", hostname(rawURL), reason)
+	}
+	return nil
+}
+
+// filter returns the URLs that may be scanned, reporting each skipped URL
+func (f *targetFilter) filter(urls []string) []string {
+	var allowed []string
+	for _, u := range urls {
+		ok, reason := f.allow(u)
+		if !ok {
+			slog.Info("Skipping site", "url", u, "reason", reason)
+			continue
+		}
+		allowed = append(allowed, u)
+	}
+	return allowed
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// parseAge parses a -max-age value: a number of days such as 7d, or a Go duration such as 12h
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q: use a number of days such as 7d, or a duration such as 12h", value)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q: use a number of days such as 7d, or a duration such as 12h", value)
+	}
+	return age, nil
+}
+
+// freshOnly passes on only the sites scanned in this run, so the results reused from the
+// database are not recorded in it a second time as if they were new, and the rows of sites
+// that could not be scanned are not recorded as results
+type freshOnly struct {
+	report.OutputWriter
+	skip map[string]bool
+}
+
+// Write writes the site unless its result was reused or its scan failed
+func (w freshOnly) Write(info *siteinfo.SiteInfo) error {
+	if w.skip[info.URL] {
+		return nil
+	}
+	return w.OutputWriter.Write(info)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// gateConditions are the -fail-on conditions without a threshold: each reports whether the site
+// violates it and, if so, a short description of why
+var gateConditions = map[string]func(info *siteinfo.SiteInfo) (bool, string){
+	"outdated-php": func(info *siteinfo.SiteInfo) (bool, string) {
+		return info.PHPStatus == "Outdated", "PHP " + info.PHPVersion + " is outdated"
+	},
+	"outdated-mysql": func(info *siteinfo.SiteInfo) (bool, string) {
+		return info.MySQLStatus == "Outdated", "MySQL " + info.MySQLVersion + " is outdated"
+	},
+	"outdated-wordpress": func(info *siteinfo.SiteInfo) (bool, string) {
+		return info.WordPressStatus == "Outdated", "WordPress " + info.WordPressVersion + " is outdated"
+	},
+	"outdated-web-server": func(info *siteinfo.SiteInfo) (bool, string) {
+		return info.WebServerStatus == "Outdated", info.WebServer + " " + info.WebServerVersion + " is outdated"
+	},
+	"ssl-expired": func(info *siteinfo.SiteInfo) (bool, string) {
+		return info.SSLExpired, "SSL certificate has expired"
+	},
+	"ssl-invalid": func(info *siteinfo.SiteInfo) (bool, string) {
+		return !info.SSLValid, "SSL certificate does not verify"
+	},
+	"ssl-expiring": func(info *siteinfo.SiteInfo) (bool, string) {
+		if info.Certificate == nil {
+			return false, ""
+		}
+		return info.Certificate.ExpiringSoon, fmt.Sprintf("SSL certificate expires in %d days", info.Certificate.DaysUntilExpiry)
+	},
+	"vulnerable": func(info *siteinfo.SiteInfo) (bool, string) {
+		return info.Vulnerabilities > 0, fmt.Sprintf("%d known vulnerabilities (highest %s)", info.Vulnerabilities, info.HighestSeverity)
+	},
+	"mixed-content": func(info *siteinfo.SiteInfo) (bool, string) {
+		return len(info.MixedContent) > 0, fmt.Sprintf("%d resources loaded over HTTP", len(info.MixedContent))
+	},
+	"wsod": func(info *siteinfo.SiteInfo) (bool, string) {
+		return info.WSODSuspected, "blank or fatal error page"
+	},
+}
+
+// gateMetrics are the -fail-on metrics compared with a threshold, e.g. ttfb>1500ms. Each
+// returns the site's value and whether the site has one.
+var gateMetrics = map[string]struct {
+	unit  string
+	value func(info *siteinfo.SiteInfo) (float64, bool)
+}{
+	"ttfb": {"ms", func(info *siteinfo.SiteInfo) (float64, bool) {
+		return siteinfo.Milliseconds(info.AverageTTFB), true
+	}},
+	"page-weight": {"bytes", func(info *siteinfo.SiteInfo) (float64, bool) {
+		return float64(info.PageWeight), info.PageWeight > 0
+	}},
+	"cert-days": {"days", func(info *siteinfo.SiteInfo) (float64, bool) {
+		if info.Certificate == nil {
+			return 0, false
+		}
+		return float64(info.Certificate.DaysUntilExpiry), true
+	}},
+	"health": {"", func(info *siteinfo.SiteInfo) (float64, bool) {
+		return float64(info.HealthScore), info.HealthGrade != ""
+	}},
+	"vulnerabilities": {"", func(info *siteinfo.SiteInfo) (float64, bool) {
+		return float64(info.Vulnerabilities), true
+	}},
+}
+
+// gateThresholdPattern matches a metric condition: a metric, a comparison and a number with an
+// optional unit, e.g. ttfb>1500ms, ttfb>=1.5s or cert-days<14
+var gateThresholdPattern = regexp.MustCompile(`^([a-z-]+)\s*(>=|<=|>|<)\s*([0-9.]+)\s*([a-z]*)$`)
+
+// gateRule is one -fail-on condition
+type gateRule struct {
+	name  string
+	check func(info *siteinfo.SiteInfo) (bool, string)
+}
+
+// violation is a site breaking a -fail-on condition
+type violation struct {
+	url    string
+	rule   string
+	detail string
+}
+
+// parseFailOn parses the -fail-on conditions, each given separately or comma-separated.
+// "scan-failed" is returned as a flag, as failed sites have no results to check.
+func parseFailOn(values []string) ([]gateRule, bool, error) {
+	var rules []gateRule
+	scanFailed := false
+	for _, value := range values {
+		for _, condition := range strings.Split(value, ",") {
+			condition = strings.ToLower(strings.TrimSpace(condition))
+			if condition == "" {
+				continue
+			}
+			if condition == "scan-failed" {
+				scanFailed = true
+				continue
+			}
+			if check, ok := gateConditions[condition]; ok {
+				rules = append(rules, gateRule{name: condition, check: check})
+				continue
+			}
+			rule, err := parseThreshold(condition)
+			if err != nil {
+				return nil, false, err
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, scanFailed, nil
+}
+
+// parseThreshold parses a metric condition such as ttfb>1500ms
+func parseThreshold(condition string) (gateRule, error) {
+	match := gateThresholdPattern.FindStringSubmatch(condition)
+	if match == nil {
+		return gateRule{}, fmt.Errorf("unknown condition %q: use one of %s, or a threshold such as ttfb>1500ms", condition, strings.Join(gateNames(), ", "))
+	}
+	name, op, unit := match[1], match[2], match[4]
+	metric, ok := gateMetrics[name]
+	if !ok {
+		return gateRule{}, fmt.Errorf("unknown metric %q in %q", name, condition)
+	}
+	threshold, err := strconv.ParseFloat(match[3], 64)
+	if err != nil {
+		return gateRule{}, fmt.Errorf("invalid threshold in %q", condition)
+	}
+	// TTFB thresholds may also be given in seconds
+	switch {
+	case unit == metric.unit || unit == "":
+	case name == "ttfb" && unit == "s":
+		threshold = siteinfo.Milliseconds(time.Duration(threshold * float64(time.Second)))
+	default:
+		return gateRule{}, fmt.Errorf("invalid unit %q in %q: %s is measured in %s", unit, condition, name, metric.unit)
+	}
+
+	return gateRule{name: condition, check: func(info *siteinfo.SiteInfo) (bool, string) {
+		value, ok := metric.value(info)
+		if !ok {
+			return false, ""
+		}
+		var violated bool
+		switch op {
+		case ">":
+			violated = value > threshold
+		case ">=":
+			violated = value >= threshold
+		case "<":
+			violated = value < threshold
+		case "<=":
+			violated = value <= threshold
+		}
+		return violated, strings.TrimSpace(fmt.Sprintf("%s is %s %s", name, strconv.FormatFloat(value, 'f', -1, 64), metric.unit))
+	}}, nil
+}
+
+// gateNames returns the conditions without a threshold, in alphabetical order
+func gateNames() []string {
+	names := []string{"scan-failed"}
+	for name := range gateConditions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkGate returns the violations of the rules by the scanned sites, and of scan-failed by
+// the sites that could not be scanned
+func checkGate(rules []gateRule, scanFailed bool, siteInfos []*siteinfo.SiteInfo, errs []error) []violation {
+	var violations []violation
+	for _, info := range siteInfos {
+		for _, rule := range rules {
+			if violated, detail := rule.check(info); violated {
+				violations = append(violations, violation{url: info.URL, rule: rule.name, detail: detail})
+			}
+		}
+	}
+	if scanFailed {
+		for _, err := range errs {
+			violations = append(violations, violation{rule: "scan-failed", detail: err.Error()})
+		}
+	}
+	return violations
+}
+
+// logViolations logs the violations grouped by condition, as the summary of a failed gate
+func logViolations(violations []violation) {
+	byRule := map[string][]violation{}
+	var names []string
+	for _, v := range violations {
+		if byRule[v.rule] == nil {
+			names = append(names, v.rule)
+		}
+		byRule[v.rule] = append(byRule[v.rule], v)
+	}
+	for _, name := range names {
+		for _, v := range byRule[name] {
+			attrs := []any{"condition", name, "reason", v.detail}
+			if v.url != "" {
+				attrs = append(attrs, "url", v.url)
+			}
+			slog.Warn("Fail-on condition violated", attrs...)
+		}
+	}
+}
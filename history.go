@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+)
+
+// runHistory prints a site's scans recorded in the SQLite database, with the TTFB trend and
+// the version changes between scans
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	dbPath := fs.String("db", "scans.db", "SQLite database written with -db")
+	registerErrorFormat(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: site-info-fetcher history [flags] <url>\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	url := fs.Arg(0)
+
+	if _, err := os.Stat(*dbPath); err != nil {
+		fail(codeHistory, err)
+	}
+	history, err := report.History(*dbPath, url)
+	if err != nil {
+		fail(codeHistory, err)
+	}
+	if len(history) == 0 {
+		fail(codeHistory, errors.New(url+" has no scans in "+*dbPath))
+	}
+
+	for i, entry := range history {
+		ssl := "SSL valid"
+		if !entry.SSLValid {
+			ssl = "SSL invalid"
+		}
+		line := fmt.Sprintf("%s  TTFB %8.1fms", entry.ScannedAt.Local().Format("2006-01-02 15:04"), entry.AverageTTFB)
+		if i > 0 {
+			line += fmt.Sprintf(" (%+.1f)", entry.AverageTTFB-history[i-1].AverageTTFB)
+		}
+		server := strings.TrimSpace(entry.WebServer + " " + entry.WebServerVersion)
+		fmt.Printf("%s  PHP %s  WordPress %s  %s  %s\n", line, orDash(entry.PHPVersion), orDash(entry.WordPressVersion), orDash(server), ssl)
+		if i == 0 {
+			continue
+		}
+
+		previous := history[i-1]
+		for _, change := range []struct{ name, before, after string }{
+			{"PHP", previous.PHPVersion, entry.PHPVersion},
+			{"MySQL", previous.MySQLVersion, entry.MySQLVersion},
+			{"WordPress", previous.WordPressVersion, entry.WordPressVersion},
+			{"Web server", strings.TrimSpace(previous.WebServer + " " + previous.WebServerVersion), strings.TrimSpace(entry.WebServer + " " + entry.WebServerVersion)},
+			{"PHP status", previous.PHPStatus, entry.PHPStatus},
+			{"WordPress status", previous.WordPressStatus, entry.WordPressStatus},
+		} {
+			if change.before != change.after {
+				fmt.Printf("    %s changed: %s -> %s\n", change.name, orDash(change.before), orDash(change.after))
+			}
+		}
+	}
+}
+
+// orDash returns the value, or a dash when it is empty
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// logLevel is the lowest level logged, set by -log-level
+var logLevel = new(slog.LevelVar)
+
+// logFormat is how log records are written: text, or json for log aggregators
+var logFormat = "text"
+
+// registerLogFlags adds the -log-level and -log-format flags to the flag set
+func registerLogFlags(fs *flag.FlagSet) {
+	fs.Func("log-level", "log messages at this level and above: debug, info, warn or error (default info)", func(value string) error {
+		return logLevel.UnmarshalText([]byte(value))
+	})
+	fs.Func("log-format", "write the log as text or json, one object per line (default text)", func(value string) error {
+		if value != "text" && value != "json" {
+			return fmt.Errorf("use text or json")
+		}
+		logFormat = value
+		return nil
+	})
+}
+
+// setupLogging makes the default logger write to stderr at -log-level in -log-format, so
+// stdout only carries the results of commands that print them
+func setupLogging() {
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if logFormat == "json" {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
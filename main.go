@@ -2,461 +2,874 @@ package main
 
 import (
 	"bufio"
-	"crypto/tls"
+	"cmp"
+	"context"
 	"encoding/csv"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"net/http"
-	"net/http/httptrace"
+	"log/slog"
+	"math/rand"
+	"net"
 	"os"
-	"regexp"
-	"sort"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/sheets"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
 )
 
-// SiteInfo holds the information about the WordPress site
-type SiteInfo struct {
-	URL              string
-	PHPVersion       string
-	MySQLVersion     string
-	WordPressVersion string
-	Caching          bool
-	CacheControl     string
-	WebServer        string
-	WebServerVersion string
-	SSLValid         string
-	TTFBs            []time.Duration
-	AverageTTFB      time.Duration
-	XPoweredBy       string
-	PHPStatus        string
-	MySQLStatus      string
-	WebServerStatus  string
-	WordPressStatus  string
+// version is the tool version recorded in run manifests. Release builds can set it with
+// -ldflags "-X main.version=<version>".
+var version = "1.0"
+
+// inputColumns are the columns read from the input CSV file or worksheet. Optional columns
+// are negative when not given.
+type inputColumns struct {
+	url, priority, competitor, auth, cookie, header, group int
+	// urlName is the header of the URL column, which replaces url when given, and whose
+	// header row is then not read as a URL
+	urlName string
+	// sheet is the worksheet of an Excel input or tab of a Google Sheet, the first when empty
+	sheet string
+	// googleCredentials is the service account key file a Google Sheet is read with
+	googleCredentials string
 }
 
-// fetchURL fetches the URL and returns the response along with the TTFB
-func fetchURL(url string) (*http.Response, time.Duration, error) {
-	// Ensure the URL includes a protocol scheme
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		url = "http://" + url
-	}
-
-	var ttfb time.Duration
-	var resp *http.Response
-	var err error
+// urlColumn returns the input columns of a file with only URLs in the given column
+func urlColumn(column int) inputColumns {
+	return inputColumns{url: column, priority: -1, competitor: -1, auth: -1, cookie: -1, header: -1, group: -1}
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+// input is what was read from the input CSV file
+type input struct {
+	urls        []string
+	competitors map[string]bool
+	credentials map[string]*siteinfo.Credentials
+	groups      map[string]string
+}
 
-	for i := 0; i < 5; i++ {
-		start := time.Now()
+// stdinInput is the input path that reads the URL list from standard input
+const stdinInput = "-"
 
-		trace := &httptrace.ClientTrace{
-			GotFirstResponseByte: func() {
-				ttfb = time.Since(start)
-			},
+// readInput reads the URLs to scan: a plain text list from standard input for "-" or from a
+// .txt file, the given columns of a worksheet of an .xlsx workbook or a tab of a Google Sheet,
+// and otherwise the given columns of a CSV file
+func readInput(filePath string, columns inputColumns) (*input, error) {
+	if filePath == stdinInput {
+		slog.Info("Reading URLs from standard input")
+		return readURLList(os.Stdin)
+	}
+	if strings.EqualFold(filepath.Ext(filePath), ".txt") {
+		slog.Info("Reading URL list", "path", filePath)
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
 		}
-
-		req, err := http.NewRequest("GET", url, nil)
+		defer file.Close()
+		return readURLList(file)
+	}
+	if sheets.IsSheetURL(filePath) {
+		slog.Info("Reading Google Sheet", "url", filePath)
+		client, err := sheets.New(columns.googleCredentials)
 		if err != nil {
-			return nil, 0, err
+			return nil, err
 		}
-		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
-
-		resp, err = client.Do(req)
-		if err == nil {
-			return resp, ttfb, nil
+		id, err := sheets.SpreadsheetID(filePath)
+		if err != nil {
+			return nil, err
 		}
-
-		if !strings.Contains(err.Error(), "Client.Timeout exceeded while awaiting headers") {
-			return nil, 0, err
+		records, err := client.Read(context.Background(), id, columns.sheet)
+		if err != nil {
+			return nil, err
 		}
-
-		fmt.Printf("Retrying %d/5 for URL: %s\n", i+1, url)
-		time.Sleep(2 * time.Second)
+		return readRecords(records, columns)
 	}
-
-	return nil, 0, err
+	if strings.EqualFold(filepath.Ext(filePath), ".xlsx") {
+		slog.Info("Reading Excel workbook", "path", filePath)
+		records, err := readXLSX(filePath, columns.sheet)
+		if err != nil {
+			return nil, err
+		}
+		return readRecords(records, columns)
+	}
+	return readCSV(filePath, columns)
 }
 
-// checkSSL checks if the site has a valid SSL certificate
-func checkSSL(url string) (bool, error) {
-	// Ensure the URL includes a protocol scheme
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		url = "https://" + url
+// readURLList reads one URL per line, ignoring blank lines and # comments
+func readURLList(r io.Reader) (*input, error) {
+	in := &input{competitors: map[string]bool{}, credentials: map[string]*siteinfo.Credentials{}, groups: map[string]string{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if url := strings.TrimSpace(line); url != "" {
+			in.urls = append(in.urls, url)
+		}
 	}
+	return in, scanner.Err()
+}
 
-	// Remove the protocol scheme for tls.Dial
-	host := strings.TrimPrefix(url, "https://")
-	host = strings.TrimPrefix(host, "http://")
+// readCSV reads the CSV file and returns the URLs from its columns, as readRecords does
+func readCSV(filePath string, columns inputColumns) (*input, error) {
+	slog.Info("Reading CSV file", "path", filePath)
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
 
-	conn, err := tls.Dial("tcp", host+":443", nil)
+	reader := csv.NewReader(file)
+	records, err := reader.ReadAll()
 	if err != nil {
-		if strings.Contains(err.Error(), "certificate is expired") {
-			return false, fmt.Errorf("expired")
-		}
-		return false, err
+		return nil, err
 	}
-	defer conn.Close()
+	return readRecords(records, columns)
+}
 
-	// Check the certificate
-	certs := conn.ConnectionState().PeerCertificates
-	if len(certs) > 0 {
-		cert := certs[0]
-		now := time.Now()
-		if now.After(cert.NotBefore) && now.Before(cert.NotAfter) {
-			return true, nil
+// readRecords returns the URLs from the URL column of the rows. When the priority
+// column is given, the URLs are ordered by the priority in that column. When the competitor
+// column is given, the URLs marked as competitors are returned as a set. The auth, cookie and
+// header columns supply credentials for password-protected sites. The group column assigns
+// each URL to the client or group it is sampled within. When the URL column is given by name,
+// it is looked up in the header row, which is skipped.
+func readRecords(records [][]string, columns inputColumns) (*input, error) {
+	if columns.urlName != "" {
+		url, err := headerColumn(records, columns.urlName)
+		if err != nil {
+			return nil, err
 		}
+		columns.url, records = url, records[1:]
 	}
-	return false, nil
-}
 
-// parseHeaders parses the HTTP headers to extract information
-func parseHeaders(headers http.Header) (string, string, bool, string, string, string, string) {
-	var webServer, webServerVersion string
-	var caching bool
-	var cacheControl, xPoweredBy, phpVersion string
-
-	for key, values := range headers {
-		lowerKey := strings.ToLower(key)
-		for _, value := range values {
-			if lowerKey == "server" {
-				parts := strings.Split(value, "/")
-				webServer = parts[0]
-				if len(parts) > 1 {
-					webServerVersion = parts[1]
-				}
+	in := &input{competitors: map[string]bool{}, credentials: map[string]*siteinfo.Credentials{}, groups: map[string]string{}}
+	var priorities []string
+	for _, record := range records {
+		if columns.url < len(record) {
+			url := record[columns.url]
+			in.urls = append(in.urls, url)
+			var priority string
+			if columns.priority >= 0 && columns.priority < len(record) {
+				priority = record[columns.priority]
 			}
-			if lowerKey == "x-powered-by" {
-				xPoweredBy = value
-				if strings.Contains(value, "PHP") {
-					parts := strings.Split(value, "/")
-					if len(parts) > 1 {
-						phpVersion = parts[1]
-					}
-				}
+			priorities = append(priorities, priority)
+			if columns.competitor >= 0 && columns.competitor < len(record) && isCompetitorMarker(record[columns.competitor]) {
+				in.competitors[url] = true
 			}
-			if lowerKey == "cache-control" {
-				cacheControl = value
-				if strings.Contains(value, "max-age=0") {
-					caching = false
-				} else if strings.Contains(value, "max-age") {
-					caching = true
-				}
+			if creds := recordCredentials(record, columns); creds != nil {
+				in.credentials[hostname(url)] = creds
+			}
+			if columns.group >= 0 && columns.group < len(record) {
+				in.groups[url] = strings.TrimSpace(record[columns.group])
 			}
 		}
 	}
-	return phpVersion, "", caching, webServer, webServerVersion, cacheControl, xPoweredBy
+	if columns.priority >= 0 {
+		sortByPriority(in.urls, priorities)
+	}
+	return in, nil
 }
 
-// parseHTML parses the HTML content to extract the WordPress version
-func parseHTML(body string) string {
-	re := regexp.MustCompile(`content="WordPress (\d+\.\d+(\.\d+)?)"`)
-	matches := re.FindStringSubmatch(body)
-	if len(matches) > 1 {
-		return matches[1]
+// headerColumn returns the index of the column whose header in the first row matches the
+// name, ignoring case, surrounding spaces and the byte order mark Excel starts CSV files with
+func headerColumn(records [][]string, name string) (int, error) {
+	if len(records) == 0 {
+		return 0, fmt.Errorf("no header row to find column %q in", name)
 	}
-	return ""
+	for i, header := range records[0] {
+		if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(header, "\ufeff")), strings.TrimSpace(name)) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no column %q in the header row: %s", name, strings.Join(records[0], ", "))
 }
 
-// fetchSupportedVersions fetches the supported versions from the endoflife.date API
-func fetchSupportedVersions(product string) ([]map[string]interface{}, error) {
-	url := fmt.Sprintf("https://endoflife.date/api/%s.json", product)
-	resp, err := http.Get(url)
+// defaultCacheDir returns the per-user cache directory for the tool, or "" if there is none
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
 	if err != nil {
-		return nil, err
+		return ""
 	}
-	defer resp.Body.Close()
+	return filepath.Join(dir, "site-info-fetcher")
+}
 
-	var versions []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
-		return nil, err
-	}
+// promptForInput asks the user on out for the CSV file path and URL column, read from stdin.
+// The column is returned as a number, or as a header name when the answer is not a number.
+func promptForInput(out io.Writer) (string, int, string) {
+	reader := bufio.NewReader(os.Stdin)
+
+	// Prompt the user for the CSV file path
+	fmt.Fprint(out, "Enter the path to the CSV file: ")
+	csvFilePath, _ := reader.ReadString('\n')
+	csvFilePath = strings.TrimSpace(csvFilePath)
 
-	return versions, nil
+	// Prompt the user for the column containing the URLs
+	fmt.Fprint(out, "Enter the column number (starting from 0) or header name containing the URLs: ")
+	answer, _ := reader.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if column, err := strconv.Atoi(answer); err == nil || answer == "" {
+		return csvFilePath, column, ""
+	}
+	return csvFilePath, 0, answer
 }
 
-// isSupported checks if a version is supported
-func isSupported(version string, supportedVersions []map[string]interface{}) bool {
-	for _, v := range supportedVersions {
-		if cycle, ok := v["cycle"].(string); ok && strings.HasPrefix(version, cycle) {
-			if eol, ok := v["eol"].(interface{}); ok {
-				if eol == false {
-					return true
-				}
-				if eolDate, ok := eol.(string); ok {
-					eolTime, err := time.Parse("2006-01-02", eolDate)
-					if err == nil && eolTime.After(time.Now()) {
-						return true
-					}
-				}
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "daemon":
+			runDaemon(os.Args[2:])
+			return
+		case "retest":
+			runRetest(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "history":
+			runHistory(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "bench":
+			runBench(os.Args[2:])
+			return
+		case "agent":
+			runAgent(os.Args[2:])
+			return
+		case "status-page":
+			runStatusPage(os.Args[2:])
+			return
+		case "dashboard":
+			runDashboard(os.Args[2:])
+			return
+		}
+	}
+
+	inputPath := flag.String("input", "", "path to the CSV file containing the URLs, a .txt file with one URL per line, an .xlsx workbook, a Google Sheets URL, or - to read URLs from stdin")
+	column := flag.Int("column", 0, "column number containing the URLs (starting from 0)")
+	columnName := flag.String("column-name", "", "header of the column containing the URLs, e.g. \"Website URL\", instead of -column; the header row is not scanned")
+	sheet := flag.String("sheet", "", "worksheet of an .xlsx input or tab of a Google Sheet to read (default the first)")
+	googleCredentials := flag.String("google-credentials", "", "service account JSON key file for reading and writing Google Sheets (or set "+sheets.CredentialsEnv+")")
+	competitorColumn := flag.Int("competitor-column", -1, "column marking competitor sites (competitor, yes, true or 1); client sites are benchmarked against them")
+	authColumn := flag.Int("auth-column", -1, "column holding user:password basic auth credentials for protected sites")
+	cookieColumn := flag.Int("cookie-column", -1, "column holding cookies to send to each site, as name=value; name2=value2")
+	headerColumn := flag.Int("header-column", -1, "column holding headers to send to each site, as Name: value; Name2: value2")
+	groupColumn := flag.Int("group-column", -1, "column holding each site's client or group, which -sample draws from in proportion")
+	priorityColumn := flag.Int("priority-column", -1, "column holding each site's scan priority (critical, high, normal, low or a number, lowest first)")
+	outputPath := flag.String("output", "", "path to the output file (default site_info_<timestamp>.<format>)")
+	singleURL := flag.String("url", "", "scan a single site instead of reading a CSV file")
+	format := flag.String("format", "csv", "output format: "+strings.Join(report.Formats(), ", "))
+	encryptKey := flag.String("encrypt-key", "", "encrypt the report into an AES-256 ZIP archive with this key (or set SITE_INFO_ENCRYPT_KEY)")
+	summary := flag.Bool("summary", false, "also write portfolio statistics to <output>_summary.csv (or .json)")
+	percentileDB := flag.String("percentile-db", "", "rank sites by percentile against the anonymized previous scans in this file, then add this scan to it")
+	wpCLIDir := flag.String("wp-cli-scripts", "", "directory to write a script of suggested wp-cli remediation commands for each WordPress site")
+	detailsDir := flag.String("details", "", "directory to write each site's full findings, raw headers and certificate chain as JSON, referenced by the Detail File column")
+	clickjackingDir := flag.String("clickjacking-poc", "", "directory to write clickjacking test pages for sites without frame protection")
+	columnList := flag.String("columns", "", "comma-separated CSV columns to write, in order, e.g. url,wordpress_version,ssl_valid (default all)")
+	templatePath := flag.String("template", "", "render the results through this Go text/template file, executed with the list of scanned sites")
+	configPath := flag.String("config", "", "path to a YAML scanning profile; command-line flags take precedence")
+	scan := registerScanFlags(flag.CommandLine)
+	registerErrorFormat(flag.CommandLine)
+	var outs stringList
+	flag.Var(&outs, "out", "write the results as format=path, e.g. json=report.json; repeat to write several outputs from one scan")
+	dbPath := flag.String("db", "", "also append every result to this SQLite database, for the history command")
+	var maxAge time.Duration
+	flag.Func("max-age", "with -db, reuse the results of sites scanned within this age, e.g. 7d or 12h, and only rescan the others", func(value string) (err error) {
+		maxAge, err = parseAge(value)
+		return err
+	})
+	checkpointPath := flag.String("checkpoint", "", "save each site's result to this state file as soon as it is scanned, so an interrupted run can be resumed")
+	resume := flag.Bool("resume", false, "continue an interrupted run, skipping the sites already saved in the -checkpoint file")
+	sampleSize := flag.String("sample", "", "scan only a random sample of the sites, as a number (500) or percentage (5%), and estimate the portfolio statistics from it")
+	seed := flag.Int64("seed", 0, "random seed for -sample, to draw the same sample again (default random)")
+	webhookURL := flag.String("webhook", "", "POST each site's result as JSON to this URL as soon as it is scanned")
+	webhookSecret := flag.String("webhook-secret", "", "sign webhook deliveries with HMAC-SHA256 using this secret (or set "+webhookSecretEnv+")")
+	var failOn stringList
+	flag.Var(&failOn, "fail-on", "exit with status 3 when a site breaks this condition, e.g. outdated-php, ssl-expired or ttfb>1500ms (repeatable or comma-separated)")
+	var notify notifySinks
+	flag.StringVar(&notify.Slack, "notify-slack", "", "post a summary of the run to this Slack incoming webhook URL")
+	flag.StringVar(&notify.Teams, "notify-teams", "", "post a summary of the run to this Microsoft Teams incoming webhook URL")
+	tuiMode := flag.Bool("tui", false, "show the scan in an interactive terminal UI: a live, sortable table of the sites, in which each site's findings can be inspected and the site scanned again")
+	webAddr := flag.String("web", "", "once the scan finishes, serve the results as a searchable dashboard at this address, e.g. :8080, until interrupted")
+	flag.BoolVar(&quiet, "quiet", false, "log only errors and show no progress bar, e.g. for cron jobs")
+	registerLogFlags(flag.CommandLine)
+	flag.Parse()
+	startedAt := time.Now()
+
+	// Load the scanning profile for any settings not given on the command line
+	if *configPath != "" {
+		cfg, err := loadConfig(*configPath)
+		if err == nil {
+			err = cfg.apply(flag.CommandLine)
+		}
+		if err != nil {
+			fail(codeConfig, err)
+		}
+	}
+	if quiet {
+		logLevel.Set(slog.LevelError)
+	}
+	setupLogging()
+
+	// Check the CI gate's conditions before scanning, so a typo does not cost a whole run
+	gateRules, gateScanFailed, err := parseFailOn(failOn)
+	if err != nil {
+		fail(codeFailOn, err)
+	}
+
+	// Make a user-defined template available as the template output format, which it
+	// becomes unless -out names the outputs
+	if *templatePath != "" {
+		tmpl, err := report.ParseTemplate(*templatePath)
+		if err != nil {
+			fail(codeTemplate, err)
+		}
+		report.Register("template", func(destination string) (report.OutputWriter, error) {
+			return report.NewTemplateWriter(tmpl, destination)
+		})
+		*format = "template"
+	}
+
+	// Write results to a new tab of a Google Sheet named by -output, authenticating with the
+	// service account only when the format is used
+	report.Register("gsheet", func(destination string) (report.OutputWriter, error) {
+		client, err := sheets.New(*googleCredentials)
+		if err != nil {
+			return nil, err
+		}
+		return report.NewSheetsWriter(client, destination)
+	})
+
+	// Collect the outputs, which are all written in one pass once the scan finishes, apart
+	// from the streamed ones written as each site finishes
+	var outputs []output
+	for _, value := range outs {
+		out, err := parseOutput(value)
+		if err != nil {
+			fail(codeOutputSpec, err)
+		}
+		outputs = append(outputs, out)
+	}
+	if len(outputs) > 0 && *outputPath != "" {
+		fail(codeOutputConflict, nil)
+	}
+	if len(outputs) == 0 && !report.HasFormat(*format) {
+		fail(codeOutputFormat, errors.New(*format))
+	}
+
+	// Without -out, write a single report, generating the output file name with timestamp
+	if len(outputs) == 0 {
+		outputFilePath := *outputPath
+		if outputFilePath == "" && *format == "gsheet" {
+			fail(codeGoogleSheet, nil)
+		}
+		if outputFilePath == "" {
+			timestamp := time.Now().Format("20060102_150405")
+			outputFilePath = fmt.Sprintf("site_info_%s.%s", timestamp, *format)
+			if *format == "template" {
+				outputFilePath = fmt.Sprintf("site_info_%s%s", timestamp, templateExtension(*templatePath))
 			}
 		}
+		outputs = []output{{format: *format, path: outputFilePath}}
+		if err := outputs[0].check(); err != nil {
+			fail(codeOutputFormat, err)
+		}
+	}
+	if *dbPath != "" {
+		outputs = append(outputs, output{format: "sqlite", path: *dbPath})
+	}
+
+	// Open the streamed outputs before anything is printed. When one goes to stdout, the
+	// prompts and the terminal UI use stderr instead so the stream can be piped.
+	stream, batch, err := openStreams(outputs)
+	if err != nil {
+		fail(codeWriteReport, err)
+	}
+	console := os.Stdout
+	for _, out := range outputs {
+		if out.path == report.Stdout {
+			console = os.Stderr
+			break
+		}
 	}
-	return false
-}
 
-// getSupportStatus checks if the versions are supported
-func getSupportStatus(phpVersion, mysqlVersion, wpVersion, webServer, webServerVersion string) (string, string, string, string) {
-	phpStatus := "Unknown"
-	mysqlStatus := "Unknown"
-	wpStatus := "Unknown"
-	webServerStatus := "Unknown"
+	var urls []string
+	var competitors map[string]bool
+	var credentials map[string]*siteinfo.Credentials
+	var groups map[string]string
+	if *singleURL != "" {
+		urls = []string{*singleURL}
+	} else {
+		// Take the input as an argument, e.g. - to read URLs piped to the tool, and fall back
+		// to interactive prompts when no input was given on the command line
+		if *inputPath == "" && flag.NArg() > 0 {
+			*inputPath = flag.Arg(0)
+		}
+		if *inputPath == "" {
+			*inputPath, *column, *columnName = promptForInput(console)
+		}
 
-	phpVersions, err := fetchSupportedVersions("PHP")
-	if err == nil && phpVersion != "" {
-		if isSupported(phpVersion, phpVersions) {
-			phpStatus = "Supported"
-		} else {
-			phpStatus = "Outdated"
+		// Read URLs from the input file
+		columns := inputColumns{url: *column, priority: *priorityColumn, competitor: *competitorColumn,
+			auth: *authColumn, cookie: *cookieColumn, header: *headerColumn, group: *groupColumn, urlName: *columnName, sheet: *sheet,
+			googleCredentials: *googleCredentials}
+		in, err := readInput(*inputPath, columns)
+		if err != nil {
+			fail(codeInput, err)
 		}
+		urls, competitors, credentials, groups = in.urls, in.competitors, in.credentials, in.groups
 	}
 
-	mysqlVersions, err := fetchSupportedVersions("mysql")
-	if err == nil && mysqlVersion != "" {
-		if isSupported(mysqlVersion, mysqlVersions) {
-			mysqlStatus = "Supported"
-		} else {
-			mysqlStatus = "Outdated"
+	// Apply the include, exclude and blocklist rules to the input
+	targets, err := scan.targetFilter()
+	if err != nil {
+		fail(codeTargetFilter, err)
+	}
+	urls = targets.filter(urls)
+
+	// Scan a random sample of a huge portfolio, drawn from every group in proportion to its
+	// size, for a quick estimate of its health
+	var weights map[string]float64
+	portfolio := len(urls)
+	if *sampleSize != "" && len(urls) > 0 {
+		size, err := parseSampleSize(*sampleSize, len(urls))
+		if err != nil {
+			fail(codeSample, err)
 		}
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		urls, weights = stratifiedSample(urls, groups, size, rand.New(rand.NewSource(*seed)))
+		slog.Info("Sampling sites", "sample", len(urls), "sites", portfolio, "seed", *seed)
 	}
 
-	wpVersions, err := fetchSupportedVersions("WordPress")
-	if err == nil && wpVersion != "" {
-		if isSupported(wpVersion, wpVersions) {
-			wpStatus = "Supported"
-		} else {
-			wpStatus = "Outdated"
+	opts, closeAudit, err := scan.options()
+	if err != nil {
+		fail(codeScannerConfig, err)
+	}
+	defer closeAudit()
+	opts.Details = *detailsDir != ""
+
+	// Add the custom fields of the detection rules and the agents' regions to the report
+	// columns, then write only the chosen CSV columns, in the order given
+	if opts.CustomFields != nil {
+		if err := report.AddCustomColumns(opts.CustomFields.Names()); err != nil {
+			fail(codeScannerConfig, err)
+		}
+	}
+	if len(opts.Agents) > 0 {
+		var regions []string
+		for _, agent := range opts.Agents {
+			regions = append(regions, agent.Region)
+		}
+		report.AddRegionColumns(regions)
+	}
+	if *columnList != "" {
+		factory, err := report.CSVColumns(strings.Split(*columnList, ","))
+		if err != nil {
+			fail(codeColumns, err)
 		}
+		report.Register("csv", factory)
 	}
 
-	if webServer != "" && webServerVersion != "" {
-		webServerVersions, err := fetchSupportedVersions(webServer)
-		if err == nil {
-			if isSupported(webServerVersion, webServerVersions) {
-				webServerStatus = "Supported"
-			} else {
-				webServerStatus = "Outdated"
+	// Credentials from the input file take precedence over the credentials file
+	if len(credentials) > 0 && opts.Credentials == nil {
+		opts.Credentials = map[string]*siteinfo.Credentials{}
+	}
+	for host, creds := range credentials {
+		opts.Credentials[host] = creds
+	}
+
+	// Save each result as it completes, skipping the sites an interrupted run already scanned
+	completed := map[string]*siteinfo.SiteInfo{}
+	pending := urls
+	var state *checkpoint
+	if *resume && *checkpointPath == "" {
+		fail(codeResume, nil)
+	}
+	if *checkpointPath != "" {
+		if *resume {
+			completed, err = loadCheckpoint(*checkpointPath)
+			if err != nil {
+				fail(codeCheckpoint, err)
 			}
+			pending = nil
+			for _, url := range urls {
+				if completed[url] == nil {
+					pending = append(pending, url)
+				}
+			}
+			slog.Info("Resuming", "scanned", len(urls)-len(pending), "sites", len(urls))
+		}
+		state, err = openCheckpoint(*checkpointPath, *resume)
+		if err != nil {
+			fail(codeCheckpoint, err)
 		}
+		opts.OnScanned = state.record
 	}
 
-	return phpStatus, mysqlStatus, webServerStatus, wpStatus
-}
-
-// getSiteInfo gets the site information for a given URL
-func getSiteInfo(url string) (*SiteInfo, error) {
-	var ttfs []time.Duration
-	for i := 0; i < 3; i++ {
-		resp, ttfb, err := fetchURL(url)
+	// Reuse the results of sites scanned recently enough, rescanning only the stale ones
+	reused := map[string]bool{}
+	if maxAge > 0 {
+		if *dbPath == "" {
+			fail(codeMaxAge, nil)
+		}
+		recent, err := report.RecentScans(*dbPath, time.Now().Add(-maxAge))
 		if err != nil {
-			return nil, fmt.Errorf("error fetching URL %s: %w", url, err)
+			fail(codeHistory, err)
 		}
-		if resp == nil {
-			return nil, fmt.Errorf("no response for URL %s after retries", url)
+		var stale []string
+		for _, url := range pending {
+			if info := recent[url]; info != nil {
+				completed[url] = info
+				reused[url] = true
+			} else {
+				stale = append(stale, url)
+			}
 		}
-		defer resp.Body.Close()
-		ttfs = append(ttfs, ttfb)
+		pending = stale
+		slog.Info("Reusing recent results", "reused", len(reused), "max_age", maxAge, "rescanning", len(pending))
 	}
 
-	// Calculate the average TTFB
-	var totalTTFB time.Duration
-	for _, ttfb := range ttfs {
-		totalTTFB += ttfb
+	// Stream each result to the webhook as it completes
+	var hook *webhook
+	if *webhookURL != "" {
+		if err := checkWebhookURL(*webhookURL); err != nil {
+			fail(codeWebhook, err)
+		}
+		hook = newWebhook(*webhookURL, cmp.Or(*webhookSecret, os.Getenv(webhookSecretEnv)))
+		record := opts.OnScanned
+		opts.OnScanned = func(url string, info *siteinfo.SiteInfo, err error) {
+			if record != nil {
+				record(url, info, err)
+			}
+			hook.record(url, info, err)
+		}
 	}
-	averageTTFB := totalTTFB / 3
 
-	// Sort TTFBs in order of longest to shortest latency
-	sort.Slice(ttfs, func(i, j int) bool {
-		return ttfs[i] > ttfs[j]
-	})
+	if err := notify.validate(); err != nil {
+		fail(codeWebhook, err)
+	}
 
-	// Print TTFB tests and average in the terminal
-	fmt.Printf("Fetching site info for URL: %s - TTFB1: %.3fms, TTFB2: %.3fms, TTFB3: %.3fms, Average TTFB: %.3fms\n",
-		url, ttfs[0].Seconds()*1000, ttfs[1].Seconds()*1000, ttfs[2].Seconds()*1000, averageTTFB.Seconds()*1000)
+	// Take the dashboard's port now, so one in use does not cost a whole run
+	var web net.Listener
+	if *webAddr != "" {
+		web = listenDashboard(*webAddr)
+	}
 
-	resp, _, err := fetchURL(url)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching URL %s: %w", url, err)
+	// Stream each result, or the failure of a site that could not be scanned, to the streamed
+	// outputs as it completes, starting with the sites an interrupted run already scanned
+	if stream != nil {
+		var mu sync.Mutex
+		for _, url := range urls {
+			if info := completed[url]; info != nil {
+				stream.Write(info)
+			}
+		}
+		record := opts.OnScanned
+		opts.OnScanned = func(url string, info *siteinfo.SiteInfo, err error) {
+			if record != nil {
+				record(url, info, err)
+			}
+			if err != nil {
+				info = siteinfo.FailedSite(url, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err := stream.Write(info); err != nil {
+				slog.Error("Error streaming result", "url", url, "error", err)
+			}
+		}
 	}
-	if resp == nil {
-		return nil, fmt.Errorf("no response for URL %s after retries", url)
+
+	// The terminal UI shows each site's progress and the last of the scanner's warnings
+	var ui *tui
+	if *tuiMode {
+		ui, err = newTUI(console, pending)
+		if err != nil {
+			fail(codeTUI, err)
+		}
+		opts.Logger = slog.New(slog.NewTextHandler(ui, &slog.HandlerOptions{Level: max(logLevel.Level(), slog.LevelWarn)}))
+		record := opts.OnScanned
+		opts.OnScanned = func(url string, info *siteinfo.SiteInfo, err error) {
+			if record != nil {
+				record(url, info, err)
+			}
+			ui.scanned(url, info, err)
+		}
+		opts.OnStarted = ui.started
 	}
-	defer resp.Body.Close()
 
-	phpVersion, mysqlVersion, caching, webServer, webServerVersion, cacheControl, xPoweredBy := parseHeaders(resp.Header)
+	// On a terminal, a progress bar replaces the scanner's messages about each site, and its
+	// warnings are printed above the bar
+	var bar *progress
+	if ui == nil && !quiet && isTerminal(os.Stderr) && len(pending) > 1 && logFormat == "text" && logLevel.Level() >= slog.LevelInfo {
+		bar = startProgress(os.Stderr, len(pending))
+		opts.Logger = slog.New(slog.NewTextHandler(bar, &slog.HandlerOptions{Level: max(logLevel.Level(), slog.LevelWarn)}))
+		record := opts.OnScanned
+		opts.OnScanned = func(url string, info *siteinfo.SiteInfo, err error) {
+			if record != nil {
+				record(url, info, err)
+			}
+			bar.record(err)
+		}
+	}
 
-	// Read the body
-	buf := new(strings.Builder)
-	_, err = io.Copy(buf, resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading response body for URL %s: %w", url, err)
-	}
-	body := buf.String()
-
-	wpVersion := parseHTML(body)
-
-	// Check SSL certificate
-	sslValid, sslErr := checkSSL(url)
-	if sslErr != nil {
-		if sslErr.Error() == "expired" {
-			return &SiteInfo{
-				URL:      url,
-				SSLValid: "Expired",
-			}, nil
-		}
-		return nil, sslErr
-	}
-
-	// Get support status
-	phpStatus, mysqlStatus, webServerStatus, wpStatus := getSupportStatus(phpVersion, mysqlVersion, wpVersion, webServer, webServerVersion)
-
-	return &SiteInfo{
-		URL:              url,
-		PHPVersion:       phpVersion,
-		MySQLVersion:     mysqlVersion,
-		WordPressVersion: wpVersion,
-		Caching:          caching,
-		CacheControl:     cacheControl,
-		WebServer:        webServer,
-		WebServerVersion: webServerVersion,
-		SSLValid:         fmt.Sprintf("%t", sslValid),
-		TTFBs:            ttfs,
-		AverageTTFB:      averageTTFB,
-		XPoweredBy:       xPoweredBy,
-		PHPStatus:        phpStatus,
-		MySQLStatus:      mysqlStatus,
-		WebServerStatus:  webServerStatus,
-		WordPressStatus:  wpStatus,
-	}, nil
-}
+	scanner := siteinfo.New(opts)
 
-// readCSV reads the CSV file and returns the URLs from the specified column
-func readCSV(filePath string, column int) ([]string, error) {
-	fmt.Printf("Reading CSV file: %s\n", filePath) // Debugging output
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
+	// On SIGINT or SIGTERM, stop starting scans, abandon those in flight and write the results
+	// completed so far rather than losing the run. A second signal exits at once.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	var scanned []*siteinfo.SiteInfo
+	var errs []error
+	if ui != nil {
+		scanned, errs, err = ui.run(ctx, stop, scanner)
+		if err != nil {
+			fail(codeTUI, err)
+		}
+	} else {
+		scanned, errs = scanner.ScanAll(ctx, pending)
 	}
-	defer file.Close()
+	interrupted := ctx.Err() != nil
+	stop()
+	if bar != nil {
+		bar.finish()
+	}
+	siteInfos := mergeCompleted(urls, completed, scanned)
+	if interrupted {
+		slog.Warn("Interrupted: writing the sites scanned so far", "scanned", len(siteInfos), "sites", len(urls))
+	}
+	if state != nil {
+		state.close()
+	}
+	if hook != nil {
+		if failed := hook.close(); failed > 0 {
+			slog.Error("Webhook deliveries failed", "failed", failed)
+		}
+	}
+	for _, err := range errs {
+		slog.Error("Error fetching site info", "error", err)
+	}
+	if len(errs) > 0 {
+		slog.Warn("Some sites could not be scanned", "failed", len(errs), "sites", len(urls))
+	}
+	usage := scanner.Usage()
+	slog.Info("Resource usage", "usage", usage)
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, err
+	// Rank the sites against previous scans, then add this scan to the dataset
+	if *percentileDB != "" {
+		dataset, err := report.LoadDataset(*percentileDB)
+		if err == nil {
+			dataset.Rank(siteInfos)
+			dataset.Add(siteInfos)
+			err = dataset.Save(*percentileDB)
+		}
+		if err != nil {
+			slog.Error("Error updating percentile dataset", "error", err)
+		}
 	}
 
-	var urls []string
-	for _, record := range records {
-		if column < len(record) {
-			urls = append(urls, record[column])
+	// Generate clickjacking proof-of-concept pages for unprotected sites
+	if *clickjackingDir != "" {
+		if err := report.WriteClickjackingPoCs(*clickjackingDir, siteInfos); err != nil {
+			slog.Error("Error writing clickjacking test pages", "error", err)
 		}
 	}
-	return urls, nil
-}
 
-// writeCSV writes the site information to a CSV file
-func writeCSV(filePath string, siteInfos []*SiteInfo) error {
-	fmt.Printf("Writing results to CSV file: %s\n", filePath) // Debugging output
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
+	// Generate wp-cli remediation scripts for hosts with SSH access to the sites
+	if *wpCLIDir != "" {
+		if err := report.WriteWPCLIScripts(*wpCLIDir, siteInfos); err != nil {
+			slog.Error("Error writing wp-cli scripts", "error", err)
+		}
 	}
-	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	// Write header
-	writer.Write([]string{"URL", "PHP Version", "MySQL Version", "WordPress Version", "Caching", "Cache Control", "Web Server", "Web Server Version", "SSL Valid", "TTFB1 - Longest (ms)", "TTFB2 (ms)", "TTFB3 - Shortest (ms)", "Average TTFB (ms)", "X-Powered-By", "PHP Status", "MySQL Status", "Web Server Status", "WordPress Status"})
-
-	// Write site information
-	for _, info := range siteInfos {
-		ttfb1 := ""
-		ttfb2 := ""
-		ttfb3 := ""
-		averageTTFB := ""
-
-		if len(info.TTFBs) > 0 {
-			ttfb1 = fmt.Sprintf("%.3f", info.TTFBs[0].Seconds()*1000) // TTFB1 - Longest in ms
-		}
-		if len(info.TTFBs) > 1 {
-			ttfb2 = fmt.Sprintf("%.3f", info.TTFBs[1].Seconds()*1000) // TTFB2 in ms
-		}
-		if len(info.TTFBs) > 2 {
-			ttfb3 = fmt.Sprintf("%.3f", info.TTFBs[2].Seconds()*1000) // TTFB3 - Shortest in ms
-		}
-		if info.AverageTTFB != 0 {
-			averageTTFB = fmt.Sprintf("%.3f", info.AverageTTFB.Seconds()*1000) // Average TTFB in ms
-		}
-
-		writer.Write([]string{
-			info.URL,
-			info.PHPVersion,
-			info.MySQLVersion,
-			info.WordPressVersion,
-			fmt.Sprintf("%t", info.Caching),
-			info.CacheControl,
-			info.WebServer,
-			info.WebServerVersion,
-			info.SSLValid,
-			ttfb1,
-			ttfb2,
-			ttfb3,
-			averageTTFB,
-			info.XPoweredBy,
-			info.PHPStatus,
-			info.MySQLStatus,
-			info.WebServerStatus,
-			info.WordPressStatus,
-		})
+	// Write each site's full findings to its own file, referenced from the compact report
+	if *detailsDir != "" {
+		if err := report.WriteDetails(*detailsDir, siteInfos); err != nil {
+			slog.Error("Error writing detail files", "error", err)
+		}
 	}
-	return nil
-}
 
-func main() {
-	reader := bufio.NewReader(os.Stdin)
+	// Finish the streamed outputs, then write the results in the other requested formats
+	if stream != nil {
+		if err := stream.Flush(); err != nil {
+			fail(codeWriteReport, err)
+		}
+	}
+	for _, out := range batch {
+		slog.Info("Writing results", "format", out.format, "path", out.path)
+	}
+	if err := writeOutputs(batch, siteInfos, reused, urls, errs); err != nil {
+		fail(codeWriteReport, err)
+	}
 
-	// Prompt the user for the CSV file path
-	fmt.Print("Enter the path to the CSV file: ")
-	csvFilePath, _ := reader.ReadString('\n')
-	csvFilePath = strings.TrimSpace(csvFilePath)
+	// The results are safely written, so the run no longer needs resuming
+	if *checkpointPath != "" && !interrupted {
+		os.Remove(*checkpointPath)
+	}
 
-	// Prompt the user for the column number containing the URLs
-	fmt.Print("Enter the column number containing the URLs (starting from 0): ")
-	var column int
-	fmt.Scanf("%d", &column)
+	// The outputs written to files, rather than streamed to stdout or sent to Google Sheets,
+	// are the ones recorded in the manifest. The first of them names the summary, comparison
+	// and manifest files.
+	var files []output
+	for _, out := range outputs {
+		switch {
+		case out.format == "gsheet":
+			slog.Info("Site information written to a new tab", "url", out.path)
+		case out.path != report.Stdout:
+			files = append(files, out)
+		}
+	}
+	var outputFilePath, outputFormat string
+	if len(files) > 0 {
+		outputFilePath, outputFormat = files[0].path, files[0].format
+	}
 
-	// Read URLs from the CSV file
-	urls, err := readCSV(csvFilePath, column)
-	if err != nil {
-		fmt.Printf("Error reading CSV file: %v\n", err)
-		return
+	// Aggregate version distributions, PHP support, TTFB and certificate expiry across the
+	// portfolio, extrapolated from the sample when only a sample was scanned
+	portfolioSummary := report.SummarizeWeighted(siteInfos, weights)
+	if weights != nil {
+		logEstimate(portfolioSummary, portfolio)
+	}
+	ext := filepath.Ext(outputFilePath)
+	var summaryPath string
+	if *summary && outputFilePath != "" {
+		summaryPath = strings.TrimSuffix(outputFilePath, ext) + "_summary" + ext
+		if err := report.WriteSummary(summaryPath, outputFormat, portfolioSummary); err != nil {
+			fail(codeWriteReport, fmt.Errorf("summary: %w", err))
+		}
 	}
 
-	var siteInfos []*SiteInfo
-	for _, url := range urls {
-		info, err := getSiteInfo(url)
+	// Benchmark the client sites against the competitors marked in the input
+	var comparisonPath string
+	if len(competitors) > 0 && outputFilePath != "" {
+		comparisonPath = strings.TrimSuffix(outputFilePath, ext) + "_competitors" + ext
+		err = report.WriteCompetitorReport(comparisonPath, outputFormat, report.CompareCompetitors(siteInfos, competitors))
 		if err != nil {
-			fmt.Printf("Error fetching site info for %s: %v\n", url, err)
-			continue
+			fail(codeWriteReport, fmt.Errorf("competitor comparison: %w", err))
 		}
-		siteInfos = append(siteInfos, info)
 	}
 
-	// Generate the output file name with timestamp
-	timestamp := time.Now().Format("20060102_150405")
-	outputFilePath := fmt.Sprintf("site_info_%s.csv", timestamp)
+	// Encrypt the report with the client's key so it can be emailed safely
+	key := *encryptKey
+	if key == "" {
+		key = os.Getenv("SITE_INFO_ENCRYPT_KEY")
+	}
+	if key != "" {
+		var err error
+		for i := range files {
+			// The database is appended to by later runs, so it stays unencrypted
+			if err == nil && files[i].format != "sqlite" {
+				files[i].path, err = report.Encrypt(files[i].path, key)
+			}
+		}
+		if len(files) > 0 {
+			outputFilePath = files[0].path
+		}
+		if err == nil && summaryPath != "" {
+			summaryPath, err = report.Encrypt(summaryPath, key)
+		}
+		if err == nil && comparisonPath != "" {
+			comparisonPath, err = report.Encrypt(comparisonPath, key)
+		}
+		if err != nil {
+			fail(codeEncrypt, err)
+		}
+	}
 
-	// Write the results to a CSV file
-	err = writeCSV(outputFilePath, siteInfos)
-	if err != nil {
-		fmt.Printf("Error writing CSV file: %v\n", err)
-		return
+	for _, out := range files {
+		slog.Info("Site information written", "path", out.path)
+	}
+	if summaryPath != "" {
+		slog.Info("Portfolio summary written", "path", summaryPath)
+	}
+	if comparisonPath != "" {
+		slog.Info("Competitor comparison written", "path", comparisonPath)
+	}
+
+	// Tell the team the run has finished, with the problems worth a look
+	if notify.enabled() {
+		if err := notify.send(runSummary("site-info-fetcher: run finished", len(urls), siteInfos, errs)); err != nil {
+			slog.Error("Error sending notification", "error", err)
+		}
+	}
+
+	// Gate the pipeline on the -fail-on conditions, exiting non-zero once the reports and
+	// manifest are written so the results can be inspected. A violation among the sites that
+	// were scanned fails the gate even if the run was interrupted, so the gate's status takes
+	// precedence over the interruption's.
+	var exitCode string
+	var exitErr error
+	if interrupted {
+		exitCode, exitErr = codeInterrupted, fmt.Errorf("%d of %d sites were not scanned", len(urls)-len(siteInfos)-len(errs), len(urls))
+	}
+	if violations := checkGate(gateRules, gateScanFailed, siteInfos, errs); len(violations) > 0 {
+		logViolations(violations)
+		exitCode, exitErr = codeGate, fmt.Errorf("%d violations of %s", len(violations), strings.Join(failOn, ", "))
+		if interrupted {
+			exitErr = fmt.Errorf("%w (run interrupted)", exitErr)
+		}
+	}
+	if exitCode != "" {
+		defer fail(exitCode, exitErr)
+	}
+
+	// Serve the results once the reports and manifest are written, before exiting with the
+	// gate's status
+	if web != nil {
+		defer serveDashboard(web, &report.Dashboard{Title: dashboardTitle, GeneratedAt: time.Now(), Sites: siteInfos, DB: *dbPath})
 	}
 
-	fmt.Printf("Site information written to %s\n", outputFilePath)
+	// Record the run alongside the report so the audit can be traced and reproduced. A run
+	// only streamed to stdout has no report to record it alongside.
+	if outputFilePath == "" {
+		return
+	}
+	manifest := &report.Manifest{
+		ToolVersion:   version,
+		StartedAt:     startedAt,
+		FinishedAt:    time.Now(),
+		OutputFile:    outputFilePath,
+		URLs:          len(urls),
+		Scanned:       len(siteInfos),
+		Failed:        len(errs),
+		Interrupted:   interrupted,
+		ResourceUsage: &usage,
+	}
+	if weights != nil {
+		manifest.SampledFrom, manifest.SampleSeed = portfolio, *seed
+	}
+	for _, err := range errs {
+		manifest.Failures = append(manifest.Failures, err.Error())
+	}
+	if *singleURL == "" && *inputPath != stdinInput {
+		manifest.InputFile = *inputPath
+		manifest.InputSHA256, _ = report.FileSHA256(*inputPath)
+	}
+	if *configPath != "" {
+		manifest.ConfigFile = *configPath
+		manifest.ConfigSHA256, _ = report.FileSHA256(*configPath)
+	}
+	for _, out := range files[1:] {
+		sum, _ := report.FileSHA256(out.path)
+		manifest.AdditionalOutputs = append(manifest.AdditionalOutputs, report.ManifestOutput{Format: out.format, File: out.path, SHA256: sum})
+	}
+	manifest.OutputSHA256, err = report.FileSHA256(outputFilePath)
+	if err == nil {
+		err = report.WriteManifest(outputFilePath+".manifest.json", manifest)
+	}
+	if err != nil {
+		fail(codeWriteReport, fmt.Errorf("run manifest: %w", err))
+	}
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// siteMetrics is the latest result of a site in one group, as exported to Prometheus
+type siteMetrics struct {
+	group     string
+	url       string
+	up        bool
+	ttfb      time.Duration
+	checkedAt time.Time
+	info      *siteinfo.SiteInfo
+}
+
+// metrics holds the latest result of every site the daemon checks and serves them in the
+// Prometheus text format
+type metrics struct {
+	mu    sync.Mutex
+	sites map[[2]string]*siteMetrics
+}
+
+// newMetrics returns an empty metrics registry
+func newMetrics() *metrics {
+	return &metrics{sites: map[[2]string]*siteMetrics{}}
+}
+
+// site returns the site's entry, creating it on its first check
+func (m *metrics) site(group, url string) *siteMetrics {
+	key := [2]string{group, url}
+	site, ok := m.sites[key]
+	if !ok {
+		site = &siteMetrics{group: group, url: url}
+		m.sites[key] = site
+	}
+	return site
+}
+
+// recordUptime records an uptime check. The full scan results of the site, if any, are kept.
+func (m *metrics) recordUptime(group string, uptime *siteinfo.Uptime) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	site := m.site(group, uptime.URL)
+	site.up, site.ttfb, site.checkedAt = uptime.Up, uptime.TTFB, uptime.CheckedAt
+}
+
+// recordScan records a completed scan
+func (m *metrics) recordScan(group string, info *siteinfo.SiteInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	site := m.site(group, info.URL)
+	site.up, site.ttfb, site.checkedAt, site.info = true, info.AverageTTFB, time.Now(), info
+}
+
+// recordFailure records a scan that could not reach the site
+func (m *metrics) recordFailure(group, url string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	site := m.site(group, url)
+	site.up, site.checkedAt = false, time.Now()
+}
+
+// metricFamily is one exported gauge and how to write its samples for a site; sites without a
+// value write none
+type metricFamily struct {
+	name  string
+	help  string
+	value func(site *siteMetrics, write func(labels string, value float64))
+}
+
+// metricFamilies are the gauges served on /metrics, labelled by group and URL
+var metricFamilies = []metricFamily{
+	{"site_up", "Whether the site answered its latest check (1) or not (0).", func(site *siteMetrics, write func(string, float64)) {
+		write("", boolGauge(site.up))
+	}},
+	{"site_ttfb_ms", "Time to first byte of the latest successful check, in milliseconds.", func(site *siteMetrics, write func(string, float64)) {
+		if site.up {
+			write("", siteinfo.Milliseconds(site.ttfb))
+		}
+	}},
+	{"site_last_check_timestamp_seconds", "Unix time of the site's latest check.", func(site *siteMetrics, write func(string, float64)) {
+		write("", float64(site.checkedAt.Unix()))
+	}},
+	{"site_ssl_valid", "Whether the site's certificate verified (1) or not (0) in the latest scan.", func(site *siteMetrics, write func(string, float64)) {
+		if site.info != nil {
+			write("", boolGauge(site.info.SSLValid))
+		}
+	}},
+	{"site_ssl_days_remaining", "Days until the site's certificate expires, from the latest scan.", func(site *siteMetrics, write func(string, float64)) {
+		if site.info != nil && site.info.Certificate != nil {
+			write("", float64(site.info.Certificate.DaysUntilExpiry))
+		}
+	}},
+	{"site_component_outdated", "Whether a component has reached end of life (1) or is supported (0), from the latest scan.", func(site *siteMetrics, write func(string, float64)) {
+		if site.info == nil {
+			return
+		}
+		for _, component := range monitoredStatuses {
+			switch status, _ := component.value(site.info); status {
+			case "Outdated", "Supported":
+				write(`component="`+component.label+`"`, boolGauge(status == "Outdated"))
+			}
+		}
+	}},
+}
+
+// ServeHTTP writes the latest results in the Prometheus text exposition format
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	sites := make([]*siteMetrics, 0, len(m.sites))
+	for _, site := range m.sites {
+		copied := *site
+		sites = append(sites, &copied)
+	}
+	m.mu.Unlock()
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].group != sites[j].group {
+			return sites[i].group < sites[j].group
+		}
+		return sites[i].url < sites[j].url
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, sites)
+}
+
+// writeMetrics writes every metric family for the sites
+func writeMetrics(w io.Writer, sites []*siteMetrics) {
+	for _, family := range metricFamilies {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", family.name, family.help, family.name)
+		for _, site := range sites {
+			labels := fmt.Sprintf(`group="%s",url="%s"`, escapeLabel(site.group), escapeLabel(site.url))
+			family.value(site, func(extra string, value float64) {
+				if extra != "" {
+					extra = "," + extra
+				}
+				fmt.Fprintf(w, "%s{%s%s} %g\n", family.name, labels, extra, value)
+			})
+		}
+	}
+}
+
+// boolGauge returns 1 for true and 0 for false
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// labelEscaper escapes a Prometheus label value
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value for the text exposition format
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// maxNotifyItems caps the sites listed under each heading of a notification, so a large
+// portfolio does not produce a message the chat service rejects
+const maxNotifyItems = 10
+
+// notifySinks are the chat webhooks that run summaries and daemon alerts are posted to
+type notifySinks struct {
+	Slack string `yaml:"slack"`
+	Teams string `yaml:"teams"`
+}
+
+// validate checks that each configured sink is an http or https URL
+func (n notifySinks) validate() error {
+	for _, sink := range []string{n.Slack, n.Teams} {
+		if sink == "" {
+			continue
+		}
+		if err := checkWebhookURL(sink); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enabled reports whether any sink is configured
+func (n notifySinks) enabled() bool {
+	return n.Slack != "" || n.Teams != ""
+}
+
+// notification is a message for the chat sinks: a title and sections of bullet points
+type notification struct {
+	title    string
+	sections []notifySection
+}
+
+// notifySection is a heading with the items under it
+type notifySection struct {
+	heading string
+	items   []string
+}
+
+// add appends a section, listing at most maxNotifyItems items. Sections without items are
+// left out.
+func (n *notification) add(heading string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	if len(items) > maxNotifyItems {
+		items = append(items[:maxNotifyItems:maxNotifyItems], fmt.Sprintf("and %d more", len(items)-maxNotifyItems))
+	}
+	n.sections = append(n.sections, notifySection{heading: heading, items: items})
+}
+
+// markdown renders the sections in the given bold markup, one bullet per line
+func (n *notification) markdown(bold, newline string) string {
+	var b strings.Builder
+	for i, section := range n.sections {
+		if i > 0 {
+			b.WriteString(newline)
+		}
+		b.WriteString(bold + section.heading + bold)
+		for _, item := range section.items {
+			b.WriteString(newline + "• " + item)
+		}
+	}
+	return b.String()
+}
+
+// send posts the notification to each configured sink. Every sink is tried; the errors of
+// those that failed are returned together.
+func (n notifySinks) send(msg *notification) error {
+	client := &http.Client{Timeout: webhookTimeout}
+	var failures []string
+	if n.Slack != "" {
+		// Slack incoming webhooks render mrkdwn, where *text* is bold
+		payload := map[string]string{"text": "*" + msg.title + "*\n" + msg.markdown("*", "\n")}
+		if err := postJSON(client, n.Slack, payload); err != nil {
+			failures = append(failures, "Slack: "+err.Error())
+		}
+	}
+	if n.Teams != "" {
+		// Teams incoming webhooks take a message card, whose text is Markdown
+		payload := map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  msg.title,
+			"title":    msg.title,
+			"text":     msg.markdown("**", "\n\n"),
+		}
+		if err := postJSON(client, n.Teams, payload); err != nil {
+			failures = append(failures, "Teams: "+err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("could not post the notification: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// postJSON posts the payload as JSON and fails on any response other than 2xx
+func postJSON(client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return webhookRejected(resp.StatusCode)
+	}
+	return nil
+}
+
+// checkWebhookURL checks that the URL can be posted to
+func checkWebhookURL(url string) error {
+	if u, err := neturl.Parse(url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https URL", url)
+	}
+	return nil
+}
+
+// runSummary returns the notification of a finished run: how many sites were scanned and
+// failed, the components past end of life and the certificates expired or expiring soon
+func runSummary(title string, total int, siteInfos []*siteinfo.SiteInfo, errs []error) *notification {
+	msg := &notification{title: title}
+	msg.add("Summary", []string{
+		fmt.Sprintf("%d of %d sites scanned", len(siteInfos), total),
+		fmt.Sprintf("%d sites could not be scanned", len(errs)),
+	})
+
+	var failures []string
+	for _, err := range errs {
+		failures = append(failures, err.Error())
+	}
+	msg.add("Failures", failures)
+
+	var outdated, certificates []string
+	for _, info := range siteInfos {
+		for _, component := range monitoredStatuses {
+			if status, version := component.value(info); status == "Outdated" {
+				outdated = append(outdated, fmt.Sprintf("%s: %s %s", info.URL, component.name, version))
+			}
+		}
+		switch {
+		case info.SSLExpired:
+			certificates = append(certificates, info.URL+": expired")
+		case info.Certificate != nil && info.Certificate.ExpiringSoon:
+			certificates = append(certificates, fmt.Sprintf("%s: expires in %d days, on %s",
+				info.URL, info.Certificate.DaysUntilExpiry, info.Certificate.NotAfter.Format(time.DateOnly)))
+		}
+	}
+	msg.add("Outdated components", outdated)
+	msg.add("Expired or expiring certificates", certificates)
+	return msg
+}
+
+// alertSummary returns the notification of the alerts raised by a daemon run
+func alertSummary(group string, alerts []alert) *notification {
+	msg := &notification{title: fmt.Sprintf("site-info-fetcher: %d alerts for %s", len(alerts), group)}
+	var items []string
+	for _, a := range alerts {
+		item := a.Kind + " " + a.URL
+		if a.Detail != "" {
+			item += ": " + a.Detail
+		}
+		items = append(items, item)
+	}
+	msg.add("Alerts", items)
+	return msg
+}
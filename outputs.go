@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// output is a report written in one format to one destination
+type output struct {
+	format string
+	path   string
+}
+
+// parseOutput parses an -out value of the form format=path, e.g. json=report.json
+func parseOutput(value string) (output, error) {
+	format, path, ok := strings.Cut(value, "=")
+	if !ok || format == "" || path == "" {
+		return output{}, fmt.Errorf("invalid output %q: use format=path, e.g. json=report.json", value)
+	}
+	if !report.HasFormat(format) {
+		return output{}, fmt.Errorf("unsupported output format: %s", format)
+	}
+	out := output{format: format, path: path}
+	return out, out.check()
+}
+
+// templateExtension returns the extension of the file a template renders, taken from the
+// template's name without its .tmpl suffix, e.g. .md for tickets.md.tmpl, or .txt
+func templateExtension(templatePath string) string {
+	ext := filepath.Ext(strings.TrimSuffix(filepath.Base(templatePath), ".tmpl"))
+	if ext == "" {
+		return ".txt"
+	}
+	return ext
+}
+
+// check rejects writing a format to stdout unless the format streams, as the others are only
+// complete once the scan finishes
+func (out output) check() error {
+	if out.path == report.Stdout && !report.IsStreaming(out.format) {
+		return fmt.Errorf("%s output cannot be written to stdout; only streamed formats such as jsonl can", out.format)
+	}
+	return nil
+}
+
+// openStreams opens the outputs in formats that stream, so each site is written to them as
+// it finishes. It returns a writer duplicating to them, or nil when there are none, and the
+// outputs left to write once the scan finishes.
+func openStreams(outputs []output) (report.OutputWriter, []output, error) {
+	var streams []report.OutputWriter
+	var rest []output
+	for _, out := range outputs {
+		if !report.IsStreaming(out.format) {
+			rest = append(rest, out)
+			continue
+		}
+		writer, err := report.NewWriter(out.format, out.path)
+		if err != nil {
+			report.MultiWriter(streams...).Flush()
+			return nil, nil, fmt.Errorf("%s: %w", out.path, err)
+		}
+		streams = append(streams, writer)
+	}
+	if len(streams) == 0 {
+		return nil, rest, nil
+	}
+	return report.MultiWriter(streams...), rest, nil
+}
+
+// withFailures returns a row for every input URL, in input order: the sites scanned, and a row
+// describing the failure of each site that could not be scanned, so the report reconciles
+// against the input list. Sites an interrupted run did not reach have no row.
+func withFailures(urls []string, siteInfos []*siteinfo.SiteInfo, errs []error) []*siteinfo.SiteInfo {
+	rows := map[string]*siteinfo.SiteInfo{}
+	for _, info := range siteInfos {
+		rows[info.URL] = info
+	}
+	for _, err := range errs {
+		var scanErr *siteinfo.ScanError
+		if errors.As(err, &scanErr) {
+			rows[scanErr.URL] = siteinfo.FailedSite(scanErr.URL, err)
+		}
+	}
+	var all []*siteinfo.SiteInfo
+	for _, url := range urls {
+		if row, ok := rows[url]; ok {
+			all = append(all, row)
+		}
+	}
+	return all
+}
+
+// writeOutputs writes the site information to every output in a single pass over the results,
+// with a row for each of the failed sites. The database only records the sites scanned, and
+// not those whose results were reused from it.
+func writeOutputs(outputs []output, siteInfos []*siteinfo.SiteInfo, reused map[string]bool, urls []string, errs []error) error {
+	skip := maps.Clone(reused)
+	for _, err := range errs {
+		var scanErr *siteinfo.ScanError
+		if errors.As(err, &scanErr) {
+			skip[scanErr.URL] = true
+		}
+	}
+	writers := make([]report.OutputWriter, 0, len(outputs))
+	for _, out := range outputs {
+		writer, err := report.NewWriter(out.format, out.path)
+		if err != nil {
+			report.MultiWriter(writers...).Flush()
+			return fmt.Errorf("%s: %w", out.path, err)
+		}
+		if out.format == "sqlite" && len(skip) > 0 {
+			writer = freshOnly{OutputWriter: writer, skip: skip}
+		}
+		writers = append(writers, writer)
+	}
+	return report.WriteAll(report.MultiWriter(writers...), withFailures(urls, siteInfos, errs))
+}
@@ -0,0 +1,96 @@
+package fingerprint
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldRule extracts a custom field from a site's homepage, for in-house technology the
+// built-in detection does not know: the capture group of a regular expression matched against
+// a response header or, when no header is named, the HTML. Group 0 is the whole match.
+type FieldRule struct {
+	Field   string `yaml:"field"`
+	Header  string `yaml:"header"`
+	Pattern string `yaml:"pattern"`
+	Group   int    `yaml:"group"`
+}
+
+// compiledField is a field rule with its pattern compiled
+type compiledField struct {
+	FieldRule
+	pattern *regexp.Regexp
+}
+
+// Fields extracts the custom fields of a rules file from each site
+type Fields struct {
+	rules []compiledField
+	names []string
+}
+
+// LoadFields reads a YAML list of field rules. Several rules may fill the same field, e.g.
+// from a header or else the HTML; the first rule that matches sets it.
+func LoadFields(filePath string) (*Fields, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var rules []FieldRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s has no rules", filePath)
+	}
+	fields := &Fields{}
+	for i, rule := range rules {
+		rule.Field = strings.TrimSpace(rule.Field)
+		if rule.Field == "" {
+			return nil, fmt.Errorf("rule %d has no field name", i+1)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Field, err)
+		}
+		if rule.Group < 0 || rule.Group > re.NumSubexp() {
+			return nil, fmt.Errorf("%s: the pattern has no capture group %d", rule.Field, rule.Group)
+		}
+		fields.rules = append(fields.rules, compiledField{FieldRule: rule, pattern: re})
+		if !slices.Contains(fields.names, rule.Field) {
+			fields.names = append(fields.names, rule.Field)
+		}
+	}
+	return fields, nil
+}
+
+// Names returns the fields, in the order they first appear in the rules file
+func (f *Fields) Names() []string {
+	return f.names
+}
+
+// Extract returns the value of each field whose rules match the response headers or HTML.
+// Fields no rule matched are left out.
+func (f *Fields) Extract(headers http.Header, body string) map[string]string {
+	values := map[string]string{}
+	for _, rule := range f.rules {
+		if _, ok := values[rule.Field]; ok {
+			continue
+		}
+		sources := []string{body}
+		if rule.Header != "" {
+			sources = headers.Values(rule.Header)
+		}
+		for _, source := range sources {
+			if m := rule.pattern.FindStringSubmatch(source); m != nil && strings.TrimSpace(m[rule.Group]) != "" {
+				values[rule.Field] = strings.TrimSpace(m[rule.Group])
+				break
+			}
+		}
+	}
+	return values
+}
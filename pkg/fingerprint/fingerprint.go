@@ -0,0 +1,199 @@
+// Package fingerprint identifies the technologies a site uses (frameworks, JavaScript libraries,
+// analytics and ecommerce platforms) from rules matching response headers, HTML, cookie names and
+// script URLs. The built-in rules can be extended with an external JSON rules file, and custom
+// fields extracted with the rules of a YAML file.
+package fingerprint
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultRules is the built-in rules file
+//
+//go:embed rules.json
+var defaultRules []byte
+
+// Rule describes how to recognise a technology. Header values, HTML and script patterns are
+// regular expressions; the first capture group of a match, if any, is reported as the version.
+// An empty header pattern matches any value. Cookie names match by prefix.
+type Rule struct {
+	Name     string            `json:"name"`
+	Category string            `json:"category"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	HTML     []string          `json:"html,omitempty"`
+	Cookies  []string          `json:"cookies,omitempty"`
+	Scripts  []string          `json:"scripts,omitempty"`
+}
+
+// Technology is a technology detected on a site
+type Technology struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Version  string `json:"version,omitempty"`
+}
+
+// String formats the technology as name or name version
+func (t Technology) String() string {
+	if t.Version == "" {
+		return t.Name
+	}
+	return t.Name + " " + t.Version
+}
+
+// compiledRule is a rule with its patterns compiled
+type compiledRule struct {
+	Rule
+	headers map[string]*regexp.Regexp
+	html    []*regexp.Regexp
+	scripts []*regexp.Regexp
+}
+
+// Engine matches sites against a set of rules
+type Engine struct {
+	rules []compiledRule
+}
+
+// Default returns an engine with the built-in rules
+func Default() *Engine {
+	engine, err := parse(defaultRules)
+	if err != nil {
+		panic("fingerprint: invalid built-in rules: " + err.Error())
+	}
+	return engine
+}
+
+// Load returns an engine with the built-in rules extended by the rules in the JSON file.
+// A rule in the file with the same name as a built-in rule replaces it.
+func Load(filePath string) (*Engine, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+	}
+	engine := Default()
+	replaced := map[string]bool{}
+	for _, rule := range extra.rules {
+		replaced[rule.Name] = true
+	}
+	rules := extra.rules
+	for _, rule := range engine.rules {
+		if !replaced[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+	return &Engine{rules: rules}, nil
+}
+
+// parse compiles a JSON array of rules
+func parse(data []byte) (*Engine, error) {
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	engine := &Engine{}
+	for _, rule := range rules {
+		compiled := compiledRule{Rule: rule, headers: map[string]*regexp.Regexp{}}
+		for name, pattern := range rule.Headers {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: header %s: %w", rule.Name, name, err)
+			}
+			compiled.headers[name] = re
+		}
+		for _, pattern := range rule.HTML {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: html: %w", rule.Name, err)
+			}
+			compiled.html = append(compiled.html, re)
+		}
+		for _, pattern := range rule.Scripts {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s: script: %w", rule.Name, err)
+			}
+			compiled.scripts = append(compiled.scripts, re)
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	return engine, nil
+}
+
+// scriptSrcPattern extracts the src of each script tag
+var scriptSrcPattern = regexp.MustCompile(`(?is)<script\b[^>]*\bsrc\s*=\s*["']?([^"'\s>]+)`)
+
+// match reports whether the pattern matches any of the values, returning the captured version
+func match(re *regexp.Regexp, values ...string) (bool, string) {
+	for _, value := range values {
+		if m := re.FindStringSubmatch(value); m != nil {
+			if len(m) > 1 {
+				return true, m[1]
+			}
+			return true, ""
+		}
+	}
+	return false, ""
+}
+
+// Detect returns the technologies matching the response headers and HTML, sorted by category and name
+func (e *Engine) Detect(headers http.Header, body string) []Technology {
+	var scripts []string
+	for _, m := range scriptSrcPattern.FindAllStringSubmatch(body, -1) {
+		scripts = append(scripts, m[1])
+	}
+	var cookies []string
+	for _, cookie := range (&http.Response{Header: headers}).Cookies() {
+		cookies = append(cookies, cookie.Name)
+	}
+
+	var technologies []Technology
+	for _, rule := range e.rules {
+		found, version := false, ""
+		check := func(ok bool, v string) {
+			if ok {
+				found = true
+				if version == "" {
+					version = v
+				}
+			}
+		}
+		for name, re := range rule.headers {
+			for _, value := range headers.Values(name) {
+				check(match(re, value))
+			}
+		}
+		for _, re := range rule.html {
+			check(match(re, body))
+		}
+		for _, re := range rule.scripts {
+			check(match(re, scripts...))
+		}
+		for _, prefix := range rule.Cookies {
+			for _, name := range cookies {
+				if strings.HasPrefix(name, prefix) {
+					found = true
+				}
+			}
+		}
+		if found {
+			technologies = append(technologies, Technology{Name: rule.Name, Category: rule.Category, Version: version})
+		}
+	}
+	sort.Slice(technologies, func(i, j int) bool {
+		if technologies[i].Category != technologies[j].Category {
+			return technologies[i].Category < technologies[j].Category
+		}
+		return technologies[i].Name < technologies[j].Name
+	})
+	return technologies
+}
@@ -0,0 +1,172 @@
+// Package geoip enriches IP addresses with the autonomous system, organization and country
+// they belong to, using local MaxMind databases or the ipinfo.io API.
+package geoip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Info describes the network an IP address belongs to
+type Info struct {
+	ASN          string `json:"asn"`
+	Organization string `json:"organization"`
+	Country      string `json:"country"`
+}
+
+// Options configures a Client
+type Options struct {
+	// ASNDatabase is the path to a GeoLite2-ASN (or GeoIP2-ISP) database.
+	ASNDatabase string
+	// CountryDatabase is the path to a GeoLite2-Country (or City) database.
+	CountryDatabase string
+	// Token is the ipinfo.io API token. The API is only used when no database is configured,
+	// and works without a token at a lower rate limit.
+	Token string
+	// HTTPClient sends the API requests. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// apiURL is the ipinfo.io API base URL
+const apiURL = "https://ipinfo.io/"
+
+// lookupResult holds the information fetched for an address during this run
+type lookupResult struct {
+	once sync.Once
+	info *Info
+	err  error
+}
+
+// Client looks up IP addresses, resolving each address at most once per run
+type Client struct {
+	opts    Options
+	asn     *maxminddb.Reader
+	country *maxminddb.Reader
+
+	mu      sync.Mutex
+	results map[string]*lookupResult
+}
+
+// New creates a Client, opening the databases if any are configured
+func New(opts Options) (*Client, error) {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	c := &Client{opts: opts, results: map[string]*lookupResult{}}
+	var err error
+	if opts.ASNDatabase != "" {
+		if c.asn, err = maxminddb.Open(opts.ASNDatabase); err != nil {
+			return nil, fmt.Errorf("error opening %s: %w", opts.ASNDatabase, err)
+		}
+	}
+	if opts.CountryDatabase != "" {
+		if c.country, err = maxminddb.Open(opts.CountryDatabase); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("error opening %s: %w", opts.CountryDatabase, err)
+		}
+	}
+	return c, nil
+}
+
+// Close closes the databases
+func (c *Client) Close() error {
+	if c.asn != nil {
+		c.asn.Close()
+	}
+	if c.country != nil {
+		c.country.Close()
+	}
+	return nil
+}
+
+// Lookup returns the network information for the IP address
+func (c *Client) Lookup(ctx context.Context, ip net.IP) (*Info, error) {
+	key := ip.String()
+	c.mu.Lock()
+	result, ok := c.results[key]
+	if !ok {
+		result = &lookupResult{}
+		c.results[key] = result
+	}
+	c.mu.Unlock()
+
+	result.once.Do(func() {
+		if c.asn != nil || c.country != nil {
+			result.info, result.err = c.lookupDatabases(ip)
+		} else {
+			result.info, result.err = c.lookupAPI(ctx, ip)
+		}
+	})
+	return result.info, result.err
+}
+
+// lookupDatabases reads the address from the configured MaxMind databases
+func (c *Client) lookupDatabases(ip net.IP) (*Info, error) {
+	info := &Info{}
+	if c.asn != nil {
+		var record struct {
+			Number       uint   `maxminddb:"autonomous_system_number"`
+			Organization string `maxminddb:"autonomous_system_organization"`
+		}
+		if err := c.asn.Lookup(ip, &record); err != nil {
+			return nil, err
+		}
+		if record.Number != 0 {
+			info.ASN = fmt.Sprintf("AS%d", record.Number)
+		}
+		info.Organization = record.Organization
+	}
+	if c.country != nil {
+		var record struct {
+			Country struct {
+				ISOCode string `maxminddb:"iso_code"`
+			} `maxminddb:"country"`
+		}
+		if err := c.country.Lookup(ip, &record); err != nil {
+			return nil, err
+		}
+		info.Country = record.Country.ISOCode
+	}
+	return info, nil
+}
+
+// lookupAPI fetches the address from the ipinfo.io API, whose org field combines the
+// AS number and organization, e.g. "AS13335 Cloudflare, Inc."
+func (c *Client) lookupAPI(ctx context.Context, ip net.IP) (*Info, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL+ip.String()+"/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.opts.Token)
+	}
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ipinfo.io returned HTTP %d for %s", resp.StatusCode, ip)
+	}
+
+	var record struct {
+		Org     string `json:"org"`
+		Country string `json:"country"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&record); err != nil {
+		return nil, err
+	}
+	info := &Info{Organization: record.Org, Country: record.Country}
+	if asn, organization, ok := strings.Cut(record.Org, " "); ok && strings.HasPrefix(asn, "AS") {
+		info.ASN, info.Organization = asn, organization
+	}
+	return info, nil
+}
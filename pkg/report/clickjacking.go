@@ -0,0 +1,64 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// clickjackingTemplate is a proof-of-concept page that frames the site under a decoy button
+const clickjackingTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Clickjacking test: %[1]s</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.stage { position: relative; width: 1000px; height: 700px; }
+.decoy { position: absolute; top: 300px; left: 400px; z-index: 1; padding: 1em 2em; background: #c00; color: #fff; }
+iframe { position: absolute; top: 0; left: 0; width: 1000px; height: 700px; opacity: 0.5; z-index: 2; border: 1px solid #999; }
+</style>
+</head>
+<body>
+<h1>Clickjacking test</h1>
+<p>If the page below loads, %[1]s can be framed by any other site. An attacker can make the frame
+invisible and trick visitors into clicking on it while they believe they are clicking the decoy button.</p>
+<p>Fix: send <code>X-Frame-Options: SAMEORIGIN</code> or <code>Content-Security-Policy: frame-ancestors 'self'</code>.</p>
+<div class="stage">
+<div class="decoy">Click here to win</div>
+<iframe src="%[1]s"></iframe>
+</div>
+</body>
+</html>
+`
+
+// WriteClickjackingPoCs generates a frame test page for each site without frame protection
+// and records its path in the site information
+func WriteClickjackingPoCs(dir string, siteInfos []*siteinfo.SiteInfo) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	unsafeChars := regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+	for _, info := range siteInfos {
+		if info.FrameProtection != "None" {
+			continue
+		}
+		url := info.URL
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			url = "https://" + url
+		}
+		name := unsafeChars.ReplaceAllString(strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://"), "_")
+		path := filepath.Join(dir, "clickjacking_"+name+".html")
+		content := fmt.Sprintf(clickjackingTemplate, html.EscapeString(url))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+		info.ClickjackingPoC = path
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// adoptionThreshold is the share of competitors that must use a technology for client sites
+// without it to be flagged as missing it
+const adoptionThreshold = 0.5
+
+// CompetitorComparison benchmarks one client site against the competitor averages.
+// Differences are percentages; positive means the client is slower or heavier.
+type CompetitorComparison struct {
+	URL                  string   `json:"url"`
+	AverageTTFB          float64  `json:"average_ttfb_ms"`
+	TTFBDifference       float64  `json:"ttfb_difference_percent"`
+	PageWeight           int64    `json:"page_weight_bytes"`
+	PageWeightDifference float64  `json:"page_weight_difference_percent"`
+	MissingTechnologies  []string `json:"missing_technologies"`
+}
+
+// CompetitorReport benchmarks the client sites against the competitor sites of the same scan
+type CompetitorReport struct {
+	Competitors        []string               `json:"competitors"`
+	AverageTTFB        float64                `json:"competitor_average_ttfb_ms"`
+	AveragePageWeight  float64                `json:"competitor_average_page_weight_bytes"`
+	TechnologyAdoption map[string]float64     `json:"competitor_technology_adoption"`
+	Clients            []CompetitorComparison `json:"clients"`
+}
+
+// techStack returns the technologies, CMS, CDN and web server a site runs
+func techStack(info *siteinfo.SiteInfo) map[string]bool {
+	stack := map[string]bool{}
+	for _, technology := range info.Technologies {
+		stack[technology.Name] = true
+	}
+	for _, name := range []string{info.CMS, info.CDN, info.WebServer} {
+		if name != "" {
+			stack[name] = true
+		}
+	}
+	return stack
+}
+
+// difference returns how far value is above the average, as a percentage
+func difference(value, average float64) float64 {
+	if average == 0 {
+		return 0
+	}
+	return (value - average) / average * 100
+}
+
+// CompareCompetitors benchmarks every site not marked as a competitor against the average
+// TTFB, page weight and technology adoption of the competitor sites
+func CompareCompetitors(siteInfos []*siteinfo.SiteInfo, competitors map[string]bool) *CompetitorReport {
+	report := &CompetitorReport{TechnologyAdoption: map[string]float64{}}
+	var clients []*siteinfo.SiteInfo
+	var totalTTFB, totalWeight float64
+	for _, info := range siteInfos {
+		if !competitors[info.URL] {
+			clients = append(clients, info)
+			continue
+		}
+		report.Competitors = append(report.Competitors, info.URL)
+		totalTTFB += siteinfo.Milliseconds(info.AverageTTFB)
+		totalWeight += float64(info.PageWeight)
+		for name := range techStack(info) {
+			report.TechnologyAdoption[name]++
+		}
+	}
+	if len(report.Competitors) == 0 {
+		return report
+	}
+
+	count := float64(len(report.Competitors))
+	report.AverageTTFB = totalTTFB / count
+	report.AveragePageWeight = totalWeight / count
+	var common []string
+	for name := range report.TechnologyAdoption {
+		report.TechnologyAdoption[name] /= count
+		if report.TechnologyAdoption[name] >= adoptionThreshold {
+			common = append(common, name)
+		}
+	}
+	sort.Strings(common)
+
+	for _, info := range clients {
+		ttfb := siteinfo.Milliseconds(info.AverageTTFB)
+		comparison := CompetitorComparison{
+			URL:                  info.URL,
+			AverageTTFB:          ttfb,
+			TTFBDifference:       difference(ttfb, report.AverageTTFB),
+			PageWeight:           info.PageWeight,
+			PageWeightDifference: difference(float64(info.PageWeight), report.AveragePageWeight),
+		}
+		stack := techStack(info)
+		for _, name := range common {
+			if !stack[name] {
+				comparison.MissingTechnologies = append(comparison.MissingTechnologies, name)
+			}
+		}
+		report.Clients = append(report.Clients, comparison)
+	}
+	return report
+}
+
+// WriteCompetitorReport writes the comparison as CSV, one row per client site, or as JSON
+func WriteCompetitorReport(filePath, format string, report *CompetitorReport) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if format == "json" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{
+		"URL", "Average TTFB (ms)", "Competitor Average TTFB (ms)", "TTFB vs Competitors (%)",
+		"Page Weight (bytes)", "Competitor Average Page Weight (bytes)", "Page Weight vs Competitors (%)",
+		"Missing Competitor Technologies",
+	})
+	for _, client := range report.Clients {
+		writer.Write([]string{
+			client.URL,
+			fmt.Sprintf("%.3f", client.AverageTTFB),
+			fmt.Sprintf("%.3f", report.AverageTTFB),
+			fmt.Sprintf("%+.1f", client.TTFBDifference),
+			fmt.Sprintf("%d", client.PageWeight),
+			fmt.Sprintf("%.0f", report.AveragePageWeight),
+			fmt.Sprintf("%+.1f", client.PageWeightDifference),
+			strings.Join(client.MissingTechnologies, "; "),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
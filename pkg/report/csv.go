@@ -0,0 +1,840 @@
+package report
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// column describes a CSV output column
+type column struct {
+	Header string
+	Value  func(info *siteinfo.SiteInfo) string
+}
+
+// ttfbColumn formats the nth TTFB sample in ms, counting from the shortest when n is negative,
+// or blank if the sample is missing
+func ttfbColumn(n int) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		i := n
+		if i < 0 {
+			i += len(info.TTFBs)
+		}
+		if i < 0 || len(info.TTFBs) <= i {
+			return ""
+		}
+		return fmt.Sprintf("%.3f", siteinfo.Milliseconds(info.TTFBs[i]))
+	}
+}
+
+// ttfbStatColumn formats a TTFB statistic in ms, or blank if no samples were taken
+func ttfbStatColumn(value func(stats siteinfo.TTFBStats) time.Duration) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if len(info.TTFBs) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3f", siteinfo.Milliseconds(value(info.TTFBStats)))
+	}
+}
+
+// durationColumn formats a duration in ms, or blank if it was not measured
+func durationColumn(value func(info *siteinfo.SiteInfo) time.Duration) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.Timing.Total == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3f", siteinfo.Milliseconds(value(info)))
+	}
+}
+
+// certColumn formats a certificate field, or blank if no certificate was retrieved
+func certColumn(value func(cert *siteinfo.CertificateInfo) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.Certificate == nil {
+			return ""
+		}
+		return value(info.Certificate)
+	}
+}
+
+// domainColumn formats a domain registration field, or blank if the registration was not looked up
+func domainColumn(value func(domain *siteinfo.DomainRegistration) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.Domain == nil {
+			return ""
+		}
+		return value(info.Domain)
+	}
+}
+
+// hstsColumn formats a Strict-Transport-Security field, or blank if the site sends no HSTS policy
+func hstsColumn(value func(hsts *siteinfo.HSTS) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.HSTS == nil {
+			return ""
+		}
+		return value(info.HSTS)
+	}
+}
+
+// cookieColumn formats a cookie audit field, or blank if the audit did not run
+func cookieColumn(value func(cookies *siteinfo.CookieAudit) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.Cookies == nil {
+			return ""
+		}
+		return value(info.Cookies)
+	}
+}
+
+// ctColumn formats a Certificate Transparency field, or blank if the lookup did not run
+func ctColumn(value func(ct *siteinfo.CTLog) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.CT == nil {
+			return ""
+		}
+		return value(info.CT)
+	}
+}
+
+// blocklistColumn formats a blocklist field, or blank if the blocklists were not checked
+func blocklistColumn(value func(blocklists *siteinfo.Blocklists) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.Blocklists == nil {
+			return ""
+		}
+		return value(info.Blocklists)
+	}
+}
+
+// seoColumn formats a search metadata field, or blank if the homepage was not audited
+func seoColumn(value func(seo *siteinfo.SEO) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.SEO == nil {
+			return ""
+		}
+		return value(info.SEO)
+	}
+}
+
+// structuredDataColumn formats a JSON-LD field, or blank if the homepage was not parsed
+func structuredDataColumn(value func(data *siteinfo.StructuredData) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.StructuredData == nil {
+			return ""
+		}
+		return value(info.StructuredData)
+	}
+}
+
+// ecommerceColumn formats a store field, or blank if the site is not a store
+func ecommerceColumn(value func(store *siteinfo.Ecommerce) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.Ecommerce == nil {
+			return ""
+		}
+		return value(info.Ecommerce)
+	}
+}
+
+// dnsColumn formats DNS records, or blank if DNS records were not collected
+func dnsColumn(value func(dns *siteinfo.DNSRecords) []string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.DNS == nil {
+			return ""
+		}
+		return strings.Join(value(info.DNS), "; ")
+	}
+}
+
+// mailColumn formats an email authentication field, or blank if the records were not checked
+func mailColumn(value func(auth *siteinfo.MailAuth) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if info.MailAuth == nil {
+			return ""
+		}
+		return value(info.MailAuth)
+	}
+}
+
+// wordPressColumn reports a WordPress-specific value as N/A on sites that are not WordPress,
+// rather than as a blank that reads as undetected
+func wordPressColumn(value func(info *siteinfo.SiteInfo) string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		if !info.IsWordPress && info.WordPressStatus == "N/A" {
+			return "N/A"
+		}
+		return value(info)
+	}
+}
+
+// exposureColumn formats the exposure probe result for path, or blank if the probe did not run
+func exposureColumn(path string) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		for _, exposure := range info.Exposures {
+			if exposure.Path == path {
+				return exposure.Status
+			}
+		}
+		return ""
+	}
+}
+
+// licenseColumn returns a column listing the licenses of the site's plugins and theme as
+// component: license, only those that are not GPL-compatible when nonGPL is set
+func licenseColumn(nonGPL bool) func(info *siteinfo.SiteInfo) string {
+	return func(info *siteinfo.SiteInfo) string {
+		var licenses []string
+		add := func(component, license string) {
+			if license != "" && (!nonGPL || !siteinfo.GPLCompatible(license)) {
+				licenses = append(licenses, component+": "+license)
+			}
+		}
+		for _, plugin := range info.Plugins {
+			add(plugin.Slug, plugin.License)
+		}
+		if info.Theme != "" {
+			add("theme "+info.Theme, info.ThemeLicense)
+		}
+		return strings.Join(licenses, "; ")
+	}
+}
+
+// columns lists the CSV columns in output order
+var columns = []column{
+	{"URL", func(info *siteinfo.SiteInfo) string { return info.URL }},
+	{"Scan Status", func(info *siteinfo.SiteInfo) string { return info.ScanStatus }},
+	{"Scan Error", func(info *siteinfo.SiteInfo) string { return info.ScanError }},
+	{"PHP Version", func(info *siteinfo.SiteInfo) string { return info.PHPVersion }},
+	{"MySQL Version", func(info *siteinfo.SiteInfo) string { return info.MySQLVersion }},
+	{"WordPress Version", wordPressColumn(func(info *siteinfo.SiteInfo) string { return info.WordPressVersion })},
+	{"Caching", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.Caching) }},
+	{"Cache Control", func(info *siteinfo.SiteInfo) string { return info.CacheControl }},
+	{"Web Server", func(info *siteinfo.SiteInfo) string { return info.WebServer }},
+	{"Web Server Version", func(info *siteinfo.SiteInfo) string { return info.WebServerVersion }},
+	{"SSL Valid", func(info *siteinfo.SiteInfo) string {
+		if info.SSLExpired {
+			return "Expired"
+		}
+		return fmt.Sprintf("%t", info.SSLValid)
+	}},
+	{"TTFB1 - Longest (ms)", ttfbColumn(0)},
+	{"TTFB2 (ms)", ttfbColumn(1)},
+	{"TTFB3 - Shortest (ms)", ttfbColumn(-1)},
+	{"Average TTFB (ms)", func(info *siteinfo.SiteInfo) string {
+		if info.AverageTTFB == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3f", siteinfo.Milliseconds(info.AverageTTFB))
+	}},
+	{"TTFB Min (ms)", ttfbStatColumn(func(stats siteinfo.TTFBStats) time.Duration { return stats.Min })},
+	{"TTFB Median (ms)", ttfbStatColumn(func(stats siteinfo.TTFBStats) time.Duration { return stats.Median })},
+	{"TTFB P95 (ms)", ttfbStatColumn(func(stats siteinfo.TTFBStats) time.Duration { return stats.P95 })},
+	{"TTFB Std Dev (ms)", ttfbStatColumn(func(stats siteinfo.TTFBStats) time.Duration { return stats.StdDev })},
+	{"X-Powered-By", func(info *siteinfo.SiteInfo) string { return info.XPoweredBy }},
+	{"PHP Status", func(info *siteinfo.SiteInfo) string { return info.PHPStatus }},
+	{"MySQL Status", func(info *siteinfo.SiteInfo) string { return info.MySQLStatus }},
+	{"Web Server Status", func(info *siteinfo.SiteInfo) string { return info.WebServerStatus }},
+	{"WordPress Status", func(info *siteinfo.SiteInfo) string { return info.WordPressStatus }},
+	{"Search Status", func(info *siteinfo.SiteInfo) string { return info.SearchStatus }},
+	{"Error Handling", func(info *siteinfo.SiteInfo) string { return info.ErrorHandling }},
+	{"WSOD Suspected", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.WSODSuspected) }},
+	{"Clock Skew (s)", func(info *siteinfo.SiteInfo) string {
+		if info.ServerDate.IsZero() {
+			return ""
+		}
+		return fmt.Sprintf("%.0f", info.ClockSkew.Seconds())
+	}},
+	{"Clock Skew Significant", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.ClockSkewFlag) }},
+	{"Header Anomalies", func(info *siteinfo.SiteInfo) string { return strings.Join(info.HeaderAnomalies, "; ") }},
+	{"CORS Allow Origin", func(info *siteinfo.SiteInfo) string { return info.CORSAllowOrigin }},
+	{"CORS Allow Credentials", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.CORSCredentials) }},
+	{"CORS Issues", func(info *siteinfo.SiteInfo) string { return strings.Join(info.CORSIssues, "; ") }},
+	{"Open Redirect Parameters", func(info *siteinfo.SiteInfo) string { return strings.Join(info.OpenRedirects, "; ") }},
+	{"Frame Protection", func(info *siteinfo.SiteInfo) string { return info.FrameProtection }},
+	{"Clickjacking PoC", func(info *siteinfo.SiteInfo) string { return info.ClickjackingPoC }},
+	{"Ownership Verified", func(info *siteinfo.SiteInfo) string { return info.OwnershipVerified }},
+	{"DNS Lookup (ms)", durationColumn(func(info *siteinfo.SiteInfo) time.Duration { return info.Timing.DNSLookup })},
+	{"TCP Connect (ms)", durationColumn(func(info *siteinfo.SiteInfo) time.Duration { return info.Timing.TCPConnect })},
+	{"TLS Handshake (ms)", durationColumn(func(info *siteinfo.SiteInfo) time.Duration { return info.Timing.TLSHandshake })},
+	{"Content TTFB (ms)", durationColumn(func(info *siteinfo.SiteInfo) time.Duration { return info.Timing.TTFB })},
+	{"Download (ms)", durationColumn(func(info *siteinfo.SiteInfo) time.Duration { return info.Timing.Download })},
+	{"Total Time (ms)", durationColumn(func(info *siteinfo.SiteInfo) time.Duration { return info.Timing.Total })},
+	{"Certificate Hostname Mismatch", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.CertificateHostnameMismatch) }},
+	{"Certificate Subject", certColumn(func(cert *siteinfo.CertificateInfo) string { return cert.Subject })},
+	{"Certificate Issuer", certColumn(func(cert *siteinfo.CertificateInfo) string { return cert.IssuerCN })},
+	{"Certificate Issuer Organization", certColumn(func(cert *siteinfo.CertificateInfo) string { return cert.IssuerOrg })},
+	{"Certificate Not Before", certColumn(func(cert *siteinfo.CertificateInfo) string { return cert.NotBefore.Format(time.RFC3339) })},
+	{"Certificate Not After", certColumn(func(cert *siteinfo.CertificateInfo) string { return cert.NotAfter.Format(time.RFC3339) })},
+	{"Certificate Days Until Expiry", certColumn(func(cert *siteinfo.CertificateInfo) string { return fmt.Sprintf("%d", cert.DaysUntilExpiry) })},
+	{"Certificate Expiring Soon", certColumn(func(cert *siteinfo.CertificateInfo) string { return fmt.Sprintf("%t", cert.ExpiringSoon) })},
+	{"Certificate SANs", certColumn(func(cert *siteinfo.CertificateInfo) string { return strings.Join(cert.SANs, "; ") })},
+	{"Certificate Key Algorithm", certColumn(func(cert *siteinfo.CertificateInfo) string { return cert.KeyAlgorithm })},
+	{"Certificate Weaknesses", certColumn(func(cert *siteinfo.CertificateInfo) string { return strings.Join(cert.Weaknesses, "; ") })},
+	{"Certificate Renewal Overdue", certColumn(func(cert *siteinfo.CertificateInfo) string { return fmt.Sprintf("%t", cert.RenewalOverdue) })},
+	{"Certificate Chain", func(info *siteinfo.SiteInfo) string { return info.ChainStatus }},
+	{"TLS Version", func(info *siteinfo.SiteInfo) string { return info.TLSVersion }},
+	{"Cipher Suite", func(info *siteinfo.SiteInfo) string { return info.CipherSuite }},
+	{"TLS Protocols Accepted", func(info *siteinfo.SiteInfo) string {
+		if info.TLSAudit == nil {
+			return ""
+		}
+		return strings.Join(info.TLSAudit.Protocols, "; ")
+	}},
+	{"Deprecated TLS", func(info *siteinfo.SiteInfo) string {
+		if info.TLSAudit == nil {
+			return ""
+		}
+		return fmt.Sprintf("%t", info.TLSAudit.Deprecated)
+	}},
+	{"TLS Endpoints", func(info *siteinfo.SiteInfo) string {
+		var addresses []string
+		for _, endpoint := range info.TLSEndpoints {
+			addresses = append(addresses, endpoint.Address)
+		}
+		return strings.Join(addresses, "; ")
+	}},
+	{"TLS Endpoint Mismatch", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.TLSEndpointMismatch) }},
+	{"Security Score", func(info *siteinfo.SiteInfo) string {
+		if info.SecurityHeaders == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d", info.SecurityHeaders.Score)
+	}},
+	{"Security Grade", func(info *siteinfo.SiteInfo) string {
+		if info.SecurityHeaders == nil {
+			return ""
+		}
+		return info.SecurityHeaders.Grade
+	}},
+	{"Security Header Issues", func(info *siteinfo.SiteInfo) string {
+		if info.SecurityHeaders == nil {
+			return ""
+		}
+		return strings.Join(info.SecurityHeaders.Issues, "; ")
+	}},
+	{"CDN", func(info *siteinfo.SiteInfo) string { return info.CDN }},
+	{"ACME Challenge", func(info *siteinfo.SiteInfo) string { return info.ACMEChallenge }},
+	{"Caching Layers", func(info *siteinfo.SiteInfo) string { return strings.Join(info.CachingLayers, "; ") }},
+	{"Plugins", wordPressColumn(func(info *siteinfo.SiteInfo) string {
+		var plugins []string
+		for _, plugin := range info.Plugins {
+			plugins = append(plugins, plugin.String())
+		}
+		return strings.Join(plugins, "; ")
+	})},
+	{"CSP Grade", func(info *siteinfo.SiteInfo) string {
+		if info.CSP == nil {
+			return "None"
+		}
+		return info.CSP.Grade
+	}},
+	{"CSP Issues", func(info *siteinfo.SiteInfo) string {
+		if info.CSP == nil {
+			return ""
+		}
+		return strings.Join(info.CSP.Issues, "; ")
+	}},
+	{"SRI Coverage", func(info *siteinfo.SiteInfo) string {
+		if info.SRI == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d/%d", info.SRI.WithIntegrity, info.SRI.ExternalResources)
+	}},
+	{"Scripts Missing SRI", func(info *siteinfo.SiteInfo) string {
+		if info.SRI == nil {
+			return ""
+		}
+		return strings.Join(info.SRI.ScriptsMissingSRI, "; ")
+	}},
+	{"Site Name", func(info *siteinfo.SiteInfo) string { return info.SiteName }},
+	{"Site Description", func(info *siteinfo.SiteInfo) string { return info.SiteDescription }},
+	{"WordPress Version Range", wordPressColumn(func(info *siteinfo.SiteInfo) string { return info.WordPressVersionRange })},
+	{"Exposed xmlrpc.php", exposureColumn("/xmlrpc.php")},
+	{"Exposed wp-login.php", exposureColumn("/wp-login.php")},
+	{"Exposed readme.html", exposureColumn("/readme.html")},
+	{"Exposed wp-config.php.bak", exposureColumn("/wp-config.php.bak")},
+	{"Exposed debug.log", exposureColumn("/wp-content/debug.log")},
+	{"Permissions-Policy Issues", func(info *siteinfo.SiteInfo) string { return strings.Join(info.PermissionsPolicyIssues, "; ") }},
+	{"Theme", wordPressColumn(func(info *siteinfo.SiteInfo) string {
+		if info.ThemeVersion == "" {
+			return info.Theme
+		}
+		return info.Theme + " (" + info.ThemeVersion + ")"
+	})},
+	{"Known Vulnerabilities", func(info *siteinfo.SiteInfo) string {
+		if info.HighestSeverity == "" {
+			return ""
+		}
+		return fmt.Sprintf("%d", info.Vulnerabilities)
+	}},
+	{"Highest Severity", func(info *siteinfo.SiteInfo) string { return info.HighestSeverity }},
+	{"CMS", func(info *siteinfo.SiteInfo) string { return info.CMS }},
+	{"CMS Version", func(info *siteinfo.SiteInfo) string { return info.CMSVersion }},
+	{"Baseline TTFB (ms)", func(info *siteinfo.SiteInfo) string {
+		if info.BaselineTTFB == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3f", siteinfo.Milliseconds(info.BaselineTTFB))
+	}},
+	{"Network Degraded", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.NetworkDegraded) }},
+	{"Technologies", func(info *siteinfo.SiteInfo) string {
+		var technologies []string
+		for _, technology := range info.Technologies {
+			technologies = append(technologies, technology.String())
+		}
+		return strings.Join(technologies, "; ")
+	}},
+	{"DNS A", dnsColumn(func(dns *siteinfo.DNSRecords) []string { return dns.A })},
+	{"DNS AAAA", dnsColumn(func(dns *siteinfo.DNSRecords) []string { return dns.AAAA })},
+	{"DNS CNAME", func(info *siteinfo.SiteInfo) string {
+		if info.DNS == nil {
+			return ""
+		}
+		return info.DNS.CNAME
+	}},
+	{"DNS MX", dnsColumn(func(dns *siteinfo.DNSRecords) []string { return dns.MX })},
+	{"DNS NS", dnsColumn(func(dns *siteinfo.DNSRecords) []string { return dns.NS })},
+	{"DNS TXT", dnsColumn(func(dns *siteinfo.DNSRecords) []string { return dns.TXT })},
+	{"SPF Policy", mailColumn(func(auth *siteinfo.MailAuth) string { return auth.SPFPolicy })},
+	{"DMARC Policy", mailColumn(func(auth *siteinfo.MailAuth) string { return auth.DMARCPolicy })},
+	{"DKIM Selectors", mailColumn(func(auth *siteinfo.MailAuth) string { return strings.Join(auth.DKIMSelectors, "; ") })},
+	{"Login TTFB (ms)", func(info *siteinfo.SiteInfo) string {
+		if info.LoginTTFB == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.3f", siteinfo.Milliseconds(info.LoginTTFB))
+	}},
+	{"Media Offload", func(info *siteinfo.SiteInfo) string { return info.MediaOffload }},
+	{"Domain Registrar", domainColumn(func(domain *siteinfo.DomainRegistration) string { return domain.Registrar })},
+	{"Domain Expires", domainColumn(func(domain *siteinfo.DomainRegistration) string {
+		if domain.Expires.IsZero() {
+			return ""
+		}
+		return domain.Expires.Format("2006-01-02")
+	})},
+	{"Domain Days Until Expiry", domainColumn(func(domain *siteinfo.DomainRegistration) string {
+		if domain.Expires.IsZero() {
+			return ""
+		}
+		return fmt.Sprintf("%d", domain.DaysUntilExpiry)
+	})},
+	{"Domain Expiring Soon", domainColumn(func(domain *siteinfo.DomainRegistration) string { return fmt.Sprintf("%t", domain.ExpiringSoon) })},
+	{"Image CDN", func(info *siteinfo.SiteInfo) string { return info.ImageCDN }},
+	{"Hosting Provider", func(info *siteinfo.SiteInfo) string { return info.HostingProvider }},
+	{"ASN", func(info *siteinfo.SiteInfo) string { return info.ASN }},
+	{"Country", func(info *siteinfo.SiteInfo) string { return info.Country }},
+	{"Contact Form", func(info *siteinfo.SiteInfo) string { return info.ContactForm }},
+	{"Form Plugins", func(info *siteinfo.SiteInfo) string { return strings.Join(info.FormPlugins, "; ") }},
+	{"IP Addresses", func(info *siteinfo.SiteInfo) string { return strings.Join(info.IPAddresses, "; ") }},
+	{"PTR", func(info *siteinfo.SiteInfo) string { return info.PTR }},
+	{"Ecommerce Platform", ecommerceColumn(func(store *siteinfo.Ecommerce) string { return store.Platform })},
+	{"Payment Gateways", ecommerceColumn(func(store *siteinfo.Ecommerce) string { return strings.Join(store.PaymentGateways, "; ") })},
+	{"Checkout SSL", ecommerceColumn(func(store *siteinfo.Ecommerce) string { return store.CheckoutSSL })},
+	{"IPv6", func(info *siteinfo.SiteInfo) string { return info.IPv6 }},
+	{"Page Weight (bytes)", func(info *siteinfo.SiteInfo) string {
+		if info.PageWeight == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d", info.PageWeight)
+	}},
+	{"HTTP Version", func(info *siteinfo.SiteInfo) string { return info.HTTPVersion }},
+	{"HTTP/3", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.HTTP3) }},
+	{"Compression", func(info *siteinfo.SiteInfo) string { return info.Compression }},
+	{"Compressed Size (bytes)", func(info *siteinfo.SiteInfo) string {
+		if info.Compression == "" {
+			return ""
+		}
+		return fmt.Sprintf("%d", info.CompressedSize)
+	}},
+	{"Uncompressed Size (bytes)", func(info *siteinfo.SiteInfo) string {
+		if info.Compression == "" {
+			return ""
+		}
+		return fmt.Sprintf("%d", info.UncompressedSize)
+	}},
+	{"TTFB Percentile", func(info *siteinfo.SiteInfo) string {
+		if info.Percentiles == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.0f", info.Percentiles.TTFB)
+	}},
+	{"Page Weight Percentile", func(info *siteinfo.SiteInfo) string {
+		if info.Percentiles == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.0f", info.Percentiles.PageWeight)
+	}},
+	{"Final URL", func(info *siteinfo.SiteInfo) string { return info.FinalURL }},
+	{"Redirect Chain", func(info *siteinfo.SiteInfo) string {
+		var hops []string
+		for _, hop := range info.RedirectChain {
+			hops = append(hops, hop.String())
+		}
+		return strings.Join(hops, "; ")
+	}},
+	{"Excessive Redirects", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.ExcessiveRedirects) }},
+	{"Canonical Redirects", func(info *siteinfo.SiteInfo) string {
+		if info.Canonicalization == nil {
+			return ""
+		}
+		var probes []string
+		for _, probe := range info.Canonicalization.Probes {
+			probes = append(probes, probe.URL+": "+probe.Result)
+		}
+		return strings.Join(probes, "; ")
+	}},
+	{"Canonicalization OK", func(info *siteinfo.SiteInfo) string {
+		if info.Canonicalization == nil {
+			return ""
+		}
+		return fmt.Sprintf("%t", info.Canonicalization.OK)
+	}},
+	{"Remediations", func(info *siteinfo.SiteInfo) string {
+		var remediations []string
+		for _, remediation := range info.Remediations {
+			remediations = append(remediations, remediation.String())
+		}
+		return strings.Join(remediations, "; ")
+	}},
+	{"Mixed Content Count", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%d", len(info.MixedContent)) }},
+	{"Mixed Content URLs", func(info *siteinfo.SiteInfo) string { return strings.Join(info.MixedContent, "; ") }},
+	{"WP-CLI Script", func(info *siteinfo.SiteInfo) string { return info.WPCLIScript }},
+	{"Scripts", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%d", info.Assets.Scripts) }},
+	{"Stylesheets", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%d", info.Assets.Stylesheets) }},
+	{"Images", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%d", info.Assets.Images) }},
+	{"DNS Propagation", func(info *siteinfo.SiteInfo) string {
+		if info.Propagation == nil {
+			return ""
+		}
+		return info.Propagation.Status
+	}},
+	{"DNS Propagation Detail", func(info *siteinfo.SiteInfo) string {
+		if info.Propagation == nil {
+			return ""
+		}
+		return info.Propagation.String()
+	}},
+	{"Purge Status", func(info *siteinfo.SiteInfo) string {
+		if info.Purge == nil {
+			return ""
+		}
+		return info.Purge.Status
+	}},
+	{"Purge Detail", func(info *siteinfo.SiteInfo) string {
+		if info.Purge == nil {
+			return ""
+		}
+		return info.Purge.Detail
+	}},
+	{"Vary", func(info *siteinfo.SiteInfo) string {
+		if info.CacheKey == nil {
+			return ""
+		}
+		return info.CacheKey.Vary
+	}},
+	{"Cache Key Issues", func(info *siteinfo.SiteInfo) string {
+		if info.CacheKey == nil {
+			return ""
+		}
+		return strings.Join(info.CacheKey.Issues, "; ")
+	}},
+	{"ETag", func(info *siteinfo.SiteInfo) string {
+		if info.Revalidation == nil {
+			return ""
+		}
+		return info.Revalidation.ETag
+	}},
+	{"Last-Modified", func(info *siteinfo.SiteInfo) string {
+		if info.Revalidation == nil {
+			return ""
+		}
+		return info.Revalidation.LastModified
+	}},
+	{"Conditional Request", func(info *siteinfo.SiteInfo) string {
+		if info.Revalidation == nil {
+			return ""
+		}
+		return info.Revalidation.Status
+	}},
+	{"Smuggling Indicators", func(info *siteinfo.SiteInfo) string { return strings.Join(info.SmugglingIndicators, "; ") }},
+	{"robots.txt", func(info *siteinfo.SiteInfo) string { return info.RobotsTxt }},
+	{"Skipped By robots.txt", func(info *siteinfo.SiteInfo) string { return strings.Join(info.RobotsBlocked, "; ") }},
+	{"Hotlink Issues", func(info *siteinfo.SiteInfo) string { return strings.Join(info.HotlinkIssues, "; ") }},
+	{"Plugin Update Lag", wordPressColumn(func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%d", info.PluginUpdateLag) })},
+	{"Outdated Plugins", wordPressColumn(func(info *siteinfo.SiteInfo) string {
+		var outdated []string
+		for _, plugin := range info.Plugins {
+			if plugin.ReleasesBehind > 0 {
+				outdated = append(outdated, fmt.Sprintf("%s %s -> %s (%d releases, %d days)", plugin.Slug, plugin.Version, plugin.LatestVersion, plugin.ReleasesBehind, plugin.DaysBehind))
+			}
+		}
+		return strings.Join(outdated, "; ")
+	})},
+	{"Abandoned Components", wordPressColumn(func(info *siteinfo.SiteInfo) string {
+		var abandoned []string
+		for _, plugin := range info.Plugins {
+			if plugin.Abandoned != "" {
+				abandoned = append(abandoned, plugin.Slug+" ("+plugin.Abandoned+")")
+			}
+		}
+		if info.ThemeAbandoned != "" {
+			abandoned = append(abandoned, "theme "+info.Theme+" ("+info.ThemeAbandoned+")")
+		}
+		return strings.Join(abandoned, "; ")
+	})},
+	{"Licenses", licenseColumn(false)},
+	{"Non-GPL Licenses", licenseColumn(true)},
+	{"Headless Frontend", func(info *siteinfo.SiteInfo) string { return info.HeadlessFrontend }},
+	{"WordPress Backend", func(info *siteinfo.SiteInfo) string { return info.WordPressBackend }},
+	{"Platform", func(info *siteinfo.SiteInfo) string { return info.Platform }},
+	{"Crawled Pages", func(info *siteinfo.SiteInfo) string {
+		if info.Crawl == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d of %d", len(info.Crawl.Pages)-info.Crawl.FailedPages, info.Crawl.SitemapPages)
+	}},
+	{"Crawl Average TTFB (ms)", func(info *siteinfo.SiteInfo) string {
+		if info.Crawl == nil || len(info.Crawl.Pages) == info.Crawl.FailedPages {
+			return ""
+		}
+		return fmt.Sprintf("%.3f", siteinfo.Milliseconds(info.Crawl.AverageTTFB))
+	}},
+	{"Crawl Slowest Page", func(info *siteinfo.SiteInfo) string {
+		if info.Crawl == nil || info.Crawl.SlowestPage == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s (%.3fms)", info.Crawl.SlowestPage, siteinfo.Milliseconds(info.Crawl.SlowestTTFB))
+	}},
+	{"Crawl Cached Pages", func(info *siteinfo.SiteInfo) string {
+		if info.Crawl == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d", info.Crawl.CachedPages)
+	}},
+	{"Crawl Mixed Content Pages", func(info *siteinfo.SiteInfo) string {
+		if info.Crawl == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d", info.Crawl.MixedContentPages)
+	}},
+	{"Health Score", func(info *siteinfo.SiteInfo) string {
+		if info.HealthGrade == "" {
+			return ""
+		}
+		return fmt.Sprintf("%d", info.HealthScore)
+	}},
+	{"Health Grade", func(info *siteinfo.SiteInfo) string { return info.HealthGrade }},
+	{"PHP Signal", func(info *siteinfo.SiteInfo) string {
+		if info.PHPDetection == nil {
+			return ""
+		}
+		return info.PHPDetection.Evidence
+	}},
+	{"PHP Confidence", func(info *siteinfo.SiteInfo) string {
+		if info.PHPDetection == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", info.PHPDetection.Confidence)
+	}},
+	{"WordPress", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.IsWordPress) }},
+	{"WordPress Signals", func(info *siteinfo.SiteInfo) string { return strings.Join(info.WordPressSignals, "; ") }},
+	{"SSL Issues", func(info *siteinfo.SiteInfo) string { return strings.Join(info.SSLIssues, "; ") }},
+	{"OCSP Stapling", func(info *siteinfo.SiteInfo) string { return info.OCSPStapling }},
+	{"Revocation Status", func(info *siteinfo.SiteInfo) string { return info.RevocationStatus }},
+	{"CT Recent Certificates", ctColumn(func(ct *siteinfo.CTLog) string { return fmt.Sprintf("%d", len(ct.Recent)) })},
+	{"CT Issuers", ctColumn(func(ct *siteinfo.CTLog) string { return strings.Join(ct.Issuers, "; ") })},
+	{"CT Unexpected Issuers", ctColumn(func(ct *siteinfo.CTLog) string { return strings.Join(ct.UnexpectedIssuers, "; ") })},
+	{"HSTS Max-Age", hstsColumn(func(hsts *siteinfo.HSTS) string { return fmt.Sprintf("%d", hsts.MaxAge) })},
+	{"HSTS includeSubDomains", hstsColumn(func(hsts *siteinfo.HSTS) string { return fmt.Sprintf("%t", hsts.IncludeSubDomains) })},
+	{"HSTS Preload Directive", hstsColumn(func(hsts *siteinfo.HSTS) string { return fmt.Sprintf("%t", hsts.Preload) })},
+	{"HSTS Preload Issues", hstsColumn(func(hsts *siteinfo.HSTS) string { return strings.Join(hsts.PreloadIssues, "; ") })},
+	{"HSTS Preload Status", func(info *siteinfo.SiteInfo) string { return info.HSTSPreload }},
+	{"Cookie Count", cookieColumn(func(cookies *siteinfo.CookieAudit) string { return fmt.Sprintf("%d", cookies.Count) })},
+	{"Cookies Missing Secure", cookieColumn(func(cookies *siteinfo.CookieAudit) string { return strings.Join(cookies.MissingSecure, "; ") })},
+	{"Cookies Missing HttpOnly", cookieColumn(func(cookies *siteinfo.CookieAudit) string { return strings.Join(cookies.MissingHttpOnly, "; ") })},
+	{"Cookies Missing SameSite", cookieColumn(func(cookies *siteinfo.CookieAudit) string { return strings.Join(cookies.MissingSameSite, "; ") })},
+	{"Status Code", func(info *siteinfo.SiteInfo) string {
+		if info.StatusCode == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d", info.StatusCode)
+	}},
+	{"Site State", func(info *siteinfo.SiteInfo) string { return info.SiteState }},
+	{"Site State Reason", func(info *siteinfo.SiteInfo) string { return info.SiteStateReason }},
+	{"WAF", func(info *siteinfo.SiteInfo) string { return strings.Join(info.WAF, "; ") }},
+	{"WAF Blocked", func(info *siteinfo.SiteInfo) string { return fmt.Sprintf("%t", info.WAFBlocked) }},
+	{"Blocklisted", blocklistColumn(func(blocklists *siteinfo.Blocklists) string { return fmt.Sprintf("%t", blocklists.Flagged) })},
+	{"Safe Browsing Threats", blocklistColumn(func(blocklists *siteinfo.Blocklists) string { return strings.Join(blocklists.SafeBrowsing, "; ") })},
+	{"DNSBL Listings", blocklistColumn(func(blocklists *siteinfo.Blocklists) string { return strings.Join(blocklists.DNSBL, "; ") })},
+	{"SEO Title", seoColumn(func(seo *siteinfo.SEO) string { return seo.Title })},
+	{"SEO Description", seoColumn(func(seo *siteinfo.SEO) string { return seo.Description })},
+	{"SEO Canonical", seoColumn(func(seo *siteinfo.SEO) string { return seo.Canonical })},
+	{"SEO Robots", seoColumn(func(seo *siteinfo.SEO) string { return seo.Robots })},
+	{"Hreflang", seoColumn(func(seo *siteinfo.SEO) string { return strings.Join(seo.Hreflang, "; ") })},
+	{"SEO Issues", seoColumn(func(seo *siteinfo.SEO) string { return strings.Join(seo.Issues, "; ") })},
+	{"Structured Data Types", structuredDataColumn(func(data *siteinfo.StructuredData) string { return strings.Join(data.Types, "; ") })},
+	{"JSON-LD Blocks", structuredDataColumn(func(data *siteinfo.StructuredData) string { return fmt.Sprintf("%d", data.Blocks) })},
+	{"Invalid JSON-LD Blocks", structuredDataColumn(func(data *siteinfo.StructuredData) string { return fmt.Sprintf("%d", data.Invalid) })},
+	{"Analytics", func(info *siteinfo.SiteInfo) string {
+		var tags []string
+		for _, tag := range info.Analytics {
+			tags = append(tags, tag.String())
+		}
+		return strings.Join(tags, "; ")
+	}},
+	{"Detail File", func(info *siteinfo.SiteInfo) string { return info.DetailFile }},
+}
+
+func init() {
+	Register("csv", func(filePath string) (OutputWriter, error) { return newCSVWriter(filePath, columns) })
+}
+
+// nonKeyChars are the runs of characters replaced by underscores in column keys
+var nonKeyChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// columnKey returns the key naming a CSV column in -columns: its header in lower case with
+// every other run of characters replaced by an underscore, e.g. php_version for PHP Version
+func columnKey(header string) string {
+	return strings.Trim(nonKeyChars.ReplaceAllString(strings.ToLower(header), "_"), "_")
+}
+
+// ColumnKeys returns the keys of every CSV column, in the default order
+func ColumnKeys() []string {
+	keys := make([]string, len(columns))
+	for i, col := range columns {
+		keys[i] = columnKey(col.Header)
+	}
+	return keys
+}
+
+// AddCustomColumns adds a column for each custom field of the user's detection rules, before
+// the Detail File column, to the outputs written in the CSV columns. It fails on a field whose
+// key is taken by another column.
+func AddCustomColumns(fields []string) error {
+	keys := map[string]bool{}
+	for _, col := range columns {
+		keys[columnKey(col.Header)] = true
+	}
+	var custom []column
+	for _, field := range fields {
+		if keys[columnKey(field)] {
+			return fmt.Errorf("custom field %q is named like another column", field)
+		}
+		keys[columnKey(field)] = true
+		custom = append(custom, column{field, func(info *siteinfo.SiteInfo) string { return info.CustomFields[field] }})
+	}
+	last := len(columns) - 1
+	columns = slices.Concat(columns[:last], custom, columns[last:])
+	return nil
+}
+
+// AddRegionColumns adds columns for the average and 95th percentile TTFB measured from each
+// agent's region, after the local TTFB columns, to the outputs written in the CSV columns
+func AddRegionColumns(regions []string) {
+	var regional []column
+	for _, region := range regions {
+		stat := func(value func(ttfb siteinfo.RegionalTTFB) time.Duration) func(info *siteinfo.SiteInfo) string {
+			return func(info *siteinfo.SiteInfo) string {
+				for _, ttfb := range info.RegionalTTFB {
+					if ttfb.Region == region && ttfb.Error == "" {
+						return fmt.Sprintf("%.3f", siteinfo.Milliseconds(value(ttfb)))
+					}
+				}
+				return ""
+			}
+		}
+		regional = append(regional,
+			column{"Average TTFB " + region + " (ms)", stat(func(ttfb siteinfo.RegionalTTFB) time.Duration { return ttfb.Average })},
+			column{"TTFB P95 " + region + " (ms)", stat(func(ttfb siteinfo.RegionalTTFB) time.Duration { return ttfb.Stats.P95 })},
+		)
+	}
+	after := slices.IndexFunc(columns, func(col column) bool { return col.Header == "TTFB Std Dev (ms)" }) + 1
+	columns = slices.Concat(columns[:after], regional, columns[after:])
+}
+
+// CSVColumns returns a factory for CSV writers with only the columns named by the keys, in
+// the order given. It fails on keys that name no column.
+func CSVColumns(keys []string) (WriterFactory, error) {
+	byKey := map[string]column{}
+	for _, col := range columns {
+		byKey[columnKey(col.Header)] = col
+	}
+	var selected []column
+	var unknown []string
+	for _, key := range keys {
+		col, ok := byKey[strings.ToLower(strings.TrimSpace(key))]
+		if !ok {
+			unknown = append(unknown, key)
+			continue
+		}
+		selected = append(selected, col)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown columns: %s", strings.Join(unknown, ", "))
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no columns selected")
+	}
+	return func(filePath string) (OutputWriter, error) { return newCSVWriter(filePath, selected) }, nil
+}
+
+// csvWriter writes one row per site to a CSV file
+type csvWriter struct {
+	file    *os.File
+	writer  *csv.Writer
+	columns []column
+}
+
+// newCSVWriter creates the CSV file and writes the header of the columns
+func newCSVWriter(filePath string, columns []column) (*csvWriter, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	writer := csv.NewWriter(file)
+
+	// Write header
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Header
+	}
+	writer.Write(header)
+	return &csvWriter{file: file, writer: writer, columns: columns}, nil
+}
+
+// Write writes the site's row
+func (w *csvWriter) Write(info *siteinfo.SiteInfo) error {
+	row := make([]string, len(w.columns))
+	for i, col := range w.columns {
+		row[i] = col.Value(info)
+	}
+	w.writer.Write(row)
+	return w.writer.Error()
+}
+
+// Flush writes any buffered rows and closes the file
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	err := w.writer.Error()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteCSV writes the site information to a CSV file
+func WriteCSV(filePath string, siteInfos []*siteinfo.SiteInfo) error {
+	writer, err := newCSVWriter(filePath, columns)
+	if err != nil {
+		return err
+	}
+	return WriteAll(writer, siteInfos)
+}
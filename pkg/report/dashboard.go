@@ -0,0 +1,369 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// Dashboard serves scan results as a searchable, filterable HTML page, with a page of each
+// site's findings and, when the SQLite database is given, its scan history
+type Dashboard struct {
+	Title       string
+	GeneratedAt time.Time
+	Sites       []*siteinfo.SiteInfo
+	// DB is the SQLite database written with -db whose history is shown on each site's page
+	DB string
+}
+
+// dashboardQuery is the search and filters of the site list, from the query string. The
+// filter lists the sites with an outdated component, with a certificate problem, or with
+// known vulnerabilities.
+type dashboardQuery struct {
+	Search string
+	Filter string
+	Grade  string
+}
+
+// matches reports whether the site is listed under the query. The search matches the URL,
+// CMS, theme, versions, web server and hosting provider, ignoring case.
+func (q dashboardQuery) matches(info *siteinfo.SiteInfo) bool {
+	if q.Search != "" {
+		text := strings.ToLower(strings.Join([]string{info.URL, info.CMS, info.CMSVersion, info.Theme, info.WordPressVersion,
+			info.PHPVersion, info.MySQLVersion, info.WebServer, info.WebServerVersion, info.HostingProvider, info.Platform}, " "))
+		if !strings.Contains(text, strings.ToLower(q.Search)) {
+			return false
+		}
+	}
+	if q.Grade != "" && info.HealthGrade != q.Grade {
+		return false
+	}
+	switch q.Filter {
+	case "outdated":
+		return slices.Contains([]string{info.WordPressStatus, info.PHPStatus, info.MySQLStatus, info.WebServerStatus}, "Outdated")
+	case "ssl":
+		return newHTMLCertificate(info).Class != "good"
+	case "vulnerable":
+		return info.Vulnerabilities > 0
+	}
+	return true
+}
+
+// dashboardTemplate has no external assets, like the HTML report whose styles and sortable
+// tables it shares
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"when": func(t time.Time) string { return t.Local().Format("2 Jan 2006 15:04") },
+}).Parse(`{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2em; color: #222; }
+a { color: #2457b3; }
+h1 { margin-bottom: 0.2em; }
+.generated { color: #666; margin-top: 0; }
+.cards { display: flex; flex-wrap: wrap; gap: 1em; margin: 1.5em 0; }
+.card { border: 1px solid #ddd; border-radius: 6px; padding: 0.8em 1.2em; min-width: 9em; }
+.card .value { font-size: 1.6em; font-weight: bold; }
+.card .label { color: #666; font-size: 0.9em; }
+form { display: flex; flex-wrap: wrap; gap: 0.6em; margin-bottom: 1.5em; }
+input, select, button { font: inherit; padding: 0.3em 0.5em; }
+input[type=search] { min-width: 20em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #eee; padding: 0.45em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f6f6f6; cursor: pointer; user-select: none; white-space: nowrap; }
+th.sorted-asc::after { content: " \25B2"; }
+th.sorted-desc::after { content: " \25BC"; }
+.status { display: inline-block; border-radius: 4px; padding: 0.1em 0.5em; font-size: 0.85em; }
+.good { background: #e3f4e6; color: #1d6b2c; }
+.warn { background: #fff3d6; color: #8a5a00; }
+.bad { background: #fde4e4; color: #a11d1d; }
+.unknown { background: #eee; color: #555; }
+.chart rect { fill: #6a8fd8; }
+.findings th { cursor: default; background: none; font-weight: normal; color: #666; width: 16em; }
+pre { background: #f6f6f6; padding: 1em; overflow-x: auto; font-size: 0.85em; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "foot"}}<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, column) {
+    th.addEventListener("click", function () {
+      var ascending = !th.classList.contains("sorted-asc");
+      table.querySelectorAll("th").forEach(function (other) { other.classList.remove("sorted-asc", "sorted-desc"); });
+      th.classList.add(ascending ? "sorted-asc" : "sorted-desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      var key = function (row) {
+        var cell = row.cells[column];
+        return cell.hasAttribute("data-sort") ? cell.getAttribute("data-sort") : cell.textContent.trim();
+      };
+      rows.sort(function (a, b) {
+        var x = key(a), y = key(b);
+        var order = (x !== "" && y !== "" && !isNaN(x) && !isNaN(y)) ? x - y : x.localeCompare(y, undefined, {numeric: true});
+        return ascending ? order : -order;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+{{end}}
+
+{{define "component"}}<td data-sort="{{.Status}} {{.Version}}">{{if .Version}}{{.Version}} {{end}}<span class="status {{.Class}}">{{.Status}}</span></td>{{end}}
+
+{{define "index"}}{{template "head" .Title}}<h1>{{.Title}}</h1>
+<p class="generated">{{.Total}} sites, scanned {{when .GeneratedAt}}{{if .DB}} &middot; history from {{.DB}}{{end}}</p>
+
+<form method="get" action="/">
+<input type="search" name="q" value="{{.Query.Search}}" placeholder="Search URL, CMS, version, server, host" autofocus>
+<select name="filter">
+<option value="">All sites</option>
+<option value="outdated"{{if eq .Query.Filter "outdated"}} selected{{end}}>Outdated software</option>
+<option value="ssl"{{if eq .Query.Filter "ssl"}} selected{{end}}>Certificate problems</option>
+<option value="vulnerable"{{if eq .Query.Filter "vulnerable"}} selected{{end}}>Known vulnerabilities</option>
+</select>
+<select name="grade">
+<option value="">Any grade</option>
+{{range .Grades}}<option value="{{.}}"{{if eq $.Query.Grade .}} selected{{end}}>Grade {{.}}</option>
+{{end}}</select>
+<button type="submit">Filter</button>
+{{if or .Query.Search .Query.Filter .Query.Grade}}<a href="/">Clear</a>{{end}}
+</form>
+
+<div class="cards">
+<div class="card"><div class="value">{{len .Sites}}</div><div class="label">Sites shown</div></div>
+<div class="card"><div class="value">{{.Outdated}}</div><div class="label">With outdated software</div></div>
+<div class="card"><div class="value">{{.CertificateIssues}}</div><div class="label">Certificates invalid or expiring</div></div>
+<div class="card"><div class="value">{{printf "%.0f" .AverageTTFB}} ms</div><div class="label">Average response time</div></div>
+</div>
+
+<table class="sortable">
+<thead><tr><th>Site</th><th>Health</th><th>CMS</th><th>WordPress</th><th>PHP</th><th>MySQL</th><th>Web server</th><th>SSL certificate</th><th>Response time</th></tr></thead>
+<tbody>
+{{range .Sites}}<tr>
+<td data-sort="{{.URL}}"><a href="/site?url={{.URL}}">{{.URL}}</a></td>
+<td data-sort="{{.HealthScore}}">{{if .HealthGrade}}{{.HealthGrade}} ({{.HealthScore}}){{else}}&ndash;{{end}}</td>
+<td>{{.CMS}}</td>
+{{template "component" .WordPress}}
+{{template "component" .PHP}}
+{{template "component" .MySQL}}
+{{template "component" .WebServer}}
+<td data-sort="{{.SSL.Sort}}"><span class="status {{.SSL.Class}}">{{.SSL.Text}}</span></td>
+<td data-sort="{{printf "%.3f" .AverageTTFB}}">{{if .TTFBs}}{{printf "%.0f" .AverageTTFB}} ms<br>{{.Chart}}{{else}}&ndash;{{end}}</td>
+</tr>
+{{else}}<tr><td colspan="9">No sites match.</td></tr>
+{{end}}</tbody>
+</table>
+<p><a href="/results.json?q={{.Query.Search}}&amp;filter={{.Query.Filter}}&amp;grade={{.Query.Grade}}">Download these sites as JSON</a></p>
+{{template "foot"}}{{end}}
+
+{{define "site"}}{{template "head" .URL}}<p><a href="/">&larr; All sites</a></p>
+<h1>{{.URL}}</h1>
+<p class="generated">{{if .HealthGrade}}Health {{.HealthGrade}} ({{.HealthScore}}){{end}}</p>
+
+<h2>Findings</h2>
+<table class="findings">
+{{range .Findings}}<tr><th>{{.Header}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+
+{{if .History}}<h2>History</h2>
+<table class="sortable">
+<thead><tr><th>Scanned</th><th>WordPress</th><th>PHP</th><th>MySQL</th><th>Web server</th><th>SSL</th><th>Response time</th></tr></thead>
+<tbody>
+{{range .History}}<tr>
+<td data-sort="{{.ScannedAt.Unix}}">{{when .ScannedAt}}</td>
+<td>{{.WordPressVersion}} {{.WordPressStatus}}</td>
+<td>{{.PHPVersion}} {{.PHPStatus}}</td>
+<td>{{.MySQLVersion}}</td>
+<td>{{.WebServer}} {{.WebServerVersion}}</td>
+<td>{{if .SSLValid}}Valid{{else}}Invalid{{end}}</td>
+<td data-sort="{{printf "%.3f" .AverageTTFB}}">{{printf "%.0f" .AverageTTFB}} ms</td>
+</tr>
+{{end}}</tbody>
+</table>
+{{end}}
+
+<h2>Full result</h2>
+<pre>{{.JSON}}</pre>
+{{template "foot"}}{{end}}
+`))
+
+// dashboardSite is one site's row on the dashboard
+type dashboardSite struct {
+	htmlSite
+	HealthScore int
+	HealthGrade string
+}
+
+// newDashboardSite returns the site's row, as in the HTML report's overview
+func newDashboardSite(info *siteinfo.SiteInfo) dashboardSite {
+	site := dashboardSite{
+		htmlSite: htmlSite{
+			URL:       info.URL,
+			CMS:       info.CMS,
+			WordPress: newHTMLComponent(info.WordPressVersion, info.WordPressStatus),
+			PHP:       newHTMLComponent(info.PHPVersion, info.PHPStatus),
+			MySQL:     newHTMLComponent(info.MySQLVersion, info.MySQLStatus),
+			WebServer: newHTMLComponent(strings.TrimSpace(info.WebServer+" "+info.WebServerVersion), info.WebServerStatus),
+			SSL:       newHTMLCertificate(info),
+		},
+		HealthScore: info.HealthScore,
+		HealthGrade: info.HealthGrade,
+	}
+	if len(info.TTFBs) > 0 {
+		site.AverageTTFB = siteinfo.Milliseconds(info.AverageTTFB)
+		for _, ttfb := range info.TTFBs {
+			site.TTFBs = append(site.TTFBs, siteinfo.Milliseconds(ttfb))
+		}
+		site.Chart = ttfbChart(site.TTFBs)
+	}
+	return site
+}
+
+// Handler returns the dashboard's routes: the site list at /, each site's page at
+// /site?url=<url>, and the listed sites as a JSON report at /results.json. The list and the
+// JSON take the search in q, a filter of outdated, ssl or vulnerable, and a health grade.
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", d.index)
+	mux.HandleFunc("GET /site", d.site)
+	mux.HandleFunc("GET /results.json", d.results)
+	return mux
+}
+
+// query returns the search and filters of the request
+func (d *Dashboard) query(r *http.Request) dashboardQuery {
+	return dashboardQuery{
+		Search: strings.TrimSpace(r.URL.Query().Get("q")),
+		Filter: r.URL.Query().Get("filter"),
+		Grade:  strings.ToUpper(r.URL.Query().Get("grade")),
+	}
+}
+
+// listed returns the sites matching the query
+func (d *Dashboard) listed(q dashboardQuery) []*siteinfo.SiteInfo {
+	var listed []*siteinfo.SiteInfo
+	for _, info := range d.Sites {
+		if q.matches(info) {
+			listed = append(listed, info)
+		}
+	}
+	return listed
+}
+
+// index serves the list of the sites matching the query
+func (d *Dashboard) index(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Title             string
+		GeneratedAt       time.Time
+		DB                string
+		Total             int
+		Query             dashboardQuery
+		Grades            []string
+		Outdated          int
+		CertificateIssues int
+		AverageTTFB       float64
+		Sites             []dashboardSite
+	}{Title: d.Title, GeneratedAt: d.GeneratedAt, DB: d.DB, Total: len(d.Sites), Query: d.query(r), Grades: []string{"A", "B", "C", "D", "F"}}
+
+	var timed int
+	for _, info := range d.listed(data.Query) {
+		site := newDashboardSite(info)
+		for _, component := range []htmlComponent{site.WordPress, site.PHP, site.MySQL, site.WebServer} {
+			if component.Status == "Outdated" {
+				data.Outdated++
+				break
+			}
+		}
+		if site.SSL.Class != "good" {
+			data.CertificateIssues++
+		}
+		if len(site.TTFBs) > 0 {
+			data.AverageTTFB += site.AverageTTFB
+			timed++
+		}
+		data.Sites = append(data.Sites, site)
+	}
+	if timed > 0 {
+		data.AverageTTFB /= float64(timed)
+	}
+	d.render(w, "index", data)
+}
+
+// site serves the page of one site: its findings, its history when there is a database, and
+// its full result
+func (d *Dashboard) site(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	i := slices.IndexFunc(d.Sites, func(info *siteinfo.SiteInfo) bool { return info.URL == url })
+	if i < 0 {
+		http.Error(w, "no results for "+url, http.StatusNotFound)
+		return
+	}
+	info := d.Sites[i]
+
+	result, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := struct {
+		URL         string
+		HealthScore int
+		HealthGrade string
+		Findings    []htmlFinding
+		History     []HistoryEntry
+		JSON        string
+	}{URL: info.URL, HealthScore: info.HealthScore, HealthGrade: info.HealthGrade, JSON: string(result)}
+	for _, col := range columns {
+		if value := col.Value(info); value != "" {
+			data.Findings = append(data.Findings, htmlFinding{Header: col.Header, Value: value})
+		}
+	}
+	if d.DB != "" {
+		data.History, err = History(d.DB, info.URL)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("history of %s: %v", info.URL, err), http.StatusInternalServerError)
+			return
+		}
+		// Newest first, as the latest scans are the ones looked for
+		slices.Reverse(data.History)
+	}
+	d.render(w, "site", data)
+}
+
+// results serves the sites matching the query in the JSON report format
+func (d *Dashboard) results(w http.ResponseWriter, r *http.Request) {
+	listed := d.listed(d.query(r))
+	if listed == nil {
+		listed = []*siteinfo.SiteInfo{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(listed)
+}
+
+// render executes the named template, answering with an error if it fails before anything
+// was written
+func (d *Dashboard) render(w http.ResponseWriter, name string, data any) {
+	var b strings.Builder
+	if err := dashboardTemplate.ExecuteTemplate(&b, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
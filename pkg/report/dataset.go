@@ -0,0 +1,110 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// Sample is the anonymized metrics of one site from a previous scan
+type Sample struct {
+	TTFB       float64 `json:"ttfb_ms"`
+	PageWeight int64   `json:"page_weight_bytes"`
+}
+
+// Dataset is an anonymized aggregate of previous scans used to rank sites by percentile.
+// Samples are keyed by a hash of the site URL, so rescanning a site replaces its sample
+// without the dataset revealing which sites were scanned.
+type Dataset struct {
+	Samples map[string]Sample `json:"samples"`
+}
+
+// LoadDataset reads the dataset from filePath. A missing file is an empty dataset.
+func LoadDataset(filePath string) (*Dataset, error) {
+	dataset := &Dataset{Samples: map[string]Sample{}}
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return dataset, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, dataset); err != nil {
+		return nil, err
+	}
+	if dataset.Samples == nil {
+		dataset.Samples = map[string]Sample{}
+	}
+	return dataset, nil
+}
+
+// Save writes the dataset to filePath
+func (d *Dataset) Save(filePath string) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// sampleKey anonymizes a site URL
+func sampleKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// percentileRank returns the percentage of values below value
+func percentileRank(values []float64, value float64) float64 {
+	below := sort.SearchFloat64s(values, value)
+	return float64(below) / float64(len(values)) * 100
+}
+
+// Rank sets each site's percentiles against the samples of the other sites in the dataset.
+// Sites are left unranked while the dataset holds no other samples.
+func (d *Dataset) Rank(siteInfos []*siteinfo.SiteInfo) {
+	for _, info := range siteInfos {
+		var ttfbs, weights []float64
+		for key, sample := range d.Samples {
+			if key == sampleKey(info.URL) {
+				continue
+			}
+			if sample.TTFB > 0 {
+				ttfbs = append(ttfbs, sample.TTFB)
+			}
+			if sample.PageWeight > 0 {
+				weights = append(weights, float64(sample.PageWeight))
+			}
+		}
+		if len(ttfbs) == 0 && len(weights) == 0 {
+			continue
+		}
+		sort.Float64s(ttfbs)
+		sort.Float64s(weights)
+		info.Percentiles = &siteinfo.Percentiles{}
+		if len(ttfbs) > 0 && info.AverageTTFB > 0 {
+			info.Percentiles.TTFB = percentileRank(ttfbs, siteinfo.Milliseconds(info.AverageTTFB))
+		}
+		if len(weights) > 0 && info.PageWeight > 0 {
+			info.Percentiles.PageWeight = percentileRank(weights, float64(info.PageWeight))
+		}
+	}
+}
+
+// Add records the metrics of the scanned sites, replacing earlier samples of the same sites
+func (d *Dataset) Add(siteInfos []*siteinfo.SiteInfo) {
+	for _, info := range siteInfos {
+		if info.AverageTTFB == 0 && info.PageWeight == 0 {
+			continue
+		}
+		d.Samples[sampleKey(info.URL)] = Sample{
+			TTFB:       siteinfo.Milliseconds(info.AverageTTFB),
+			PageWeight: info.PageWeight,
+		}
+	}
+}
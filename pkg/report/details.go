@@ -0,0 +1,34 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// WriteDetails writes every finding of each site, including its raw response headers and
+// certificate chain when they were recorded, to its own indented JSON file, and records the
+// file's path in the site information so the compact CSV row can reference it
+func WriteDetails(dir string, siteInfos []*siteinfo.SiteInfo) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	unsafeChars := regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+	for _, info := range siteInfos {
+		name := unsafeChars.ReplaceAllString(strings.TrimPrefix(strings.TrimPrefix(info.URL, "https://"), "http://"), "_")
+		path := filepath.Join(dir, "detail_"+name+".json")
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+		info.DetailFile = path
+	}
+	return nil
+}
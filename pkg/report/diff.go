@@ -0,0 +1,212 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/vuln"
+)
+
+// Kinds of change between two scans
+const (
+	ChangeUpgrade       = "upgrade"
+	ChangeDowngrade     = "downgrade"
+	ChangeChanged       = "changed"
+	ChangeNewlyOutdated = "newly_outdated"
+	ChangeNowSupported  = "now_supported"
+	ChangeSSL           = "ssl"
+	ChangeSlower        = "ttfb_slower"
+	ChangeFaster        = "ttfb_faster"
+	ChangeAdded         = "added"
+	ChangeRemoved       = "removed"
+)
+
+// Change is one difference between two scans of a site
+type Change struct {
+	URL    string `json:"url"`
+	Kind   string `json:"kind"`
+	Field  string `json:"field,omitempty"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ScanDiff is the change report between an earlier and a later scan
+type ScanDiff struct {
+	Changes []Change `json:"changes"`
+}
+
+// diffVersions are the version columns compared for upgrades and downgrades
+var diffVersions = []string{"PHP Version", "MySQL Version", "WordPress Version", "Web Server Version", "CMS Version"}
+
+// diffStatuses are the support status columns compared for newly outdated components
+var diffStatuses = []string{"PHP Status", "MySQL Status", "Web Server Status", "WordPress Status"}
+
+// diffSSL are the columns compared for SSL status changes
+var diffSSL = []string{"SSL Valid", "Certificate Hostname Mismatch", "Certificate Chain"}
+
+// LoadScan reads an earlier CSV or JSON report into one row per site, keyed by CSV column
+// header. JSON reports are flattened into the CSV columns, so reports in either format can be
+// compared with each other.
+func LoadScan(filePath string) ([]map[string]string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]string
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var siteInfos []*siteinfo.SiteInfo
+		if err := json.Unmarshal(data, &siteInfos); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+		}
+		for _, info := range siteInfos {
+			row := map[string]string{}
+			for _, col := range columns {
+				row[col.Header] = col.Value(info)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	for _, record := range records[1:] {
+		row := map[string]string{}
+		for i, value := range record {
+			if i < len(header) {
+				row[header[i]] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// DiffScans compares two scans site by site: version upgrades and downgrades, components that
+// became outdated, SSL status changes, average TTFB changes of at least ttfbThreshold
+// milliseconds, and sites added or removed. Sites are matched by URL.
+func DiffScans(before, after []map[string]string, ttfbThreshold float64) *ScanDiff {
+	diff := &ScanDiff{Changes: []Change{}}
+	earlier := map[string]map[string]string{}
+	for _, row := range before {
+		earlier[row["URL"]] = row
+	}
+	seen := map[string]bool{}
+
+	for _, row := range after {
+		url := row["URL"]
+		seen[url] = true
+		old, ok := earlier[url]
+		if !ok {
+			diff.Changes = append(diff.Changes, Change{URL: url, Kind: ChangeAdded})
+			continue
+		}
+
+		for _, field := range diffVersions {
+			from, to := detectedVersion(old[field]), detectedVersion(row[field])
+			if from == to || !inBoth(old, row, field) {
+				continue
+			}
+			kind := ChangeChanged
+			if from != "" && to != "" {
+				switch vuln.CompareVersions(to, from) {
+				case 1:
+					kind = ChangeUpgrade
+				case -1:
+					kind = ChangeDowngrade
+				}
+			}
+			diff.Changes = append(diff.Changes, Change{URL: url, Kind: kind, Field: field, Before: from, After: to})
+		}
+
+		for _, field := range diffStatuses {
+			from, to := old[field], row[field]
+			switch {
+			case !inBoth(old, row, field):
+			case from != "Outdated" && to == "Outdated":
+				diff.Changes = append(diff.Changes, Change{URL: url, Kind: ChangeNewlyOutdated, Field: field, Before: from, After: to})
+			case from == "Outdated" && to == "Supported":
+				diff.Changes = append(diff.Changes, Change{URL: url, Kind: ChangeNowSupported, Field: field, Before: from, After: to})
+			}
+		}
+
+		for _, field := range diffSSL {
+			if old[field] != row[field] && inBoth(old, row, field) {
+				diff.Changes = append(diff.Changes, Change{URL: url, Kind: ChangeSSL, Field: field, Before: old[field], After: row[field]})
+			}
+		}
+
+		const ttfbField = "Average TTFB (ms)"
+		from, errFrom := strconv.ParseFloat(old[ttfbField], 64)
+		to, errTo := strconv.ParseFloat(row[ttfbField], 64)
+		if errFrom == nil && errTo == nil && math.Abs(to-from) >= ttfbThreshold {
+			kind := ChangeSlower
+			if to < from {
+				kind = ChangeFaster
+			}
+			diff.Changes = append(diff.Changes, Change{URL: url, Kind: kind, Field: ttfbField,
+				Before: old[ttfbField], After: row[ttfbField], Detail: fmt.Sprintf("%+.1f ms", to-from)})
+		}
+	}
+
+	for _, row := range before {
+		if !seen[row["URL"]] {
+			diff.Changes = append(diff.Changes, Change{URL: row["URL"], Kind: ChangeRemoved})
+		}
+	}
+	return diff
+}
+
+// detectedVersion returns the version, or "" for one the site did not disclose, as older
+// reports left it blank
+func detectedVersion(version string) string {
+	if version == siteinfo.Undetectable {
+		return ""
+	}
+	return version
+}
+
+// inBoth reports whether both reports have the column, since reports written by older
+// versions lack the newer columns
+func inBoth(before, after map[string]string, field string) bool {
+	_, inBefore := before[field]
+	_, inAfter := after[field]
+	return inBefore && inAfter
+}
+
+// WriteDiff writes the change report as CSV, one row per change, or as JSON
+func WriteDiff(filePath, format string, diff *ScanDiff) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if format == "json" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"URL", "Change", "Field", "Before", "After", "Detail"})
+	for _, change := range diff.Changes {
+		writer.Write([]string{change.URL, change.Kind, change.Field, change.Before, change.After, change.Detail})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,115 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+)
+
+// winZipAESExtra is the AE-2 extra field for AES-256 encrypted, deflated entries
+var winZipAESExtra = []byte{
+	0x01, 0x99, // header ID
+	0x07, 0x00, // data size
+	0x02, 0x00, // AE-2, CRC omitted
+	'A', 'E', // vendor ID
+	0x03,       // AES-256
+	0x08, 0x00, // actual compression method: deflate
+}
+
+// winZipCTR applies AES in the counter mode used by WinZip, which starts at 1 and
+// increments a little-endian counter
+func winZipCTR(block cipher.Block, dst, src []byte) {
+	counter := make([]byte, aes.BlockSize)
+	keystream := make([]byte, aes.BlockSize)
+	var n uint64
+	for i := 0; i < len(src); i += aes.BlockSize {
+		n++
+		binary.LittleEndian.PutUint64(counter, n)
+		block.Encrypt(keystream, counter)
+		end := min(i+aes.BlockSize, len(src))
+		for j := i; j < end; j++ {
+			dst[j] = src[j] ^ keystream[j-i]
+		}
+	}
+}
+
+// Encrypt packs the report into a WinZip AES-256 encrypted ZIP archive, which
+// opens in 7-Zip, WinZip and most archive managers, and removes the plaintext report
+func Encrypt(filePath, key string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	// Compress the report before encrypting it
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return "", err
+	}
+	if err := fw.Close(); err != nil {
+		return "", err
+	}
+
+	// Derive the encryption key, authentication key and password verifier
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	keys, err := pbkdf2.Key(sha1.New, key, salt, 1000, 2*32+2)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(keys[:32])
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, compressed.Len())
+	winZipCTR(block, ciphertext, compressed.Bytes())
+	mac := hmac.New(sha1.New, keys[32:64])
+	mac.Write(ciphertext)
+
+	payload := append(append(append(salt, keys[64:]...), ciphertext...), mac.Sum(nil)[:10]...)
+
+	zipPath := filePath + ".zip"
+	file, err := os.Create(zipPath)
+	if err != nil {
+		return "", err
+	}
+	archive := zip.NewWriter(file)
+	w, err := archive.CreateRaw(&zip.FileHeader{
+		Name:               filepath.Base(filePath),
+		Method:             99, // WinZip AES
+		Flags:              0x1,
+		Extra:              winZipAESExtra,
+		CompressedSize64:   uint64(len(payload)),
+		UncompressedSize64: uint64(len(data)),
+	})
+	if err == nil {
+		_, err = w.Write(payload)
+	}
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	// Keep the plaintext report unless the whole archive reached the disk
+	if err != nil {
+		os.Remove(zipPath)
+		return "", err
+	}
+	return zipPath, os.Remove(filePath)
+}
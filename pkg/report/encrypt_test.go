@@ -0,0 +1,91 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	const key = "client secret"
+	report := []byte(strings.Repeat("url,php_version\nhttps://example.com,8.3.4\n", 200))
+	filePath := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(filePath, report, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	zipPath, err := Encrypt(filePath, key)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("plaintext report still exists: %v", err)
+	}
+
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	if len(archive.File) != 1 {
+		t.Fatalf("archive has %d entries, want 1", len(archive.File))
+	}
+	entry := archive.File[0]
+	if entry.Name != "report.csv" || entry.Method != 99 || entry.Flags&0x1 == 0 {
+		t.Errorf("entry %q has method %d and flags %#x, want report.csv, 99 and encrypted", entry.Name, entry.Method, entry.Flags)
+	}
+	if !bytes.Equal(entry.Extra, winZipAESExtra) {
+		t.Errorf("extra field is %x, want %x", entry.Extra, winZipAESExtra)
+	}
+	if entry.UncompressedSize64 != uint64(len(report)) {
+		t.Errorf("uncompressed size is %d, want %d", entry.UncompressedSize64, len(report))
+	}
+
+	raw, err := entry.OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := io.ReadAll(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// salt, password verifier, ciphertext, authentication code
+	salt, verifier := payload[:16], payload[16:18]
+	ciphertext, code := payload[18:len(payload)-10], payload[len(payload)-10:]
+	keys, err := pbkdf2.Key(sha1.New, key, salt, 1000, 2*32+2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(verifier, keys[64:]) {
+		t.Errorf("password verifier is %x, want %x", verifier, keys[64:])
+	}
+	mac := hmac.New(sha1.New, keys[32:64])
+	mac.Write(ciphertext)
+	if !hmac.Equal(code, mac.Sum(nil)[:10]) {
+		t.Error("authentication code does not match the ciphertext")
+	}
+
+	block, err := aes.NewCipher(keys[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := make([]byte, len(ciphertext))
+	winZipCTR(block, compressed, ciphertext)
+	decrypted, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatalf("inflating the decrypted entry: %v", err)
+	}
+	if !bytes.Equal(decrypted, report) {
+		t.Error("decrypted report differs from the input")
+	}
+}
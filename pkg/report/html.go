@@ -0,0 +1,278 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+func init() {
+	Register("html", func(filePath string) (OutputWriter, error) { return newHTMLWriter(filePath) })
+}
+
+// htmlTemplate is a single-file report with no external assets, so it can be emailed or
+// opened from disk. The tables sort by clicking a header; cells sort by their data-sort value.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Site report, {{.Generated}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+.generated { color: #666; margin-top: 0; }
+.cards { display: flex; flex-wrap: wrap; gap: 1em; margin: 1.5em 0; }
+.card { border: 1px solid #ddd; border-radius: 6px; padding: 0.8em 1.2em; min-width: 9em; }
+.card .value { font-size: 1.6em; font-weight: bold; }
+.card .label { color: #666; font-size: 0.9em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #eee; padding: 0.45em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f6f6f6; cursor: pointer; user-select: none; white-space: nowrap; }
+th.sorted-asc::after { content: " \25B2"; }
+th.sorted-desc::after { content: " \25BC"; }
+.status { display: inline-block; border-radius: 4px; padding: 0.1em 0.5em; font-size: 0.85em; }
+.good { background: #e3f4e6; color: #1d6b2c; }
+.warn { background: #fff3d6; color: #8a5a00; }
+.bad { background: #fde4e4; color: #a11d1d; }
+.unknown { background: #eee; color: #555; }
+.chart rect { fill: #6a8fd8; }
+details { margin-bottom: 0.6em; }
+summary { cursor: pointer; font-weight: bold; }
+details table { width: auto; margin: 0.5em 0 1em; }
+details th { cursor: default; background: none; font-weight: normal; color: #666; }
+</style>
+</head>
+<body>
+<h1>Site report</h1>
+<p class="generated">Generated {{.Generated}}</p>
+
+<div class="cards">
+<div class="card"><div class="value">{{.Summary.Sites}}</div><div class="label">Sites scanned</div></div>
+<div class="card"><div class="value">{{printf "%.0f" .Summary.SupportedPHPPercent}}%</div><div class="label">On supported PHP</div></div>
+<div class="card"><div class="value">{{.Outdated}}</div><div class="label">Sites with outdated software</div></div>
+<div class="card"><div class="value">{{.CertificateIssues}}</div><div class="label">Certificates invalid or expiring</div></div>
+<div class="card"><div class="value">{{printf "%.0f" .Summary.AverageTTFB}} ms</div><div class="label">Average response time</div></div>
+</div>
+
+<h2>Overview</h2>
+<table class="sortable">
+<thead><tr><th>Site</th><th>CMS</th><th>WordPress</th><th>PHP</th><th>MySQL</th><th>Web server</th><th>SSL certificate</th><th>Response time</th></tr></thead>
+<tbody>
+{{range .Sites}}<tr>
+<td data-sort="{{.URL}}">{{.URL}}</td>
+<td>{{.CMS}}</td>
+{{template "component" .WordPress}}
+{{template "component" .PHP}}
+{{template "component" .MySQL}}
+{{template "component" .WebServer}}
+<td data-sort="{{.SSL.Sort}}"><span class="status {{.SSL.Class}}">{{.SSL.Text}}</span></td>
+<td data-sort="{{printf "%.3f" .AverageTTFB}}">{{if .TTFBs}}{{printf "%.0f" .AverageTTFB}} ms<br>{{.Chart}}{{else}}&ndash;{{end}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+
+<h2>All findings</h2>
+{{range .Sites}}<details>
+<summary>{{.URL}}</summary>
+<table>
+{{range .Findings}}<tr><th>{{.Header}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}
+
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, column) {
+    th.addEventListener("click", function () {
+      var ascending = !th.classList.contains("sorted-asc");
+      table.querySelectorAll("th").forEach(function (other) { other.classList.remove("sorted-asc", "sorted-desc"); });
+      th.classList.add(ascending ? "sorted-asc" : "sorted-desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      var key = function (row) {
+        var cell = row.cells[column];
+        return cell.hasAttribute("data-sort") ? cell.getAttribute("data-sort") : cell.textContent.trim();
+      };
+      rows.sort(function (a, b) {
+        var x = key(a), y = key(b);
+        var order = (x !== "" && y !== "" && !isNaN(x) && !isNaN(y)) ? x - y : x.localeCompare(y, undefined, {numeric: true});
+        return ascending ? order : -order;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+{{define "component"}}<td data-sort="{{.Status}} {{.Version}}">{{if .Version}}{{.Version}} {{end}}<span class="status {{.Class}}">{{.Status}}</span></td>{{end}}
+`))
+
+// htmlComponent is a versioned component and its support status
+type htmlComponent struct {
+	Version string
+	Status  string
+	Class   string
+}
+
+// htmlCertificate is the state of a site's certificate
+type htmlCertificate struct {
+	Text  string
+	Class string
+	Sort  int
+}
+
+// htmlFinding is a non-empty report column of a site
+type htmlFinding struct {
+	Header string
+	Value  string
+}
+
+// htmlSite is one site's row and findings in the HTML report
+type htmlSite struct {
+	URL                              string
+	CMS                              string
+	WordPress, PHP, MySQL, WebServer htmlComponent
+	SSL                              htmlCertificate
+	TTFBs                            []float64
+	AverageTTFB                      float64
+	Chart                            template.HTML
+	Findings                         []htmlFinding
+}
+
+// statusClass returns the colour class of a support status
+func statusClass(status string) string {
+	switch status {
+	case "Supported":
+		return "good"
+	case "Outdated":
+		return "bad"
+	}
+	return "unknown"
+}
+
+// newHTMLComponent returns the component's cell
+func newHTMLComponent(version, status string) htmlComponent {
+	if status == "" {
+		status = "Unknown"
+	}
+	return htmlComponent{Version: version, Status: status, Class: statusClass(status)}
+}
+
+// newHTMLCertificate summarizes the certificate, sorting the worst first
+func newHTMLCertificate(info *siteinfo.SiteInfo) htmlCertificate {
+	switch {
+	case info.SSLExpired:
+		return htmlCertificate{Text: "Expired", Class: "bad", Sort: -1}
+	case !info.SSLValid:
+		return htmlCertificate{Text: "Invalid", Class: "bad", Sort: -1}
+	case info.Certificate == nil:
+		return htmlCertificate{Text: "Valid", Class: "good", Sort: 100000}
+	case info.Certificate.ExpiringSoon:
+		return htmlCertificate{Text: fmt.Sprintf("Expires in %d days", info.Certificate.DaysUntilExpiry), Class: "warn", Sort: info.Certificate.DaysUntilExpiry}
+	}
+	return htmlCertificate{Text: fmt.Sprintf("Valid, %d days left", info.Certificate.DaysUntilExpiry), Class: "good", Sort: info.Certificate.DaysUntilExpiry}
+}
+
+// ttfbChart draws the TTFB samples as a small inline SVG bar chart, scaled to the slowest sample
+func ttfbChart(ttfbs []float64) template.HTML {
+	const width, height, gap = 8, 24, 2
+	slowest := 0.0
+	for _, ttfb := range ttfbs {
+		slowest = max(slowest, ttfb)
+	}
+	if slowest == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" width="%d" height="%d" role="img" aria-label="TTFB samples">`, len(ttfbs)*(width+gap), height)
+	for i, ttfb := range ttfbs {
+		bar := max(ttfb/slowest*height, 1)
+		fmt.Fprintf(&b, `<rect x="%d" y="%.1f" width="%d" height="%.1f"><title>%.0f ms</title></rect>`, i*(width+gap), height-bar, width, bar, ttfb)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// htmlWriter collects the sites and renders the report when flushed, since the summary
+// cards at the top depend on every site
+type htmlWriter struct {
+	filePath  string
+	siteInfos []*siteinfo.SiteInfo
+}
+
+// newHTMLWriter checks the file can be created before the scan results arrive
+func newHTMLWriter(filePath string) (*htmlWriter, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	return &htmlWriter{filePath: filePath}, nil
+}
+
+// Write adds the site to the report
+func (w *htmlWriter) Write(info *siteinfo.SiteInfo) error {
+	w.siteInfos = append(w.siteInfos, info)
+	return nil
+}
+
+// Flush renders the report to the file
+func (w *htmlWriter) Flush() error {
+	data := struct {
+		Generated         string
+		Summary           *Summary
+		Outdated          int
+		CertificateIssues int
+		Sites             []htmlSite
+	}{Generated: time.Now().Format("2 January 2006 15:04"), Summary: Summarize(w.siteInfos)}
+
+	for _, info := range w.siteInfos {
+		site := htmlSite{
+			URL:       info.URL,
+			CMS:       info.CMS,
+			WordPress: newHTMLComponent(info.WordPressVersion, info.WordPressStatus),
+			PHP:       newHTMLComponent(info.PHPVersion, info.PHPStatus),
+			MySQL:     newHTMLComponent(info.MySQLVersion, info.MySQLStatus),
+			WebServer: newHTMLComponent(strings.TrimSpace(info.WebServer+" "+info.WebServerVersion), info.WebServerStatus),
+			SSL:       newHTMLCertificate(info),
+		}
+		if len(info.TTFBs) > 0 {
+			site.AverageTTFB = siteinfo.Milliseconds(info.AverageTTFB)
+			for _, ttfb := range info.TTFBs {
+				site.TTFBs = append(site.TTFBs, siteinfo.Milliseconds(ttfb))
+			}
+			site.Chart = ttfbChart(site.TTFBs)
+		}
+		for _, col := range columns {
+			if value := col.Value(info); value != "" {
+				site.Findings = append(site.Findings, htmlFinding{Header: col.Header, Value: value})
+			}
+		}
+		for _, component := range []htmlComponent{site.WordPress, site.PHP, site.MySQL, site.WebServer} {
+			if component.Status == "Outdated" {
+				data.Outdated++
+				break
+			}
+		}
+		if site.SSL.Class != "good" {
+			data.CertificateIssues++
+		}
+		data.Sites = append(data.Sites, site)
+	}
+
+	file, err := os.Create(w.filePath)
+	if err != nil {
+		return err
+	}
+	err = htmlTemplate.Execute(file, data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+func init() {
+	Register("json", func(filePath string) (OutputWriter, error) { return newJSONWriter(filePath) })
+}
+
+// jsonWriter writes the sites as an indented JSON array, one element at a time
+type jsonWriter struct {
+	file  *os.File
+	buf   *bufio.Writer
+	sites int
+}
+
+// newJSONWriter creates the JSON file
+func newJSONWriter(filePath string) (*jsonWriter, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonWriter{file: file, buf: bufio.NewWriter(file)}, nil
+}
+
+// Write appends the site to the array
+func (w *jsonWriter) Write(info *siteinfo.SiteInfo) error {
+	data, err := json.MarshalIndent(info, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := ",\n  "
+	if w.sites == 0 {
+		separator = "[\n  "
+	}
+	w.sites++
+	w.buf.WriteString(separator)
+	_, err = w.buf.Write(data)
+	return err
+}
+
+// Flush closes the array and the file
+func (w *jsonWriter) Flush() error {
+	end := "\n]\n"
+	if w.sites == 0 {
+		end = "[]\n"
+	}
+	w.buf.WriteString(end)
+	err := w.buf.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteJSON writes the site information to a JSON file
+func WriteJSON(filePath string, siteInfos []*siteinfo.SiteInfo) error {
+	writer, err := newJSONWriter(filePath)
+	if err != nil {
+		return err
+	}
+	return WriteAll(writer, siteInfos)
+}
@@ -0,0 +1,44 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+func init() {
+	RegisterStreaming("jsonl", func(destination string) (OutputWriter, error) { return newJSONLWriter(destination) })
+}
+
+// Stdout is the destination that writes a streamed output to standard output
+const Stdout = "-"
+
+// jsonlWriter writes one JSON object per line, each as soon as the site is written, so the
+// output can be piped into other tools while the scan is still running
+type jsonlWriter struct {
+	close   func() error
+	encoder *json.Encoder
+}
+
+// newJSONLWriter creates the file, or writes to standard output for Stdout
+func newJSONLWriter(destination string) (*jsonlWriter, error) {
+	if destination == Stdout {
+		return &jsonlWriter{close: func() error { return nil }, encoder: json.NewEncoder(os.Stdout)}, nil
+	}
+	file, err := os.Create(destination)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonlWriter{close: file.Close, encoder: json.NewEncoder(file)}, nil
+}
+
+// Write writes the site's line, unbuffered
+func (w *jsonlWriter) Write(info *siteinfo.SiteInfo) error {
+	return w.encoder.Encode(info)
+}
+
+// Flush closes the file
+func (w *jsonlWriter) Flush() error {
+	return w.close()
+}
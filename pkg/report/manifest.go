@@ -0,0 +1,77 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// Manifest records how a report was produced so that audits are reproducible and traceable
+type Manifest struct {
+	ToolVersion  string    `json:"tool_version"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	InputFile    string    `json:"input_file,omitempty"`
+	InputSHA256  string    `json:"input_sha256,omitempty"`
+	ConfigFile   string    `json:"config_file,omitempty"`
+	ConfigSHA256 string    `json:"config_sha256,omitempty"`
+	OutputFile   string    `json:"output_file"`
+	OutputSHA256 string    `json:"output_sha256"`
+	// AdditionalOutputs are the other outputs written in the same run with -out
+	AdditionalOutputs []ManifestOutput `json:"additional_outputs,omitempty"`
+	URLs              int              `json:"urls"`
+	Scanned           int              `json:"scanned"`
+	Failed            int              `json:"failed"`
+	Failures          []string         `json:"failures"`
+	// Interrupted is set when the run was stopped by a signal, so only some sites were scanned
+	Interrupted bool `json:"interrupted,omitempty"`
+	// SampledFrom is the number of sites in the input when only a random sample of them was
+	// scanned, drawn with SampleSeed
+	SampledFrom int   `json:"sampled_from,omitempty"`
+	SampleSeed  int64 `json:"sample_seed,omitempty"`
+	// ResourceUsage is the scanner's own CPU, memory and bandwidth use during the scan
+	ResourceUsage *siteinfo.Usage `json:"resource_usage,omitempty"`
+}
+
+// ManifestOutput is an output file written by the run
+type ManifestOutput struct {
+	Format string `json:"format"`
+	File   string `json:"file"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// FileSHA256 returns the hex-encoded SHA-256 hash of the file's contents
+func FileSHA256(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// WriteManifest writes the run manifest to a JSON file
+func WriteManifest(filePath string, manifest *Manifest) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if manifest.Failures == nil {
+		manifest.Failures = []string{}
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(manifest)
+}
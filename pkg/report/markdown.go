@@ -0,0 +1,106 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+func init() {
+	Register("md", func(filePath string) (OutputWriter, error) { return newMarkdownWriter(filePath) })
+}
+
+// markdownCell escapes a value for a Markdown table cell, where a pipe ends the cell and a
+// line break ends the row
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "|", `\|`)
+	value = strings.ReplaceAll(value, "\r", "")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
+
+// markdownComponent formats a component's version and support status for the overview table
+func markdownComponent(version, status string) string {
+	if status == "" {
+		status = "Unknown"
+	}
+	if version == "" {
+		return status
+	}
+	return version + " (" + status + ")"
+}
+
+// markdownWriter collects the sites and renders the report when flushed, since the overview
+// table precedes the per-site sections
+type markdownWriter struct {
+	filePath  string
+	siteInfos []*siteinfo.SiteInfo
+}
+
+// newMarkdownWriter checks the file can be created before the scan results arrive
+func newMarkdownWriter(filePath string) (*markdownWriter, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	return &markdownWriter{filePath: filePath}, nil
+}
+
+// Write adds the site to the report
+func (w *markdownWriter) Write(info *siteinfo.SiteInfo) error {
+	w.siteInfos = append(w.siteInfos, info)
+	return nil
+}
+
+// Flush renders the report to the file: the portfolio figures, an overview table of each
+// site's versions and certificate, then a section listing every finding of each site
+func (w *markdownWriter) Flush() error {
+	summary := Summarize(w.siteInfos)
+	var b strings.Builder
+	b.WriteString("# Site report\n\n")
+	fmt.Fprintf(&b, "Generated %s\n\n", time.Now().Format("2 January 2006 15:04"))
+	fmt.Fprintf(&b, "- **Sites scanned:** %d\n", summary.Sites)
+	fmt.Fprintf(&b, "- **On supported PHP:** %.0f%%\n", summary.SupportedPHPPercent)
+	fmt.Fprintf(&b, "- **Average response time:** %.0f ms\n\n", summary.AverageTTFB)
+
+	b.WriteString("## Overview\n\n")
+	b.WriteString("| Site | CMS | WordPress | PHP | MySQL | Web server | SSL certificate | Response time |\n")
+	b.WriteString("|------|-----|-----------|-----|-------|------------|-----------------|---------------|\n")
+	for _, info := range w.siteInfos {
+		ttfb := "–"
+		if len(info.TTFBs) > 0 {
+			ttfb = fmt.Sprintf("%.0f ms", siteinfo.Milliseconds(info.AverageTTFB))
+		}
+		cells := []string{
+			info.URL,
+			info.CMS,
+			markdownComponent(info.WordPressVersion, info.WordPressStatus),
+			markdownComponent(info.PHPVersion, info.PHPStatus),
+			markdownComponent(info.MySQLVersion, info.MySQLStatus),
+			markdownComponent(strings.TrimSpace(info.WebServer+" "+info.WebServerVersion), info.WebServerStatus),
+			newHTMLCertificate(info).Text,
+			ttfb,
+		}
+		for i, cell := range cells {
+			cells[i] = markdownCell(cell)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+
+	b.WriteString("\n## All findings\n")
+	for _, info := range w.siteInfos {
+		fmt.Fprintf(&b, "\n### %s\n\n", markdownCell(info.URL))
+		b.WriteString("| Finding | Value |\n|---------|-------|\n")
+		for _, col := range columns {
+			if value := col.Value(info); value != "" {
+				fmt.Fprintf(&b, "| %s | %s |\n", markdownCell(col.Header), markdownCell(value))
+			}
+		}
+	}
+
+	return os.WriteFile(w.filePath, []byte(b.String()), 0644)
+}
@@ -0,0 +1,2 @@
+// Package report writes scanned site information to report files.
+package report
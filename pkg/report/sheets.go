@@ -0,0 +1,52 @@
+package report
+
+import (
+	"context"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/sheets"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// sheetsWriter writes the sites to a new tab of a Google Sheet in the CSV columns. The rows
+// are sent in one request when flushed, as the API limits the requests per minute.
+type sheetsWriter struct {
+	client        *sheets.Client
+	spreadsheetID string
+	tab           string
+	rows          [][]string
+}
+
+// NewSheetsWriter returns a writer adding a tab named after the scan time to the spreadsheet
+// the destination names, as a Google Sheets URL or spreadsheet ID
+func NewSheetsWriter(client *sheets.Client, destination string) (OutputWriter, error) {
+	id, err := sheets.SpreadsheetID(destination)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Header
+	}
+	return &sheetsWriter{
+		client:        client,
+		spreadsheetID: id,
+		tab:           "Scan " + time.Now().Format("2006-01-02 15:04:05"),
+		rows:          [][]string{header},
+	}, nil
+}
+
+// Write adds the site's row
+func (w *sheetsWriter) Write(info *siteinfo.SiteInfo) error {
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		row[i] = col.Value(info)
+	}
+	w.rows = append(w.rows, row)
+	return nil
+}
+
+// Flush adds the tab with every row
+func (w *sheetsWriter) Flush() error {
+	return w.client.AddTab(context.Background(), w.spreadsheetID, w.tab, w.rows)
+}
@@ -0,0 +1,175 @@
+package report
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	Register("sqlite", func(filePath string) (OutputWriter, error) { return newSQLiteWriter(filePath) })
+}
+
+// sqliteSchema keeps one row per site per scan. The headline findings get their own columns
+// for trend queries; the full result is kept as JSON.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS scans (
+	id INTEGER PRIMARY KEY,
+	url TEXT NOT NULL,
+	scanned_at TEXT NOT NULL,
+	php_version TEXT,
+	mysql_version TEXT,
+	wordpress_version TEXT,
+	web_server TEXT,
+	web_server_version TEXT,
+	php_status TEXT,
+	wordpress_status TEXT,
+	ssl_valid INTEGER,
+	average_ttfb_ms REAL,
+	result TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS scans_url_scanned_at ON scans (url, scanned_at);`
+
+// sqliteWriter appends each site to the scans table of a SQLite database, in one transaction
+// per run so an interrupted write leaves no partial run behind
+type sqliteWriter struct {
+	db        *sql.DB
+	tx        *sql.Tx
+	insert    *sql.Stmt
+	scannedAt string
+}
+
+// newSQLiteWriter opens or creates the database and starts the run's transaction
+func newSQLiteWriter(filePath string) (*sqliteWriter, error) {
+	db, err := sql.Open("sqlite", filePath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	insert, err := tx.Prepare(`INSERT INTO scans (url, scanned_at, php_version, mysql_version, wordpress_version,
+		web_server, web_server_version, php_status, wordpress_status, ssl_valid, average_ttfb_ms, result)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+	return &sqliteWriter{db: db, tx: tx, insert: insert, scannedAt: time.Now().UTC().Format(time.RFC3339)}, nil
+}
+
+// Write inserts the site's row
+func (w *sqliteWriter) Write(info *siteinfo.SiteInfo) error {
+	result, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	_, err = w.insert.Exec(info.URL, w.scannedAt, info.PHPVersion, info.MySQLVersion, info.WordPressVersion,
+		info.WebServer, info.WebServerVersion, info.PHPStatus, info.WordPressStatus, info.SSLValid,
+		siteinfo.Milliseconds(info.AverageTTFB), string(result))
+	return err
+}
+
+// Flush commits the run and closes the database
+func (w *sqliteWriter) Flush() error {
+	w.insert.Close()
+	err := w.tx.Commit()
+	if closeErr := w.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// HistoryEntry is one scan of a site recorded in the database
+type HistoryEntry struct {
+	ScannedAt        time.Time `json:"scanned_at"`
+	PHPVersion       string    `json:"php_version"`
+	MySQLVersion     string    `json:"mysql_version"`
+	WordPressVersion string    `json:"wordpress_version"`
+	WebServer        string    `json:"web_server"`
+	WebServerVersion string    `json:"web_server_version"`
+	PHPStatus        string    `json:"php_status"`
+	WordPressStatus  string    `json:"wordpress_status"`
+	SSLValid         bool      `json:"ssl_valid"`
+	AverageTTFB      float64   `json:"average_ttfb_ms"`
+}
+
+// History returns the site's scans recorded in the database, oldest first
+func History(filePath, url string) ([]HistoryEntry, error) {
+	db, err := sql.Open("sqlite", filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT scanned_at, php_version, mysql_version, wordpress_version, web_server,
+		web_server_version, php_status, wordpress_status, ssl_valid, average_ttfb_ms
+		FROM scans WHERE url = ? ORDER BY scanned_at, id`, url)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		var scannedAt string
+		err := rows.Scan(&scannedAt, &entry.PHPVersion, &entry.MySQLVersion, &entry.WordPressVersion, &entry.WebServer,
+			&entry.WebServerVersion, &entry.PHPStatus, &entry.WordPressStatus, &entry.SSLValid, &entry.AverageTTFB)
+		if err != nil {
+			return nil, err
+		}
+		entry.ScannedAt, _ = time.Parse(time.RFC3339, scannedAt)
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+// RecentScans returns the latest result of each site scanned in the database since the given
+// time, keyed by URL, so a run can reuse them instead of rescanning. A missing database has no
+// scans.
+func RecentScans(filePath string, since time.Time) (map[string]*siteinfo.SiteInfo, error) {
+	recent := map[string]*siteinfo.SiteInfo{}
+	if _, err := os.Stat(filePath); errors.Is(err, fs.ErrNotExist) {
+		return recent, nil
+	}
+	db, err := sql.Open("sqlite", filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT url, result FROM scans WHERE scanned_at >= ? ORDER BY scanned_at, id`,
+		since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url, result string
+		if err := rows.Scan(&url, &result); err != nil {
+			return nil, err
+		}
+		info := &siteinfo.SiteInfo{}
+		if err := json.Unmarshal([]byte(result), info); err != nil {
+			return nil, fmt.Errorf("result of %s: %w", url, err)
+		}
+		recent[url] = info
+	}
+	return recent, rows.Err()
+}
@@ -0,0 +1,166 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// StatusPage is the public uptime and incident history of the monitored sites
+type StatusPage struct {
+	Title       string       `json:"title"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Days        int          `json:"days"`
+	Sites       []StatusSite `json:"sites"`
+}
+
+// StatusSite is one monitored site on the status page
+type StatusSite struct {
+	URL   string `json:"url"`
+	Group string `json:"group"`
+	// Up is false while the site has an unresolved incident or failed its latest check
+	Up          bool      `json:"up"`
+	LastChecked time.Time `json:"last_checked,omitzero"`
+	// Checks and UptimePercent cover the availability checks of uptime groups in the window
+	Checks        int         `json:"checks"`
+	UptimePercent float64     `json:"uptime_percent"`
+	Daily         []StatusDay `json:"daily,omitempty"`
+	Incidents     []Incident  `json:"incidents"`
+}
+
+// StatusDay is a site's availability on one day
+type StatusDay struct {
+	Date          string  `json:"date"`
+	Checks        int     `json:"checks"`
+	UptimePercent float64 `json:"uptime_percent"`
+}
+
+// Incident is a period a site was down, open until it recovers
+type Incident struct {
+	Started  time.Time `json:"started"`
+	Resolved time.Time `json:"resolved,omitzero"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// Duration returns how long the incident lasted, or has lasted so far
+func (i Incident) Duration(now time.Time) time.Duration {
+	if i.Resolved.IsZero() {
+		return now.Sub(i.Started)
+	}
+	return i.Resolved.Sub(i.Started)
+}
+
+// statusTemplate is a static page with no external assets, so the directory can be published
+// as is to S3 or GitHub Pages
+var statusTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"dayClass": func(day StatusDay) string {
+		switch {
+		case day.Checks == 0:
+			return "none"
+		case day.UptimePercent >= 100:
+			return "good"
+		case day.UptimePercent >= 95:
+			return "warn"
+		}
+		return "bad"
+	},
+	"duration": func(incident Incident, now time.Time) string {
+		return incident.Duration(now).Round(time.Minute).String()
+	},
+	"when": func(t time.Time) string { return t.UTC().Format("2 Jan 2006 15:04 UTC") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2em auto; max-width: 60em; padding: 0 1em; color: #222; }
+.generated { color: #666; }
+.overall { border-radius: 6px; padding: 0.8em 1.2em; font-weight: bold; margin: 1.5em 0; }
+.site { border: 1px solid #ddd; border-radius: 6px; padding: 0.8em 1.2em; margin-bottom: 1em; }
+.site h2 { font-size: 1.1em; margin: 0 0 0.4em; display: flex; justify-content: space-between; }
+.status { border-radius: 4px; padding: 0.1em 0.5em; font-size: 0.85em; }
+.good { background: #3fa455; color: #fff; }
+.warn { background: #e5a50a; color: #fff; }
+.bad { background: #c6362f; color: #fff; }
+.none { background: #ddd; }
+.days { display: flex; gap: 2px; margin: 0.4em 0; }
+.days span { flex: 1; height: 1.8em; border-radius: 2px; }
+.meta { color: #666; font-size: 0.9em; }
+.incidents { margin: 0.5em 0 0; padding-left: 1.2em; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="generated">Updated {{when .GeneratedAt}}. Availability over the last {{.Days}} days.</p>
+{{if .AllUp}}<div class="overall good">All sites operational</div>{{else}}<div class="overall bad">{{.Down}} of {{len .Sites}} sites down</div>{{end}}
+{{range .Sites}}<div class="site">
+<h2><span>{{.URL}}</span>{{if .Up}}<span class="status good">Operational</span>{{else}}<span class="status bad">Down</span>{{end}}</h2>
+{{if .Daily}}<div class="days">{{range .Daily}}<span class="{{dayClass .}}" title="{{.Date}}: {{if .Checks}}{{printf "%.2f" .UptimePercent}}% of {{.Checks}} checks{{else}}no data{{end}}"></span>{{end}}</div>{{end}}
+<div class="meta">{{if .Checks}}{{printf "%.2f" .UptimePercent}}% uptime over {{.Checks}} checks{{else}}Monitored by scheduled scans{{end}}{{if not .LastChecked.IsZero}}, last checked {{when .LastChecked}}{{end}}</div>
+{{if .Incidents}}<ul class="incidents">
+{{range .Incidents}}<li>{{when .Started}}: {{if .Resolved.IsZero}}ongoing, {{else}}resolved after {{end}}{{duration . $.GeneratedAt}}{{if .Reason}} ({{.Reason}}){{end}}</li>
+{{end}}</ul>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
+
+// WriteStatusPage writes the status page to the directory as index.html, with the same data
+// in status.json for clients that build their own page
+func WriteStatusPage(dir string, page *StatusPage) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(page, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "status.json"), append(data, '\n')); err != nil {
+		return err
+	}
+
+	view := struct {
+		*StatusPage
+		AllUp bool
+		Down  int
+	}{StatusPage: page}
+	for _, site := range page.Sites {
+		if !site.Up {
+			view.Down++
+		}
+	}
+	view.AllUp = view.Down == 0
+	var b bytes.Buffer
+	if err := statusTemplate.Execute(&b, view); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, "index.html"), b.Bytes())
+}
+
+// writeFileAtomic replaces the file through a temporary file, so a sync to the publishing
+// bucket never picks up a half-written page
+func writeFileAtomic(filePath string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(filePath), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filePath)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
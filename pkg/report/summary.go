@@ -0,0 +1,172 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// expiryBuckets are the certificate expiry timeline buckets, in order
+var expiryBuckets = []string{"Expired", "Within 7 days", "Within 30 days", "Within 90 days", "Later"}
+
+// expiryBucket places a certificate on the expiry timeline
+func expiryBucket(cert *siteinfo.CertificateInfo, expired bool) string {
+	switch days := cert.DaysUntilExpiry; {
+	case expired || days < 0:
+		return "Expired"
+	case days <= 7:
+		return "Within 7 days"
+	case days <= 30:
+		return "Within 30 days"
+	case days <= 90:
+		return "Within 90 days"
+	default:
+		return "Later"
+	}
+}
+
+// Summary aggregates the results of a scan across the whole portfolio
+type Summary struct {
+	Sites               int            `json:"sites"`
+	PHPVersions         map[string]int `json:"php_versions"`
+	WordPressVersions   map[string]int `json:"wordpress_versions"`
+	WebServers          map[string]int `json:"web_servers"`
+	SupportedPHPPercent float64        `json:"supported_php_percent"`
+	AverageTTFB         float64        `json:"average_ttfb_ms"`
+	CertificateExpiry   map[string]int `json:"certificate_expiry"`
+	// SampledSites is the number of sites scanned when the summary is extrapolated from a
+	// sample, in which case the counts are estimates for the whole portfolio
+	SampledSites int `json:"sampled_sites,omitempty"`
+}
+
+// minorVersion truncates a version to major.minor, e.g. 8.1.27 to 8.1, so the histograms
+// group sites by release branch
+func minorVersion(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// Summarize aggregates version distributions, the share of sites on supported PHP, the
+// average TTFB and the certificate expiry timeline across the scanned sites
+func Summarize(siteInfos []*siteinfo.SiteInfo) *Summary {
+	return SummarizeWeighted(siteInfos, nil)
+}
+
+// SummarizeWeighted extrapolates the summary of a sample to the whole portfolio, counting
+// each site as the number of portfolio sites its weight, keyed by URL, says it stands for.
+// The counts are estimates rounded to whole sites. Nil weights count each site once.
+func SummarizeWeighted(siteInfos []*siteinfo.SiteInfo, weights map[string]float64) *Summary {
+	phpVersions, wordpressVersions, webServers, certificateExpiry := map[string]float64{}, map[string]float64{}, map[string]float64{}, map[string]float64{}
+	var sites, phpKnown, phpSupported, measured, totalTTFB float64
+	for _, info := range siteInfos {
+		weight := 1.0
+		if weights != nil {
+			weight = weights[info.URL]
+		}
+		sites += weight
+		if info.PHPVersion != "" {
+			phpVersions[minorVersion(info.PHPVersion)] += weight
+		}
+		if info.WordPressVersion != "" {
+			wordpressVersions[minorVersion(info.WordPressVersion)] += weight
+		}
+		if info.WebServer != "" {
+			webServers[info.WebServer] += weight
+		}
+		if info.PHPStatus == "Supported" || info.PHPStatus == "Outdated" {
+			phpKnown += weight
+			if info.PHPStatus == "Supported" {
+				phpSupported += weight
+			}
+		}
+		if info.AverageTTFB > 0 {
+			measured += weight
+			totalTTFB += weight * siteinfo.Milliseconds(info.AverageTTFB)
+		}
+		if info.Certificate != nil {
+			certificateExpiry[expiryBucket(info.Certificate, info.SSLExpired)] += weight
+		}
+	}
+
+	summary := &Summary{
+		Sites:             int(math.Round(sites)),
+		PHPVersions:       roundCounts(phpVersions),
+		WordPressVersions: roundCounts(wordpressVersions),
+		WebServers:        roundCounts(webServers),
+		CertificateExpiry: roundCounts(certificateExpiry),
+	}
+	if weights != nil {
+		summary.SampledSites = len(siteInfos)
+	}
+	if phpKnown > 0 {
+		summary.SupportedPHPPercent = phpSupported / phpKnown * 100
+	}
+	if measured > 0 {
+		summary.AverageTTFB = totalTTFB / measured
+	}
+	return summary
+}
+
+// roundCounts rounds weighted counts to whole sites
+func roundCounts(weighted map[string]float64) map[string]int {
+	counts := make(map[string]int, len(weighted))
+	for value, count := range weighted {
+		counts[value] = int(math.Round(count))
+	}
+	return counts
+}
+
+// histogramRows returns a metric's counts as CSV rows, sorted by value
+func histogramRows(metric string, counts map[string]int) [][]string {
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	rows := make([][]string, 0, len(values))
+	for _, value := range values {
+		rows = append(rows, []string{metric, value, fmt.Sprintf("%d", counts[value])})
+	}
+	return rows
+}
+
+// WriteSummary writes the summary as a CSV sheet of metric, value and count rows, or as JSON
+func WriteSummary(filePath, format string, summary *Summary) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if format == "json" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summary)
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"Metric", "Value", "Count"})
+	writer.Write([]string{"Sites", "", fmt.Sprintf("%d", summary.Sites)})
+	if summary.SampledSites > 0 {
+		writer.Write([]string{"Sampled Sites", "", fmt.Sprintf("%d", summary.SampledSites)})
+	}
+	writer.Write([]string{"Supported PHP (%)", fmt.Sprintf("%.1f", summary.SupportedPHPPercent), ""})
+	writer.Write([]string{"Average TTFB (ms)", fmt.Sprintf("%.3f", summary.AverageTTFB), ""})
+	writer.WriteAll(histogramRows("PHP Version", summary.PHPVersions))
+	writer.WriteAll(histogramRows("WordPress Version", summary.WordPressVersions))
+	writer.WriteAll(histogramRows("Web Server", summary.WebServers))
+	for _, bucket := range expiryBuckets {
+		writer.Write([]string{"Certificate Expiry", bucket, fmt.Sprintf("%d", summary.CertificateExpiry[bucket])})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,68 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// templateFuncs are the helpers available to user templates besides the text/template builtins
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"ms":    func(d time.Duration) float64 { return siteinfo.Milliseconds(d) },
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// ParseTemplate reads a user-defined output template, written with Go's text/template and
+// executed with the scanned []*siteinfo.SiteInfo as its data
+func ParseTemplate(filePath string) (*template.Template, error) {
+	return template.New(filepath.Base(filePath)).Funcs(templateFuncs).ParseFiles(filePath)
+}
+
+// templateWriter collects the sites and renders them through the user's template when flushed,
+// since the template ranges over every site
+type templateWriter struct {
+	tmpl      *template.Template
+	filePath  string
+	siteInfos []*siteinfo.SiteInfo
+}
+
+// NewTemplateWriter returns a writer rendering the sites through the template to the file,
+// checking the file can be created before the scan results arrive
+func NewTemplateWriter(tmpl *template.Template, filePath string) (OutputWriter, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+	return &templateWriter{tmpl: tmpl, filePath: filePath}, nil
+}
+
+// Write adds the site to the output
+func (w *templateWriter) Write(info *siteinfo.SiteInfo) error {
+	w.siteInfos = append(w.siteInfos, info)
+	return nil
+}
+
+// Flush renders the template to the file
+func (w *templateWriter) Flush() error {
+	file, err := os.Create(w.filePath)
+	if err != nil {
+		return err
+	}
+	err = w.tmpl.Execute(file, w.siteInfos)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// cacheFlushCommands are the wp-cli commands that purge each caching plugin's page cache
+var cacheFlushCommands = map[string]string{
+	"WP Rocket":        "wp rocket clean --confirm",
+	"W3 Total Cache":   "wp w3-total-cache flush all",
+	"WP Super Cache":   "wp super-cache flush",
+	"LiteSpeed Cache":  "wp litespeed-purge all",
+	"WP Fastest Cache": "wp fastest-cache clear all",
+}
+
+// shellQuote quotes a value for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// wpCLICommands returns the wp-cli commands applying the site's remediations. Remediations
+// that wp-cli cannot apply, such as PHP upgrades or certificate renewals, become comments.
+func wpCLICommands(info *siteinfo.SiteInfo) []string {
+	var commands []string
+	updated := false
+	for _, remediation := range info.Remediations {
+		version := ""
+		if remediation.SuggestedVersion != "" {
+			version = " --version=" + shellQuote(remediation.SuggestedVersion)
+		}
+		switch remediation.Component {
+		case "wordpress":
+			commands = append(commands, "wp core update"+version, "wp core update-db")
+			updated = true
+		case "plugin":
+			commands = append(commands, "wp plugin update "+shellQuote(remediation.Name)+version)
+			updated = true
+		case "theme":
+			commands = append(commands, "wp theme update "+shellQuote(remediation.Name)+version)
+			updated = true
+		default:
+			commands = append(commands, "# "+remediation.String()+": "+remediation.Reason)
+		}
+	}
+	if !updated {
+		return commands
+	}
+	commands = append(commands, "wp cache flush")
+	for _, layer := range info.CachingLayers {
+		if command, ok := cacheFlushCommands[layer]; ok {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// WriteWPCLIScripts generates a shell script of suggested wp-cli commands for each WordPress
+// site with remediations, to be run from the site's root over SSH, and records its path in
+// the site information
+func WriteWPCLIScripts(dir string, siteInfos []*siteinfo.SiteInfo) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	unsafeChars := regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+	for _, info := range siteInfos {
+		if info.CMS != "WordPress" {
+			continue
+		}
+		commands := wpCLICommands(info)
+		if len(commands) == 0 {
+			continue
+		}
+		name := unsafeChars.ReplaceAllString(strings.TrimPrefix(strings.TrimPrefix(info.URL, "https://"), "http://"), "_")
+		path := filepath.Join(dir, "wp-cli_"+name+".sh")
+		var script strings.Builder
+		script.WriteString("#!/bin/sh\n")
+		fmt.Fprintf(&script, "# Suggested remediation for %s. Review before running from the site's root.\n", info.URL)
+		script.WriteString("set -e\n\n")
+		for _, command := range commands {
+			script.WriteString(command + "\n")
+		}
+		if err := os.WriteFile(path, []byte(script.String()), 0755); err != nil {
+			return err
+		}
+		info.WPCLIScript = path
+	}
+	return nil
+}
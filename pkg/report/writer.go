@@ -0,0 +1,123 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// OutputWriter writes scanned site information to one output, such as a file or a database.
+// Write is called once per site in input order and Flush once after the last site; the
+// writer's resources are released by Flush.
+type OutputWriter interface {
+	// Write adds one site's information to the output
+	Write(info *siteinfo.SiteInfo) error
+	// Flush completes the output once every site has been written
+	Flush() error
+}
+
+// WriterFactory creates the output writer for a format, writing to the destination, which is
+// usually a file path
+type WriterFactory func(destination string) (OutputWriter, error)
+
+// writers maps each output format to its factory
+var writers = map[string]WriterFactory{}
+
+// streaming is the set of formats whose writers output each site as soon as it is written
+var streaming = map[string]bool{}
+
+// Register makes an output format available to NewWriter. Registering a format twice replaces
+// the earlier factory.
+func Register(format string, factory WriterFactory) {
+	writers[format] = factory
+	delete(streaming, format)
+}
+
+// RegisterStreaming registers an output format whose writer outputs each site as soon as it
+// is written, rather than when flushed, so a run can write it while sites are being scanned
+func RegisterStreaming(format string, factory WriterFactory) {
+	Register(format, factory)
+	streaming[format] = true
+}
+
+// IsStreaming reports whether the output format is written while sites are being scanned
+func IsStreaming(format string) bool {
+	return streaming[format]
+}
+
+// Formats returns the registered output formats in alphabetical order
+func Formats() []string {
+	formats := make([]string, 0, len(writers))
+	for format := range writers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// HasFormat reports whether an output format is registered
+func HasFormat(format string) bool {
+	_, ok := writers[format]
+	return ok
+}
+
+// NewWriter creates the writer for a registered output format
+func NewWriter(format, destination string) (OutputWriter, error) {
+	factory, ok := writers[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+	return factory(destination)
+}
+
+// Write writes the site information to the destination in a registered output format
+func Write(format, destination string, siteInfos []*siteinfo.SiteInfo) error {
+	writer, err := NewWriter(format, destination)
+	if err != nil {
+		return err
+	}
+	return WriteAll(writer, siteInfos)
+}
+
+// WriteAll writes every site to the writer and flushes it, flushing even after a failed
+// write so the writer's resources are released
+func WriteAll(writer OutputWriter, siteInfos []*siteinfo.SiteInfo) error {
+	for _, info := range siteInfos {
+		if err := writer.Write(info); err != nil {
+			writer.Flush()
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// multiWriter writes each site to several outputs in one pass
+type multiWriter []OutputWriter
+
+// MultiWriter returns a writer that duplicates each site to all the writers, so a single run
+// can produce several outputs
+func MultiWriter(writers ...OutputWriter) OutputWriter {
+	return multiWriter(writers)
+}
+
+// Write writes the site to each output, stopping at the first error
+func (m multiWriter) Write(info *siteinfo.SiteInfo) error {
+	for _, writer := range m {
+		if err := writer.Write(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush flushes every output, returning the first error
+func (m multiWriter) Flush() error {
+	var first error
+	for _, writer := range m {
+		if err := writer.Flush(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
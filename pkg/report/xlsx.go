@@ -0,0 +1,198 @@
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+func init() {
+	Register("xlsx", func(filePath string) (OutputWriter, error) { return newXLSXWriter(filePath) })
+}
+
+// maxXLSXColumnWidth caps the auto-fitted column width, in characters, so long lists such as
+// the plugins wrap out of view rather than making the sheet unreadable
+const maxXLSXColumnWidth = 60
+
+// xlsxStaticParts are the workbook parts that do not depend on the sites. The styles define
+// the bold, shaded header (style 1) and the red and yellow fills of the conditional formats
+// (dxf 0 and 1).
+var xlsxStaticParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Sites" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill><fill><patternFill patternType="solid"><fgColor rgb="FFD9E1F2"/></patternFill></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/></cellXfs>
+<dxfs count="2">
+<dxf><font><color rgb="FF9C0006"/></font><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf>
+<dxf><font><color rgb="FF9C5700"/></font><fill><patternFill><bgColor rgb="FFFFEB9C"/></patternFill></fill></dxf>
+</dxfs>
+</styleSheet>`},
+}
+
+// xlsxColumn returns the spreadsheet name of the zero-based column index: A to Z, then AA onwards
+func xlsxColumn(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxEscape escapes the value for XML text, replacing the characters XML cannot hold
+func xlsxEscape(value string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+// xlsxWriter writes the sites to an Excel workbook with one row per site, in the CSV columns.
+// The rows are kept until Flush, as the column widths precede them in the sheet.
+type xlsxWriter struct {
+	filePath string
+	rows     bytes.Buffer
+	count    int
+	widths   []int
+}
+
+// newXLSXWriter checks the file can be created and starts the sheet with the header row
+func newXLSXWriter(filePath string) (*xlsxWriter, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	w := &xlsxWriter{filePath: filePath, widths: make([]int, len(columns))}
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Header
+	}
+	w.writeRow(header, 1)
+	return w, nil
+}
+
+// writeRow appends a row of inline string cells in the style, widening the columns to fit
+func (w *xlsxWriter) writeRow(values []string, style int) {
+	w.count++
+	fmt.Fprintf(&w.rows, `<row r="%d">`, w.count)
+	for i, value := range values {
+		w.widths[i] = min(max(w.widths[i], utf8.RuneCountInString(value)), maxXLSXColumnWidth)
+		if value == "" {
+			continue
+		}
+		fmt.Fprintf(&w.rows, `<c r="%s%d" t="inlineStr" s="%d"><is><t xml:space="preserve">%s</t></is></c>`, xlsxColumn(i), w.count, style, xlsxEscape(value))
+	}
+	w.rows.WriteString(`</row>`)
+}
+
+// Write appends the site's row
+func (w *xlsxWriter) Write(info *siteinfo.SiteInfo) error {
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		row[i] = col.Value(info)
+	}
+	w.writeRow(row, 0)
+	return nil
+}
+
+// sheet returns the worksheet: the header row and URL column frozen, the columns sized to
+// their content, and support statuses of Outdated shown in red and the certificate columns of
+// sites whose certificate expires soon in yellow
+func (w *xlsxWriter) sheet() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetViews><sheetView workbookViewId="0"><pane xSplit="1" ySplit="1" topLeftCell="B2" activePane="bottomRight" state="frozen"/></sheetView></sheetViews>
+<cols>`)
+	for i, width := range w.widths {
+		fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, width+2)
+	}
+	b.WriteString(`</cols><sheetData>`)
+	b.Write(w.rows.Bytes())
+	b.WriteString(`</sheetData>`)
+
+	last := strconv.Itoa(max(w.count, 2))
+	fmt.Fprintf(&b, `<conditionalFormatting sqref="A2:%s%s"><cfRule type="cellIs" dxfId="0" priority="1" operator="equal"><formula>"Outdated"</formula></cfRule></conditionalFormatting>`, xlsxColumn(len(columns)-1), last)
+	expiring := -1
+	for i, col := range columns {
+		if col.Header == "Certificate Expiring Soon" {
+			expiring = i
+		}
+	}
+	if expiring >= 0 {
+		priority := 1
+		for i, col := range columns {
+			if col.Header == "Certificate Not After" || col.Header == "Certificate Days Until Expiry" || col.Header == "Certificate Expiring Soon" {
+				priority++
+				fmt.Fprintf(&b, `<conditionalFormatting sqref="%[1]s2:%[1]s%[2]s"><cfRule type="expression" dxfId="1" priority="%[4]d"><formula>$%[3]s2="true"</formula></cfRule></conditionalFormatting>`, xlsxColumn(i), last, xlsxColumn(expiring), priority)
+			}
+		}
+	}
+	b.WriteString(`</worksheet>`)
+	return b.Bytes()
+}
+
+// Flush writes the workbook
+func (w *xlsxWriter) Flush() error {
+	file, err := os.Create(w.filePath)
+	if err != nil {
+		return err
+	}
+	archive := zip.NewWriter(file)
+	// [Content_Types].xml is conventionally the first entry
+	for _, part := range xlsxStaticParts {
+		if err == nil {
+			err = writeZipEntry(archive, part.name, []byte(part.content))
+		}
+	}
+	if err == nil {
+		err = writeZipEntry(archive, "xl/worksheets/sheet1.xml", w.sheet())
+	}
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeZipEntry adds a file to the archive
+func writeZipEntry(archive *zip.Writer, name string, content []byte) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = entry.Write(content)
+	return err
+}
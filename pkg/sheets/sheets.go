@@ -0,0 +1,282 @@
+// Package sheets reads and writes Google Sheets through the Sheets API, authenticating as a
+// service account. The spreadsheet must be shared with the service account's email address.
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CredentialsEnv is the environment variable naming the service account key file when none
+// is given, as in Google's client libraries
+const CredentialsEnv = "GOOGLE_APPLICATION_CREDENTIALS"
+
+// apiURL is the Sheets API base URL
+const apiURL = "https://sheets.googleapis.com/v4/spreadsheets/"
+
+// scope grants read and write access to the spreadsheets shared with the service account
+const scope = "https://www.googleapis.com/auth/spreadsheets"
+
+// sheetURLPattern matches the spreadsheet ID in a Google Sheets URL
+var sheetURLPattern = regexp.MustCompile(`^https://docs\.google\.com/spreadsheets/d/([A-Za-z0-9_-]+)`)
+
+// sheetIDPattern matches a bare spreadsheet ID
+var sheetIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{25,}$`)
+
+// IsSheetURL reports whether the value is a Google Sheets URL, such as the address of a
+// spreadsheet open in the browser
+func IsSheetURL(value string) bool {
+	return sheetURLPattern.MatchString(value)
+}
+
+// SpreadsheetID returns the ID of the spreadsheet a Google Sheets URL, or a bare ID, names
+func SpreadsheetID(value string) (string, error) {
+	if match := sheetURLPattern.FindStringSubmatch(value); match != nil {
+		return match[1], nil
+	}
+	if sheetIDPattern.MatchString(value) {
+		return value, nil
+	}
+	return "", fmt.Errorf("%s is not a Google Sheets URL or spreadsheet ID", value)
+}
+
+// serviceAccount is the part of a service account key file used to obtain access tokens
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// Client calls the Sheets API with access tokens obtained for the service account, which are
+// reused until shortly before they expire
+type Client struct {
+	account    serviceAccount
+	key        *rsa.PrivateKey
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// New creates a Client from a service account JSON key file, or from the file named by
+// GOOGLE_APPLICATION_CREDENTIALS when the path is empty
+func New(credentialsFile string) (*Client, error) {
+	if credentialsFile == "" {
+		credentialsFile = os.Getenv(CredentialsEnv)
+	}
+	if credentialsFile == "" {
+		return nil, fmt.Errorf("a service account key file is needed for Google Sheets: use -google-credentials or set %s", CredentialsEnv)
+	}
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", credentialsFile, err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("%s is not a service account key file", credentialsFile)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("%s has no PEM private key", credentialsFile)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the private key in %s: %w", credentialsFile, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key in %s is not an RSA key", credentialsFile)
+	}
+	return &Client{account: account, key: key, httpClient: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// accessToken returns a valid access token, exchanging a signed JWT assertion for a new one
+// when the last has expired
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	now := time.Now()
+	encode := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	unsigned := encode(map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encode(map[string]any{
+		"iss":   c.account.ClientEmail,
+		"scope": scope,
+		"aud":   c.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	form := neturl.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := c.send(req, &token); err != nil {
+		return "", fmt.Errorf("error obtaining a Google access token: %w", err)
+	}
+	// Renew a minute early so a token does not expire mid-request
+	c.token, c.expires = token.AccessToken, now.Add(time.Duration(token.ExpiresIn)*time.Second-time.Minute)
+	return c.token, nil
+}
+
+// call sends an authenticated Sheets API request, encoding the body as JSON and decoding the
+// response into out
+func (c *Client) call(ctx context.Context, method, path string, body, out any) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiURL+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, out)
+}
+
+// send sends the request and decodes the JSON response, turning API errors into Go errors
+func (c *Client) send(req *http.Request, out any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+			Description string `json:"error_description"`
+		}
+		json.Unmarshal(data, &apiError)
+		message := apiError.Error.Message
+		if message == "" {
+			message = apiError.Description
+		}
+		return fmt.Errorf("%s: %s", resp.Status, message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// tabRange returns the A1 notation range covering the whole tab
+func tabRange(tab string) string {
+	return neturl.PathEscape("'" + strings.ReplaceAll(tab, "'", "''") + "'")
+}
+
+// Tabs returns the titles of the spreadsheet's tabs in order
+func (c *Client) Tabs(ctx context.Context, spreadsheetID string) ([]string, error) {
+	var spreadsheet struct {
+		Sheets []struct {
+			Properties struct {
+				Title string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	if err := c.call(ctx, "GET", spreadsheetID+"?fields=sheets.properties.title", nil, &spreadsheet); err != nil {
+		return nil, err
+	}
+	var tabs []string
+	for _, sheet := range spreadsheet.Sheets {
+		tabs = append(tabs, sheet.Properties.Title)
+	}
+	return tabs, nil
+}
+
+// Read returns the rows of the tab as displayed in the spreadsheet, or of the first tab when
+// no tab is given. Trailing empty cells of each row are omitted.
+func (c *Client) Read(ctx context.Context, spreadsheetID, tab string) ([][]string, error) {
+	if tab == "" {
+		tabs, err := c.Tabs(ctx, spreadsheetID)
+		if err != nil {
+			return nil, err
+		}
+		if len(tabs) == 0 {
+			return nil, errors.New("the spreadsheet has no tabs")
+		}
+		tab = tabs[0]
+	}
+	var values struct {
+		Values [][]string `json:"values"`
+	}
+	err := c.call(ctx, "GET", spreadsheetID+"/values/"+tabRange(tab)+"?majorDimension=ROWS&valueRenderOption=FORMATTED_VALUE", nil, &values)
+	return values.Values, err
+}
+
+// AddTab adds a tab to the spreadsheet and writes the rows to it. Values are stored as
+// entered, so text such as =HYPERLINK(...) taken from a scanned site is never run as a formula.
+func (c *Client) AddTab(ctx context.Context, spreadsheetID, tab string, rows [][]string) error {
+	addSheet := map[string]any{
+		"requests": []any{map[string]any{
+			"addSheet": map[string]any{"properties": map[string]any{"title": tab}},
+		}},
+	}
+	if err := c.call(ctx, "POST", spreadsheetID+":batchUpdate", addSheet, nil); err != nil {
+		return fmt.Errorf("error adding tab %s: %w", tab, err)
+	}
+	values := map[string]any{"majorDimension": "ROWS", "values": rows}
+	if err := c.call(ctx, "PUT", spreadsheetID+"/values/"+tabRange(tab)+"?valueInputOption=RAW", values, nil); err != nil {
+		return fmt.Errorf("error writing tab %s: %w", tab, err)
+	}
+	return nil
+}
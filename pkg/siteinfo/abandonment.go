@@ -0,0 +1,54 @@
+package siteinfo
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// abandonedAfter is how long without a release in the wordpress.org directory marks a plugin
+// or theme as abandoned
+const abandonedAfter = 2 * 365 * 24 * time.Hour
+
+// abandonment returns why the directory entry marks the plugin or theme as abandoned: closed,
+// or not updated for abandonedAfter. It returns "" for maintained ones.
+func abandonment(release *pluginRelease) string {
+	if release.Closed {
+		if closed, ok := parsePluginDate(release.ClosedDate); ok {
+			return "closed on " + closed.Format(time.DateOnly)
+		}
+		return "closed"
+	}
+	updated, ok := parsePluginDate(release.LastUpdated)
+	if !ok || time.Since(updated) < abandonedAfter {
+		return ""
+	}
+	return fmt.Sprintf("not updated since %s", updated.Format(time.DateOnly))
+}
+
+// checkAbandonment looks up each plugin and the theme in the wordpress.org directory and
+// records those that were closed or have not been updated in two years. Plugins and themes
+// the directory does not list, such as premium ones, are not judged.
+func (s *Scanner) checkAbandonment(ctx context.Context, info *SiteInfo) error {
+	for i := range info.Plugins {
+		plugin := &info.Plugins[i]
+		release, err := s.latestPluginRelease(ctx, pluginsDirectory, plugin.Slug)
+		if err != nil {
+			return err
+		}
+		if release != nil {
+			plugin.Abandoned = abandonment(release)
+		}
+	}
+	if info.Theme == "" {
+		return nil
+	}
+	release, err := s.latestPluginRelease(ctx, themesDirectory, info.Theme)
+	if err != nil {
+		return err
+	}
+	if release != nil {
+		info.ThemeAbandoned = abandonment(release)
+	}
+	return nil
+}
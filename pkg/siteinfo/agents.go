@@ -0,0 +1,154 @@
+package siteinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// agentTimeout bounds a query to an agent, which makes its warm-up and sample requests to
+// the site before answering
+const agentTimeout = 2 * time.Minute
+
+// Agent is a remote agent, run with the agent command, that measures TTFB from its region
+type Agent struct {
+	Region string
+	URL    string
+}
+
+// AgentRequest is the body of an agent's POST /ttfb
+type AgentRequest struct {
+	URL string `json:"url"`
+}
+
+// AgentResponse is an agent's answer: the TTFB samples it measured, or why it could not
+type AgentResponse struct {
+	Region string    `json:"region"`
+	TTFBs  []float64 `json:"ttfbs_ms"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// RegionalTTFB is the TTFB an agent measured from its region, or the error it failed with
+type RegionalTTFB struct {
+	Region  string
+	TTFBs   []time.Duration
+	Average time.Duration
+	Stats   TTFBStats
+	Error   string
+}
+
+// regionalTTFBJSON is the JSON form of RegionalTTFB, in milliseconds
+type regionalTTFBJSON struct {
+	Region  string    `json:"region"`
+	TTFBs   []float64 `json:"ttfbs_ms"`
+	Average float64   `json:"average_ttfb_ms"`
+	Stats   TTFBStats `json:"ttfb_stats"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the samples in milliseconds
+func (r RegionalTTFB) MarshalJSON() ([]byte, error) {
+	ttfbs := make([]float64, len(r.TTFBs))
+	for i, ttfb := range r.TTFBs {
+		ttfbs[i] = Milliseconds(ttfb)
+	}
+	return json.Marshal(regionalTTFBJSON{
+		Region:  r.Region,
+		TTFBs:   ttfbs,
+		Average: Milliseconds(r.Average),
+		Stats:   r.Stats,
+		Error:   r.Error,
+	})
+}
+
+// UnmarshalJSON decodes samples written by MarshalJSON
+func (r *RegionalTTFB) UnmarshalJSON(data []byte) error {
+	var decoded regionalTTFBJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*r = RegionalTTFB{
+		Region:  decoded.Region,
+		Average: fromMilliseconds(decoded.Average),
+		Stats:   decoded.Stats,
+		Error:   decoded.Error,
+	}
+	for _, ttfb := range decoded.TTFBs {
+		r.TTFBs = append(r.TTFBs, fromMilliseconds(ttfb))
+	}
+	return nil
+}
+
+// SampleTTFB measures the TTFB samples of the URL as a scan does, warm-up requests included,
+// for agents answering requests from another machine's scan
+func (s *Scanner) SampleTTFB(ctx context.Context, url string) ([]time.Duration, error) {
+	if err := s.allowTarget(url); err != nil {
+		return nil, err
+	}
+	return s.sampleTTFB(ctx, asciiURL(url))
+}
+
+// queryAgent asks the agent to measure the URL's TTFB from its region
+func (s *Scanner) queryAgent(ctx context.Context, agent Agent, url string) ([]time.Duration, error) {
+	body, err := json.Marshal(AgentRequest{URL: url})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(agent.URL, "/")+"/ttfb", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.opts.AgentToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.opts.AgentToken)
+	}
+	resp, err := (&http.Client{Timeout: agentTimeout}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var answer AgentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if answer.Error != "" {
+			return nil, fmt.Errorf("agent %s: %s", agent.Region, answer.Error)
+		}
+		return nil, fmt.Errorf("agent %s returned HTTP %d", agent.Region, resp.StatusCode)
+	}
+	ttfbs := make([]time.Duration, len(answer.TTFBs))
+	for i, ttfb := range answer.TTFBs {
+		ttfbs[i] = fromMilliseconds(ttfb)
+	}
+	return ttfbs, nil
+}
+
+// measureRegions has every agent measure the URL's TTFB from its region in parallel, so
+// latency is reported as visitors elsewhere see it rather than from this machine alone.
+// Agents that fail are reported with their error.
+func (s *Scanner) measureRegions(ctx context.Context, url string) []RegionalTTFB {
+	regions := make([]RegionalTTFB, len(s.opts.Agents))
+	var wg sync.WaitGroup
+	for i, agent := range s.opts.Agents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			regions[i].Region = agent.Region
+			ttfbs, err := s.queryAgent(ctx, agent, url)
+			if err != nil {
+				regions[i].Error = err.Error()
+				return
+			}
+			regions[i].TTFBs = ttfbs
+			regions[i].Average, regions[i].Stats = ttfbStatistics(ttfbs)
+		}()
+	}
+	wg.Wait()
+	return regions
+}
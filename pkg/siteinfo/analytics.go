@@ -0,0 +1,90 @@
+package siteinfo
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// AnalyticsTag is an analytics or tag manager installation and the tracking ID it reports to
+type AnalyticsTag struct {
+	Tool string `json:"tool"`
+	ID   string `json:"id,omitempty"`
+}
+
+// String formats the tag as tool or tool (ID)
+func (t AnalyticsTag) String() string {
+	if t.ID == "" {
+		return t.Tool
+	}
+	return t.Tool + " (" + t.ID + ")"
+}
+
+// analyticsTool recognises an analytics tool by the tracking IDs in its script URLs and
+// inline snippets, or by markers when the ID cannot be found
+type analyticsTool struct {
+	name    string
+	ids     []*regexp.Regexp
+	markers []string
+}
+
+// analyticsTools lists the analytics tools that can be detected. Universal Analytics stopped
+// processing data in 2023, so its snippets are remnants to remove.
+var analyticsTools = []analyticsTool{
+	{
+		name: "Google Analytics 4",
+		ids: []*regexp.Regexp{
+			regexp.MustCompile(`googletagmanager\.com/gtag/js\?id=(G-[A-Z0-9]+)`),
+			regexp.MustCompile(`gtag\(\s*['"]config['"]\s*,\s*['"](G-[A-Z0-9]+)['"]`),
+		},
+	},
+	{
+		name:    "Universal Analytics",
+		ids:     []*regexp.Regexp{regexp.MustCompile(`['"](UA-\d{4,10}-\d{1,4})['"]`)},
+		markers: []string{"google-analytics.com/analytics.js", "google-analytics.com/ga.js"},
+	},
+	{
+		name: "Google Tag Manager",
+		ids: []*regexp.Regexp{
+			regexp.MustCompile(`googletagmanager\.com/(?:gtm\.js|ns\.html)\?id=(GTM-[A-Z0-9]+)`),
+			regexp.MustCompile(`['"]dataLayer['"]\s*,\s*['"](GTM-[A-Z0-9]+)['"]`),
+		},
+		markers: []string{"googletagmanager.com/gtm.js"},
+	},
+	{
+		name:    "Matomo",
+		ids:     []*regexp.Regexp{regexp.MustCompile(`['"]setSiteId['"]\s*,\s*['"]?(\d+)`)},
+		markers: []string{"matomo.js", "piwik.js"},
+	},
+	{
+		name: "Meta Pixel",
+		ids: []*regexp.Regexp{
+			regexp.MustCompile(`fbq\(\s*['"]init['"]\s*,\s*['"](\d+)['"]`),
+			regexp.MustCompile(`facebook\.com/tr\?id=(\d+)`),
+		},
+		markers: []string{"/fbevents.js"},
+	},
+}
+
+// detectAnalytics finds the analytics tools and tag managers installed on the page and the
+// tracking IDs they report to, one tag per ID. A tool recognised only by its script is
+// reported without an ID.
+func detectAnalytics(body string) []AnalyticsTag {
+	tags := []AnalyticsTag{}
+	for _, tool := range analyticsTools {
+		found := false
+		for _, pattern := range tool.ids {
+			for _, match := range pattern.FindAllStringSubmatch(body, -1) {
+				found = true
+				tag := AnalyticsTag{Tool: tool.name, ID: match[1]}
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+		if !found && slices.ContainsFunc(tool.markers, func(marker string) bool { return strings.Contains(body, marker) }) {
+			tags = append(tags, AnalyticsTag{Tool: tool.name})
+		}
+	}
+	return tags
+}
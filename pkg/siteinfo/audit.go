@@ -0,0 +1,69 @@
+package siteinfo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AuditEntry records a single outbound request made by the scanner
+type AuditEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Status     int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+}
+
+// auditLog writes audit entries as newline-delimited JSON
+type auditLog struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// record writes an entry to the audit log
+func (a *auditLog) record(entry AuditEntry) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.encoder.Encode(entry)
+}
+
+// newAuditLog creates an audit log writing to w, or nil if w is nil
+func newAuditLog(w io.Writer) *auditLog {
+	if w == nil {
+		return nil
+	}
+	return &auditLog{encoder: json.NewEncoder(w)}
+}
+
+// auditTransport records every request passing through it in the audit log
+type auditTransport struct {
+	next http.RoundTripper
+	log  *auditLog
+}
+
+// RoundTrip sends the request and records it
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	entry := AuditEntry{
+		Timestamp:  start,
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		DurationMS: Milliseconds(time.Since(start)),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+	}
+	t.log.record(entry)
+	return resp, err
+}
@@ -0,0 +1,225 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+)
+
+// benchBuckets are the upper bounds of the latency histogram's buckets; slower responses
+// fall in a final unbounded bucket
+var benchBuckets = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// BenchBucket counts the responses with a latency up to UpperBound, and above the previous
+// bucket's. The last bucket has no upper bound.
+type BenchBucket struct {
+	UpperBound time.Duration
+	Count      int
+}
+
+// BenchResult summarises a load benchmark of one URL. Latency is the time to the end of the
+// response body, over the requests that got a response; errors are requests that failed and
+// responses with a server error status.
+type BenchResult struct {
+	URL               string
+	Concurrency       int
+	Duration          time.Duration
+	Requests          int
+	Errors            int
+	ErrorRate         float64
+	CacheHits         int
+	StatusCodes       map[int]int
+	RequestsPerSecond float64
+	MeanTTFB          time.Duration
+	Mean              time.Duration
+	Min               time.Duration
+	P50               time.Duration
+	P90               time.Duration
+	P99               time.Duration
+	Max               time.Duration
+	Histogram         []BenchBucket
+}
+
+// MarshalJSON encodes the benchmark with durations in milliseconds
+func (b *BenchResult) MarshalJSON() ([]byte, error) {
+	type bucketJSON struct {
+		UpperBound float64 `json:"le_ms,omitempty"`
+		Count      int     `json:"count"`
+	}
+	histogram := make([]bucketJSON, len(b.Histogram))
+	for i, bucket := range b.Histogram {
+		histogram[i] = bucketJSON{UpperBound: Milliseconds(bucket.UpperBound), Count: bucket.Count}
+	}
+	return json.Marshal(struct {
+		URL               string       `json:"url"`
+		Concurrency       int          `json:"concurrency"`
+		Duration          float64      `json:"duration_s"`
+		Requests          int          `json:"requests"`
+		Errors            int          `json:"errors"`
+		ErrorRate         float64      `json:"error_rate"`
+		CacheHits         int          `json:"cache_hits"`
+		StatusCodes       map[int]int  `json:"status_codes"`
+		RequestsPerSecond float64      `json:"requests_per_second"`
+		MeanTTFB          float64      `json:"mean_ttfb_ms"`
+		Mean              float64      `json:"mean_ms"`
+		Min               float64      `json:"min_ms"`
+		P50               float64      `json:"p50_ms"`
+		P90               float64      `json:"p90_ms"`
+		P99               float64      `json:"p99_ms"`
+		Max               float64      `json:"max_ms"`
+		Histogram         []bucketJSON `json:"histogram"`
+	}{
+		URL:               b.URL,
+		Concurrency:       b.Concurrency,
+		Duration:          b.Duration.Seconds(),
+		Requests:          b.Requests,
+		Errors:            b.Errors,
+		ErrorRate:         b.ErrorRate,
+		CacheHits:         b.CacheHits,
+		StatusCodes:       b.StatusCodes,
+		RequestsPerSecond: b.RequestsPerSecond,
+		MeanTTFB:          Milliseconds(b.MeanTTFB),
+		Mean:              Milliseconds(b.Mean),
+		Min:               Milliseconds(b.Min),
+		P50:               Milliseconds(b.P50),
+		P90:               Milliseconds(b.P90),
+		P99:               Milliseconds(b.P99),
+		Max:               Milliseconds(b.Max),
+		Histogram:         histogram,
+	})
+}
+
+// benchSample is the outcome of one benchmark request
+type benchSample struct {
+	latency time.Duration
+	ttfb    time.Duration
+	status  int
+	hit     bool
+	failed  bool
+}
+
+// benchRequest makes one benchmark request, timed with the same trace as the scan's requests
+func (s *Scanner) benchRequest(ctx context.Context, client *http.Client, url string) benchSample {
+	var timing Timing
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, traceTiming(&timing)), "GET", url, nil)
+	if err != nil {
+		return benchSample{failed: true}
+	}
+	timing.start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return benchSample{latency: time.Since(timing.start), failed: true}
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return benchSample{
+		latency: time.Since(timing.start),
+		ttfb:    timing.TTFB,
+		status:  resp.StatusCode,
+		hit:     isCacheHit(cacheStatus(resp.Header), resp.Header.Get("Age")),
+		failed:  err != nil || resp.StatusCode >= 500,
+	}
+}
+
+// Bench requests the URL from the given number of concurrent workers, each sending its next
+// request as soon as the last completes, until the duration has elapsed or ctx is cancelled.
+// Requests bypass the rate limit, as the load is the point, so only benchmark sites you are
+// allowed to load test; sites Options.AllowTarget refuses are not requested at all.
+func (s *Scanner) Bench(ctx context.Context, url string, concurrency int, duration time.Duration) (*BenchResult, error) {
+	if err := s.allowTarget(url); err != nil {
+		return nil, err
+	}
+	url = withScheme(asciiURL(url), "http")
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	client := s.clientFor(hostOf(url))
+
+	var mu sync.Mutex
+	var samples []benchSample
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range max(concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				sample := s.benchRequest(ctx, client, url)
+				// Requests cut off by the end of the run are not counted as errors
+				if ctx.Err() != nil {
+					return
+				}
+				mu.Lock()
+				samples = append(samples, sample)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	result := &BenchResult{
+		URL:               url,
+		Concurrency:       max(concurrency, 1),
+		Duration:          elapsed,
+		Requests:          len(samples),
+		StatusCodes:       map[int]int{},
+		RequestsPerSecond: float64(len(samples)) / elapsed.Seconds(),
+		Histogram:         make([]BenchBucket, len(benchBuckets)+1),
+	}
+	for i, bound := range benchBuckets {
+		result.Histogram[i].UpperBound = bound
+	}
+	if len(samples) == 0 {
+		return result, nil
+	}
+
+	var latencies []time.Duration
+	var totalLatency, totalTTFB time.Duration
+	for _, sample := range samples {
+		if sample.failed {
+			result.Errors++
+		}
+		if sample.status == 0 {
+			continue
+		}
+		result.StatusCodes[sample.status]++
+		if sample.hit {
+			result.CacheHits++
+		}
+		latencies = append(latencies, sample.latency)
+		totalLatency += sample.latency
+		totalTTFB += sample.ttfb
+		bucket, _ := slices.BinarySearch(benchBuckets, sample.latency)
+		result.Histogram[bucket].Count++
+	}
+	result.ErrorRate = float64(result.Errors) / float64(len(samples))
+	if len(latencies) == 0 {
+		return result, nil
+	}
+	slices.Sort(latencies)
+	percentile := func(p float64) time.Duration {
+		// Nearest-rank percentile, as for the TTFB samples
+		return latencies[int(math.Ceil(p*float64(len(latencies))))-1]
+	}
+	result.Mean = totalLatency / time.Duration(len(latencies))
+	result.MeanTTFB = totalTTFB / time.Duration(len(latencies))
+	result.Min, result.Max = latencies[0], latencies[len(latencies)-1]
+	result.P50, result.P90, result.P99 = percentile(0.5), percentile(0.9), percentile(0.99)
+	return result, nil
+}
@@ -0,0 +1,169 @@
+package siteinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"slices"
+	"strings"
+)
+
+// safeBrowsingURL is the Google Safe Browsing v4 lookup API
+const safeBrowsingURL = "https://safebrowsing.googleapis.com/v4/threatMatches:find"
+
+// safeBrowsingThreatTypes are the threat lists a URL is checked against
+var safeBrowsingThreatTypes = []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE", "POTENTIALLY_HARMFUL_APPLICATION"}
+
+// dnsbl is a DNS blocklist, queried with the domain, or the reversed IPv4 address, prepended
+// to its zone
+type dnsbl struct {
+	name string
+	zone string
+	// byIP lists the site's addresses rather than its domain
+	byIP bool
+}
+
+// dnsbls are the blocklists checked. Spamhaus ZEN lists the addresses of compromised hosts;
+// the others list domains seen in spam and malware campaigns.
+var dnsbls = []dnsbl{
+	{name: "Spamhaus DBL", zone: "dbl.spamhaus.org"},
+	{name: "SURBL", zone: "multi.surbl.org"},
+	{name: "URIBL", zone: "multi.uribl.com"},
+	{name: "Spamhaus ZEN", zone: "zen.spamhaus.org", byIP: true},
+}
+
+// Blocklists holds whether the site is flagged as malicious or compromised
+type Blocklists struct {
+	// SafeBrowsing lists the Google Safe Browsing threat types the URL matches, e.g.
+	// MALWARE or SOCIAL_ENGINEERING. It is nil when no API key was given.
+	SafeBrowsing []string `json:"safe_browsing,omitempty"`
+	// DNSBL lists the DNS blocklists the domain or its addresses are on
+	DNSBL   []string `json:"dnsbl,omitempty"`
+	Flagged bool     `json:"flagged"`
+}
+
+// checkSafeBrowsing returns the threat types Google Safe Browsing matches the URL to, empty
+// when it is not flagged
+func (s *Scanner) checkSafeBrowsing(ctx context.Context, url, key string) ([]string, error) {
+	var lookup struct {
+		Client struct {
+			ClientID      string `json:"clientId"`
+			ClientVersion string `json:"clientVersion"`
+		} `json:"client"`
+		ThreatInfo struct {
+			ThreatTypes      []string            `json:"threatTypes"`
+			PlatformTypes    []string            `json:"platformTypes"`
+			ThreatEntryTypes []string            `json:"threatEntryTypes"`
+			ThreatEntries    []map[string]string `json:"threatEntries"`
+		} `json:"threatInfo"`
+	}
+	lookup.Client.ClientID = "site-info-fetcher"
+	lookup.Client.ClientVersion = "1.0"
+	lookup.ThreatInfo.ThreatTypes = safeBrowsingThreatTypes
+	lookup.ThreatInfo.PlatformTypes = []string{"ANY_PLATFORM"}
+	lookup.ThreatInfo.ThreatEntryTypes = []string{"URL"}
+	lookup.ThreatInfo.ThreatEntries = []map[string]string{{"url": url}}
+	body, err := json.Marshal(lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", safeBrowsingURL+"?"+neturl.Values{"key": {key}}.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Safe Browsing returned HTTP %d for %s", resp.StatusCode, url)
+	}
+	var result struct {
+		Matches []struct {
+			ThreatType string `json:"threatType"`
+		} `json:"matches"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	threats := []string{}
+	for _, match := range result.Matches {
+		if !slices.Contains(threats, match.ThreatType) {
+			threats = append(threats, match.ThreatType)
+		}
+	}
+	return threats, nil
+}
+
+// dnsblListed reports whether the name is on the blocklist. Listings answer with an address
+// in 127.0.0.0/8; 127.255.255.0/24 is how Spamhaus refuses queries through public resolvers,
+// and URIBL and SURBL answer 127.0.0.1 for the same reason, so neither counts as a listing.
+func (s *Scanner) dnsblListed(ctx context.Context, name string) bool {
+	addrs, err := s.resolver().LookupIP(ctx, "ip4", name)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		ip := addr.To4()
+		if ip[0] == 127 && !(ip[1] == 255 && ip[2] == 255) && !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+			return true
+		}
+	}
+	return false
+}
+
+// reverseIPv4 returns the address with its octets reversed, as DNSBLs are queried
+func reverseIPv4(ip net.IP) string {
+	v4 := ip.To4()
+	return fmt.Sprintf("%d.%d.%d.%d", v4[3], v4[2], v4[1], v4[0])
+}
+
+// checkDNSBLs returns the DNS blocklists the site's registrable domain or IPv4 addresses are on
+func (s *Scanner) checkDNSBLs(ctx context.Context, url string) []string {
+	host := hostOf(url)
+	domain := registrableDomain(host)
+	ips, _ := s.resolver().LookupIP(ctx, "ip4", host)
+
+	listed := []string{}
+	for _, list := range dnsbls {
+		names := []string{domain + "." + list.zone}
+		if list.byIP {
+			names = nil
+			for _, ip := range ips {
+				names = append(names, reverseIPv4(ip)+"."+list.zone)
+			}
+		}
+		if slices.ContainsFunc(names, func(name string) bool { return s.dnsblListed(ctx, name) }) {
+			listed = append(listed, list.name)
+		}
+	}
+	return listed
+}
+
+// checkBlocklists looks the site up on Google Safe Browsing, when an API key is given, and on
+// the DNS blocklists. Flagged is set when any of them lists the site.
+func (s *Scanner) checkBlocklists(ctx context.Context, url string) (*Blocklists, error) {
+	blocklists := &Blocklists{DNSBL: s.checkDNSBLs(ctx, url)}
+	var err error
+	if s.opts.SafeBrowsingKey != "" {
+		blocklists.SafeBrowsing, err = s.checkSafeBrowsing(ctx, url, s.opts.SafeBrowsingKey)
+	}
+	blocklists.Flagged = len(blocklists.SafeBrowsing) > 0 || len(blocklists.DNSBL) > 0
+	return blocklists, err
+}
+
+// String summarises the listings, e.g. "Safe Browsing: MALWARE; Spamhaus DBL"
+func (b *Blocklists) String() string {
+	var parts []string
+	if len(b.SafeBrowsing) > 0 {
+		parts = append(parts, "Safe Browsing: "+strings.Join(b.SafeBrowsing, ", "))
+	}
+	return strings.Join(append(parts, b.DNSBL...), "; ")
+}
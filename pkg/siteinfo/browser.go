@@ -0,0 +1,42 @@
+package siteinfo
+
+import "net/http"
+
+// browserUserAgent is the User-Agent of the browser profile: desktop Chrome on Windows
+const browserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+
+// browserHeaders are the headers desktop Chrome sends when navigating to a page, in Chrome's
+// order. Go writes request headers in its own order, so the order only documents Chrome's.
+var browserHeaders = [][2]string{
+	{"sec-ch-ua", `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`},
+	{"sec-ch-ua-mobile", "?0"},
+	{"sec-ch-ua-platform", `"Windows"`},
+	{"Upgrade-Insecure-Requests", "1"},
+	{"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+	{"Sec-Fetch-Site", "none"},
+	{"Sec-Fetch-Mode", "navigate"},
+	{"Sec-Fetch-User", "?1"},
+	{"Sec-Fetch-Dest", "document"},
+	{"Accept-Language", "en-US,en;q=0.9"},
+}
+
+// browserTransport sends the browser profile's headers with every request, keeping any header
+// the request already sets. Accept-Encoding is left to the transport, which decompresses the
+// responses it asked to be compressed.
+type browserTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip adds the browser headers and sends the request
+func (t *browserTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", browserUserAgent)
+	}
+	for _, header := range browserHeaders {
+		if req.Header.Get(header[0]) == "" {
+			req.Header.Set(header[0], header[1])
+		}
+	}
+	return t.next.RoundTrip(req)
+}
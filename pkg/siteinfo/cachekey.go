@@ -0,0 +1,77 @@
+package siteinfo
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// CacheKeyAudit reports Vary headers and cookie handling that make page caches ineffective
+type CacheKeyAudit struct {
+	Vary   string   `json:"vary,omitempty"`
+	Issues []string `json:"issues"`
+}
+
+// varyIssues maps Vary header fields to the way they fragment or disable the page cache
+var varyIssues = map[string]string{
+	"*":          "Vary: * makes every response uncacheable",
+	"cookie":     "Vary: Cookie caches a separate copy for every visitor",
+	"user-agent": "Vary: User-Agent caches a separate copy for every browser version",
+}
+
+// Cookies sent to see whether the cache bypasses on them. Analytics cookies are set for every
+// visitor, so bypassing on them defeats the cache; the logged-in cookie must always bypass.
+const (
+	analyticsCookie = "_ga=GA1.1.1234567890.1234567890"
+	loggedInCookie  = "wordpress_logged_in_site-info-fetcher=site-info-fetcher"
+)
+
+// auditCacheKey checks the homepage headers for Vary fields and cookies that defeat the page
+// cache, and when the homepage is a cache hit, whether analytics and logged-in cookies bypass it
+func (s *Scanner) auditCacheKey(ctx context.Context, url string, header http.Header) *CacheKeyAudit {
+	audit := &CacheKeyAudit{Vary: strings.Join(header.Values("Vary"), ", ")}
+
+	var fields []string
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			fields = append(fields, strings.ToLower(strings.TrimSpace(field)))
+		}
+	}
+	for _, field := range []string{"*", "cookie", "user-agent"} {
+		if slices.Contains(fields, field) {
+			audit.Issues = append(audit.Issues, varyIssues[field])
+		}
+	}
+
+	var cookies []string
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		cookies = append(cookies, cookie.Name)
+	}
+	if len(cookies) > 0 {
+		audit.Issues = append(audit.Issues, "sets cookies for anonymous visitors ("+strings.Join(cookies, ", ")+"), which most caches will not store")
+	}
+
+	if !isCacheHit(cacheStatus(header), strings.TrimSpace(header.Get("Age"))) {
+		return audit
+	}
+	if status, ok := s.cacheStatusWithCookie(ctx, url, analyticsCookie); ok && !isCacheHit(status, "") {
+		audit.Issues = append(audit.Issues, "analytics cookies bypass the cache ("+status+")")
+	}
+	if status, ok := s.cacheStatusWithCookie(ctx, url, loggedInCookie); ok && isCacheHit(status, "") {
+		audit.Issues = append(audit.Issues, "logged-in cookie is served from the cache")
+	}
+	return audit
+}
+
+// cacheStatusWithCookie fetches the URL with the cookie and returns the cache status header,
+// reporting false if the request failed or the response has no cache status
+func (s *Scanner) cacheStatusWithCookie(ctx context.Context, url, cookie string) (string, bool) {
+	resp, err := s.doRequest(ctx, "GET", url, http.Header{"Cookie": {cookie}})
+	if err != nil {
+		return "", false
+	}
+	resp.Body.Close()
+	status := cacheStatus(resp.Header)
+	return status, status != ""
+}
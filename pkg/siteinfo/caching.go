@@ -0,0 +1,66 @@
+package siteinfo
+
+import (
+	"net/http"
+	"strings"
+)
+
+// cacheHeaders maps response headers to the caching layer that sets them. The header
+// value, usually HIT or MISS, is reported alongside the layer.
+var cacheHeaders = []struct {
+	header string
+	layer  string
+}{
+	{"X-Varnish", "Varnish"},
+	{"X-LiteSpeed-Cache", "LiteSpeed"},
+	{"CF-Cache-Status", "Cloudflare"},
+	{"X-Proxy-Cache", "Nginx proxy cache"},
+	{"X-FastCGI-Cache", "Nginx FastCGI cache"},
+	{"X-Kinsta-Cache", "Kinsta"},
+	{"X-WPE-Cached", "WP Engine"},
+	{"X-SG-Cache", "SiteGround"},
+	{"X-Cache", "Proxy cache"},
+}
+
+// cachePluginFootprints maps HTML comments left by WordPress caching plugins to the plugin name
+var cachePluginFootprints = []struct {
+	footprint string
+	plugin    string
+}{
+	{"This website is like a Rocket", "WP Rocket"},
+	{"Performance optimized by W3 Total Cache", "W3 Total Cache"},
+	{"WP-Super-Cache", "WP Super Cache"},
+	{"Page optimized by LiteSpeed Cache", "LiteSpeed Cache"},
+	{"Cached by WP-Optimize", "WP-Optimize"},
+	{"Cache Enabler by KeyCDN", "Cache Enabler"},
+	{"WP Fastest Cache file", "WP Fastest Cache"},
+	{"Hummingbird cache file", "Hummingbird"},
+}
+
+// detectCachingLayers reports the caching layers found in the response headers and the page body
+func detectCachingLayers(headers http.Header, body string) []string {
+	var layers []string
+	for _, cache := range cacheHeaders {
+		value := strings.TrimSpace(headers.Get(cache.header))
+		if value == "" {
+			continue
+		}
+		if cache.header == "X-Varnish" {
+			// X-Varnish carries request IDs rather than a status; two IDs mean a cache hit
+			value = "MISS"
+			if len(strings.Fields(headers.Get(cache.header))) > 1 {
+				value = "HIT"
+			}
+		}
+		layers = append(layers, cache.layer+" ("+strings.ToUpper(value)+")")
+	}
+	if age := strings.TrimSpace(headers.Get("Age")); age != "" && age != "0" && len(layers) == 0 {
+		layers = append(layers, "Shared cache (Age "+age+"s)")
+	}
+	for _, plugin := range cachePluginFootprints {
+		if strings.Contains(body, plugin.footprint) {
+			layers = append(layers, plugin.plugin)
+		}
+	}
+	return layers
+}
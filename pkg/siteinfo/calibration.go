@@ -0,0 +1,55 @@
+package siteinfo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// degradedFactor is how much slower than the first measurement the baseline must respond
+// before results are annotated as taken under degraded network conditions
+const degradedFactor = 2
+
+// calibration tracks the TTFB of a reference endpoint during long runs, so that slow results
+// caused by the scanner's own network can be told apart from slow sites
+type calibration struct {
+	url      string
+	interval time.Duration
+
+	mu        sync.Mutex
+	reference time.Duration
+	current   time.Duration
+	measured  time.Time
+}
+
+// baseline returns the reference and latest baseline TTFB, re-measuring the baseline URL
+// when the calibration interval has elapsed. Failed measurements keep the previous value.
+func (s *Scanner) baseline(ctx context.Context) (time.Duration, time.Duration) {
+	c := s.calibration
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.measured) >= c.interval {
+		resp, timing, err := s.fetchURL(ctx, c.url)
+		if err == nil {
+			resp.Body.Close()
+			c.current = timing.TTFB
+			if c.reference == 0 {
+				c.reference = c.current
+			}
+			if c.current > c.reference*degradedFactor {
+				s.opts.Logger.Warn("Network conditions degraded", "baseline_ttfb_ms", Milliseconds(c.current), "initial_baseline_ttfb_ms", Milliseconds(c.reference))
+			}
+		}
+		c.measured = time.Now()
+	}
+	return c.reference, c.current
+}
+
+// annotateBaseline records the latest baseline TTFB on the result and flags it when the
+// baseline has slowed to more than twice its first measurement
+func (s *Scanner) annotateBaseline(ctx context.Context, info *SiteInfo) {
+	reference, current := s.baseline(ctx)
+	info.BaselineTTFB = current
+	info.NetworkDegraded = reference > 0 && current > reference*degradedFactor
+}
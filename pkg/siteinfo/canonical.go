@@ -0,0 +1,92 @@
+package siteinfo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// CanonicalProbe is the response of one scheme and www variant of the site's hostname
+type CanonicalProbe struct {
+	URL    string `json:"url"`
+	Result string `json:"result"`
+}
+
+// Canonicalization reports how the http, https, www and bare variants of the hostname
+// redirect to the canonical origin the homepage resolved to
+type Canonicalization struct {
+	Canonical string           `json:"canonical"`
+	Probes    []CanonicalProbe `json:"probes"`
+	OK        bool             `json:"ok"`
+}
+
+// origin returns the scheme and host of a URL
+func origin(u *neturl.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// checkCanonicalization requests http://host, https://host, http://www.host and
+// https://www.host without following redirects. Each variant other than the canonical one
+// should answer with a single 301 to the canonical origin; SSL-only vhosts and missing
+// redirects show up as other results. The result of each probe is "Canonical", "301",
+// "<status> to canonical" for other redirect codes, "<status> to <origin>" for redirects
+// elsewhere, "<status> (no redirect)" or "Unreachable".
+func (s *Scanner) checkCanonicalization(ctx context.Context, finalURL string) *Canonicalization {
+	final, err := neturl.Parse(finalURL)
+	if err != nil {
+		return nil
+	}
+	canonical := origin(final)
+	host := strings.TrimPrefix(final.Hostname(), "www.")
+
+	scanClient := s.clientFor(final.Hostname())
+	client := &http.Client{
+		Transport: scanClient.Transport,
+		Timeout:   scanClient.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	result := &Canonicalization{Canonical: canonical, OK: true}
+	for _, variant := range []string{"http://" + host, "https://" + host, "http://www." + host, "https://www." + host} {
+		probe := CanonicalProbe{URL: variant}
+		if variant == canonical {
+			probe.Result = "Canonical"
+			result.Probes = append(result.Probes, probe)
+			continue
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", variant+"/", nil)
+		if err != nil {
+			continue
+		}
+		resp, err := s.do(client, req)
+		if err != nil {
+			probe.Result = "Unreachable"
+			result.OK = false
+			result.Probes = append(result.Probes, probe)
+			continue
+		}
+		resp.Body.Close()
+
+		location, err := resp.Location()
+		switch {
+		case err != nil:
+			probe.Result = fmt.Sprintf("%d (no redirect)", resp.StatusCode)
+		case origin(location) != canonical:
+			probe.Result = fmt.Sprintf("%d to %s", resp.StatusCode, origin(location))
+		case resp.StatusCode == http.StatusMovedPermanently:
+			probe.Result = "301"
+		default:
+			probe.Result = fmt.Sprintf("%d to canonical", resp.StatusCode)
+		}
+		if probe.Result != "301" {
+			result.OK = false
+		}
+		result.Probes = append(result.Probes, probe)
+	}
+	return result
+}
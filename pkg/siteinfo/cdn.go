@@ -0,0 +1,87 @@
+package siteinfo
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// cdnSignature identifies a CDN by its response headers, Server header and CNAME targets
+type cdnSignature struct {
+	name    string
+	headers []string
+	servers []string
+	cnames  []string
+}
+
+// cdnSignatures lists the CDNs that can be detected
+var cdnSignatures = []cdnSignature{
+	{name: "Cloudflare", headers: []string{"CF-Ray", "CF-Cache-Status"}, servers: []string{"cloudflare"}, cnames: []string{".cdn.cloudflare.net"}},
+	{name: "Fastly", headers: []string{"X-Fastly-Request-ID"}, cnames: []string{".fastly.net", ".fastlylb.net"}},
+	{name: "Akamai", headers: []string{"X-Akamai-Transformed", "Akamai-GRN"}, servers: []string{"akamaighost", "akamainetstorage"}, cnames: []string{".akamaiedge.net", ".akamai.net", ".edgekey.net", ".edgesuite.net"}},
+	{name: "CloudFront", headers: []string{"X-Amz-Cf-Id", "X-Amz-Cf-Pop"}, servers: []string{"cloudfront"}, cnames: []string{".cloudfront.net"}},
+	{name: "Azure Front Door", headers: []string{"X-Azure-Ref"}, cnames: []string{".azurefd.net", ".azureedge.net"}},
+	{name: "Google Cloud CDN", servers: []string{"google frontend"}, cnames: []string{".googleusercontent.com"}},
+	{name: "Sucuri", headers: []string{"X-Sucuri-ID"}, servers: []string{"sucuri/cloudproxy"}, cnames: []string{".sucuri.net"}},
+	{name: "StackPath", headers: []string{"X-HW"}, cnames: []string{".stackpathdns.com"}},
+	{name: "BunnyCDN", headers: []string{"CDN-PullZone"}, servers: []string{"bunnycdn"}, cnames: []string{".b-cdn.net"}},
+	{name: "KeyCDN", servers: []string{"keycdn-engine"}, cnames: []string{".kxcdn.com"}},
+}
+
+// detectCDNHeaders identifies the CDN from the response headers. Fastly is also recognised
+// from its cache node names in X-Served-By, and Varnish-based CDNs from the Via header.
+func detectCDNHeaders(headers http.Header) string {
+	server := strings.ToLower(headers.Get("Server"))
+	for _, cdn := range cdnSignatures {
+		for _, name := range cdn.headers {
+			if headers.Get(name) != "" {
+				return cdn.name
+			}
+		}
+		for _, prefix := range cdn.servers {
+			if strings.HasPrefix(server, prefix) {
+				return cdn.name
+			}
+		}
+	}
+	if strings.Contains(headers.Get("X-Served-By"), "cache-") {
+		return "Fastly"
+	}
+	if via := strings.ToLower(headers.Get("Via")); strings.Contains(via, "cloudfront") {
+		return "CloudFront"
+	}
+	return ""
+}
+
+// detectCDN identifies the CDN in front of the site from the response headers, falling back
+// to the host's CNAME record. It returns "None" when no CDN is recognised.
+func (s *Scanner) detectCDN(ctx context.Context, url string, headers http.Header) string {
+	if cdn := detectCDNHeaders(headers); cdn != "" {
+		return cdn
+	}
+	cname, err := s.resolver().LookupCNAME(ctx, hostOf(url))
+	if err == nil {
+		cname = strings.ToLower(strings.TrimSuffix(cname, "."))
+		for _, cdn := range cdnSignatures {
+			for _, suffix := range cdn.cnames {
+				if strings.HasSuffix(cname, suffix) {
+					return cdn.name
+				}
+			}
+		}
+	}
+	return "None"
+}
+
+// isCDNServer reports whether the Server header names a CDN edge rather than the origin web server
+func isCDNServer(webServer string) bool {
+	webServer = strings.ToLower(strings.TrimSpace(webServer))
+	for _, cdn := range cdnSignatures {
+		for _, prefix := range cdn.servers {
+			if strings.SplitN(prefix, "/", 2)[0] == webServer {
+				return true
+			}
+		}
+	}
+	return false
+}
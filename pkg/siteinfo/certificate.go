@@ -0,0 +1,128 @@
+package siteinfo
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// CertificateInfo describes the certificate served by the site
+type CertificateInfo struct {
+	Subject         string    `json:"subject"`
+	IssuerCN        string    `json:"issuer_cn"`
+	IssuerOrg       string    `json:"issuer_org"`
+	NotBefore       time.Time `json:"not_before"`
+	NotAfter        time.Time `json:"not_after"`
+	DaysUntilExpiry int       `json:"days_until_expiry"`
+	ExpiringSoon    bool      `json:"expiring_soon"`
+	SANs            []string  `json:"sans"`
+	KeyAlgorithm    string    `json:"key_algorithm"`
+	RenewalOverdue  bool      `json:"renewal_overdue"`
+	Weaknesses      []string  `json:"weaknesses"`
+}
+
+// maxCertificateValidity is the longest validity period browsers accept for certificates
+// issued since 1 September 2020
+const maxCertificateValidity = 398 * 24 * time.Hour
+
+// certificateWeaknesses flags keys, signatures and validity periods that modern browsers reject,
+// each with the remediation to apply
+func certificateWeaknesses(cert *x509.Certificate) []string {
+	var weaknesses []string
+	if key, ok := cert.PublicKey.(*rsa.PublicKey); ok && key.N.BitLen() < 2048 {
+		weaknesses = append(weaknesses, fmt.Sprintf("RSA-%d key is too short: reissue with an RSA-2048 or ECDSA P-256 key", key.N.BitLen()))
+	}
+	switch cert.SignatureAlgorithm {
+	case x509.SHA1WithRSA, x509.ECDSAWithSHA1, x509.DSAWithSHA1, x509.MD5WithRSA, x509.MD2WithRSA:
+		weaknesses = append(weaknesses, fmt.Sprintf("%s signature is rejected by browsers: reissue with a SHA-256 signature", cert.SignatureAlgorithm))
+	}
+	if validity := cert.NotAfter.Sub(cert.NotBefore); validity > maxCertificateValidity {
+		weaknesses = append(weaknesses, fmt.Sprintf("%d day validity exceeds the 398 day browser limit: reissue with a shorter validity period", int(validity.Hours()/24)))
+	}
+	return weaknesses
+}
+
+// letsEncryptRenewalAge is the certificate age after which Let's Encrypt certificates should
+// have been renewed. Certbot and most ACME clients renew 30 days before the 90 day expiry.
+const letsEncryptRenewalAge = 60 * 24 * time.Hour
+
+// isLetsEncrypt reports whether the certificate was issued by Let's Encrypt
+func isLetsEncrypt(cert *x509.Certificate) bool {
+	for _, org := range cert.Issuer.Organization {
+		if org == "Let's Encrypt" {
+			return true
+		}
+	}
+	return false
+}
+
+// keyAlgorithm describes the certificate's public key, e.g. RSA-2048 or ECDSA-P256
+func keyAlgorithm(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	}
+	return cert.PublicKeyAlgorithm.String()
+}
+
+// describeCertificate summarizes the leaf certificate, flagging it as expiring soon
+// when fewer than warningDays remain and as overdue for renewal when it is a Let's Encrypt
+// certificate older than 60 days
+func describeCertificate(cert *x509.Certificate, warningDays int) *CertificateInfo {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	days := int(time.Until(cert.NotAfter).Hours() / 24)
+	var issuerOrg string
+	if len(cert.Issuer.Organization) > 0 {
+		issuerOrg = cert.Issuer.Organization[0]
+	}
+	return &CertificateInfo{
+		Subject:         cert.Subject.String(),
+		IssuerCN:        cert.Issuer.CommonName,
+		IssuerOrg:       issuerOrg,
+		NotBefore:       cert.NotBefore,
+		NotAfter:        cert.NotAfter,
+		DaysUntilExpiry: days,
+		ExpiringSoon:    days < warningDays,
+		SANs:            sans,
+		KeyAlgorithm:    keyAlgorithm(cert),
+		Weaknesses:      certificateWeaknesses(cert),
+		RenewalOverdue:  isLetsEncrypt(cert) && time.Since(cert.NotBefore) > letsEncryptRenewalAge,
+	}
+}
+
+// ChainCertificate summarizes one certificate of the chain the server sent, leaf first
+type ChainCertificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	NotAfter     time.Time `json:"not_after"`
+	KeyAlgorithm string    `json:"key_algorithm"`
+	Fingerprint  string    `json:"sha256_fingerprint"`
+}
+
+// describeChain summarizes each certificate the server sent
+func describeChain(certs []*x509.Certificate) []ChainCertificate {
+	var chain []ChainCertificate
+	for _, cert := range certs {
+		sum := sha256.Sum256(cert.Raw)
+		chain = append(chain, ChainCertificate{
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			NotAfter:     cert.NotAfter,
+			KeyAlgorithm: keyAlgorithm(cert),
+			Fingerprint:  hex.EncodeToString(sum[:]),
+		})
+	}
+	return chain
+}
@@ -0,0 +1,80 @@
+package siteinfo
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxAIADepth bounds how many missing issuers are fetched through AIA
+const maxAIADepth = 3
+
+// chainStatus checks whether the server sends the full intermediate chain. Browsers
+// often work around a missing intermediate by fetching it, but Android and curl fail.
+// It returns Complete, Incomplete (verifies only after fetching issuers through AIA)
+// or Untrusted.
+func (s *Scanner) chainStatus(ctx context.Context, certs []*x509.Certificate) string {
+	if len(certs) == 0 {
+		return ""
+	}
+	leaf := certs[0]
+
+	// Evaluate the chain independently of hostname and leaf expiry problems
+	opts := x509.VerifyOptions{Intermediates: x509.NewCertPool(), CurrentTime: time.Now()}
+	if opts.CurrentTime.After(leaf.NotAfter) {
+		opts.CurrentTime = leaf.NotAfter.Add(-time.Minute)
+	}
+	for _, intermediate := range certs[1:] {
+		opts.Intermediates.AddCert(intermediate)
+	}
+
+	_, err := leaf.Verify(opts)
+	if err == nil {
+		return "Complete"
+	}
+	var unknownAuthority x509.UnknownAuthorityError
+	if !errors.As(err, &unknownAuthority) {
+		return "Untrusted"
+	}
+
+	// Fetch the missing issuers the way browsers do and verify again
+	issuer := certs[len(certs)-1]
+	for i := 0; i < maxAIADepth && len(issuer.IssuingCertificateURL) > 0; i++ {
+		fetched, err := s.fetchIssuer(ctx, issuer.IssuingCertificateURL[0])
+		if err != nil {
+			break
+		}
+		opts.Intermediates.AddCert(fetched)
+		if _, err := leaf.Verify(opts); err == nil {
+			return "Incomplete"
+		}
+		issuer = fetched
+	}
+	return "Untrusted"
+}
+
+// fetchIssuer downloads an issuer certificate from an AIA CA Issuers URL
+func (s *Scanner) fetchIssuer(ctx context.Context, url string) (*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	return x509.ParseCertificate(data)
+}
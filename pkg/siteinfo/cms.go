@@ -0,0 +1,134 @@
+package siteinfo
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// generatorPattern extracts the content of the generator meta tag, in either attribute order
+var generatorPattern = regexp.MustCompile(`(?i)<meta[^>]+(?:name=["']generator["'][^>]+content=["']([^"']+)["']|content=["']([^"']+)["'][^>]+name=["']generator["'])`)
+
+// generatorVersionPattern extracts a version number from a generator string
+var generatorVersionPattern = regexp.MustCompile(`v?(\d+(?:\.\d+)*)`)
+
+// cmsSignature identifies a CMS or site generator by its generator tag, headers and page
+// footprints. frontend marks the frameworks and generators that can render content from a
+// headless CMS.
+type cmsSignature struct {
+	name       string
+	generator  string
+	headers    []string
+	footprints []string
+	frontend   bool
+}
+
+// cmsSignatures lists the detectable platforms other than WordPress, checked in order
+var cmsSignatures = []cmsSignature{
+	{name: "Drupal", generator: "drupal", headers: []string{"X-Drupal-Cache", "X-Drupal-Dynamic-Cache"}, footprints: []string{"drupal-settings-json", "Drupal.settings", "/sites/default/files/"}},
+	{name: "Joomla", generator: "joomla", footprints: []string{"/media/jui/", "/media/system/js/core.js"}},
+	{name: "Shopify", headers: []string{"X-ShopId", "X-Shopify-Stage"}, footprints: []string{"cdn.shopify.com", "Shopify.theme"}},
+	{name: "Wix", generator: "wix.com", headers: []string{"X-Wix-Request-Id"}, footprints: []string{"static.wixstatic.com"}},
+	{name: "Squarespace", generator: "squarespace", footprints: []string{"static1.squarespace.com", "Static.SQUARESPACE_CONTEXT"}},
+	{name: "Ghost", generator: "ghost", footprints: []string{"ghost-portal"}},
+	{name: "Hugo", generator: "hugo", frontend: true},
+	{name: "Jekyll", generator: "jekyll", frontend: true},
+	{name: "Gatsby", generator: "gatsby", footprints: []string{"___gatsby"}, frontend: true},
+	{name: "Eleventy", generator: "eleventy", frontend: true},
+	{name: "Hexo", generator: "hexo", frontend: true},
+	{name: "Docusaurus", generator: "docusaurus", frontend: true},
+	{name: "Astro", generator: "astro", frontend: true},
+	{name: "Nuxt", generator: "nuxt", footprints: []string{"window.__NUXT__", "/_nuxt/"}, frontend: true},
+	{name: "SvelteKit", footprints: []string{"__sveltekit_", "/_app/immutable/"}, frontend: true},
+	{name: "Remix", footprints: []string{"window.__remixContext"}, frontend: true},
+	{name: "Next.js", footprints: []string{"__NEXT_DATA__", "/_next/static/"}, frontend: true},
+}
+
+// wordpressForks lists the forks of WordPress, identified by their generator tag. Their pages
+// carry the WordPress footprints, but they have their own versions and support periods.
+var wordpressForks = []cmsSignature{
+	{name: "ClassicPress", generator: "classicpress"},
+}
+
+// isWordPressFork reports whether the CMS is a fork of WordPress
+func isWordPressFork(cms string) bool {
+	for _, fork := range wordpressForks {
+		if fork.name == cms {
+			return true
+		}
+	}
+	return false
+}
+
+// wordPressProduct returns the product whose releases the site's WordPress version is
+// compared with: WordPress, or the fork the site runs
+func wordPressProduct(info *SiteInfo) string {
+	if isWordPressFork(info.CMS) {
+		return info.CMS
+	}
+	return "WordPress"
+}
+
+// wordPressRelease returns the site's WordPress version, or the fork's version for forks
+func wordPressRelease(info *SiteInfo) string {
+	if isWordPressFork(info.CMS) {
+		return info.CMSVersion
+	}
+	return info.WordPressVersion
+}
+
+// generatorTag returns the content of the page's generator meta tag
+func generatorTag(body string) string {
+	match := generatorPattern.FindStringSubmatch(body)
+	if match == nil {
+		return ""
+	}
+	return match[1] + match[2]
+}
+
+// detectCMS identifies the CMS or static site generator from the generator tag, response
+// headers (including Drupal's X-Generator) and path footprints, returning its name and
+// version where the site discloses one. wordpress reports whether the page was already
+// recognised as WordPress, in which case only the WordPress forks are told apart from it.
+func detectCMS(headers http.Header, body string, wordpress bool, wpVersion string) (string, string) {
+	generator := generatorTag(body)
+	if generator == "" {
+		generator = headers.Get("X-Generator")
+	}
+	lowerGenerator := strings.ToLower(generator)
+	version := func() string {
+		if match := generatorVersionPattern.FindStringSubmatch(generator); match != nil {
+			return match[1]
+		}
+		return ""
+	}
+
+	if wordpress {
+		for _, fork := range wordpressForks {
+			if strings.Contains(lowerGenerator, fork.generator) {
+				return fork.name, version()
+			}
+		}
+		return "WordPress", wpVersion
+	}
+
+	for _, cms := range cmsSignatures {
+		if cms.generator != "" && strings.Contains(lowerGenerator, cms.generator) {
+			return cms.name, version()
+		}
+		for _, header := range cms.headers {
+			if headers.Get(header) != "" {
+				return cms.name, ""
+			}
+		}
+		for _, footprint := range cms.footprints {
+			if strings.Contains(body, footprint) {
+				return cms.name, ""
+			}
+		}
+	}
+	if strings.Contains(headers.Get("X-Powered-By"), "Next.js") {
+		return "Next.js", ""
+	}
+	return "Unknown", ""
+}
@@ -0,0 +1,29 @@
+package siteinfo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// checkCompression requests the page accepting gzip and Brotli and reports the encoding the
+// server uses for HTML ("none" if it sends it uncompressed) and the size transferred. Setting
+// Accept-Encoding stops the transport decompressing the body, so the bytes read are the
+// compressed size.
+func (s *Scanner) checkCompression(ctx context.Context, url string) (string, int64, error) {
+	resp, err := s.doRequest(ctx, "GET", url, http.Header{"Accept-Encoding": {"gzip, br"}})
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	size, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		encoding = "none"
+	}
+	return encoding, size, nil
+}
@@ -0,0 +1,56 @@
+package siteinfo
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Revalidation reports the homepage's cache validators and whether the server honours them.
+// Status is "Not Modified" when a conditional request with the validators is answered 304,
+// "Full Response" when the server sends the whole page again, "No Validators" when the
+// homepage has neither an ETag nor a Last-Modified header, and "Failed" when the conditional
+// request could not be made. Without a 304, browsers and caches must download the page again
+// once its max-age expires rather than cheaply revalidating their copy.
+type Revalidation struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Status       string `json:"status"`
+	StatusCode   int    `json:"status_code,omitempty"`
+}
+
+// checkRevalidation requests the page again with If-None-Match and If-Modified-Since set from
+// the homepage's ETag and Last-Modified headers, and reports whether it is answered 304
+func (s *Scanner) checkRevalidation(ctx context.Context, url string, header http.Header) *Revalidation {
+	revalidation := &Revalidation{
+		ETag:         strings.TrimSpace(header.Get("ETag")),
+		LastModified: strings.TrimSpace(header.Get("Last-Modified")),
+	}
+	if revalidation.ETag == "" && revalidation.LastModified == "" {
+		revalidation.Status = "No Validators"
+		return revalidation
+	}
+
+	conditional := http.Header{}
+	if revalidation.ETag != "" {
+		conditional.Set("If-None-Match", revalidation.ETag)
+	}
+	if revalidation.LastModified != "" {
+		conditional.Set("If-Modified-Since", revalidation.LastModified)
+	}
+	resp, err := s.doRequest(ctx, "GET", url, conditional)
+	if err != nil {
+		revalidation.Status = "Failed"
+		return revalidation
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	revalidation.StatusCode = resp.StatusCode
+	revalidation.Status = "Full Response"
+	if resp.StatusCode == http.StatusNotModified {
+		revalidation.Status = "Not Modified"
+	}
+	return revalidation
+}
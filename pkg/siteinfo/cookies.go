@@ -0,0 +1,33 @@
+package siteinfo
+
+import "net/http"
+
+// CookieAudit lists the cookies the homepage sets without the attributes that protect them:
+// Secure keeps a cookie off plain HTTP, HttpOnly out of reach of scripts, and SameSite off
+// cross-site requests
+type CookieAudit struct {
+	Count           int      `json:"count"`
+	MissingSecure   []string `json:"missing_secure,omitempty"`
+	MissingHttpOnly []string `json:"missing_httponly,omitempty"`
+	MissingSameSite []string `json:"missing_samesite,omitempty"`
+}
+
+// auditCookies inspects the Set-Cookie headers of the response, listing each cookie by name
+// under every attribute it lacks
+func auditCookies(headers http.Header) *CookieAudit {
+	audit := &CookieAudit{}
+	for _, cookie := range (&http.Response{Header: headers}).Cookies() {
+		audit.Count++
+		if !cookie.Secure {
+			audit.MissingSecure = append(audit.MissingSecure, cookie.Name)
+		}
+		if !cookie.HttpOnly {
+			audit.MissingHttpOnly = append(audit.MissingHttpOnly, cookie.Name)
+		}
+		// Without the attribute SameSite is zero, and SameSiteDefaultMode when it has no valid value
+		if cookie.SameSite == 0 || cookie.SameSite == http.SameSiteDefaultMode {
+			audit.MissingSameSite = append(audit.MissingSameSite, cookie.Name)
+		}
+	}
+	return audit
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package siteinfo
+
+import "runtime/metrics"
+
+// cpuSeconds returns the CPU time the process has used, as estimated by the Go runtime at
+// each garbage collection
+func cpuSeconds() float64 {
+	samples := []metrics.Sample{{Name: "/cpu/classes/total:cpu-seconds"}, {Name: "/cpu/classes/idle:cpu-seconds"}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() != metrics.KindFloat64 || samples[1].Value.Kind() != metrics.KindFloat64 {
+		return 0
+	}
+	return samples[0].Value.Float64() - samples[1].Value.Float64()
+}
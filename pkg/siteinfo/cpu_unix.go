@@ -0,0 +1,17 @@
+//go:build unix
+
+package siteinfo
+
+import "syscall"
+
+// cpuSeconds returns the user and system CPU time the process has used
+func cpuSeconds() float64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	seconds := func(tv syscall.Timeval) float64 {
+		return float64(tv.Sec) + float64(tv.Usec)/1e6
+	}
+	return seconds(usage.Utime) + seconds(usage.Stime)
+}
@@ -0,0 +1,211 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+// sitemapPaths are where sitemaps are looked for: the conventional location, the sitemap
+// WordPress core generates and the index of Yoast and Rank Math
+var sitemapPaths = []string{"/sitemap.xml", "/wp-sitemap.xml", "/sitemap_index.xml"}
+
+// maxSitemapFetches bounds the sitemaps fetched per site when following sitemap indexes
+const maxSitemapFetches = 10
+
+// Crawl aggregates the TTFB, caching and mixed content of pages sampled from a site's
+// sitemap, so a site is not judged from its homepage alone
+type Crawl struct {
+	// Sitemap is the sitemap the pages were found in, empty when the site has none
+	Sitemap string `json:"sitemap,omitempty"`
+	// SitemapPages is the number of pages the sitemap lists on the site's host
+	SitemapPages      int           `json:"sitemap_pages"`
+	Pages             []CrawledPage `json:"pages"`
+	FailedPages       int           `json:"failed_pages"`
+	AverageTTFB       time.Duration `json:"-"`
+	SlowestTTFB       time.Duration `json:"-"`
+	SlowestPage       string        `json:"slowest_page,omitempty"`
+	CachedPages       int           `json:"cached_pages"`
+	MixedContentPages int           `json:"mixed_content_pages"`
+}
+
+// CrawledPage is one page sampled from the sitemap
+type CrawledPage struct {
+	URL          string        `json:"url"`
+	StatusCode   int           `json:"status_code,omitempty"`
+	TTFB         time.Duration `json:"-"`
+	Cached       bool          `json:"cached"`
+	MixedContent []string      `json:"mixed_content,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the crawl with the TTFBs in milliseconds
+func (c *Crawl) MarshalJSON() ([]byte, error) {
+	type crawlJSON Crawl
+	return json.Marshal(struct {
+		*crawlJSON
+		AverageTTFB float64 `json:"average_ttfb_ms"`
+		SlowestTTFB float64 `json:"slowest_ttfb_ms"`
+	}{
+		crawlJSON:   (*crawlJSON)(c),
+		AverageTTFB: Milliseconds(c.AverageTTFB),
+		SlowestTTFB: Milliseconds(c.SlowestTTFB),
+	})
+}
+
+// UnmarshalJSON decodes a crawl written by MarshalJSON
+func (c *Crawl) UnmarshalJSON(data []byte) error {
+	type crawlJSON Crawl
+	decoded := struct {
+		*crawlJSON
+		AverageTTFB float64 `json:"average_ttfb_ms"`
+		SlowestTTFB float64 `json:"slowest_ttfb_ms"`
+	}{crawlJSON: (*crawlJSON)(c)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	c.AverageTTFB = fromMilliseconds(decoded.AverageTTFB)
+	c.SlowestTTFB = fromMilliseconds(decoded.SlowestTTFB)
+	return nil
+}
+
+// MarshalJSON encodes the page with its TTFB in milliseconds
+func (p CrawledPage) MarshalJSON() ([]byte, error) {
+	type pageJSON CrawledPage
+	return json.Marshal(struct {
+		pageJSON
+		TTFB float64 `json:"ttfb_ms"`
+	}{pageJSON: pageJSON(p), TTFB: Milliseconds(p.TTFB)})
+}
+
+// UnmarshalJSON decodes a page written by MarshalJSON
+func (p *CrawledPage) UnmarshalJSON(data []byte) error {
+	type pageJSON CrawledPage
+	decoded := struct {
+		*pageJSON
+		TTFB float64 `json:"ttfb_ms"`
+	}{pageJSON: (*pageJSON)(p)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	p.TTFB = fromMilliseconds(decoded.TTFB)
+	return nil
+}
+
+// sitemap is a sitemap or a sitemap index, which lists further sitemaps
+type sitemap struct {
+	URLs     []string `xml:"url>loc"`
+	Sitemaps []string `xml:"sitemap>loc"`
+}
+
+// crawlSitemap samples up to pages pages from the site's sitemap, spread evenly through it so
+// every section of the site is represented, and measures each one
+func (s *Scanner) crawlSitemap(ctx context.Context, finalURL string, pages int) *Crawl {
+	crawl := &Crawl{}
+	u, err := neturl.Parse(finalURL)
+	if err != nil {
+		return crawl
+	}
+	var found []string
+	crawl.Sitemap, found = s.readSitemaps(ctx, u.Scheme+"://"+u.Host)
+	for _, page := range found {
+		if p, err := neturl.Parse(page); err == nil && strings.EqualFold(p.Hostname(), u.Hostname()) {
+			crawl.Pages = append(crawl.Pages, CrawledPage{URL: page})
+		}
+	}
+	crawl.SitemapPages = len(crawl.Pages)
+	if len(crawl.Pages) > pages {
+		sampled := make([]CrawledPage, pages)
+		for i := range sampled {
+			sampled[i] = crawl.Pages[i*len(crawl.Pages)/pages]
+		}
+		crawl.Pages = sampled
+	}
+
+	var total time.Duration
+	for i := range crawl.Pages {
+		page := &crawl.Pages[i]
+		s.measurePage(ctx, page)
+		if page.Error != "" {
+			crawl.FailedPages++
+			continue
+		}
+		total += page.TTFB
+		if page.TTFB > crawl.SlowestTTFB {
+			crawl.SlowestTTFB, crawl.SlowestPage = page.TTFB, page.URL
+		}
+		if page.Cached {
+			crawl.CachedPages++
+		}
+		if len(page.MixedContent) > 0 {
+			crawl.MixedContentPages++
+		}
+	}
+	if measured := len(crawl.Pages) - crawl.FailedPages; measured > 0 {
+		crawl.AverageTTFB = total / time.Duration(measured)
+	}
+	return crawl
+}
+
+// readSitemaps finds the site's sitemap and returns its URL and the pages it lists, following
+// sitemap indexes to the sitemaps they list
+func (s *Scanner) readSitemaps(ctx context.Context, origin string) (string, []string) {
+	for _, path := range sitemapPaths {
+		root := origin + path
+		queue := []string{root}
+		var pages []string
+		fetched := 0
+		for len(queue) > 0 && fetched < maxSitemapFetches {
+			next := queue[0]
+			queue = queue[1:]
+			fetched++
+			resp, body, err := s.fetchPage(ctx, next)
+			if err != nil || resp.StatusCode != 200 {
+				continue
+			}
+			var sm sitemap
+			if xml.Unmarshal([]byte(body), &sm) != nil {
+				continue
+			}
+			for _, loc := range sm.URLs {
+				pages = append(pages, strings.TrimSpace(loc))
+			}
+			for _, loc := range sm.Sitemaps {
+				queue = append(queue, strings.TrimSpace(loc))
+			}
+		}
+		if len(pages) > 0 {
+			return root, pages
+		}
+	}
+	return "", nil
+}
+
+// measurePage fetches the page once, recording its status, TTFB, whether a cache served it and
+// the resources it loads over plain HTTP
+func (s *Scanner) measurePage(ctx context.Context, page *CrawledPage) {
+	resp, timing, err := s.fetchURL(ctx, page.URL)
+	if err != nil {
+		page.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	body, err := readBody(resp)
+	if err != nil {
+		page.Error = err.Error()
+		return
+	}
+	page.StatusCode = resp.StatusCode
+	page.TTFB = timing.TTFB
+	if resp.StatusCode >= 400 {
+		page.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		return
+	}
+	caching, _, _, _, _ := parseHeaders(resp.Header)
+	page.Cached = caching || len(detectCachingLayers(resp.Header, body)) > 0
+	page.MixedContent = checkMixedContent(body, resp.Request.URL.String())
+}
@@ -0,0 +1,60 @@
+package siteinfo
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Credentials give access to a password-protected site, such as a staging environment
+type Credentials struct {
+	// Username and Password are sent with HTTP basic authentication
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Headers are added to every request, e.g. an access token required by the staging proxy
+	Headers map[string]string `yaml:"headers"`
+	// Cookies are sent with every request, e.g. a session cookie set by a staging login page
+	Cookies map[string]string `yaml:"cookies"`
+}
+
+// credentialsTransport adds each site's credentials to the requests sent to its hostname, and
+// only to that hostname, so they never reach third-party asset hosts or redirect targets
+type credentialsTransport struct {
+	next        http.RoundTripper
+	credentials map[string]*Credentials
+}
+
+// newCredentialsTransport keys the credentials by lowercase hostname, so they can be given
+// for either a hostname or a site URL
+func newCredentialsTransport(next http.RoundTripper, credentials map[string]*Credentials) *credentialsTransport {
+	byHost := map[string]*Credentials{}
+	for site, creds := range credentials {
+		byHost[strings.ToLower(hostOf(site))] = creds
+	}
+	return &credentialsTransport{next: next, credentials: byHost}
+}
+
+// RoundTrip adds the hostname's credentials and sends the request
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	creds := t.credentials[strings.ToLower(req.URL.Hostname())]
+	if creds == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	if creds.Username != "" && req.Header.Get("Authorization") == "" {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	for name, value := range creds.Headers {
+		req.Header.Set(name, value)
+	}
+	names := make([]string, 0, len(creds.Cookies))
+	for name := range creds.Cookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		req.AddCookie(&http.Cookie{Name: name, Value: creds.Cookies[name]})
+	}
+	return t.next.RoundTrip(req)
+}
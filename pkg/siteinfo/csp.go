@@ -0,0 +1,161 @@
+package siteinfo
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// CSPAnalysis grades how well the Content-Security-Policy resists known bypasses
+type CSPAnalysis struct {
+	Grade  string   `json:"grade"`
+	Issues []string `json:"issues"`
+}
+
+// cspWildcardSources are script sources that allow loading code from almost anywhere
+var cspWildcardSources = []string{"*", "http:", "https:", "data:", "blob:"}
+
+// parseCSP splits a policy into its directives, keyed by lowercase directive name.
+// The first occurrence of a directive wins, as in browsers.
+func parseCSP(policy string) map[string][]string {
+	directives := map[string][]string{}
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(strings.ToLower(directive))
+		if len(fields) == 0 {
+			continue
+		}
+		if _, ok := directives[fields[0]]; !ok {
+			directives[fields[0]] = fields[1:]
+		}
+	}
+	return directives
+}
+
+// cspSources returns the sources for a fetch directive, falling back to default-src
+func cspSources(directives map[string][]string, name string) ([]string, bool) {
+	if sources, ok := directives[name]; ok {
+		return sources, true
+	}
+	sources, ok := directives["default-src"]
+	return sources, ok
+}
+
+// hasSource reports whether any of the sources is one of the candidates
+func hasSource(sources []string, candidates ...string) bool {
+	for _, source := range sources {
+		for _, candidate := range candidates {
+			if source == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isAnyOriginSource reports whether the source allows any origin: * or a bare scheme such as https:
+func isAnyOriginSource(source string) bool {
+	return source == "*" || (strings.HasSuffix(source, ":") && !strings.Contains(source, "/"))
+}
+
+// cspPolicies returns the enforced policies the site sends, each enforced on its own, whether
+// in separate Content-Security-Policy headers or separated by commas in one
+func cspPolicies(headers http.Header) []string {
+	var policies []string
+	for _, value := range headers.Values("Content-Security-Policy") {
+		for _, policy := range strings.Split(value, ",") {
+			if strings.TrimSpace(policy) != "" {
+				policies = append(policies, policy)
+			}
+		}
+	}
+	return policies
+}
+
+// cspIssue is a weakness of a policy and the points it costs
+type cspIssue struct {
+	points int
+	issue  string
+}
+
+// analyzeCSP evaluates the enforced Content-Security-Policy against known-bypassable patterns:
+// unsafe-inline without nonces or hashes, unsafe-eval, wildcard script sources, and missing
+// object-src and base-uri restrictions. It returns nil when the site sends no policy.
+// Browsers enforce every policy a site sends, in separate headers or separated by commas, so
+// a weakness only counts when each of the policies has it.
+func analyzeCSP(headers http.Header) *CSPAnalysis {
+	policies := cspPolicies(headers)
+	if len(policies) == 0 {
+		if headers.Get("Content-Security-Policy-Report-Only") != "" {
+			return &CSPAnalysis{Grade: "F", Issues: []string{"Policy is report-only and not enforced"}}
+		}
+		return nil
+	}
+
+	issues := cspPolicyIssues(parseCSP(policies[0]))
+	for _, policy := range policies[1:] {
+		other := cspPolicyIssues(parseCSP(policy))
+		issues = slices.DeleteFunc(issues, func(issue cspIssue) bool {
+			return !slices.Contains(other, issue)
+		})
+	}
+	analysis := &CSPAnalysis{}
+	score := 100
+	for _, issue := range issues {
+		score -= issue.points
+		analysis.Issues = append(analysis.Issues, issue.issue)
+	}
+
+	switch {
+	case score >= 90:
+		analysis.Grade = "A"
+	case score >= 75:
+		analysis.Grade = "B"
+	case score >= 60:
+		analysis.Grade = "C"
+	case score >= 40:
+		analysis.Grade = "D"
+	default:
+		analysis.Grade = "F"
+	}
+	return analysis
+}
+
+// cspPolicyIssues returns the weaknesses of a single policy
+func cspPolicyIssues(directives map[string][]string) []cspIssue {
+	var issues []cspIssue
+	deduct := func(points int, issue string) {
+		issues = append(issues, cspIssue{points, issue})
+	}
+
+	scriptSources, ok := cspSources(directives, "script-src")
+	if !ok {
+		deduct(40, "No script-src or default-src; scripts are unrestricted")
+	} else {
+		nonceOrHash := false
+		for _, source := range scriptSources {
+			if strings.HasPrefix(source, "'nonce-") || strings.HasPrefix(source, "'sha") {
+				nonceOrHash = true
+			}
+		}
+		if hasSource(scriptSources, "'unsafe-inline'") && !nonceOrHash {
+			deduct(30, "script-src allows 'unsafe-inline'")
+		}
+		if hasSource(scriptSources, "'unsafe-eval'") {
+			deduct(15, "script-src allows 'unsafe-eval'")
+		}
+		if hasSource(scriptSources, cspWildcardSources...) && !hasSource(scriptSources, "'strict-dynamic'") {
+			deduct(30, "script-src allows wildcard sources")
+		}
+	}
+
+	if objectSources, ok := cspSources(directives, "object-src"); !ok || !hasSource(objectSources, "'none'") {
+		deduct(10, "object-src is not 'none'")
+	}
+	if _, ok := directives["base-uri"]; !ok {
+		deduct(5, "base-uri missing")
+	}
+	if styleSources, ok := cspSources(directives, "style-src"); ok && hasSource(styleSources, cspWildcardSources...) {
+		deduct(5, "style-src allows wildcard sources")
+	}
+	return issues
+}
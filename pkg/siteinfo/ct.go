@@ -0,0 +1,137 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// crtShURL is the crt.sh search, which returns the certificates Certificate Transparency
+// logs hold for a domain and its subdomains
+const crtShURL = "https://crt.sh/"
+
+// ctRecentDays is how far back a logged certificate counts as recently issued
+const ctRecentDays = 90
+
+// ctTimeLayout is the layout of crt.sh timestamps, which are in UTC without a zone
+const ctTimeLayout = "2006-01-02T15:04:05"
+
+// CTLog holds the certificates Certificate Transparency logs show were issued for the site's
+// domain, so issuance the site owner did not request can be spotted
+type CTLog struct {
+	Domain            string          `json:"domain"`
+	Recent            []CTCertificate `json:"recent"`
+	Issuers           []string        `json:"issuers"`
+	UnexpectedIssuers []string        `json:"unexpected_issuers,omitempty"`
+}
+
+// CTCertificate is a certificate logged for the domain
+type CTCertificate struct {
+	ID        int64     `json:"id"`
+	Names     []string  `json:"names"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// crtShEntry is a certificate in a crt.sh JSON response
+type crtShEntry struct {
+	ID           int64  `json:"id"`
+	IssuerName   string `json:"issuer_name"`
+	NameValue    string `json:"name_value"`
+	SerialNumber string `json:"serial_number"`
+	NotBefore    string `json:"not_before"`
+	NotAfter     string `json:"not_after"`
+}
+
+// organizationPattern matches the organization of a distinguished name, quoted when it
+// contains a comma
+var organizationPattern = regexp.MustCompile(`(?:^|, )O=("[^"]*"|[^,]*)`)
+
+// issuerOrganization returns the organization of a distinguished name such as
+// "C=US, O=Let's Encrypt, CN=R3", or the whole name when it has none
+func issuerOrganization(dn string) string {
+	if m := organizationPattern.FindStringSubmatch(dn); m != nil {
+		return strings.Trim(m[1], `"`)
+	}
+	return dn
+}
+
+// expectedIssuer reports whether the issuer is one of the expected CAs, matched
+// case-insensitively on part of the name so "DigiCert" covers "DigiCert Inc"
+func expectedIssuer(issuer string, expected []string) bool {
+	return slices.ContainsFunc(expected, func(name string) bool {
+		return name != "" && strings.Contains(strings.ToLower(issuer), strings.ToLower(name))
+	})
+}
+
+// lookupCT queries crt.sh for the unexpired certificates logged for the site's registrable
+// domain and its subdomains. Certificates issued in the last 90 days are listed, and the CAs
+// of all of them are flagged unless they are expected: the CA of the certificate the site
+// serves, or one of expectedIssuers.
+func (s *Scanner) lookupCT(ctx context.Context, url string, servedIssuer string, expectedIssuers []string) (*CTLog, error) {
+	domain := registrableDomain(hostOf(url))
+	query := neturl.Values{"q": {domain}, "output": {"json"}, "exclude": {"expired"}}
+	req, err := http.NewRequestWithContext(ctx, "GET", crtShURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("crt.sh returned HTTP %d for %s", resp.StatusCode, domain)
+	}
+
+	var entries []crtShEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	expected := slices.Clone(expectedIssuers)
+	if servedIssuer != "" {
+		expected = append(expected, servedIssuer)
+	}
+	ct := &CTLog{Domain: domain}
+	recentSince := time.Now().AddDate(0, 0, -ctRecentDays)
+	// A precertificate and the certificate issued from it share a serial number
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if seen[entry.IssuerName+entry.SerialNumber] {
+			continue
+		}
+		seen[entry.IssuerName+entry.SerialNumber] = true
+
+		issuer := issuerOrganization(entry.IssuerName)
+		if !slices.Contains(ct.Issuers, issuer) {
+			ct.Issuers = append(ct.Issuers, issuer)
+			if !expectedIssuer(issuer, expected) {
+				ct.UnexpectedIssuers = append(ct.UnexpectedIssuers, issuer)
+			}
+		}
+		notBefore, err := time.Parse(ctTimeLayout, entry.NotBefore)
+		if err != nil || notBefore.Before(recentSince) {
+			continue
+		}
+		notAfter, _ := time.Parse(ctTimeLayout, entry.NotAfter)
+		ct.Recent = append(ct.Recent, CTCertificate{
+			ID:        entry.ID,
+			Names:     strings.Fields(entry.NameValue),
+			Issuer:    issuer,
+			NotBefore: notBefore,
+			NotAfter:  notAfter,
+		})
+	}
+	slices.SortFunc(ct.Recent, func(a, b CTCertificate) int { return b.NotBefore.Compare(a.NotBefore) })
+	slices.Sort(ct.Issuers)
+	slices.Sort(ct.UnexpectedIssuers)
+	return ct, nil
+}
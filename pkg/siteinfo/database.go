@@ -0,0 +1,110 @@
+package siteinfo
+
+import (
+	"cmp"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Undetectable is the MySQLVersion of a site that does not disclose its database version
+const Undetectable = "Undetectable"
+
+// mariaDBSuffix marks a MariaDB version in MySQLVersion, as in the server version string
+// MariaDB reports itself with, e.g. 10.11.6-MariaDB
+const mariaDBSuffix = "-MariaDB"
+
+// databaseHeaders are the response headers stacks and hosts name the database server in.
+// The headers named after the server carry only the version.
+var databaseHeaders = []struct {
+	name, product string
+}{
+	{"X-Powered-By", ""},
+	{"Server", ""},
+	{"X-Database", ""},
+	{"X-DB-Server", ""},
+	{"X-MySQL-Version", "mysql"},
+	{"X-MariaDB-Version", "mariadb"},
+}
+
+// databaseVersionPattern matches a MySQL, MariaDB or Percona Server version, as in
+// "MySQL/8.0.36", "MariaDB 10.11.6" or the server version string "10.11.6-MariaDB"
+var databaseVersionPattern = regexp.MustCompile(`(?i)\b(mysql|mariadb|percona(?: server)?)[/ :]+v?(\d+\.\d+(?:\.\d+)?)|(\d+\.\d+\.\d+)-(mariadb)`)
+
+// bareVersionPattern matches a header value that is only a version
+var bareVersionPattern = regexp.MustCompile(`^v?(\d+\.\d+(?:\.\d+)?)`)
+
+// htmlCommentPattern matches the HTML comments debug and performance plugins print their
+// database details in. Page text is not searched, where a post about MySQL would match.
+var htmlCommentPattern = regexp.MustCompile(`(?s)<!--(.*?)-->`)
+
+// databaseVersion returns the version in MySQLVersion form: the bare version for MySQL and
+// Percona Server, and with the MariaDB suffix for MariaDB
+func databaseVersion(product, version string) string {
+	if strings.EqualFold(product, "mariadb") {
+		return version + mariaDBSuffix
+	}
+	return version
+}
+
+// matchDatabaseVersion returns the database version named in the text, or ""
+func matchDatabaseVersion(text string) string {
+	m := databaseVersionPattern.FindStringSubmatch(text)
+	switch {
+	case m == nil:
+		return ""
+	case m[3] != "":
+		return databaseVersion(m[4], m[3])
+	}
+	return databaseVersion(m[1], m[2])
+}
+
+// detectDatabase returns the MySQL or MariaDB version the site discloses in its response
+// headers, or the generator meta tags or HTML comments of its homepage, and the evidence it
+// was found in. Most sites disclose none of them, and the database is not reachable from
+// outside to ask, so an empty version means the version is undetectable rather than unknown.
+func detectDatabase(headers http.Header, body string) (string, *Provenance) {
+	for _, header := range databaseHeaders {
+		for _, value := range headers.Values(header.name) {
+			version := matchDatabaseVersion(value)
+			if version == "" && header.product != "" {
+				if m := bareVersionPattern.FindStringSubmatch(strings.TrimSpace(value)); m != nil {
+					version = databaseVersion(header.product, m[1])
+				}
+			}
+			if version != "" {
+				return version, &Provenance{SourceHeader, header.name + ": " + value, 0.9}
+			}
+		}
+	}
+	for _, m := range generatorPattern.FindAllStringSubmatch(body, -1) {
+		generator := cmp.Or(m[1], m[2])
+		if version := matchDatabaseVersion(generator); version != "" {
+			return version, &Provenance{SourceHTML, `generator meta tag "` + generator + `"`, 0.8}
+		}
+	}
+	for _, m := range htmlCommentPattern.FindAllStringSubmatch(body, -1) {
+		if version := matchDatabaseVersion(m[1]); version != "" {
+			comment := strings.Join(strings.Fields(m[1]), " ")
+			if len(comment) > 100 {
+				comment = comment[:100] + "..."
+			}
+			return version, &Provenance{SourceHTML, "HTML comment: " + comment, 0.7}
+		}
+	}
+	return "", nil
+}
+
+// detectDatabaseHeaders returns the database version disclosed in the response headers alone
+func detectDatabaseHeaders(headers http.Header) (string, *Provenance) {
+	return detectDatabase(headers, "")
+}
+
+// databaseProduct returns the endoflife.date product of a MySQLVersion, mysql or mariadb,
+// and the version without the MariaDB suffix
+func databaseProduct(version string) (string, string) {
+	if bare, ok := strings.CutSuffix(version, mariaDBSuffix); ok {
+		return "mariadb", bare
+	}
+	return "mysql", version
+}
@@ -0,0 +1,78 @@
+package siteinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DNSRecords holds the DNS records of the site's hostname and its registrable domain
+type DNSRecords struct {
+	A     []string `json:"a"`
+	AAAA  []string `json:"aaaa"`
+	CNAME string   `json:"cname,omitempty"`
+	MX    []string `json:"mx"`
+	NS    []string `json:"ns"`
+	TXT   []string `json:"txt"`
+}
+
+// registrableDomain returns the domain a hostname was registered under, e.g. example.co.uk
+// for www.example.co.uk, or the hostname itself if it cannot be determined
+func registrableDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// collectDNS resolves the A, AAAA and CNAME records of the hostname, and the MX, NS and TXT
+// records of its registrable domain, where mail and name server records are published.
+// Lookups that fail leave their records empty.
+func (s *Scanner) collectDNS(ctx context.Context, url string) *DNSRecords {
+	resolver := s.resolver()
+	host := hostOf(url)
+	domain := registrableDomain(host)
+	records := &DNSRecords{}
+
+	if addrs, err := resolver.LookupIPAddr(ctx, host); err == nil {
+		for _, addr := range addrs {
+			if addr.IP.To4() != nil {
+				records.A = append(records.A, addr.IP.String())
+			} else {
+				records.AAAA = append(records.AAAA, addr.IP.String())
+			}
+		}
+	}
+	if cname, err := resolver.LookupCNAME(ctx, host); err == nil {
+		cname = strings.TrimSuffix(cname, ".")
+		if !strings.EqualFold(cname, host) {
+			records.CNAME = cname
+		}
+	}
+	if mxs, err := resolver.LookupMX(ctx, domain); err == nil {
+		for _, mx := range mxs {
+			records.MX = append(records.MX, fmt.Sprintf("%d %s", mx.Pref, strings.TrimSuffix(mx.Host, ".")))
+		}
+	}
+	if nss, err := resolver.LookupNS(ctx, domain); err == nil {
+		for _, ns := range nss {
+			records.NS = append(records.NS, strings.TrimSuffix(ns.Host, "."))
+		}
+	}
+	if txts, err := resolver.LookupTXT(ctx, domain); err == nil {
+		records.TXT = txts
+	}
+	return records
+}
+
+// reverseDNS returns the PTR record of the address, or "" if it has none
+func (s *Scanner) reverseDNS(ctx context.Context, ip string) string {
+	names, err := s.resolver().LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
@@ -0,0 +1,90 @@
+package siteinfo
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/fingerprint"
+)
+
+// Ecommerce describes a detected store: its platform, the payment gateways loaded by the
+// frontend and whether the checkout is served entirely over HTTPS
+type Ecommerce struct {
+	Platform        string   `json:"platform"`
+	PaymentGateways []string `json:"payment_gateways"`
+	CheckoutURL     string   `json:"checkout_url,omitempty"`
+	CheckoutSSL     string   `json:"checkout_ssl"`
+}
+
+// paymentGateways maps the scripts and plugin assets of payment gateways to the gateway name
+var paymentGateways = []struct {
+	pattern *regexp.Regexp
+	gateway string
+}{
+	{regexp.MustCompile(`js\.stripe\.com|woocommerce-gateway-stripe`), "Stripe"},
+	{regexp.MustCompile(`paypal\.com/sdk/js|paypalobjects\.com|woocommerce-paypal-payments`), "PayPal"},
+	{regexp.MustCompile(`js\.braintreegateway\.com|woocommerce-gateway-paypal-powered-by-braintree`), "Braintree"},
+	{regexp.MustCompile(`(?:web|sandbox\.web)\.squarecdn\.com|js\.squareup\.com|woocommerce-square`), "Square"},
+	{regexp.MustCompile(`klarnacdn\.net|klarnaservices\.com`), "Klarna"},
+	{regexp.MustCompile(`js\.afterpay\.com|static\.afterpay\.com|afterpay-gateway-for-woocommerce`), "Afterpay"},
+	{regexp.MustCompile(`js\.authorize\.net|woocommerce-gateway-authorize-net`), "Authorize.Net"},
+	{regexp.MustCompile(`checkoutshopper-live\.adyen\.com`), "Adyen"},
+	{regexp.MustCompile(`payments-amazon\.com`), "Amazon Pay"},
+	{regexp.MustCompile(`pay\.google\.com/gp/p/js`), "Google Pay"},
+	{regexp.MustCompile(`/plugins/woocommerce-payments/`), "WooPayments"},
+	{regexp.MustCompile(`shopify-payment-button`), "Shop Pay"},
+}
+
+// insecureCheckoutPattern matches forms and resources loaded over plain HTTP
+var insecureCheckoutPattern = regexp.MustCompile(`(?i)(?:action|src)\s*=\s*["']http://`)
+
+// detectPaymentGateways reports the payment gateways whose scripts or plugin assets appear in the page
+func detectPaymentGateways(body string) []string {
+	var gateways []string
+	for _, payment := range paymentGateways {
+		if payment.pattern.MatchString(body) {
+			gateways = append(gateways, payment.gateway)
+		}
+	}
+	return gateways
+}
+
+// checkEcommerce reports the store features of sites running an ecommerce platform, or nil
+// for other sites. The checkout page is fetched to find gateways only loaded there and to
+// confirm it is served over HTTPS without insecure forms or resources.
+func (s *Scanner) checkEcommerce(ctx context.Context, body, url string, technologies []fingerprint.Technology) *Ecommerce {
+	var store *Ecommerce
+	for _, technology := range technologies {
+		if technology.Category == "Ecommerce" {
+			store = &Ecommerce{Platform: technology.Name}
+			break
+		}
+	}
+	if store == nil {
+		return nil
+	}
+	store.PaymentGateways = detectPaymentGateways(body)
+
+	resp, checkoutBody, err := s.fetchPage(ctx, strings.TrimRight(url, "/")+"/checkout/")
+	if err != nil {
+		store.CheckoutSSL = "Unknown"
+		return store
+	}
+	store.CheckoutURL = resp.Request.URL.String()
+	for _, gateway := range detectPaymentGateways(checkoutBody) {
+		if !slices.Contains(store.PaymentGateways, gateway) {
+			store.PaymentGateways = append(store.PaymentGateways, gateway)
+		}
+	}
+	switch {
+	case resp.Request.URL.Scheme != "https":
+		store.CheckoutSSL = "Not HTTPS"
+	case insecureCheckoutPattern.MatchString(checkoutBody):
+		store.CheckoutSSL = "Mixed"
+	default:
+		store.CheckoutSSL = "Consistent"
+	}
+	return store
+}
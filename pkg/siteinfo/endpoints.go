@@ -0,0 +1,59 @@
+package siteinfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+)
+
+// TLSEndpoint describes the certificate and TLS configuration served by one address of a host
+type TLSEndpoint struct {
+	Address     string `json:"address"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	TLSVersion  string `json:"tls_version,omitempty"`
+	CipherSuite string `json:"cipher_suite,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// checkTLSEndpoints completes a handshake with every address the host resolves to and
+// reports whether the load-balanced backends serve the same certificate and TLS configuration.
+// Hosts with a single address are skipped.
+func (s *Scanner) checkTLSEndpoints(ctx context.Context, url string) ([]TLSEndpoint, bool) {
+	host := hostOf(url)
+	addrs, err := s.resolver().LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) < 2 {
+		return nil, false
+	}
+
+	var endpoints []TLSEndpoint
+	for _, addr := range addrs {
+		endpoint := TLSEndpoint{Address: addr.IP.String()}
+		conn, err := s.dialTLS(ctx, net.JoinHostPort(endpoint.Address, "443"), &tls.Config{ServerName: host, InsecureSkipVerify: true})
+		if err != nil {
+			endpoint.Error = err.Error()
+			endpoints = append(endpoints, endpoint)
+			continue
+		}
+		state := conn.ConnectionState()
+		conn.Close()
+		if len(state.PeerCertificates) > 0 {
+			sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+			endpoint.Fingerprint = hex.EncodeToString(sum[:])
+		}
+		endpoint.TLSVersion = tls.VersionName(state.Version)
+		endpoint.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+		endpoints = append(endpoints, endpoint)
+	}
+
+	mismatch := false
+	for _, endpoint := range endpoints[1:] {
+		first := endpoints[0]
+		if endpoint.Fingerprint != first.Fingerprint || endpoint.TLSVersion != first.TLSVersion ||
+			endpoint.CipherSuite != first.CipherSuite || (endpoint.Error == "") != (first.Error == "") {
+			mismatch = true
+		}
+	}
+	return endpoints, mismatch
+}
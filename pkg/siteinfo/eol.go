@@ -0,0 +1,126 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fetchSupportedVersions returns the supported versions for a product, fetching them from
+// the endoflife.date API at most once per run
+func (s *Scanner) fetchSupportedVersions(ctx context.Context, product string) ([]map[string]interface{}, error) {
+	ctx, span := s.tracer.Start(ctx, "eol lookup", trace.WithAttributes(attribute.String("eol.product", product)))
+	versions, err := s.eol.get(ctx, product, s.fetchEOLAPI)
+	endSpan(span, err)
+	return versions, err
+}
+
+// fetchEOLAPI fetches the supported versions from the endoflife.date API. A product it does
+// not track, such as most web servers and some WordPress forks, has no versions.
+func (s *Scanner) fetchEOLAPI(ctx context.Context, product string) ([]map[string]interface{}, error) {
+	url := fmt.Sprintf("https://endoflife.date/api/%s.json", product)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return []map[string]interface{}{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("endoflife.date returned HTTP %d for %s", resp.StatusCode, product)
+	}
+
+	var versions []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// isSupported checks if a version is supported
+func isSupported(version string, supportedVersions []map[string]interface{}) bool {
+	for _, v := range supportedVersions {
+		if cycle, ok := v["cycle"].(string); ok && strings.HasPrefix(version, cycle) {
+			if eol, ok := v["eol"].(interface{}); ok {
+				if eol == false {
+					return true
+				}
+				if eolDate, ok := eol.(string); ok {
+					eolTime, err := time.Parse("2006-01-02", eolDate)
+					if err == nil && eolTime.After(time.Now()) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// getSupportStatus checks if the versions are supported. wpProduct is WordPress or the
+// WordPress fork the site runs, whose own release data applies to wpVersion.
+func (s *Scanner) getSupportStatus(ctx context.Context, phpVersion, mysqlVersion, wpProduct, wpVersion, webServer, webServerVersion string) (string, string, string, string) {
+	phpStatus := "Unknown"
+	mysqlStatus := "Unknown"
+	wpStatus := "Unknown"
+	webServerStatus := "Unknown"
+
+	phpVersions, err := s.fetchSupportedVersions(ctx, "PHP")
+	if err == nil && phpVersion != "" && len(phpVersions) > 0 {
+		if isSupported(phpVersion, phpVersions) {
+			phpStatus = "Supported"
+		} else {
+			phpStatus = "Outdated"
+		}
+	}
+
+	// The database is rarely disclosed, so a missing version is reported as undetectable
+	// rather than as a lookup that failed
+	if mysqlVersion == "" || mysqlVersion == Undetectable {
+		mysqlStatus = Undetectable
+	} else {
+		product, version := databaseProduct(mysqlVersion)
+		mysqlVersions, err := s.fetchSupportedVersions(ctx, product)
+		if err == nil && len(mysqlVersions) > 0 {
+			if isSupported(version, mysqlVersions) {
+				mysqlStatus = "Supported"
+			} else {
+				mysqlStatus = "Outdated"
+			}
+		}
+	}
+
+	wpVersions, err := s.fetchSupportedVersions(ctx, wpProduct)
+	if err == nil && wpVersion != "" && len(wpVersions) > 0 {
+		if isSupported(wpVersion, wpVersions) {
+			wpStatus = "Supported"
+		} else {
+			wpStatus = "Outdated"
+		}
+	}
+
+	if webServer != "" && webServerVersion != "" {
+		webServerVersions, err := s.fetchSupportedVersions(ctx, webServer)
+		if err == nil && len(webServerVersions) > 0 {
+			if isSupported(webServerVersion, webServerVersions) {
+				webServerStatus = "Supported"
+			} else {
+				webServerStatus = "Outdated"
+			}
+		}
+	}
+
+	return phpStatus, mysqlStatus, webServerStatus, wpStatus
+}
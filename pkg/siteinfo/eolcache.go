@@ -0,0 +1,138 @@
+package siteinfo
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// eolSnapshot is a bundled copy of the endoflife.date data for the most common
+// products, used offline when no cached copy exists
+//
+//go:embed eolsnapshot/*.json
+var eolSnapshot embed.FS
+
+// eolCache fetches each product's versions once per run, backed by an on-disk cache with a
+// TTL and the bundled snapshot. Once the API cannot be reached, the rest of the run uses the
+// cache and snapshot without trying it again.
+type eolCache struct {
+	dir     string
+	ttl     time.Duration
+	offline bool
+	down    atomic.Bool
+
+	products lookups[[]map[string]interface{}]
+}
+
+// newEOLCache creates a cache storing files in dir; an empty dir disables the disk cache
+func newEOLCache(dir string, ttl time.Duration, offline bool) *eolCache {
+	return &eolCache{dir: dir, ttl: ttl, offline: offline}
+}
+
+// get returns the versions for the product, calling fetch only when neither this run
+// nor a fresh disk cache entry has them
+func (c *eolCache) get(ctx context.Context, product string, fetch func(context.Context, string) ([]map[string]interface{}, error)) ([]map[string]interface{}, error) {
+	product = strings.ToLower(product)
+	return c.products.get(ctx, product, func() ([]map[string]interface{}, bool, error) {
+		return c.load(ctx, product, fetch)
+	})
+}
+
+// load resolves the versions from the disk cache, the API or the bundled snapshot, reporting
+// whether they may be kept for the rest of the run. A fallback is kept too, unless the API
+// lookup was only cut off with its scan, which says nothing about the API.
+func (c *eolCache) load(ctx context.Context, product string, fetch func(context.Context, string) ([]map[string]interface{}, error)) ([]map[string]interface{}, bool, error) {
+	cached, fresh, cacheErr := c.readDisk(product)
+	if cacheErr == nil && (fresh || c.offline) {
+		return cached, true, nil
+	}
+
+	keep := true
+	var fetchErr error
+	if !c.offline && !c.down.Load() {
+		versions, err := fetch(ctx, product)
+		if err == nil {
+			c.writeDisk(product, versions)
+			return versions, true, nil
+		}
+		if ctx.Err() != nil {
+			keep = false
+		} else {
+			c.down.Store(true)
+		}
+		fetchErr = err
+	}
+
+	// Fall back to stale data when the API is unreachable or offline
+	if cacheErr == nil {
+		return cached, keep, nil
+	}
+	versions, err := readSnapshot(product)
+	if err != nil {
+		if fetchErr != nil {
+			err = fetchErr
+		}
+		return nil, false, err
+	}
+	return versions, keep, nil
+}
+
+// cachePath returns the disk cache file for the product
+func (c *eolCache) cachePath(product string) string {
+	return filepath.Join(c.dir, "eol-"+product+".json")
+}
+
+// readDisk reads the product from the disk cache and reports whether it is within the TTL
+func (c *eolCache) readDisk(product string) ([]map[string]interface{}, bool, error) {
+	if c.dir == "" {
+		return nil, false, errors.New("disk cache disabled")
+	}
+	path := c.cachePath(product)
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	var versions []map[string]interface{}
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, false, err
+	}
+	return versions, time.Since(stat.ModTime()) < c.ttl, nil
+}
+
+// writeDisk stores the product in the disk cache; failures only cost a refetch next run
+func (c *eolCache) writeDisk(product string, versions []map[string]interface{}) {
+	if c.dir == "" {
+		return
+	}
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return
+	}
+	os.WriteFile(c.cachePath(product), data, 0644)
+}
+
+// readSnapshot reads the product from the bundled snapshot
+func readSnapshot(product string) ([]map[string]interface{}, error) {
+	data, err := eolSnapshot.ReadFile("eolsnapshot/" + product + ".json")
+	if err != nil {
+		return nil, err
+	}
+	var versions []map[string]interface{}
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
@@ -0,0 +1,49 @@
+package siteinfo
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Exposure is the result of probing one commonly abused WordPress path
+type Exposure struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// exposurePaths lists the probed paths with a marker that shows the real file was served,
+// so soft 404 pages answering with HTTP 200 are not reported as exposed
+var exposurePaths = []struct {
+	path   string
+	marker string
+}{
+	{"/xmlrpc.php", "XML-RPC server accepts POST requests only"},
+	{"/wp-login.php", "user_login"},
+	{"/readme.html", "WordPress"},
+	{"/wp-config.php.bak", "DB_"},
+	{"/wp-content/debug.log", "PHP "},
+}
+
+// checkExposure requests xmlrpc.php, wp-login.php, readme.html and common backup and debug
+// artifacts and reports each as Exposed, Blocked or Not Found
+func (s *Scanner) checkExposure(ctx context.Context, url string) []Exposure {
+	base := strings.TrimRight(url, "/")
+	var exposures []Exposure
+	for _, probe := range exposurePaths {
+		exposure := Exposure{Path: probe.path}
+		resp, body, err := s.fetchPage(ctx, base+probe.path)
+		switch {
+		case err != nil:
+			exposure.Status = "Failed"
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			exposure.Status = "Blocked"
+		case strings.Contains(body, probe.marker):
+			exposure.Status = "Exposed"
+		default:
+			exposure.Status = "Not Found"
+		}
+		exposures = append(exposures, exposure)
+	}
+	return exposures
+}
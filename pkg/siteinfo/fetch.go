@@ -0,0 +1,156 @@
+package siteinfo
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+// withScheme ensures the URL includes a protocol scheme
+func withScheme(url, scheme string) string {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return scheme + "://" + url
+	}
+	return url
+}
+
+// hostOf returns the hostname of the URL without scheme or port, in its ASCII form for lookups
+func hostOf(url string) string {
+	u, err := neturl.Parse(withScheme(url, "http"))
+	if err != nil {
+		return ""
+	}
+	return asciiHost(u.Hostname())
+}
+
+// userAgentTransport sets the User-Agent header on requests that do not have one
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+// RoundTrip sets the user agent and sends the request
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// traceTiming returns a client trace recording the connection phases into timing.
+// Phases repeated across redirects are summed.
+func traceTiming(timing *Timing) *httptrace.ClientTrace {
+	var dnsStart, connectStart, tlsStart time.Time
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			timing.DNSLookup += time.Since(dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			timing.TCPConnect += time.Since(connectStart)
+		},
+		TLSHandshakeStart: func() {
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			timing.TLSHandshake += time.Since(tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			timing.TTFB = time.Since(timing.start)
+		},
+	}
+}
+
+// fetchURL fetches the URL and returns the response along with the connection timing
+func (s *Scanner) fetchURL(ctx context.Context, url string) (*http.Response, Timing, error) {
+	url = withScheme(url, "http")
+
+	var err error
+
+	for i := 0; i < s.opts.Retries; i++ {
+		var timing Timing
+		var req *http.Request
+		req, err = http.NewRequestWithContext(httptrace.WithClientTrace(ctx, traceTiming(&timing)), "GET", url, nil)
+		if err != nil {
+			return nil, Timing{}, err
+		}
+
+		// Start timing once the rate limiter lets the request through
+		var release func()
+		release, err = s.limiter.wait(ctx, req.URL.Hostname())
+		if err != nil {
+			return nil, Timing{}, err
+		}
+		timing.start = time.Now()
+		var resp *http.Response
+		resp, err = s.clientFor(req.URL.Hostname()).Do(req)
+		release()
+		if err == nil {
+			return resp, timing, nil
+		}
+
+		if !strings.Contains(err.Error(), "Client.Timeout exceeded while awaiting headers") &&
+			!strings.Contains(err.Error(), "timeout awaiting response headers") {
+			return nil, Timing{}, err
+		}
+
+		s.log(url).Debug("Retrying", "attempt", i+1, "retries", s.opts.Retries)
+		select {
+		case <-ctx.Done():
+			return nil, Timing{}, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	return nil, Timing{}, fmt.Errorf("no response after %d attempts: %w", s.opts.Retries, err)
+}
+
+// readBody reads the response body into a string
+func readBody(resp *http.Response) (string, error) {
+	buf := new(strings.Builder)
+	if _, err := io.Copy(buf, resp.Body); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// fetchPage fetches the URL and returns the response along with the body
+func (s *Scanner) fetchPage(ctx context.Context, url string) (*http.Response, string, error) {
+	resp, _, err := s.fetchURL(ctx, url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp, body, nil
+}
+
+// doRequest sends a single request with the given headers, without retries
+func (s *Scanner) doRequest(ctx context.Context, method, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, withScheme(url, "http"), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	return s.do(s.clientFor(req.URL.Hostname()), req)
+}
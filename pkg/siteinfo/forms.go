@@ -0,0 +1,90 @@
+package siteinfo
+
+import (
+	"context"
+	neturl "net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// formPluginFootprints maps the markup left by WordPress form plugins to the plugin name
+var formPluginFootprints = []struct {
+	footprint string
+	plugin    string
+}{
+	{"wpcf7", "Contact Form 7"},
+	{"gform_wrapper", "Gravity Forms"},
+	{"wpforms-form", "WPForms"},
+	{"nf-form-cont", "Ninja Forms"},
+	{"frm_forms", "Formidable Forms"},
+	{"fluentform", "Fluent Forms"},
+	{"elementor-form", "Elementor Forms"},
+	{"forminator-custom-form", "Forminator"},
+}
+
+var (
+	// formPattern matches form elements and their contents
+	formPattern = regexp.MustCompile(`(?is)<form\b.*?</form>`)
+	// contactFieldPattern matches the fields that make a form a contact form rather than a search or login form
+	contactFieldPattern = regexp.MustCompile(`(?is)<textarea\b|<input\b[^>]*type\s*=\s*["']?email`)
+	// contactLinkPattern matches links to a contact page
+	contactLinkPattern = regexp.MustCompile(`(?is)<a\b[^>]*href\s*=\s*["']([^"']*contact[^"']*)["']`)
+)
+
+// detectFormPlugins reports the form plugins whose markup appears in the page
+func detectFormPlugins(body string) []string {
+	var plugins []string
+	for _, form := range formPluginFootprints {
+		if strings.Contains(body, form.footprint) {
+			plugins = append(plugins, form.plugin)
+		}
+	}
+	return plugins
+}
+
+// hasContactForm reports whether the page contains a form with a message or email field
+func hasContactForm(body string) bool {
+	for _, form := range formPattern.FindAllString(body, -1) {
+		if contactFieldPattern.MatchString(form) {
+			return true
+		}
+	}
+	return false
+}
+
+// findContactForm reports where the site's contact form is: "Homepage", the URL of the
+// contact page linked from the homepage, or "None". The form plugins found on either page
+// are returned alongside.
+func (s *Scanner) findContactForm(ctx context.Context, body, url string) (string, []string) {
+	plugins := detectFormPlugins(body)
+	if hasContactForm(body) {
+		return "Homepage", plugins
+	}
+
+	match := contactLinkPattern.FindStringSubmatch(body)
+	if match == nil {
+		return "None", plugins
+	}
+	base, err := neturl.Parse(withScheme(url, "http"))
+	if err != nil {
+		return "None", plugins
+	}
+	contact, err := base.Parse(match[1])
+	if err != nil || !strings.EqualFold(contact.Hostname(), base.Hostname()) {
+		return "None", plugins
+	}
+	_, contactBody, err := s.fetchPage(ctx, contact.String())
+	if err != nil {
+		return "None", plugins
+	}
+	for _, plugin := range detectFormPlugins(contactBody) {
+		if !slices.Contains(plugins, plugin) {
+			plugins = append(plugins, plugin)
+		}
+	}
+	if hasContactForm(contactBody) {
+		return contact.String(), plugins
+	}
+	return "None", plugins
+}
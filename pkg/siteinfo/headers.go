@@ -0,0 +1,113 @@
+package siteinfo
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// parseHeaders parses the HTTP headers to extract information
+func parseHeaders(headers http.Header) (bool, string, string, string, string) {
+	var webServer, webServerVersion string
+	var caching bool
+	var cacheControl, xPoweredBy string
+
+	for key, values := range headers {
+		lowerKey := strings.ToLower(key)
+		for _, value := range values {
+			if lowerKey == "server" {
+				parts := strings.Split(value, "/")
+				webServer = parts[0]
+				if len(parts) > 1 {
+					webServerVersion = parts[1]
+				}
+			}
+			if lowerKey == "x-powered-by" {
+				xPoweredBy = value
+			}
+			if lowerKey == "cache-control" {
+				cacheControl = value
+				if strings.Contains(value, "max-age=0") {
+					caching = false
+				} else if strings.Contains(value, "max-age") {
+					caching = true
+				}
+			}
+		}
+	}
+	return caching, webServer, webServerVersion, cacheControl, xPoweredBy
+}
+
+// maxClockSkew is the server clock drift beyond which skew is reported as significant
+const maxClockSkew = 60 * time.Second
+
+// checkClockSkew compares the server's Date header with local time. A positive skew
+// means the server clock is ahead of ours.
+func checkClockSkew(headers http.Header, received time.Time) (time.Time, time.Duration, bool) {
+	serverDate, err := http.ParseTime(headers.Get("Date"))
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	skew := serverDate.Sub(received.Truncate(time.Second))
+	return serverDate, skew, skew > maxClockSkew || skew < -maxClockSkew
+}
+
+// singletonHeaders are response headers that must appear at most once; duplicates
+// are resolved differently by CDNs and browsers
+var singletonHeaders = []string{
+	"Cache-Control",
+	"Content-Type",
+	"Content-Length",
+	"Expires",
+	"ETag",
+	"Last-Modified",
+	"Location",
+	"Strict-Transport-Security",
+	"X-Frame-Options",
+	"X-Content-Type-Options",
+	"Referrer-Policy",
+	"Access-Control-Allow-Origin",
+	"Access-Control-Allow-Credentials",
+	"Access-Control-Allow-Methods",
+	"Access-Control-Allow-Headers",
+}
+
+// checkHeaderAnomalies reports singleton headers that were sent more than once
+func checkHeaderAnomalies(headers http.Header) []string {
+	var anomalies []string
+	for _, name := range singletonHeaders {
+		values := headers.Values(name)
+		if len(values) < 2 {
+			continue
+		}
+		distinct := map[string]bool{}
+		for _, value := range values {
+			distinct[strings.TrimSpace(value)] = true
+		}
+		if len(distinct) > 1 {
+			anomalies = append(anomalies, fmt.Sprintf("%s: %d conflicting values (%s)", name, len(values), strings.Join(values, " | ")))
+		} else {
+			anomalies = append(anomalies, fmt.Sprintf("%s: duplicated %d times", name, len(values)))
+		}
+	}
+	return anomalies
+}
+
+// checkFrameProtection reports which header, if any, prevents the page from being framed. A
+// frame-ancestors directive allowing any origin, with * or a scheme such as https:, protects
+// nothing, so X-Frame-Options decides instead.
+func checkFrameProtection(headers http.Header) string {
+	for _, csp := range cspPolicies(headers) {
+		ancestors, ok := parseCSP(csp)["frame-ancestors"]
+		if ok && !slices.ContainsFunc(ancestors, isAnyOriginSource) {
+			return "CSP frame-ancestors"
+		}
+	}
+	switch strings.ToUpper(strings.TrimSpace(headers.Get("X-Frame-Options"))) {
+	case "DENY", "SAMEORIGIN":
+		return "X-Frame-Options"
+	}
+	return "None"
+}
@@ -0,0 +1,56 @@
+package siteinfo
+
+import (
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// wordpressBackendPattern matches absolute URLs into a WordPress install: its uploads, core
+// assets or REST API
+var wordpressBackendPattern = regexp.MustCompile(`(?i)https?://[a-z0-9.-]+(?::\d+)?(?:/[^"'\s<>()]*?)?/(?:wp-content|wp-includes|wp-json)/`)
+
+// backendEscapes undoes the escaping frameworks apply to URLs embedded in the page: JSON in
+// __NEXT_DATA__ and Gatsby's page data, and the query strings of image optimizers
+var backendEscapes = strings.NewReplacer(`\/`, "/", "%3A", ":", "%3a", ":", "%2F", "/", "%2f", "/")
+
+// detectHeadless recognises a decoupled WordPress site: a page rendered by a frontend
+// framework such as Next.js or Gatsby whose content comes from WordPress on another host. It
+// returns the framework and the backend's origin, or "" when the page is not such a frontend.
+// A traditional WordPress page that only offloads its uploads to another host is not
+// headless, as it still references its own /wp-content/ and /wp-includes/ paths.
+func detectHeadless(headers http.Header, body, pageURL string) (string, string) {
+	frontend := frontendFramework(headers, body)
+	if frontend == "" {
+		return "", ""
+	}
+	page, err := neturl.Parse(withScheme(pageURL, "http"))
+	if err != nil {
+		return "", ""
+	}
+	if strings.Contains(body, `"/wp-content/`) || strings.Contains(body, `"/wp-includes/`) {
+		return "", ""
+	}
+
+	for _, match := range wordpressBackendPattern.FindAllString(backendEscapes.Replace(body), -1) {
+		backend, err := neturl.Parse(match)
+		if err != nil || strings.EqualFold(backend.Host, page.Host) {
+			continue
+		}
+		return frontend, origin(backend)
+	}
+	return "", ""
+}
+
+// frontendFramework returns the framework or static site generator that rendered the page,
+// or "" if it was not one that builds pages from a separate content source
+func frontendFramework(headers http.Header, body string) string {
+	name, _ := detectCMS(headers, body, false, "")
+	for _, cms := range cmsSignatures {
+		if cms.name == name && cms.frontend {
+			return name
+		}
+	}
+	return ""
+}
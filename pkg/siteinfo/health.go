@@ -0,0 +1,143 @@
+package siteinfo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// HealthWeights sets how much each area counts towards the health score. Only the ratios
+// matter; areas a site has no data for are left out and the rest reweighted.
+type HealthWeights struct {
+	// Support scores the end-of-life status of PHP, MySQL, the web server and WordPress
+	Support float64
+	// SSL scores certificate validity, expiry and chain
+	SSL float64
+	// Security is the security headers score
+	Security float64
+	// Caching scores whether a page cache or CDN serves the site
+	Caching float64
+	// TTFB scores the average time to first byte
+	TTFB float64
+}
+
+// DefaultHealthWeights are the weights used when Options.HealthWeights is not set
+var DefaultHealthWeights = HealthWeights{Support: 30, SSL: 25, Security: 20, Caching: 10, TTFB: 15}
+
+// ParseHealthWeights parses weights given as comma-separated area=weight pairs, e.g.
+// support=40,ttfb=10. Areas not listed keep their default weight; a weight of 0 leaves the
+// area out of the score.
+func ParseHealthWeights(value string) (HealthWeights, error) {
+	weights := DefaultHealthWeights
+	fields := map[string]*float64{
+		"support":  &weights.Support,
+		"ssl":      &weights.SSL,
+		"security": &weights.Security,
+		"caching":  &weights.Caching,
+		"ttfb":     &weights.TTFB,
+	}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, number, ok := strings.Cut(pair, "=")
+		field, known := fields[strings.ToLower(strings.TrimSpace(name))]
+		if !ok || !known {
+			return weights, fmt.Errorf("invalid weight %q: use support, ssl, security, caching or ttfb, e.g. ssl=25", pair)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		if err != nil || weight < 0 {
+			return weights, fmt.Errorf("invalid weight %q: weights are numbers of 0 or more", pair)
+		}
+		*field = weight
+	}
+	if weights == (HealthWeights{}) {
+		return weights, fmt.Errorf("every weight in %q is 0", value)
+	}
+	return weights, nil
+}
+
+// healthGrades maps minimum health scores to letter grades, best first
+var healthGrades = []struct {
+	minScore int
+	grade    string
+}{
+	{90, "A"},
+	{80, "B"},
+	{70, "C"},
+	{60, "D"},
+	{0, "F"},
+}
+
+// TTFB scores fall linearly from 100 at goodTTFBMs to 0 at poorTTFBMs
+const (
+	goodTTFBMs = 200
+	poorTTFBMs = 1500
+)
+
+// healthScore combines the site's support statuses, SSL health, security headers, caching and
+// TTFB into one score from 0 to 100 and a letter grade from A to F
+func healthScore(info *SiteInfo, weights HealthWeights) (int, string) {
+	var total, weight float64
+	add := func(w, score float64) {
+		if w > 0 {
+			total += w * score
+			weight += w
+		}
+	}
+
+	// Support: the share of detected components that are still supported
+	var known, supported float64
+	for _, status := range []string{info.PHPStatus, info.MySQLStatus, info.WebServerStatus, info.WordPressStatus} {
+		switch status {
+		case "Supported":
+			known++
+			supported++
+		case "Outdated":
+			known++
+		}
+	}
+	if known > 0 {
+		add(weights.Support, supported/known*100)
+	}
+
+	// SSL: a certificate browsers reject scores nothing, one needing attention soon half
+	ssl := 100.0
+	switch {
+	case info.SSLExpired, !info.SSLValid, info.CertificateHostnameMismatch:
+		ssl = 0
+	case info.Certificate != nil && info.Certificate.ExpiringSoon:
+		ssl = 50
+	case info.ChainStatus == "Incomplete":
+		ssl = 70
+	}
+	add(weights.SSL, ssl)
+
+	if info.SecurityHeaders != nil {
+		add(weights.Security, float64(info.SecurityHeaders.Score))
+	}
+
+	caching := 0.0
+	if info.Caching || info.CDN != "" {
+		caching = 100
+	}
+	add(weights.Caching, caching)
+
+	if info.AverageTTFB > 0 {
+		ms := Milliseconds(info.AverageTTFB)
+		add(weights.TTFB, math.Max(0, math.Min(100, (poorTTFBMs-ms)/(poorTTFBMs-goodTTFBMs)*100)))
+	}
+
+	if weight == 0 {
+		return 0, ""
+	}
+	score := int(math.Round(total / weight))
+	for _, band := range healthGrades {
+		if score >= band.minScore {
+			return score, band.grade
+		}
+	}
+	return score, "F"
+}
@@ -0,0 +1,31 @@
+package siteinfo
+
+import (
+	"context"
+	"fmt"
+)
+
+// lookupHosting resolves the site's hostname and looks up the network its first address
+// belongs to, showing which hosting provider the site actually runs on
+func (s *Scanner) lookupHosting(ctx context.Context, url string) (string, string, string, error) {
+	addrs, err := s.resolver().LookupIPAddr(ctx, hostOf(url))
+	if err != nil {
+		return "", "", "", err
+	}
+	if len(addrs) == 0 {
+		return "", "", "", fmt.Errorf("no addresses found for %s", hostOf(url))
+	}
+	// Prefer IPv4, which is what most hosting providers are registered under
+	ip := addrs[0].IP
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			ip = addr.IP
+			break
+		}
+	}
+	info, err := s.opts.GeoIP.Lookup(ctx, ip)
+	if err != nil {
+		return "", "", "", err
+	}
+	return info.Organization, info.ASN, info.Country, nil
+}
@@ -0,0 +1,130 @@
+package siteinfo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// maxHotlinkChecks caps the number of assets checked for hotlink protection on one page
+const maxHotlinkChecks = 50
+
+// crossOriginPattern matches a crossorigin attribute, which may be given without a value
+var crossOriginPattern = regexp.MustCompile(`(?i)\scrossorigin\b`)
+
+// pageAsset is a script, stylesheet or image the page loads
+type pageAsset struct {
+	url string
+	// cors is set for assets browsers fetch in CORS mode: those with a crossorigin attribute
+	// and module scripts
+	cors bool
+	// credentials is set for crossorigin="use-credentials", which a wildcard does not satisfy
+	credentials bool
+}
+
+// loadedAssets returns the page's scripts, stylesheets and images and how browsers fetch them
+func loadedAssets(body string) []pageAsset {
+	var assets []pageAsset
+	add := func(tag, url string) {
+		url = strings.TrimSpace(url)
+		if url == "" || strings.HasPrefix(url, "data:") {
+			return
+		}
+		attributes := tagAttributes(tag)
+		asset := pageAsset{url: url, cors: crossOriginPattern.MatchString(tag)}
+		asset.credentials = strings.EqualFold(attributes["crossorigin"], "use-credentials")
+		if strings.EqualFold(attributes["type"], "module") {
+			asset.cors = true
+		}
+		assets = append(assets, asset)
+	}
+	for _, tag := range scriptTagPattern.FindAllString(body, -1) {
+		add(tag, tagAttributes(tag)["src"])
+	}
+	for _, tag := range stylesheetTagPattern.FindAllString(body, -1) {
+		if attributes := tagAttributes(tag); strings.Contains(strings.ToLower(attributes["rel"]), "stylesheet") {
+			add(tag, attributes["href"])
+		}
+	}
+	for _, tag := range imgTagPattern.FindAllString(body, -1) {
+		add(tag, tagAttributes(tag)["src"])
+	}
+	return assets
+}
+
+// checkHotlink requests each asset the page loads as a browser showing the page does: with the
+// page as Referer and, for CORS-mode assets on other origins, the page's Origin. An asset that
+// fails with the Referer but loads without one is blocked by hotlink protection that does not
+// recognise the site's own pages; a CORS-mode asset answered without an
+// Access-Control-Allow-Origin the browser accepts is blocked by the browser. Both break the
+// page for real visitors while the asset itself looks fine when opened directly.
+func (s *Scanner) checkHotlink(ctx context.Context, body, pageURL string) []string {
+	page, err := neturl.Parse(withScheme(pageURL, "http"))
+	if err != nil {
+		return nil
+	}
+	pageOrigin := origin(page)
+	referer := page.String()
+
+	var issues []string
+	seen := map[string]bool{}
+	for _, asset := range loadedAssets(body) {
+		resolved, err := page.Parse(asset.url)
+		if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") || seen[resolved.String()] {
+			continue
+		}
+		if len(seen) >= maxHotlinkChecks {
+			break
+		}
+		seen[resolved.String()] = true
+
+		header := http.Header{"Referer": {referer}}
+		crossOrigin := asset.cors && origin(resolved) != pageOrigin
+		if crossOrigin {
+			header.Set("Origin", pageOrigin)
+		}
+		status, allowOrigin, allowCredentials, err := s.assetStatus(ctx, resolved.String(), header)
+		if err != nil {
+			continue
+		}
+
+		if status >= 400 {
+			// Only a failure the Referer causes is hotlink protection; assets that fail either
+			// way are simply missing
+			direct, _, _, err := s.assetStatus(ctx, resolved.String(), nil)
+			if err == nil && direct < 400 {
+				issues = append(issues, fmt.Sprintf("%s: HTTP %d with the page as Referer, HTTP %d without (hotlink protection)", resolved, status, direct))
+			}
+			continue
+		}
+
+		if crossOrigin {
+			allowed := allowOrigin == pageOrigin || (allowOrigin == "*" && !asset.credentials)
+			if asset.credentials && !strings.EqualFold(allowCredentials, "true") {
+				allowed = false
+			}
+			if !allowed {
+				reason := "no Access-Control-Allow-Origin"
+				if allowOrigin != "" {
+					reason = "Access-Control-Allow-Origin " + allowOrigin
+				}
+				issues = append(issues, fmt.Sprintf("%s: %s for %s on a crossorigin asset (blocked by CORS)", resolved, reason, pageOrigin))
+			}
+		}
+	}
+	return issues
+}
+
+// assetStatus fetches an asset with the given headers and returns its status code and CORS
+// response headers
+func (s *Scanner) assetStatus(ctx context.Context, url string, header http.Header) (int, string, string, error) {
+	resp, err := s.doRequest(ctx, "GET", url, header)
+	if err != nil {
+		return 0, "", "", err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, strings.ToLower(resp.Header.Get("Access-Control-Allow-Origin")), resp.Header.Get("Access-Control-Allow-Credentials"), nil
+}
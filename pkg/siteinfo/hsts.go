@@ -0,0 +1,116 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+)
+
+// hstsPreloadStatusURL is the status API of hstspreload.org, which tracks the Chromium HSTS
+// preload list that other browsers also ship
+const hstsPreloadStatusURL = "https://hstspreload.org/api/v2/status"
+
+// minHSTSPreloadMaxAge is the shortest max-age, in seconds, the preload list accepts (one year)
+const minHSTSPreloadMaxAge = 31536000
+
+// HSTS holds the site's Strict-Transport-Security policy
+type HSTS struct {
+	Header            string   `json:"header"`
+	MaxAge            int64    `json:"max_age"`
+	IncludeSubDomains bool     `json:"include_subdomains"`
+	Preload           bool     `json:"preload"`
+	PreloadIssues     []string `json:"preload_issues,omitempty"`
+}
+
+// parseHSTS parses the Strict-Transport-Security header, returning nil when it is absent or
+// was not served over TLS, where browsers ignore it. PreloadIssues lists what keeps the
+// policy from meeting the preload list's requirements.
+func parseHSTS(headers http.Header, https bool) *HSTS {
+	header := headers.Get("Strict-Transport-Security")
+	if !https || header == "" {
+		return nil
+	}
+	hsts := &HSTS{Header: header}
+	if match := hstsMaxAgePattern.FindStringSubmatch(header); match != nil {
+		hsts.MaxAge, _ = strconv.ParseInt(match[1], 10, 64)
+	}
+	for _, directive := range strings.Split(header, ";") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "includesubdomains":
+			hsts.IncludeSubDomains = true
+		case "preload":
+			hsts.Preload = true
+		}
+	}
+
+	if hsts.MaxAge < minHSTSPreloadMaxAge {
+		hsts.PreloadIssues = append(hsts.PreloadIssues, "max-age under one year")
+	}
+	if !hsts.IncludeSubDomains {
+		hsts.PreloadIssues = append(hsts.PreloadIssues, "includeSubDomains missing")
+	}
+	if !hsts.Preload {
+		hsts.PreloadIssues = append(hsts.PreloadIssues, "preload directive missing")
+	}
+	return hsts
+}
+
+// hstsPreloadStatus returns the preload list status of the domain as hstspreload.org reports
+// it: preloaded, pending, unknown (not on the list), rejected, pending-removal or removed
+func (s *Scanner) hstsPreloadStatus(ctx context.Context, domain string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", hstsPreloadStatusURL+"?"+neturl.Values{"domain": {domain}}.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("hstspreload.org returned HTTP %d for %s", resp.StatusCode, domain)
+	}
+	var status struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return "", err
+	}
+	return status.Status, nil
+}
+
+// checkHSTSPreload looks the hostname up on the HSTS preload list: Preloaded, Pending or Not
+// Preloaded, or Removed or Rejected. Registrable domains are preloaded with their subdomains,
+// so a hostname not on the list itself is covered by its domain's entry.
+func (s *Scanner) checkHSTSPreload(ctx context.Context, url string) (string, error) {
+	host := hostOf(url)
+	names := []string{host}
+	if domain := registrableDomain(host); domain != host {
+		names = append(names, domain)
+	}
+	status := "unknown"
+	for _, name := range names {
+		var err error
+		if status, err = s.hstsPreloadStatus(ctx, name); err != nil {
+			return "", err
+		}
+		if status != "unknown" {
+			break
+		}
+	}
+	switch status {
+	case "preloaded":
+		return "Preloaded", nil
+	case "pending":
+		return "Pending", nil
+	case "pending-removal", "removed":
+		return "Removed", nil
+	case "rejected":
+		return "Rejected", nil
+	}
+	return "Not Preloaded", nil
+}
@@ -0,0 +1,67 @@
+package siteinfo
+
+import (
+	"regexp"
+	"strings"
+)
+
+// parseHTML parses the HTML content to extract the WordPress version
+func parseHTML(body string) string {
+	re := regexp.MustCompile(`content="WordPress (\d+\.\d+(\.\d+)?)"`)
+	matches := re.FindStringSubmatch(body)
+	if len(matches) > 1 {
+		return matches[1]
+	}
+	return ""
+}
+
+// isWordPress reports whether the HTML content looks like a WordPress page
+func isWordPress(body string) bool {
+	return parseHTML(body) != "" || strings.Contains(body, "/wp-content/") || strings.Contains(body, "/wp-includes/")
+}
+
+// phpFatalErrorPatterns match the errors PHP and WordPress print when a page fails to render:
+// PHP's own output, with html_errors on or off, and WordPress's fatal error and database pages.
+// A bare "Fatal error:" is not enough, as pages and scripts mention it in ordinary text.
+var phpFatalErrorPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`<b>(Fatal|Parse) error</b>:`),
+	regexp.MustCompile(`(Fatal|Parse) error: [^\n]* in \S+\.php(:\d+| on line \d+)`),
+	regexp.MustCompile(`There has been a critical error on this website`),
+	regexp.MustCompile(`Error establishing a database connection`),
+}
+
+// hasPHPFatalError checks if the HTML content contains a PHP fatal error
+func hasPHPFatalError(body string) bool {
+	for _, pattern := range phpFatalErrorPatterns {
+		if pattern.MatchString(body) {
+			return true
+		}
+	}
+	return false
+}
+
+// hiddenContentPattern matches the parts of a page that are never shown: comments, and the
+// scripts and styles with their contents
+var hiddenContentPattern = regexp.MustCompile(`(?is)<!--.*?-->|<script\b.*?</script\s*>|<style\b.*?</style\s*>`)
+
+// tagPattern matches an HTML tag
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// visibleText returns the text a browser would show for the page, without its markup
+func visibleText(body string) string {
+	text := tagPattern.ReplaceAllString(hiddenContentPattern.ReplaceAllString(body, " "), " ")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// minTextLength is the visible text length below which a page is considered suspiciously empty
+const minTextLength = 32
+
+// isWSOD reports whether the page looks like a PHP fatal error or white screen of death:
+// a page showing no or almost no text, however much markup surrounds it, or one containing
+// a PHP fatal error
+func isWSOD(body string) bool {
+	if len(visibleText(body)) < minTextLength {
+		return true
+	}
+	return hasPHPFatalError(body)
+}
@@ -0,0 +1,76 @@
+package siteinfo
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// asciiHost returns the hostname in its ASCII form, converting an internationalized domain
+// name such as bücher.example to punycode (xn--bcher-kva.example) for DNS, TLS and HTTP. A
+// hostname that cannot be converted is returned unchanged, so the lookup fails with its error.
+func asciiHost(host string) string {
+	if isASCII(host) {
+		return host
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return host
+	}
+	return ascii
+}
+
+// isASCII reports whether the string has only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// asciiURL returns the URL with its hostname in ASCII form, keeping a missing scheme missing
+func asciiURL(url string) string {
+	return convertHost(url, asciiHost)
+}
+
+// unicodeURL returns the URL with a punycode hostname in its Unicode form, so reports show
+// internationalized domains as they were given
+func unicodeURL(url string) string {
+	return convertHost(url, func(host string) string {
+		if !strings.Contains(host, "xn--") {
+			return host
+		}
+		unicode, err := idna.Lookup.ToUnicode(host)
+		if err != nil {
+			return host
+		}
+		return unicode
+	})
+}
+
+// convertHost rewrites the hostname of the URL, leaving everything else as written
+func convertHost(url string, convert func(string) string) string {
+	prefix, rest := "", url
+	if scheme, after, ok := strings.Cut(url, "://"); ok {
+		prefix, rest = scheme+"://", after
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	authority, tail := rest[:end], rest[end:]
+	if at := strings.LastIndex(authority, "@"); at >= 0 {
+		prefix, authority = prefix+authority[:at+1], authority[at+1:]
+	}
+	// IP literals have no name to convert
+	if strings.HasPrefix(authority, "[") {
+		return url
+	}
+	host, port, ok := strings.Cut(authority, ":")
+	if ok {
+		port = ":" + port
+	}
+	return prefix + convert(host) + port + tail
+}
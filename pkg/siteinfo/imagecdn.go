@@ -0,0 +1,103 @@
+package siteinfo
+
+import (
+	"context"
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// imageCDNHosts maps the hosts of image CDNs and on-the-fly optimization services to the service name
+var imageCDNHosts = []struct {
+	suffix  string
+	service string
+}{
+	{"i0.wp.com", "Jetpack Photon"},
+	{"i1.wp.com", "Jetpack Photon"},
+	{"i2.wp.com", "Jetpack Photon"},
+	{"i3.wp.com", "Jetpack Photon"},
+	{".imgix.net", "imgix"},
+	{"res.cloudinary.com", "Cloudinary"},
+	{"ik.imagekit.io", "ImageKit"},
+	{".i.optimole.com", "Optimole"},
+	{"cdn.shortpixel.ai", "ShortPixel"},
+	{".exactdn.com", "EWWW Easy IO"},
+	{".sirv.com", "Sirv"},
+	{"cdn.statically.io", "Statically"},
+}
+
+// imageCDNHeaders maps response headers on images to the optimization service that sets them
+var imageCDNHeaders = []struct {
+	header  string
+	service string
+}{
+	{"CF-Polished", "Cloudflare Polish"},
+	{"CF-Resized", "Cloudflare Images"},
+}
+
+// imgTagPattern matches img tags
+var imgTagPattern = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+
+// imageCDNForURL returns the image service an image URL is served through, or "" if none
+func imageCDNForURL(src string) string {
+	if strings.Contains(src, "/cdn-cgi/image/") {
+		return "Cloudflare Images"
+	}
+	host := thirdPartyHost(src, "")
+	for _, cdn := range imageCDNHosts {
+		if strings.HasSuffix(host, cdn.suffix) || host == strings.TrimPrefix(cdn.suffix, ".") {
+			return cdn.service
+		}
+	}
+	return ""
+}
+
+// detectImageCDN identifies the image CDN or optimization service from the page's image
+// URLs. When the URLs do not name one, the headers of the first image are checked for
+// services such as Cloudflare Polish that optimize images in place.
+func (s *Scanner) detectImageCDN(ctx context.Context, body, url string) string {
+	var first string
+	for _, tag := range imgTagPattern.FindAllString(body, -1) {
+		attributes := tagAttributes(tag)
+		sources := []string{attributes["src"], attributes["data-src"]}
+		for _, candidate := range strings.Split(attributes["srcset"], ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 {
+				sources = append(sources, fields[0])
+			}
+		}
+		for _, src := range sources {
+			if src == "" || strings.HasPrefix(src, "data:") {
+				continue
+			}
+			if service := imageCDNForURL(src); service != "" {
+				return service
+			}
+			if first == "" {
+				first = src
+			}
+		}
+	}
+	if first == "" {
+		return ""
+	}
+
+	base, err := neturl.Parse(withScheme(url, "http"))
+	if err != nil {
+		return ""
+	}
+	image, err := base.Parse(first)
+	if err != nil {
+		return ""
+	}
+	resp, err := s.doRequest(ctx, "HEAD", image.String(), nil)
+	if err != nil {
+		return ""
+	}
+	resp.Body.Close()
+	for _, cdn := range imageCDNHeaders {
+		if resp.Header.Get(cdn.header) != "" {
+			return cdn.service
+		}
+	}
+	return ""
+}
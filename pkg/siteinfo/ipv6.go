@@ -0,0 +1,51 @@
+package siteinfo
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// checkIPv6 looks up the host's AAAA records and fetches the site over IPv6 only, since
+// many hosts advertise AAAA records that do not serve traffic. It reports "No AAAA",
+// "Reachable" or "Unreachable", or "Not Tested (proxy in use)" when the site is reached
+// through a proxy, whose own connection to the site the scanner cannot choose.
+func (s *Scanner) checkIPv6(ctx context.Context, url string) string {
+	addrs, err := s.resolver().LookupIP(ctx, "ip6", hostOf(url))
+	if err != nil || len(addrs) == 0 {
+		return "No AAAA"
+	}
+
+	port := "80"
+	if strings.HasPrefix(url, "https://") {
+		port = "443"
+	}
+	if proxyURL, err := s.proxyFor(net.JoinHostPort(hostOf(url), port)); err != nil || proxyURL != nil {
+		return "Not Tested (proxy in use)"
+	}
+
+	// Connect straight to the site, never through a proxy, so the request goes over IPv6
+	transport := s.newTransport()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return s.dialContext(ctx, "tcp6", addr)
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: s.wrapTransport(transport),
+		Timeout:   s.opts.Timeout,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", withScheme(url, "http"), nil)
+	if err != nil {
+		return "Unreachable"
+	}
+	resp, err := s.do(client, req)
+	if err != nil {
+		s.log(url).Warn("IPv6 unreachable", "error", err)
+		return "Unreachable"
+	}
+	resp.Body.Close()
+	return "Reachable"
+}
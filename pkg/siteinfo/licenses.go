@@ -0,0 +1,81 @@
+package siteinfo
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// licenseHeaderPattern matches the License header of a plugin's readme.txt or a theme's style.css
+var licenseHeaderPattern = regexp.MustCompile(`(?im)^[ \t/*#@]*License:[ \t]*(.+?)[ \t]*$`)
+
+// directoryLicense is the license recorded for plugins and themes without a License header that
+// are listed in the wordpress.org directory, which only accepts GPL-compatible licenses
+const directoryLicense = "GPL-compatible (wordpress.org directory)"
+
+// gplCompatibleLicenses are the license names, in lower case, that can be combined with
+// WordPress under the GPL
+var gplCompatibleLicenses = []string{"gpl", "gnu general public", "mit", "expat", "bsd", "apache", "mpl", "mozilla public", "isc", "public domain", "cc0", "unlicense", "zlib", "artistic"}
+
+// GPLCompatible reports whether the license is one of the licenses WordPress code may be
+// distributed under. Apache 2.0 is only compatible with GPLv3, but is counted as compatible.
+func GPLCompatible(license string) bool {
+	license = strings.ToLower(license)
+	for _, name := range gplCompatibleLicenses {
+		if strings.Contains(license, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileLicense returns the License header of the file on the site, or "" if the file cannot be
+// read or has no header
+func (s *Scanner) fileLicense(ctx context.Context, url string) string {
+	resp, body, err := s.fetchPage(ctx, url)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	// The headers are at the top; a soft 404 page will not match within the first 8 KB
+	if len(body) > 8<<10 {
+		body = body[:8<<10]
+	}
+	match := licenseHeaderPattern.FindStringSubmatch(body)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// componentLicense returns the license of the plugin or theme: the License header of its
+// readme.txt or style.css on the site, or directoryLicense for those listed in the
+// wordpress.org directory
+func (s *Scanner) componentLicense(ctx context.Context, url, directory, slug string) string {
+	file := "readme.txt"
+	if directory == themesDirectory {
+		file = "style.css"
+	}
+	if license := s.fileLicense(ctx, strings.TrimRight(url, "/")+"/wp-content/"+directory+"/"+slug+"/"+file); license != "" {
+		return license
+	}
+	if s.opts.Offline {
+		return ""
+	}
+	release, err := s.latestPluginRelease(ctx, directory, slug)
+	if err != nil || release == nil || release.Closed {
+		return ""
+	}
+	return directoryLicense
+}
+
+// checkLicenses records the license of each plugin and the theme, for due diligence on the
+// GPL compliance of a site's code
+func (s *Scanner) checkLicenses(ctx context.Context, url string, info *SiteInfo) {
+	for i := range info.Plugins {
+		info.Plugins[i].License = s.componentLicense(ctx, url, pluginsDirectory, info.Plugins[i].Slug)
+	}
+	if info.Theme != "" {
+		info.ThemeLicense = s.componentLicense(ctx, url, themesDirectory, info.Theme)
+	}
+}
@@ -0,0 +1,60 @@
+package siteinfo
+
+import (
+	"context"
+	"sync"
+)
+
+// lookup is one keyed lookup in progress or done; done is closed once it finishes
+type lookup[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// lookups runs each keyed lookup once at a time across concurrent scans and remembers the
+// results worth keeping for the rest of the run. Failed lookups, such as those cancelled with
+// their scan or cut off by a network error, are forgotten so the next scan tries again.
+type lookups[T any] struct {
+	mu      sync.Mutex
+	entries map[string]*lookup[T]
+}
+
+// get returns the remembered result for the key, or calls load for it. load reports whether
+// its result may be remembered; an error is never remembered. Scans waiting on another's
+// lookup of the same key try again themselves if it fails.
+func (l *lookups[T]) get(ctx context.Context, key string, load func() (T, bool, error)) (T, error) {
+	for {
+		l.mu.Lock()
+		if l.entries == nil {
+			l.entries = map[string]*lookup[T]{}
+		}
+		entry, ok := l.entries[key]
+		if !ok {
+			entry = &lookup[T]{done: make(chan struct{})}
+			l.entries[key] = entry
+			l.mu.Unlock()
+
+			var keep bool
+			entry.value, keep, entry.err = load()
+			if entry.err != nil || !keep {
+				l.mu.Lock()
+				delete(l.entries, key)
+				l.mu.Unlock()
+			}
+			close(entry.done)
+			return entry.value, entry.err
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+		if entry.err == nil {
+			return entry.value, nil
+		}
+	}
+}
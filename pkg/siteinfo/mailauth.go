@@ -0,0 +1,89 @@
+package siteinfo
+
+import (
+	"context"
+	"strings"
+)
+
+// MailAuth reports the email authentication records of the site's domain
+type MailAuth struct {
+	Domain        string   `json:"domain"`
+	SPF           string   `json:"spf,omitempty"`
+	SPFPolicy     string   `json:"spf_policy"`
+	DMARC         string   `json:"dmarc,omitempty"`
+	DMARCPolicy   string   `json:"dmarc_policy"`
+	DKIMSelectors []string `json:"dkim_selectors"`
+}
+
+// dkimSelectors are the DKIM selectors used by common mail providers. DKIM keys cannot be
+// listed, so only these selectors are checked.
+var dkimSelectors = []string{"default", "google", "selector1", "selector2", "k1", "k2", "mail", "dkim", "s1", "s2", "smtp", "mandrill", "mailjet", "zoho", "protonmail", "fm1", "mxvault"}
+
+// spfPolicy describes the strictness of an SPF record from its all mechanism
+func spfPolicy(record string) string {
+	for _, field := range strings.Fields(record) {
+		switch strings.ToLower(field) {
+		case "-all":
+			return "Strict (-all)"
+		case "~all":
+			return "Soft Fail (~all)"
+		case "?all":
+			return "Neutral (?all)"
+		case "all", "+all":
+			return "Permissive (+all)"
+		}
+		if strings.HasPrefix(strings.ToLower(field), "redirect=") {
+			return "Redirect (" + field[len("redirect="):] + ")"
+		}
+	}
+	return "No all Mechanism"
+}
+
+// dmarcPolicy returns the p= tag of a DMARC record
+func dmarcPolicy(record string) string {
+	for _, tag := range strings.Split(record, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(tag), "=")
+		if strings.EqualFold(name, "p") {
+			return strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	return "No Policy"
+}
+
+// checkMailAuth looks up the SPF record, the _dmarc record and common DKIM selectors of the
+// site's registrable domain, reporting each record's presence and policy strictness
+func (s *Scanner) checkMailAuth(ctx context.Context, url string) *MailAuth {
+	resolver := s.resolver()
+	domain := registrableDomain(hostOf(url))
+	auth := &MailAuth{Domain: domain, SPFPolicy: "Missing", DMARCPolicy: "Missing"}
+
+	if txts, err := resolver.LookupTXT(ctx, domain); err == nil {
+		for _, txt := range txts {
+			if strings.HasPrefix(strings.ToLower(txt), "v=spf1") {
+				auth.SPF = txt
+				auth.SPFPolicy = spfPolicy(txt)
+			}
+		}
+	}
+	if txts, err := resolver.LookupTXT(ctx, "_dmarc."+domain); err == nil {
+		for _, txt := range txts {
+			if strings.HasPrefix(strings.ToLower(txt), "v=dmarc1") {
+				auth.DMARC = txt
+				auth.DMARCPolicy = dmarcPolicy(txt)
+			}
+		}
+	}
+	for _, selector := range dkimSelectors {
+		txts, err := resolver.LookupTXT(ctx, selector+"._domainkey."+domain)
+		if err != nil {
+			continue
+		}
+		for _, txt := range txts {
+			if strings.Contains(txt, "p=") {
+				auth.DKIMSelectors = append(auth.DKIMSelectors, selector)
+				break
+			}
+		}
+	}
+	return auth
+}
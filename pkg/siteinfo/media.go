@@ -0,0 +1,49 @@
+package siteinfo
+
+import (
+	"regexp"
+	"strings"
+)
+
+// mediaStorageHosts maps the host suffixes of object storage and media services to the provider name
+var mediaStorageHosts = []struct {
+	suffix   string
+	provider string
+}{
+	{".amazonaws.com", "Amazon S3"},
+	{".digitaloceanspaces.com", "DigitalOcean Spaces"},
+	{"res.cloudinary.com", "Cloudinary"},
+	{"storage.googleapis.com", "Google Cloud Storage"},
+	{".blob.core.windows.net", "Azure Blob Storage"},
+	{".backblazeb2.com", "Backblaze B2"},
+	{".r2.dev", "Cloudflare R2"},
+	{".wasabisys.com", "Wasabi"},
+	{".linodeobjects.com", "Linode Object Storage"},
+}
+
+// mediaURLPattern matches image sources and media library URLs in the page
+var mediaURLPattern = regexp.MustCompile(`(?i)(?:https?:)?//[a-z0-9.-]+(?::\d+)?/[^"'\s,)]*(?:wp-content/uploads/[^"'\s,)]*|\.(?:jpe?g|png|gif|webp|avif|svg|mp4))`)
+
+// detectMediaOffload reports where the site's media is served from: the name of the object
+// storage provider when uploads are offloaded, "Local" when they are served from the site
+// host, or "" when the page references no media.
+func detectMediaOffload(body, url string) string {
+	pageHost := hostOf(url)
+	local := strings.Contains(body, "/wp-content/uploads/")
+	for _, media := range mediaURLPattern.FindAllString(body, -1) {
+		host := thirdPartyHost(media, pageHost)
+		if host == "" {
+			local = true
+			continue
+		}
+		for _, storage := range mediaStorageHosts {
+			if strings.HasSuffix(host, storage.suffix) || host == strings.TrimPrefix(storage.suffix, ".") {
+				return storage.provider + " (" + host + ")"
+			}
+		}
+	}
+	if local {
+		return "Local"
+	}
+	return ""
+}
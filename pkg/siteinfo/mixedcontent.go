@@ -0,0 +1,52 @@
+package siteinfo
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// iframeTagPattern matches iframe tags
+var iframeTagPattern = regexp.MustCompile(`(?is)<iframe\b[^>]*>`)
+
+// insecureURL reports whether a resource URL is loaded over plain HTTP
+func insecureURL(resource string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(resource)), "http://")
+}
+
+// checkMixedContent returns the scripts, stylesheets, images and iframes an HTTPS page loads
+// over plain HTTP, which browsers block or warn about after an SSL migration. Pages served
+// over HTTP have no mixed content.
+func checkMixedContent(body, finalURL string) []string {
+	if !strings.HasPrefix(finalURL, "https://") {
+		return nil
+	}
+	var insecure []string
+	add := func(resource string) {
+		if insecureURL(resource) && !slices.Contains(insecure, resource) {
+			insecure = append(insecure, resource)
+		}
+	}
+	for _, tag := range scriptTagPattern.FindAllString(body, -1) {
+		add(tagAttributes(tag)["src"])
+	}
+	for _, tag := range stylesheetTagPattern.FindAllString(body, -1) {
+		attributes := tagAttributes(tag)
+		if strings.Contains(strings.ToLower(attributes["rel"]), "stylesheet") {
+			add(attributes["href"])
+		}
+	}
+	for _, tag := range imgTagPattern.FindAllString(body, -1) {
+		attributes := tagAttributes(tag)
+		add(attributes["src"])
+		for _, candidate := range strings.Split(attributes["srcset"], ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 {
+				add(fields[0])
+			}
+		}
+	}
+	for _, tag := range iframeTagPattern.FindAllString(body, -1) {
+		add(tagAttributes(tag)["src"])
+	}
+	return insecure
+}
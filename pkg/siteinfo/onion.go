@@ -0,0 +1,47 @@
+package siteinfo
+
+import (
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// errOnionWithoutTor is returned for .onion sites when no proxy can reach them
+var errOnionWithoutTor = errors.New(".onion sites can only be reached through a Tor SOCKS5 proxy")
+
+// isOnion reports whether the host is a Tor onion service
+func isOnion(host string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(host, ".")), ".onion")
+}
+
+// ParseTorProxy parses the address of Tor's SOCKS5 proxy, such as socks5h://127.0.0.1:9050
+func ParseTorProxy(raw string) (*neturl.URL, error) {
+	u, err := ParseProxy(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, errors.New("the Tor proxy must be a socks5:// or socks5h:// URL")
+	}
+	return u, nil
+}
+
+// newOnionTransport returns a transport sending every request through the Tor proxy. The
+// per-phase timeouts are left out, as building a circuit to an onion service routinely takes
+// longer than they allow; the client's OnionTimeout bounds each request instead.
+func (s *Scanner) newOnionTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(s.opts.TorProxy)
+	transport.TLSHandshakeTimeout = s.opts.OnionTimeout
+	return transport
+}
+
+// clientFor returns the client for requests to the host: the Tor client for onion services
+// when a Tor proxy is configured, otherwise the scanner's client
+func (s *Scanner) clientFor(host string) *http.Client {
+	if s.onionClient != nil && isOnion(host) {
+		return s.onionClient
+	}
+	return s.client
+}
@@ -0,0 +1,105 @@
+package siteinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// errUnverified is returned for requests beyond the homepage of a site whose ownership was
+// not verified when verification is required
+var errUnverified = errors.New("site ownership not verified")
+
+// verificationPrefix precedes the token in DNS TXT records
+const verificationPrefix = "site-info-fetcher-verification="
+
+// verificationPath is the well-known file containing the token
+const verificationPath = "/.well-known/site-info-fetcher.txt"
+
+// verifyOwnership checks whether the site owner has published the verification token,
+// either as a DNS TXT record on the host (or _site-info-fetcher.<host>) or in a
+// well-known file, and returns how it was verified or "Unverified"
+func (s *Scanner) verifyOwnership(ctx context.Context, url string) string {
+	token := s.opts.VerificationToken
+	host := hostOf(url)
+
+	for _, name := range []string{host, "_site-info-fetcher." + host} {
+		records, err := s.resolver().LookupTXT(ctx, name)
+		if err != nil {
+			continue
+		}
+		for _, record := range records {
+			if strings.TrimSpace(record) == verificationPrefix+token {
+				return "DNS TXT"
+			}
+		}
+	}
+
+	resp, body, err := s.fetchPage(ctx, "https://"+host+verificationPath)
+	if err == nil && resp.StatusCode == 200 && strings.TrimSpace(body) == token {
+		return "Well-known File"
+	}
+	return "Unverified"
+}
+
+// activeChecksAllowed reports whether intrusive checks may run against the site. When
+// verification is required they only run on sites whose ownership was verified.
+func (s *Scanner) activeChecksAllowed(info *SiteInfo) bool {
+	if !s.opts.RequireVerification {
+		return true
+	}
+	return info.OwnershipVerified != "" && info.OwnershipVerified != "Unverified"
+}
+
+// checkOwnership verifies the site's ownership into info when a token is configured, and
+// reports whether active checks may run. When they may not, the returned context keeps every
+// later request to the site's hosts at the homepage, so no probe reaches it.
+func (s *Scanner) checkOwnership(ctx context.Context, info *SiteInfo, urls ...string) (context.Context, bool) {
+	if s.opts.VerificationToken != "" {
+		info.OwnershipVerified = s.verifyOwnership(ctx, urls[0])
+	} else if s.opts.RequireVerification {
+		info.OwnershipVerified = "Unverified"
+	}
+	if s.activeChecksAllowed(info) {
+		return ctx, true
+	}
+	// The www and bare hostnames of a site redirect to each other, so both are kept out
+	hosts := map[string]bool{}
+	for _, url := range urls {
+		host := strings.ToLower(hostOf(url))
+		hosts[host] = true
+		if bare, ok := strings.CutPrefix(host, "www."); ok {
+			hosts[bare] = true
+		} else {
+			hosts["www."+host] = true
+		}
+	}
+	return context.WithValue(ctx, ownershipGateKey{}, hosts), false
+}
+
+// ownershipGateKey is the context key of the hosts of an unverified site
+type ownershipGateKey struct{}
+
+// ownershipTransport refuses requests beyond the homepage to the hosts of an unverified
+// site, as checkOwnership marks them in the request context
+type ownershipTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip sends the request unless it probes an unverified site
+func (t *ownershipTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hosts, _ := req.Context().Value(ownershipGateKey{}).(map[string]bool)
+	if hosts[strings.ToLower(req.URL.Hostname())] && !isHomepage(req.URL) {
+		return nil, fmt.Errorf("%s: %w", req.URL.Path, errUnverified)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// isHomepage reports whether the URL is a site's bare homepage, without a query
+func isHomepage(u *neturl.URL) bool {
+	path := u.EscapedPath()
+	return (path == "" || path == "/") && u.RawQuery == ""
+}
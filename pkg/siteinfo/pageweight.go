@@ -0,0 +1,90 @@
+package siteinfo
+
+import (
+	"context"
+	"io"
+	neturl "net/url"
+	"strings"
+)
+
+// maxAssetFetches caps the number of assets whose size is fetched for one page
+const maxAssetFetches = 200
+
+// Assets counts the scripts, stylesheets and images a page references
+type Assets struct {
+	Scripts     int `json:"scripts"`
+	Stylesheets int `json:"stylesheets"`
+	Images      int `json:"images"`
+}
+
+// pageAssets counts the page's scripts, stylesheets and images, returning their URLs
+func pageAssets(body string) (Assets, []string) {
+	var assets Assets
+	var urls []string
+	for _, tag := range scriptTagPattern.FindAllString(body, -1) {
+		assets.Scripts++
+		if src := tagAttributes(tag)["src"]; src != "" {
+			urls = append(urls, src)
+		}
+	}
+	for _, tag := range stylesheetTagPattern.FindAllString(body, -1) {
+		attributes := tagAttributes(tag)
+		if strings.Contains(strings.ToLower(attributes["rel"]), "stylesheet") {
+			assets.Stylesheets++
+			if attributes["href"] != "" {
+				urls = append(urls, attributes["href"])
+			}
+		}
+	}
+	for _, tag := range imgTagPattern.FindAllString(body, -1) {
+		assets.Images++
+		if src := tagAttributes(tag)["src"]; src != "" && !strings.HasPrefix(src, "data:") {
+			urls = append(urls, src)
+		}
+	}
+	return assets, urls
+}
+
+// assetSize returns the size of an asset from its Content-Length, downloading it when the
+// server does not send one
+func (s *Scanner) assetSize(ctx context.Context, url string) int64 {
+	resp, err := s.doRequest(ctx, "HEAD", url, nil)
+	if err == nil {
+		resp.Body.Close()
+		if resp.ContentLength >= 0 {
+			return resp.ContentLength
+		}
+	}
+	resp, err = s.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+	size, _ := io.Copy(io.Discard, resp.Body)
+	return size
+}
+
+// measurePageWeight returns the total size of the page and, when fetchAssets is set, of the
+// scripts, stylesheets and images it references, along with the asset counts
+func (s *Scanner) measurePageWeight(ctx context.Context, body, pageURL string, fetchAssets bool) (int64, Assets) {
+	assets, urls := pageAssets(body)
+	weight := int64(len(body))
+	if !fetchAssets {
+		return weight, assets
+	}
+
+	base, err := neturl.Parse(withScheme(pageURL, "http"))
+	if err != nil {
+		return weight, assets
+	}
+	seen := map[string]bool{}
+	for _, asset := range urls {
+		resolved, err := base.Parse(strings.TrimSpace(asset))
+		if err != nil || seen[resolved.String()] || len(seen) >= maxAssetFetches {
+			continue
+		}
+		seen[resolved.String()] = true
+		weight += s.assetSize(ctx, resolved.String())
+	}
+	return weight, assets
+}
@@ -0,0 +1,93 @@
+package siteinfo
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// permissionsFeatureUsage maps Permissions-Policy features to the JavaScript APIs that use them
+var permissionsFeatureUsage = map[string][]string{
+	"geolocation":    {"navigator.geolocation"},
+	"camera":         {"getUserMedia", "getDisplayMedia"},
+	"microphone":     {"getUserMedia"},
+	"payment":        {"PaymentRequest"},
+	"usb":            {"navigator.usb"},
+	"bluetooth":      {"navigator.bluetooth"},
+	"fullscreen":     {"requestFullscreen"},
+	"clipboard-read": {"clipboard.read"},
+	"accelerometer":  {"Accelerometer(", "DeviceMotionEvent"},
+	"gyroscope":      {"Gyroscope(", "DeviceOrientationEvent"},
+	"serial":         {"navigator.serial"},
+	"hid":            {"navigator.hid"},
+}
+
+// parsePermissionsPolicy returns the allowlist of each feature declared in the
+// Permissions-Policy header, falling back to the legacy Feature-Policy syntax
+func parsePermissionsPolicy(headers http.Header) map[string]string {
+	policy := map[string]string{}
+	if header := strings.Join(headers.Values("Permissions-Policy"), ","); header != "" {
+		for _, directive := range strings.Split(header, ",") {
+			feature, allowlist, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if feature != "" {
+				policy[strings.ToLower(feature)] = strings.TrimSpace(allowlist)
+			}
+		}
+		return policy
+	}
+	for _, directive := range strings.Split(headers.Get("Feature-Policy"), ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		allowlist := "(" + strings.Join(fields[1:], " ") + ")"
+		if len(fields) > 1 && fields[1] == "'none'" {
+			allowlist = "()"
+		}
+		policy[strings.ToLower(fields[0])] = allowlist
+	}
+	return policy
+}
+
+// checkPermissionsPolicy compares the declared Permissions-Policy against the browser features
+// the page's inline scripts visibly use. It reports features the page uses that the
+// policy disables or does not declare, and declared allowlists for features the page never uses.
+// Scripts loaded from other files are not inspected, so unused features are a hint to review.
+func checkPermissionsPolicy(headers http.Header, body string) []string {
+	policy := parsePermissionsPolicy(headers)
+	var issues []string
+
+	features := make([]string, 0, len(permissionsFeatureUsage))
+	for feature := range permissionsFeatureUsage {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+
+	used := map[string]bool{}
+	for _, feature := range features {
+		for _, api := range permissionsFeatureUsage[feature] {
+			if strings.Contains(body, api) {
+				used[feature] = true
+			}
+		}
+		allowlist, declared := policy[feature]
+		switch {
+		case used[feature] && allowlist == "()":
+			issues = append(issues, feature+" used but disabled by policy")
+		case used[feature] && !declared && len(policy) > 0:
+			issues = append(issues, feature+" used but not declared")
+		}
+	}
+
+	declared := make([]string, 0, len(policy))
+	for feature := range policy {
+		declared = append(declared, feature)
+	}
+	sort.Strings(declared)
+	for _, feature := range declared {
+		if _, known := permissionsFeatureUsage[feature]; known && !used[feature] && policy[feature] != "()" {
+			issues = append(issues, feature+" allowed but not used")
+		}
+	}
+	return issues
+}
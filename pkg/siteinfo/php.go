@@ -0,0 +1,75 @@
+package siteinfo
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// phpVersionPattern matches a PHP version token, as in "PHP/8.2.12"
+var phpVersionPattern = regexp.MustCompile(`(?i)\bPHP/(\d+\.\d+(?:\.\d+)?)`)
+
+// phpVersionHeaders are the headers some stacks and hosts report the bare PHP version in when
+// X-Powered-By is stripped
+var phpVersionHeaders = []string{"X-PHP-Version", "X-PHP-Ver", "X-PHP"}
+
+// phpSessionCookie is the session cookie PHP sets by default, which reveals PHP but not its version
+const phpSessionCookie = "PHPSESSID"
+
+// detectPHP returns the PHP version the response headers reveal and the signal it came from,
+// strongest first: X-Powered-By, a PHP version header, then a PHP/<version> token in any
+// other header such as Server. Without a version, a PHPSESSID cookie still shows the site runs
+// PHP. The signal is nil when there is no sign of PHP.
+func detectPHP(headers http.Header) (string, *Provenance) {
+	for _, value := range headers.Values("X-Powered-By") {
+		if m := phpVersionPattern.FindStringSubmatch(value); m != nil {
+			return m[1], &Provenance{SourceHeader, "X-Powered-By: " + value, 0.9}
+		}
+	}
+	for _, name := range phpVersionHeaders {
+		if m := bareVersionPattern.FindStringSubmatch(strings.TrimSpace(headers.Get(name))); m != nil {
+			return m[1], &Provenance{SourceHeader, name + ": " + headers.Get(name), 0.85}
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range headers[name] {
+			if m := phpVersionPattern.FindStringSubmatch(value); m != nil {
+				return m[1], &Provenance{SourceHeader, name + ": " + value, 0.8}
+			}
+		}
+	}
+	for _, cookie := range (&http.Response{Header: headers}).Cookies() {
+		if cookie.Name == phpSessionCookie {
+			return "", &Provenance{SourceHeader, phpSessionCookie + " cookie", 0.8}
+		}
+	}
+	return "", nil
+}
+
+// restAPIHeaders returns the response headers of a WordPress site's REST API, or nil when it
+// cannot be fetched. A page cache or CDN serving the homepage often drops X-Powered-By and
+// the headers naming the database, while the REST API is answered by PHP itself.
+func (s *Scanner) restAPIHeaders(ctx context.Context, url string) http.Header {
+	resp, _, err := s.fetchPage(ctx, strings.TrimRight(url, "/")+"/wp-json/")
+	if err != nil {
+		return nil
+	}
+	return resp.Header
+}
+
+// restAPIVersion returns the version detect finds in the REST API's headers, with the
+// evidence marked as coming from the REST API
+func restAPIVersion(headers http.Header, detect func(http.Header) (string, *Provenance)) (string, *Provenance) {
+	version, from := detect(headers)
+	if version == "" {
+		return "", nil
+	}
+	return version, &Provenance{SourceRESTAPI, "/wp-json/ " + from.Evidence, 0.8}
+}
@@ -0,0 +1,73 @@
+package siteinfo
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// platformSignature identifies a deployment platform by its response headers, Server header,
+// default hostnames and the CNAME targets of custom domains
+type platformSignature struct {
+	name    string
+	headers []string
+	servers []string
+	hosts   []string
+}
+
+// platformSignatures lists the frontend and edge deployment platforms that can be detected.
+// Custom domains point at the platform's default hostnames, so the hosts double as CNAME targets.
+var platformSignatures = []platformSignature{
+	{name: "Vercel", headers: []string{"X-Vercel-Id", "X-Vercel-Cache"}, servers: []string{"vercel"}, hosts: []string{".vercel.app", ".vercel-dns.com", ".now.sh"}},
+	{name: "Netlify", headers: []string{"X-NF-Request-ID"}, servers: []string{"netlify"}, hosts: []string{".netlify.app", ".netlify.com"}},
+	{name: "Cloudflare Pages", hosts: []string{".pages.dev"}},
+	{name: "Cloudflare Workers", hosts: []string{".workers.dev"}},
+	{name: "GitHub Pages", servers: []string{"github.com"}, hosts: []string{".github.io"}},
+	{name: "AWS Amplify", hosts: []string{".amplifyapp.com"}},
+	{name: "Firebase Hosting", hosts: []string{".web.app", ".firebaseapp.com"}},
+	{name: "Azure Static Web Apps", hosts: []string{".azurestaticapps.net"}},
+	{name: "Render", headers: []string{"Rndr-Id"}, hosts: []string{".onrender.com"}},
+	{name: "Fly.io", headers: []string{"Fly-Request-Id"}, servers: []string{"fly/"}, hosts: []string{".fly.dev"}},
+	{name: "Deno Deploy", servers: []string{"deno/"}, hosts: []string{".deno.dev"}},
+}
+
+// matchPlatformHost returns the platform whose default hostnames include the host, or ""
+func matchPlatformHost(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, platform := range platformSignatures {
+		for _, suffix := range platform.hosts {
+			if strings.HasSuffix(host, suffix) {
+				return platform.name
+			}
+		}
+	}
+	return ""
+}
+
+// detectPlatform identifies the platform the site is deployed on from the response headers,
+// the site's hostname and, for custom domains, its CNAME record. It returns "None" when no
+// platform is recognised, as for sites on their own servers.
+func (s *Scanner) detectPlatform(ctx context.Context, url string, headers http.Header) string {
+	server := strings.ToLower(headers.Get("Server"))
+	for _, platform := range platformSignatures {
+		for _, name := range platform.headers {
+			if headers.Get(name) != "" {
+				return platform.name
+			}
+		}
+		for _, prefix := range platform.servers {
+			if strings.HasPrefix(server, prefix) {
+				return platform.name
+			}
+		}
+	}
+	if platform := matchPlatformHost(hostOf(url)); platform != "" {
+		return platform
+	}
+	if cname, err := s.resolver().LookupCNAME(ctx, hostOf(url)); err == nil {
+		if platform := matchPlatformHost(cname); platform != "" {
+			return platform
+		}
+	}
+	return "None"
+}
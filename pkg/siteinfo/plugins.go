@@ -0,0 +1,71 @@
+package siteinfo
+
+import (
+	"regexp"
+	"sort"
+)
+
+// Plugin is a WordPress plugin detected from the page's asset URLs
+type Plugin struct {
+	Slug    string `json:"slug"`
+	Version string `json:"version,omitempty"`
+	// LatestVersion, ReleasesBehind and DaysBehind compare the version with the plugin's
+	// latest release in the wordpress.org directory
+	LatestVersion  string `json:"latest_version,omitempty"`
+	ReleasesBehind int    `json:"releases_behind,omitempty"`
+	DaysBehind     int    `json:"days_behind,omitempty"`
+	// Abandoned says why the plugin is a maintenance risk: closed in the wordpress.org
+	// directory or not updated in two years
+	Abandoned string `json:"abandoned,omitempty"`
+	// License is the License header of the plugin's readme.txt, or whether the wordpress.org
+	// directory lists it
+	License string `json:"license,omitempty"`
+}
+
+// String formats the plugin as slug or slug (version)
+func (p Plugin) String() string {
+	if p.Version == "" {
+		return p.Slug
+	}
+	return p.Slug + " (" + p.Version + ")"
+}
+
+// pluginAssetPattern matches asset URLs under /wp-content/plugins/, capturing the plugin
+// slug and, where present, the ?ver= query string
+var pluginAssetPattern = regexp.MustCompile(`/wp-content/plugins/([A-Za-z0-9_.-]+)/[^"'\s<>)]*?(?:[?&](?:amp;|#038;)?ver=([A-Za-z0-9_.-]+))?["'\s<>)]`)
+
+// detectPlugins lists the plugins whose assets the page loads, sorted by slug. The version is
+// taken from the first asset with a ?ver= query string; themes and WordPress often append
+// their own version instead, so it is a hint rather than a certainty.
+func detectPlugins(body string) []Plugin {
+	versions := map[string]string{}
+	for _, match := range pluginAssetPattern.FindAllStringSubmatch(body, -1) {
+		slug, version := match[1], match[2]
+		if existing, ok := versions[slug]; !ok || existing == "" {
+			versions[slug] = version
+		}
+	}
+
+	plugins := make([]Plugin, 0, len(versions))
+	for slug, version := range versions {
+		plugins = append(plugins, Plugin{Slug: slug, Version: version})
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Slug < plugins[j].Slug
+	})
+	return plugins
+}
+
+// themeAssetPattern matches asset URLs under /wp-content/themes/, capturing the theme
+// slug and, where present, the ?ver= query string
+var themeAssetPattern = regexp.MustCompile(`/wp-content/themes/([A-Za-z0-9_.-]+)/[^"'\s<>)]*?(?:[?&](?:amp;|#038;)?ver=([A-Za-z0-9_.-]+))?["'\s<>)]`)
+
+// detectTheme returns the active theme's slug and, where present, the ?ver= version of its
+// assets. Child themes load their parent's assets too; the first theme referenced is reported.
+func detectTheme(body string) (string, string) {
+	match := themeAssetPattern.FindStringSubmatch(body)
+	if match == nil {
+		return "", ""
+	}
+	return match[1], match[2]
+}
@@ -0,0 +1,137 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/vuln"
+)
+
+// directoryAPI is the wordpress.org plugin and theme directory API, for plugins or themes
+const directoryAPI = "https://api.wordpress.org/%s/info/1.2/"
+
+// Directories of the wordpress.org API
+const (
+	pluginsDirectory = "plugins"
+	themesDirectory  = "themes"
+)
+
+// pluginRelease is a plugin's or theme's release history in the wordpress.org directory.
+// Closed plugins have no release history, only the date they were closed.
+type pluginRelease struct {
+	Version     string            `json:"version"`
+	LastUpdated string            `json:"last_updated"`
+	Versions    map[string]string `json:"versions"`
+	Closed      bool              `json:"closed"`
+	ClosedDate  string            `json:"closed_date"`
+}
+
+// pluginDirectory looks up each plugin and theme in the wordpress.org directory once per run,
+// as most sites share their plugins
+type pluginDirectory struct {
+	releases lookups[*pluginRelease]
+}
+
+// latestPluginRelease returns the plugin's or theme's release history from the wordpress.org
+// directory, or nil for those it does not list, such as premium plugins
+func (s *Scanner) latestPluginRelease(ctx context.Context, directory, slug string) (*pluginRelease, error) {
+	return s.pluginDirectory.releases.get(ctx, directory+"/"+slug, func() (*pluginRelease, bool, error) {
+		release, err := s.fetchPluginRelease(ctx, directory, slug)
+		return release, true, err
+	})
+}
+
+// fetchPluginRelease fetches the plugin's or theme's release history from the wordpress.org
+// directory
+func (s *Scanner) fetchPluginRelease(ctx context.Context, directory, slug string) (*pluginRelease, error) {
+	query := neturl.Values{
+		"action":                    {strings.TrimSuffix(directory, "s") + "_information"},
+		"request[slug]":             {slug},
+		"request[fields][versions]": {"1"},
+		"request[fields][sections]": {"0"},
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(directoryAPI, directory)+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Closed plugins answer 404 with the closure in the body
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotFound:
+	default:
+		return nil, fmt.Errorf("wordpress.org returned HTTP %d for %s %s", resp.StatusCode, strings.TrimSuffix(directory, "s"), slug)
+	}
+	var release pluginRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if release.Version == "" && !release.Closed {
+		return nil, nil
+	}
+	return &release, nil
+}
+
+// checkPluginUpdates compares each plugin's detected version with its latest release in the
+// wordpress.org directory, recording the latest version, the number of releases published
+// since the installed one and the days since the latest release. It returns the site's
+// update lag: the releases behind summed over its plugins.
+func (s *Scanner) checkPluginUpdates(ctx context.Context, plugins []Plugin) (int, error) {
+	lag := 0
+	for i := range plugins {
+		plugin := &plugins[i]
+		if plugin.Version == "" || plugin.Version == Unknown {
+			continue
+		}
+		release, err := s.latestPluginRelease(ctx, pluginsDirectory, plugin.Slug)
+		if err != nil {
+			return lag, err
+		}
+		if release == nil || release.Closed {
+			continue
+		}
+		plugin.LatestVersion = release.Version
+		if vuln.CompareVersions(plugin.Version, release.Version) >= 0 {
+			continue
+		}
+
+		behind := 0
+		for version := range release.Versions {
+			if version != "trunk" && vuln.CompareVersions(version, plugin.Version) > 0 && vuln.CompareVersions(version, release.Version) <= 0 {
+				behind++
+			}
+		}
+		// The version list can be incomplete, but an older version is at least one behind
+		plugin.ReleasesBehind = max(behind, 1)
+		// The directory does not date each release, so the lag in days runs from the latest
+		if updated, ok := parsePluginDate(release.LastUpdated); ok {
+			plugin.DaysBehind = int(time.Since(updated).Hours() / 24)
+		}
+		lag += plugin.ReleasesBehind
+	}
+	return lag, nil
+}
+
+// parsePluginDate parses the directory's dates: a plugin's last_updated time, e.g.
+// "2024-07-10 10:47am GMT", or a theme's last_updated or closed_date day, e.g. "2024-07-10"
+func parsePluginDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02 3:04pm MST", time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
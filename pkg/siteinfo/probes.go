@@ -0,0 +1,202 @@
+package siteinfo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// checkSearch issues a search query and verifies the search results template renders.
+// The search page is rarely cached, so it catches fatal errors the homepage hides.
+func (s *Scanner) checkSearch(ctx context.Context, url string) string {
+	resp, body, err := s.fetchPage(ctx, strings.TrimRight(url, "/")+"/?s=site-info-fetcher")
+	if err != nil {
+		return "Failed"
+	}
+	switch {
+	case hasPHPFatalError(body):
+		return "Fatal Error"
+	case resp.StatusCode >= 500:
+		return fmt.Sprintf("HTTP %d", resp.StatusCode)
+	case strings.Contains(body, "search-results") || strings.Contains(body, "search-no-results"):
+		return "OK"
+	}
+	return "Template Not Detected"
+}
+
+// checkErrorPage requests a guaranteed-nonexistent path and verifies the site
+// answers with a proper 404 status and a custom error page
+func (s *Scanner) checkErrorPage(ctx context.Context, url string) string {
+	path := fmt.Sprintf("/site-info-fetcher-404-%d", time.Now().UnixNano())
+	resp, body, err := s.fetchPage(ctx, strings.TrimRight(url, "/")+path)
+	if err != nil {
+		return "Failed"
+	}
+	switch {
+	case hasPHPFatalError(body):
+		return "Fatal Error"
+	case resp.Request != nil && resp.Request.URL.Path != path:
+		return fmt.Sprintf("Redirected (HTTP %d)", resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		if len(strings.TrimSpace(body)) == 0 {
+			return "Blank Error Page"
+		}
+		return "Good"
+	case resp.StatusCode == http.StatusOK:
+		return "Soft 404 (HTTP 200)"
+	}
+	return fmt.Sprintf("HTTP %d", resp.StatusCode)
+}
+
+// corsProbeOrigin is an origin no legitimate site should trust
+const corsProbeOrigin = "https://site-info-fetcher.invalid"
+
+// corsFinding is a risky CORS configuration found on one path. credentials is set when the
+// path also allows credentials, so other sites can read a logged-in visitor's responses.
+type corsFinding struct {
+	path        string
+	problem     string
+	credentials bool
+}
+
+// String describes the finding as reported in the CORS Issues column
+func (f corsFinding) String() string {
+	return f.path + ": " + f.problem
+}
+
+// corsIssues describes the findings for the report
+func corsIssues(findings []corsFinding) []string {
+	var issues []string
+	for _, finding := range findings {
+		issues = append(issues, finding.String())
+	}
+	return issues
+}
+
+// checkCORS sends a cross-origin request to the homepage and the REST API and reports
+// the Access-Control-Allow-Origin configuration along with any risky patterns
+func (s *Scanner) checkCORS(ctx context.Context, url string) (string, bool, []corsFinding) {
+	var allowOrigin string
+	var allowCredentials bool
+	var findings []corsFinding
+
+	base := strings.TrimRight(url, "/")
+	for _, path := range []string{"/", "/wp-json/"} {
+		resp, err := s.doRequest(ctx, "GET", base+path, http.Header{"Origin": {corsProbeOrigin}})
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		origin := resp.Header.Get("Access-Control-Allow-Origin")
+		if origin == "" {
+			continue
+		}
+		credentials := strings.EqualFold(resp.Header.Get("Access-Control-Allow-Credentials"), "true")
+		if allowOrigin == "" {
+			allowOrigin = origin
+			allowCredentials = credentials
+		}
+
+		switch {
+		case origin == "*" && credentials:
+			findings = append(findings, corsFinding{path, "wildcard origin with credentials", true})
+		case origin == corsProbeOrigin && credentials:
+			findings = append(findings, corsFinding{path, "reflects arbitrary origin with credentials", true})
+		case origin == corsProbeOrigin:
+			findings = append(findings, corsFinding{path, "reflects arbitrary origin", false})
+		case origin == "null" && credentials:
+			findings = append(findings, corsFinding{path, "allows null origin with credentials", true})
+		case origin == "null":
+			findings = append(findings, corsFinding{path, "allows null origin", false})
+		}
+	}
+	return allowOrigin, allowCredentials, findings
+}
+
+// openRedirectParams are query parameters commonly used to carry redirect targets
+var openRedirectParams = []string{
+	"redirect", "redirect_to", "redirect_uri", "redirectUrl", "return", "returnTo", "return_url",
+	"returnUrl", "next", "url", "goto", "dest", "destination", "continue", "r", "u",
+}
+
+// openRedirectHost is the attacker-controlled host used as the redirect target
+const openRedirectHost = "site-info-fetcher.invalid"
+
+// checkOpenRedirect requests the homepage with each redirect parameter pointing at an
+// attacker-controlled host and returns the parameters that redirect there
+func (s *Scanner) checkOpenRedirect(ctx context.Context, url string) []string {
+	base := strings.TrimRight(withScheme(url, "http"), "/") + "/?"
+
+	scanClient := s.clientFor(hostOf(url))
+	client := &http.Client{
+		Transport: scanClient.Transport,
+		Timeout:   scanClient.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var vulnerable []string
+	for _, param := range openRedirectParams {
+		req, err := http.NewRequestWithContext(ctx, "GET", base+param+"=https%3A%2F%2F"+openRedirectHost+"%2F", nil)
+		if err != nil {
+			continue
+		}
+		resp, err := s.do(client, req)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+			continue
+		}
+		location, err := resp.Location()
+		if err == nil && location.Hostname() == openRedirectHost {
+			vulnerable = append(vulnerable, param)
+		}
+	}
+	return vulnerable
+}
+
+// checkACMEChallenge requests a made-up token under /.well-known/acme-challenge/ over plain HTTP,
+// as Let's Encrypt does for HTTP-01 validation, and reports whether the path reaches the origin.
+// A 404 is the expected answer; WAF blocks and redirects to other ports break future renewals.
+func (s *Scanner) checkACMEChallenge(ctx context.Context, url string) string {
+	path := fmt.Sprintf("/.well-known/acme-challenge/site-info-fetcher-%d", time.Now().UnixNano())
+	resp, err := s.doRequest(ctx, "GET", "http://"+hostOf(url)+path, nil)
+	if err != nil {
+		return "Failed"
+	}
+	resp.Body.Close()
+
+	if port := resp.Request.URL.Port(); port != "" && port != "80" && port != "443" {
+		return "Redirected to port " + port
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden ||
+		resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Sprintf("Blocked (HTTP %d)", resp.StatusCode)
+	case resp.Request.URL.Path != path:
+		return fmt.Sprintf("Redirected away from challenge path (HTTP %d)", resp.StatusCode)
+	}
+	return "Reachable"
+}
+
+// measureLoginTTFB measures the TTFB of /wp-login.php, which is rarely cached and always
+// runs PHP, as a truer picture of backend performance than the often cached homepage.
+// It returns zero when the login page is not served.
+func (s *Scanner) measureLoginTTFB(ctx context.Context, url string) time.Duration {
+	resp, timing, err := s.fetchURL(ctx, strings.TrimRight(url, "/")+"/wp-login.php")
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0
+	}
+	return timing.TTFB
+}
@@ -0,0 +1,107 @@
+package siteinfo
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+)
+
+// publicResolver is a public DNS resolver queried by the propagation check
+type publicResolver struct {
+	name    string
+	address string
+}
+
+// publicResolvers are queried directly, bypassing the scanner's own resolver and its cache
+var publicResolvers = []publicResolver{
+	{"Google", "8.8.8.8:53"},
+	{"Cloudflare", "1.1.1.1:53"},
+	{"Quad9", "9.9.9.9:53"},
+	{"OpenDNS", "208.67.222.222:53"},
+}
+
+// ResolverAnswer is the A and AAAA records one public resolver returned for the hostname
+type ResolverAnswer struct {
+	Resolver  string   `json:"resolver"`
+	Addresses []string `json:"addresses"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Propagation compares the hostname's addresses across public resolvers. Status is
+// "Consistent" when every resolver returned the same addresses, "Inconsistent" when they
+// differ, "Incomplete" when the resolvers that answered agree but others failed, and
+// "Failed" when none answered.
+type Propagation struct {
+	Status  string           `json:"status"`
+	Answers []ResolverAnswer `json:"answers"`
+}
+
+// String lists each resolver's addresses, e.g. "Google: 192.0.2.1; Quad9: 192.0.2.2"
+func (p *Propagation) String() string {
+	answers := make([]string, len(p.Answers))
+	for i, answer := range p.Answers {
+		if answer.Error != "" {
+			answers[i] = answer.Resolver + ": error"
+			continue
+		}
+		answers[i] = answer.Resolver + ": " + strings.Join(answer.Addresses, " ")
+	}
+	return strings.Join(answers, "; ")
+}
+
+// resolverAt returns a resolver that sends every query to the given server
+func resolverAt(server string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// checkPropagation resolves the hostname's A and AAAA records against each public resolver
+// and reports whether a DNS change has propagated consistently to all of them
+func checkPropagation(ctx context.Context, url string) *Propagation {
+	host := hostOf(url)
+	propagation := &Propagation{}
+
+	var agreed []string
+	answered, failed, consistent := 0, 0, true
+	for _, resolver := range publicResolvers {
+		answer := ResolverAnswer{Resolver: resolver.name}
+		ips, err := resolverAt(resolver.address).LookupIP(ctx, "ip", host)
+		if err != nil {
+			answer.Error = err.Error()
+			failed++
+			propagation.Answers = append(propagation.Answers, answer)
+			continue
+		}
+		for _, ip := range ips {
+			answer.Addresses = append(answer.Addresses, ip.String())
+		}
+		slices.Sort(answer.Addresses)
+		answer.Addresses = slices.Compact(answer.Addresses)
+
+		if answered == 0 {
+			agreed = answer.Addresses
+		} else if !slices.Equal(agreed, answer.Addresses) {
+			consistent = false
+		}
+		answered++
+		propagation.Answers = append(propagation.Answers, answer)
+	}
+
+	switch {
+	case answered == 0:
+		propagation.Status = "Failed"
+	case !consistent:
+		propagation.Status = "Inconsistent"
+	case failed > 0:
+		propagation.Status = "Incomplete"
+	default:
+		propagation.Status = "Consistent"
+	}
+	return propagation
+}
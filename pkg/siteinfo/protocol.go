@@ -0,0 +1,20 @@
+package siteinfo
+
+import (
+	"net/http"
+	"strings"
+)
+
+// advertisesHTTP3 reports whether the Alt-Svc header offers HTTP/3, e.g.
+// Alt-Svc: h3=":443"; ma=86400, h3-29=":443"; ma=86400
+func advertisesHTTP3(headers http.Header) bool {
+	for _, value := range headers.Values("Alt-Svc") {
+		for _, service := range strings.Split(value, ",") {
+			protocol, _, _ := strings.Cut(strings.TrimSpace(service), "=")
+			if protocol == "h3" || strings.HasPrefix(protocol, "h3-") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package siteinfo
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Sources a finding can be determined from
+const (
+	SourceHeader  = "header"
+	SourceHTML    = "html"
+	SourceRESTAPI = "rest_api"
+	SourceDNS     = "dns"
+	SourceTLS     = "tls"
+	SourceGeoIP   = "geoip"
+	SourceDerived = "derived"
+	// SourceFallback is a heuristic used when no direct evidence was found
+	SourceFallback = "fallback"
+)
+
+// Provenance records how a finding was determined, so a disputed result can be traced to the
+// evidence behind it. Confidence runs from 0 to 1: headers and tags the site sends itself are
+// strong evidence, while versions inferred from asset URLs or API routes are weaker.
+type Provenance struct {
+	Source     string  `json:"source"`
+	Evidence   string  `json:"evidence,omitempty"`
+	Confidence float64 `json:"confidence"`
+}
+
+// recordProvenance returns the provenance of each finding, keyed by its JSON field name.
+// Findings that were not detected are left out.
+func recordProvenance(info *SiteInfo, headers http.Header, body string) map[string]Provenance {
+	provenance := map[string]Provenance{}
+
+	if info.PHPVersion != "" && info.PHPDetection != nil {
+		provenance["php_version"] = *info.PHPDetection
+	}
+	if info.WebServer != "" {
+		server := Provenance{SourceHeader, "Server: " + headers.Get("Server"), 0.9}
+		provenance["web_server"] = server
+		if info.WebServerVersion != "" {
+			provenance["web_server_version"] = server
+		}
+	}
+
+	if info.MySQLVersion != Undetectable && info.MySQLDetection != nil {
+		provenance["mysql_version"] = *info.MySQLDetection
+	}
+
+	generator := parseHTML(body)
+	switch {
+	case info.WordPressVersion != "" && generator != "":
+		provenance["wordpress_version"] = Provenance{SourceHTML, `generator meta tag "WordPress ` + generator + `"`, 0.9}
+	case info.WordPressVersionRange != "":
+		provenance["wordpress_version_range"] = Provenance{SourceRESTAPI, "inferred from the routes the REST API offers", 0.5}
+	}
+
+	switch {
+	case info.CMS == "WordPress" && generator != "":
+		provenance["cms"] = Provenance{SourceHTML, "WordPress generator meta tag", 0.95}
+	case info.CMS == "WordPress" && isWordPress(body):
+		provenance["cms"] = Provenance{SourceHTML, "/wp-content/ or /wp-includes/ asset paths", 0.85}
+	case info.CMS == "WordPress":
+		provenance["cms"] = Provenance{SourceRESTAPI, "WordPress REST API index at /wp-json/", 0.9}
+	case info.CMS != "" && generatorTag(body) != "":
+		provenance["cms"] = Provenance{SourceHTML, `generator meta tag "` + generatorTag(body) + `"`, 0.85}
+	case info.CMS != "" && headers.Get("X-Generator") != "":
+		provenance["cms"] = Provenance{SourceHeader, "X-Generator: " + headers.Get("X-Generator"), 0.85}
+	case info.CMS != "":
+		provenance["cms"] = Provenance{SourceHTML, "CMS-specific markup", 0.7}
+	}
+
+	if info.IsWordPress {
+		confidence := 0.85
+		if len(info.WordPressSignals) > 1 {
+			confidence = 0.95
+		}
+		provenance["is_wordpress"] = Provenance{SourceDerived, "WordPress fingerprints: " + strings.Join(info.WordPressSignals, ", "), confidence}
+	}
+
+	if info.WordPressBackend != "" {
+		provenance["wordpress_backend"] = Provenance{SourceHTML, "URLs into WordPress on another host in a " + info.HeadlessFrontend + " page", 0.8}
+	}
+
+	if info.Theme != "" {
+		provenance["theme"] = Provenance{SourceHTML, "asset paths under /wp-content/themes/" + info.Theme + "/", 0.8}
+	}
+	if info.ThemeVersion != "" {
+		provenance["theme_version"] = Provenance{SourceHTML, "ver query string of the theme's assets", 0.6}
+	}
+	if len(info.Plugins) > 0 {
+		provenance["plugins"] = Provenance{SourceHTML, "asset paths under /wp-content/plugins/", 0.8}
+		// The ver query string is often the WordPress version or a cache buster rather than
+		// the plugin's own version
+		provenance["plugin_versions"] = Provenance{SourceHTML, "ver query strings of the plugins' assets", 0.5}
+	}
+
+	if len(info.CachingLayers) > 0 {
+		provenance["caching"] = Provenance{SourceHeader, "cache headers of " + strings.Join(info.CachingLayers, ", "), 0.9}
+	} else if info.CacheControl != "" {
+		provenance["caching"] = Provenance{SourceFallback, "Cache-Control: " + info.CacheControl, 0.4}
+	}
+
+	if info.CDN != "" {
+		if detectCDNHeaders(headers) != "" {
+			provenance["cdn"] = Provenance{SourceHeader, "response headers of " + info.CDN, 0.9}
+		} else {
+			provenance["cdn"] = Provenance{SourceDNS, "CNAME record pointing at " + info.CDN, 0.8}
+		}
+	}
+
+	if info.HostingProvider != "" {
+		provenance["hosting_provider"] = Provenance{SourceGeoIP, "GeoIP lookup of the site's IP address", 0.8}
+	}
+	if info.TLSVersion != "" {
+		provenance["tls_version"] = Provenance{SourceTLS, "TLS handshake with the server", 1}
+		provenance["ssl_valid"] = Provenance{SourceTLS, "certificate verification against the system roots", 1}
+	}
+
+	// Support statuses are only as reliable as the versions they are looked up for
+	for _, status := range []struct{ field, version, product string }{
+		{"php_status", "php_version", "PHP"},
+		{"mysql_status", "mysql_version", "database"},
+		{"wordpress_status", "wordpress_version", "WordPress"},
+		{"web_server_status", "web_server_version", "web server"},
+	} {
+		if from, ok := provenance[status.version]; ok {
+			provenance[status.field] = Provenance{SourceDerived, "end-of-life data for the detected " + status.product + " version", from.Confidence}
+		}
+	}
+	if isWordPressFork(info.CMS) && info.CMSVersion != "" {
+		provenance["wordpress_status"] = Provenance{SourceDerived, "end-of-life data for the detected " + info.CMS + " version", provenance["cms"].Confidence}
+	}
+	return provenance
+}
+
+// Unknown replaces a value strict mode will not guess
+const Unknown = "Unknown"
+
+// minStrictConfidence is the confidence below which strict mode reports a value as Unknown
+const minStrictConfidence = 0.7
+
+// strictFields suppress each guessable finding, keyed by its provenance key
+var strictFields = map[string]func(info *SiteInfo){
+	"wordpress_version_range": func(info *SiteInfo) { info.WordPressVersionRange = Unknown },
+	"theme_version":           func(info *SiteInfo) { info.ThemeVersion = Unknown },
+	"plugin_versions": func(info *SiteInfo) {
+		for i := range info.Plugins {
+			if info.Plugins[i].Version != "" {
+				info.Plugins[i].Version = Unknown
+			}
+		}
+	},
+	"php_status":        func(info *SiteInfo) { info.PHPStatus = Unknown },
+	"mysql_status":      func(info *SiteInfo) { info.MySQLStatus = Unknown },
+	"wordpress_status":  func(info *SiteInfo) { info.WordPressStatus = Unknown },
+	"web_server_status": func(info *SiteInfo) { info.WebServerStatus = Unknown },
+}
+
+// applyStrict reports the findings whose confidence is too low as Unknown rather than guessed
+func applyStrict(info *SiteInfo, provenance map[string]Provenance) {
+	for field, from := range provenance {
+		if suppress, ok := strictFields[field]; ok && from.Confidence < minStrictConfidence {
+			suppress(info)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package siteinfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PurgeCheck reports whether a CDN or page cache serves fresh content after a purge. Status is
+// "Fresh" when the cache missed or its copy matches the origin, "Stale" when it serves a copy
+// that differs from the origin, "Not Cached" when no cache is in front of the page and
+// "Unverified" when the cached copy cannot be compared with the origin.
+type PurgeCheck struct {
+	Status      string `json:"status"`
+	CacheStatus string `json:"cache_status,omitempty"`
+	Age         string `json:"age,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// purgeParam is the query parameter whose timestamp makes each cache-busting URL unique
+const purgeParam = "site-info-fetcher-purge"
+
+// cacheStatus returns the HIT or MISS status reported by the first cache header found,
+// or "" if the response has none
+func cacheStatus(headers http.Header) string {
+	for _, cache := range cacheHeaders {
+		value := strings.TrimSpace(headers.Get(cache.header))
+		if value == "" {
+			continue
+		}
+		if cache.header == "X-Varnish" {
+			if len(strings.Fields(value)) > 1 {
+				return "HIT"
+			}
+			return "MISS"
+		}
+		return strings.ToUpper(value)
+	}
+	return ""
+}
+
+// isCacheHit reports whether the cache status or Age header shows a cached copy was served
+func isCacheHit(status, age string) bool {
+	if status != "" {
+		return strings.Contains(status, "HIT")
+	}
+	return age != "" && age != "0"
+}
+
+// purgeSnapshot is what one request observed of the page
+type purgeSnapshot struct {
+	status       string
+	age          string
+	etag         string
+	lastModified string
+	bodyHash     [32]byte
+}
+
+// fetchPurgeSnapshot fetches the URL and records its cache status, validators and body hash
+func (s *Scanner) fetchPurgeSnapshot(ctx context.Context, url string) (*purgeSnapshot, error) {
+	resp, body, err := s.fetchPage(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return &purgeSnapshot{
+		status:       cacheStatus(resp.Header),
+		age:          strings.TrimSpace(resp.Header.Get("Age")),
+		etag:         strings.TrimPrefix(resp.Header.Get("ETag"), "W/"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		bodyHash:     sha256.Sum256([]byte(body)),
+	}, nil
+}
+
+// checkPurge compares the page as the cache serves it with a timestamped cache-busting
+// request that must reach the origin, and reports whether the cache holds fresh content
+func (s *Scanner) checkPurge(ctx context.Context, url string) *PurgeCheck {
+	cached, err := s.fetchPurgeSnapshot(ctx, url)
+	if err != nil {
+		return &PurgeCheck{Status: "Failed", Detail: err.Error()}
+	}
+	check := &PurgeCheck{CacheStatus: cached.status, Age: cached.age}
+	if cached.status == "" && cached.age == "" {
+		check.Status = "Not Cached"
+		return check
+	}
+	if !isCacheHit(cached.status, cached.age) {
+		check.Status = "Fresh"
+		check.Detail = "cache missed, content served from origin"
+		return check
+	}
+
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	origin, err := s.fetchPurgeSnapshot(ctx, fmt.Sprintf("%s%s%s=%d", withScheme(url, "http"), separator, purgeParam, time.Now().UnixNano()))
+	if err != nil {
+		check.Status = "Unverified"
+		check.Detail = "cache-busting request failed: " + err.Error()
+		return check
+	}
+	if isCacheHit(origin.status, "") {
+		check.Status = "Unverified"
+		check.Detail = "cache ignores the query string, so the origin copy could not be fetched"
+		return check
+	}
+
+	switch {
+	case cached.etag != "" && origin.etag != "":
+		check.Status, check.Detail = purgeVerdict(cached.etag == origin.etag, "ETag")
+	case cached.lastModified != "" && origin.lastModified != "":
+		check.Status, check.Detail = purgeVerdict(cached.lastModified == origin.lastModified, "Last-Modified")
+	case cached.bodyHash == origin.bodyHash:
+		check.Status, check.Detail = purgeVerdict(true, "content")
+	default:
+		// Pages embedding nonces or timestamps differ on every request, so a mismatch is not proof
+		check.Status = "Unverified"
+		check.Detail = "cached content differs from origin but the page has no ETag or Last-Modified"
+	}
+	return check
+}
+
+// purgeVerdict describes whether the cached copy matched the origin on the compared validator
+func purgeVerdict(match bool, validator string) (string, string) {
+	if match {
+		return "Fresh", "cached " + validator + " matches origin"
+	}
+	return "Stale", "cached " + validator + " differs from origin"
+}
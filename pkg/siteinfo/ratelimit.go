@@ -0,0 +1,102 @@
+package siteinfo
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces requests to a global rate with a token bucket and caps the requests in
+// flight to each host, so portfolios with many sites on one host or behind one WAF do not
+// trigger blocks
+type rateLimiter struct {
+	// rate is the global requests per second; zero is unlimited
+	rate float64
+	// hostConcurrency is the number of requests awaiting a response from each host; zero is unlimited
+	hostConcurrency int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	hosts  map[string]chan struct{}
+}
+
+// newRateLimiter returns a limiter, or nil when neither limit is set
+func newRateLimiter(rate float64, hostConcurrency int) *rateLimiter {
+	if rate <= 0 && hostConcurrency <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: rate, hostConcurrency: hostConcurrency, tokens: 1, last: time.Now(), hosts: map[string]chan struct{}{}}
+}
+
+// take waits for a token from the global bucket, which holds at most one so requests are
+// spread evenly rather than sent in bursts
+func (l *rateLimiter) take(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(1, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// wait blocks until a request to host may be sent. The returned function must be called once
+// the response headers have arrived, freeing the host's slot.
+func (l *rateLimiter) wait(ctx context.Context, host string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	release := func() {}
+	if l.hostConcurrency > 0 {
+		host = strings.ToLower(host)
+		l.mu.Lock()
+		slots, ok := l.hosts[host]
+		if !ok {
+			slots = make(chan struct{}, l.hostConcurrency)
+			l.hosts[host] = slots
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case slots <- struct{}{}:
+		}
+		release = func() { <-slots }
+	}
+
+	if l.rate > 0 {
+		if err := l.take(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// do sends the request with the client once the rate limiter allows it. Time spent waiting
+// does not count towards the client's timeout.
+func (s *Scanner) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	release, err := s.limiter.wait(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.Do(req)
+}
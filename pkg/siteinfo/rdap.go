@@ -0,0 +1,102 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// rdapBaseURL is the RDAP bootstrap service, which redirects each domain lookup to the
+// registry responsible for its TLD
+const rdapBaseURL = "https://rdap.org/domain/"
+
+// DomainRegistration holds the registrar and expiry of the site's registrable domain
+type DomainRegistration struct {
+	Domain          string    `json:"domain"`
+	Registrar       string    `json:"registrar"`
+	Expires         time.Time `json:"expires,omitzero"`
+	DaysUntilExpiry int       `json:"days_until_expiry"`
+	ExpiringSoon    bool      `json:"expiring_soon"`
+}
+
+// rdapDomain is the part of an RDAP domain response used to find the registrar and expiry
+type rdapDomain struct {
+	Events []struct {
+		Action string `json:"eventAction"`
+		Date   string `json:"eventDate"`
+	} `json:"events"`
+	Entities []struct {
+		Roles      []string        `json:"roles"`
+		VCardArray json.RawMessage `json:"vcardArray"`
+	} `json:"entities"`
+}
+
+// lookupDomainExpiry queries RDAP for the registrar and expiry date of the site's registrable
+// domain, flagging it as expiring soon when fewer than warningDays remain
+func (s *Scanner) lookupDomainExpiry(ctx context.Context, url string, warningDays int) (*DomainRegistration, error) {
+	domain := registrableDomain(hostOf(url))
+	req, err := http.NewRequestWithContext(ctx, "GET", rdapBaseURL+domain, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RDAP returned HTTP %d for %s", resp.StatusCode, domain)
+	}
+
+	var rdap rdapDomain
+	if err := json.NewDecoder(resp.Body).Decode(&rdap); err != nil {
+		return nil, err
+	}
+
+	registration := &DomainRegistration{Domain: domain}
+	for _, event := range rdap.Events {
+		if event.Action != "expiration" {
+			continue
+		}
+		if expires, err := time.Parse(time.RFC3339, event.Date); err == nil {
+			registration.Expires = expires
+			registration.DaysUntilExpiry = int(time.Until(expires).Hours() / 24)
+			registration.ExpiringSoon = registration.DaysUntilExpiry < warningDays
+		}
+	}
+	for _, entity := range rdap.Entities {
+		for _, role := range entity.Roles {
+			if role == "registrar" {
+				registration.Registrar = vcardName(entity.VCardArray)
+			}
+		}
+	}
+	return registration, nil
+}
+
+// vcardName returns the formatted name (fn) from an RDAP jCard, e.g.
+// ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Example Registrar"]]]
+func vcardName(raw json.RawMessage) string {
+	var vcard []json.RawMessage
+	if err := json.Unmarshal(raw, &vcard); err != nil || len(vcard) < 2 {
+		return ""
+	}
+	var properties [][]json.RawMessage
+	if err := json.Unmarshal(vcard[1], &properties); err != nil {
+		return ""
+	}
+	for _, property := range properties {
+		var name, value string
+		if len(property) < 4 || json.Unmarshal(property[0], &name) != nil || name != "fn" {
+			continue
+		}
+		if json.Unmarshal(property[3], &value) == nil {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package siteinfo
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// maxRedirectHops is the longest redirect chain not flagged as excessive
+const maxRedirectHops = 3
+
+// RedirectHop is one redirect followed on the way to the final URL
+type RedirectHop struct {
+	URL      string `json:"url"`
+	Status   int    `json:"status"`
+	Location string `json:"location"`
+}
+
+// String formats the hop as status URL -> location
+func (h RedirectHop) String() string {
+	return fmt.Sprintf("%d %s -> %s", h.Status, h.URL, h.Location)
+}
+
+// redirectChain reconstructs the redirects the client followed to reach the response,
+// in the order they were followed
+func redirectChain(resp *http.Response) []RedirectHop {
+	var chain []RedirectHop
+	for previous := resp.Request.Response; previous != nil; previous = previous.Request.Response {
+		hop := RedirectHop{
+			URL:      previous.Request.URL.String(),
+			Status:   previous.StatusCode,
+			Location: previous.Header.Get("Location"),
+		}
+		chain = append([]RedirectHop{hop}, chain...)
+	}
+	return chain
+}
@@ -0,0 +1,105 @@
+package siteinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Remediation is a machine-readable action that resolves a finding, so automation tooling
+// can turn findings into update jobs. Component is one of wordpress, plugin, theme, php,
+// mysql, web-server, certificate, domain or site; Name identifies the plugin, theme or web server.
+type Remediation struct {
+	Action           string `json:"action"`
+	Component        string `json:"component"`
+	Name             string `json:"name,omitempty"`
+	CurrentVersion   string `json:"current_version,omitempty"`
+	SuggestedVersion string `json:"suggested_version,omitempty"`
+	Reason           string `json:"reason"`
+}
+
+// String formats the remediation as action component [name] [current -> suggested]
+func (r Remediation) String() string {
+	parts := []string{r.Action, r.Component}
+	if r.Name != "" {
+		parts = append(parts, r.Name)
+	}
+	switch {
+	case r.SuggestedVersion != "" && r.CurrentVersion != "":
+		parts = append(parts, r.CurrentVersion+" -> "+r.SuggestedVersion)
+	case r.SuggestedVersion != "":
+		parts = append(parts, "to "+r.SuggestedVersion)
+	}
+	return strings.Join(parts, " ")
+}
+
+// latestRelease returns the newest release of a product from the endoflife.date data, or "" if unknown
+func (s *Scanner) latestRelease(ctx context.Context, product string) string {
+	versions, err := s.fetchSupportedVersions(ctx, product)
+	if err != nil || len(versions) == 0 {
+		return ""
+	}
+	latest, _ := versions[0]["latest"].(string)
+	return latest
+}
+
+// remediations derives the remediations for outdated software, abandoned plugins and themes,
+// expiring certificates, blocklisted sites and expiring domains, after the vulnerability remediations found during the scan
+func (s *Scanner) remediations(ctx context.Context, info *SiteInfo) []Remediation {
+	var remediations []Remediation
+	database, _ := databaseProduct(info.MySQLVersion)
+	outdated := []struct {
+		status, component, name, product, version, action string
+	}{
+		{info.WordPressStatus, "wordpress", "", wordPressProduct(info), wordPressRelease(info), "update"},
+		{info.PHPStatus, "php", "", "PHP", info.PHPVersion, "upgrade"},
+		{info.MySQLStatus, "mysql", "", database, info.MySQLVersion, "upgrade"},
+		{info.WebServerStatus, "web-server", info.WebServer, info.WebServer, info.WebServerVersion, "upgrade"},
+	}
+	for _, component := range outdated {
+		if component.status != "Outdated" {
+			continue
+		}
+		remediations = append(remediations, Remediation{
+			Action:           component.action,
+			Component:        component.component,
+			Name:             component.name,
+			CurrentVersion:   component.version,
+			SuggestedVersion: s.latestRelease(ctx, component.product),
+			Reason:           "version is no longer supported",
+		})
+	}
+
+	switch {
+	case info.SSLExpired:
+		remediations = append(remediations, Remediation{Action: "renew", Component: "certificate", Reason: "certificate has expired"})
+	case len(info.SSLIssues) > 0:
+		remediations = append(remediations, Remediation{Action: "fix", Component: "certificate", Reason: "certificate issues: " + strings.Join(info.SSLIssues, ", ")})
+	case info.Certificate != nil && info.Certificate.ExpiringSoon:
+		remediations = append(remediations, Remediation{
+			Action:    "renew",
+			Component: "certificate",
+			Reason:    fmt.Sprintf("certificate expires in %d days", info.Certificate.DaysUntilExpiry),
+		})
+	}
+	if info.Blocklists != nil && info.Blocklists.Flagged {
+		remediations = append(remediations, Remediation{Action: "clean", Component: "site", Reason: "site is blocklisted: " + info.Blocklists.String()})
+	}
+	for _, plugin := range info.Plugins {
+		if plugin.Abandoned != "" {
+			remediations = append(remediations, Remediation{Action: "replace", Component: "plugin", Name: plugin.Slug, Reason: "plugin is abandoned: " + plugin.Abandoned})
+		}
+	}
+	if info.ThemeAbandoned != "" {
+		remediations = append(remediations, Remediation{Action: "replace", Component: "theme", Name: info.Theme, Reason: "theme is abandoned: " + info.ThemeAbandoned})
+	}
+	if info.Domain != nil && info.Domain.ExpiringSoon {
+		remediations = append(remediations, Remediation{
+			Action:    "renew",
+			Component: "domain",
+			Name:      info.Domain.Domain,
+			Reason:    fmt.Sprintf("domain registration expires in %d days", info.Domain.DaysUntilExpiry),
+		})
+	}
+	return remediations
+}
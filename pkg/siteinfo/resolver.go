@@ -0,0 +1,139 @@
+package siteinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+)
+
+// dohTimeout bounds a DNS-over-HTTPS query when the resolver sets no deadline
+const dohTimeout = 10 * time.Second
+
+// NewResolver returns a resolver that sends every query to the given DNS server, as host or
+// host:port (port 53 by default), or to a DNS-over-HTTPS endpoint given as an https:// URL such
+// as https://cloudflare-dns.com/dns-query. The endpoint's own hostname is resolved by the system
+// resolver, so give it by IP address to keep every lookup off the local resolver.
+func NewResolver(server string) (*net.Resolver, error) {
+	if strings.Contains(server, "://") {
+		u, err := neturl.Parse(server)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("DNS-over-HTTPS resolver %q must be an https:// URL with a host", server)
+		}
+		return dohResolver(u.String()), nil
+	}
+
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	if host, _, _ := net.SplitHostPort(server); host == "" {
+		return nil, fmt.Errorf("resolver %q has no host", server)
+	}
+	return resolverAt(server), nil
+}
+
+// resolver returns the resolver for the scan's lookups: the configured one, or the system's
+func (s *Scanner) resolver() *net.Resolver {
+	if s.opts.Resolver != nil {
+		return s.opts.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// dohResolver returns a resolver that sends every query to the DNS-over-HTTPS endpoint
+func dohResolver(endpoint string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, endpoint: endpoint}, nil
+		},
+	}
+}
+
+// dohConn carries the Go resolver's queries to a DNS-over-HTTPS endpoint. It is not a
+// net.PacketConn, so the resolver frames each message with its length as over TCP; each
+// complete query written is POSTed to the endpoint and its answer is read back the same way.
+type dohConn struct {
+	ctx      context.Context
+	endpoint string
+	deadline time.Time
+	query    bytes.Buffer
+	answer   bytes.Reader
+}
+
+// Write buffers the query, sending it once the whole message has been written
+func (c *dohConn) Write(p []byte) (int, error) {
+	c.query.Write(p)
+	buffered := c.query.Bytes()
+	if len(buffered) < 2 || len(buffered) < 2+int(binary.BigEndian.Uint16(buffered)) {
+		return len(p), nil
+	}
+	answer, err := c.exchange(buffered[2:])
+	c.query.Reset()
+	if err != nil {
+		return 0, err
+	}
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(answer)))
+	c.answer.Reset(append(framed, answer...))
+	return len(p), nil
+}
+
+// exchange POSTs the DNS message to the endpoint as RFC 8484 describes and returns the answer
+func (c *dohConn) exchange(message []byte) ([]byte, error) {
+	deadline := c.deadline
+	if deadline.IsZero() {
+		deadline = time.Now().Add(dohTimeout)
+	}
+	ctx, cancel := context.WithDeadline(c.ctx, deadline)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, bytes.NewReader(message))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS resolver returned HTTP %d for %s", resp.StatusCode, c.endpoint)
+	}
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		return nil, err
+	}
+	if len(answer) >= 1<<16 {
+		return nil, fmt.Errorf("DNS-over-HTTPS answer from %s is too large", c.endpoint)
+	}
+	return answer, nil
+}
+
+// Read returns the framed answer to the last query
+func (c *dohConn) Read(p []byte) (int, error) {
+	return c.answer.Read(p)
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr(c.endpoint) }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr(c.endpoint) }
+func (c *dohConn) SetDeadline(t time.Time) error      { c.deadline = t; return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { c.deadline = t; return nil }
+
+// dohAddr is the address of a DNS-over-HTTPS endpoint
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,284 @@
+package siteinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// retestCheck re-runs one detector against a site and describes its result
+type retestCheck struct {
+	run      func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error
+	describe func(info *SiteInfo) string
+}
+
+// fetchHomepage fetches the homepage into info, recording the final URL, and returns its body
+func (s *Scanner) fetchHomepage(ctx context.Context, url string, info *SiteInfo) (string, error) {
+	resp, body, err := s.fetchPage(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	info.FinalURL = resp.Request.URL.String()
+	info.RedirectChain = redirectChain(resp)
+	info.ExcessiveRedirects = len(info.RedirectChain) > maxRedirectHops
+	info.SecurityHeaders = gradeSecurityHeaders(resp.Header, resp.TLS != nil)
+	info.HSTS = parseHSTS(resp.Header, resp.TLS != nil)
+	return body, nil
+}
+
+// retestChecks are the detectors that can be re-run on their own, keyed by name
+var retestChecks = map[string]retestCheck{
+	"ssl": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			ssl, err := s.checkSSL(ctx, url)
+			if err != nil && !errors.Is(err, errCertificateExpired) {
+				return err
+			}
+			if len(ssl.state.PeerCertificates) > 0 {
+				info.Certificate = describeCertificate(ssl.state.PeerCertificates[0], s.opts.ExpiryWarningDays)
+				info.ChainStatus = s.chainStatus(ctx, ssl.state.PeerCertificates)
+			}
+			info.SSLExpired = errors.Is(err, errCertificateExpired)
+			info.SSLValid = ssl.valid
+			info.CertificateHostnameMismatch = ssl.hostnameMismatch
+			info.SSLIssues = certificateIssues(ssl, info.SSLExpired, info.ChainStatus)
+			return nil
+		},
+		describe: func(info *SiteInfo) string {
+			if info.Certificate == nil {
+				return fmt.Sprintf("valid=%t expired=%t", info.SSLValid, info.SSLExpired)
+			}
+			return fmt.Sprintf("valid=%t expired=%t expires in %d days, chain %s, hostname mismatch=%t, issues=%s",
+				info.SSLValid, info.SSLExpired, info.Certificate.DaysUntilExpiry, info.ChainStatus, info.CertificateHostnameMismatch, strings.Join(info.SSLIssues, ", "))
+		},
+	},
+	"ttfb": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			ttfs, err := s.sampleTTFB(ctx, url)
+			if err != nil {
+				return err
+			}
+			info.TTFBs = ttfs
+			info.AverageTTFB, info.TTFBStats = ttfbStatistics(ttfs)
+			return nil
+		},
+		describe: func(info *SiteInfo) string {
+			return fmt.Sprintf("average TTFB %.3fms, median %.3fms, p95 %.3fms, stddev %.3fms", Milliseconds(info.AverageTTFB),
+				Milliseconds(info.TTFBStats.Median), Milliseconds(info.TTFBStats.P95), Milliseconds(info.TTFBStats.StdDev))
+		},
+	},
+	"headers": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			_, err := s.fetchHomepage(ctx, url, info)
+			return err
+		},
+		describe: func(info *SiteInfo) string {
+			if info.SecurityHeaders == nil {
+				return ""
+			}
+			return fmt.Sprintf("grade %s (score %d), issues: %s", info.SecurityHeaders.Grade, info.SecurityHeaders.Score, strings.Join(info.SecurityHeaders.Issues, "; "))
+		},
+	},
+	"redirects": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			_, err := s.fetchHomepage(ctx, url, info)
+			return err
+		},
+		describe: func(info *SiteInfo) string {
+			hops := []string{}
+			for _, hop := range info.RedirectChain {
+				hops = append(hops, hop.String())
+			}
+			return fmt.Sprintf("final URL %s, %d hops: %s", info.FinalURL, len(hops), strings.Join(hops, "; "))
+		},
+	},
+	"canonical": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			if _, err := s.fetchHomepage(ctx, url, info); err != nil {
+				return err
+			}
+			info.Canonicalization = s.checkCanonicalization(ctx, info.FinalURL)
+			return nil
+		},
+		describe: func(info *SiteInfo) string {
+			if info.Canonicalization == nil {
+				return ""
+			}
+			var probes []string
+			for _, probe := range info.Canonicalization.Probes {
+				probes = append(probes, probe.URL+": "+probe.Result)
+			}
+			return fmt.Sprintf("ok=%t, %s", info.Canonicalization.OK, strings.Join(probes, "; "))
+		},
+	},
+	"mixed-content": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			body, err := s.fetchHomepage(ctx, url, info)
+			info.MixedContent = checkMixedContent(body, info.FinalURL)
+			return err
+		},
+		describe: func(info *SiteInfo) string {
+			return fmt.Sprintf("%d insecure resources: %s", len(info.MixedContent), strings.Join(info.MixedContent, "; "))
+		},
+	},
+	"cors": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			var findings []corsFinding
+			info.CORSAllowOrigin, info.CORSCredentials, findings = s.checkCORS(ctx, url)
+			info.CORSIssues = corsIssues(findings)
+			return nil
+		},
+		describe: func(info *SiteInfo) string {
+			return fmt.Sprintf("allow origin %q, credentials=%t, issues: %s", info.CORSAllowOrigin, info.CORSCredentials, strings.Join(info.CORSIssues, "; "))
+		},
+	},
+	"dns": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			info.DNS = s.collectDNS(ctx, url)
+			return nil
+		},
+		describe: func(info *SiteInfo) string {
+			if info.DNS == nil {
+				return ""
+			}
+			return fmt.Sprintf("A %s, AAAA %s, CNAME %s", strings.Join(info.DNS.A, " "), strings.Join(info.DNS.AAAA, " "), info.DNS.CNAME)
+		},
+	},
+	"propagation": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			info.Propagation = checkPropagation(ctx, url)
+			return nil
+		},
+		describe: func(info *SiteInfo) string {
+			if info.Propagation == nil {
+				return ""
+			}
+			return info.Propagation.Status + " (" + info.Propagation.String() + ")"
+		},
+	},
+	"purge": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			info.Purge = s.checkPurge(ctx, url)
+			return nil
+		},
+		describe: func(info *SiteInfo) string {
+			if info.Purge == nil {
+				return ""
+			}
+			if info.Purge.Detail == "" {
+				return info.Purge.Status
+			}
+			return info.Purge.Status + " (" + info.Purge.Detail + ")"
+		},
+	},
+	"revalidation": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			resp, err := s.doRequest(ctx, "GET", url, nil)
+			if err != nil {
+				return err
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			info.Revalidation = s.checkRevalidation(ctx, resp.Request.URL.String(), resp.Header)
+			return nil
+		},
+		describe: func(info *SiteInfo) string {
+			if info.Revalidation == nil {
+				return ""
+			}
+			if info.Revalidation.StatusCode == 0 {
+				return info.Revalidation.Status
+			}
+			return fmt.Sprintf("%s (HTTP %d)", info.Revalidation.Status, info.Revalidation.StatusCode)
+		},
+	},
+	"ipv6": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			info.IPv6 = s.checkIPv6(ctx, url)
+			return nil
+		},
+		describe: func(info *SiteInfo) string { return info.IPv6 },
+	},
+	"mail": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			info.MailAuth = s.checkMailAuth(ctx, url)
+			return nil
+		},
+		describe: func(info *SiteInfo) string {
+			if info.MailAuth == nil {
+				return ""
+			}
+			return fmt.Sprintf("SPF %s, DMARC %s, DKIM %s", info.MailAuth.SPFPolicy, info.MailAuth.DMARCPolicy, strings.Join(info.MailAuth.DKIMSelectors, " "))
+		},
+	},
+	"acme": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			info.ACMEChallenge = s.checkACMEChallenge(ctx, url)
+			return nil
+		},
+		describe: func(info *SiteInfo) string { return info.ACMEChallenge },
+	},
+	"compression": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			var err error
+			info.Compression, info.CompressedSize, err = s.checkCompression(ctx, url)
+			return err
+		},
+		describe: func(info *SiteInfo) string {
+			return fmt.Sprintf("%s, %d bytes transferred", info.Compression, info.CompressedSize)
+		},
+	},
+	"domain-expiry": {
+		run: func(ctx context.Context, s *Scanner, url string, info *SiteInfo) error {
+			var err error
+			info.Domain, err = s.lookupDomainExpiry(ctx, url, s.opts.ExpiryWarningDays)
+			return err
+		},
+		describe: func(info *SiteInfo) string {
+			if info.Domain == nil {
+				return ""
+			}
+			return fmt.Sprintf("registrar %s, expires in %d days", info.Domain.Registrar, info.Domain.DaysUntilExpiry)
+		},
+	},
+}
+
+// Checks returns the names of the detectors that can be re-run with Retest
+func Checks() []string {
+	names := make([]string, 0, len(retestChecks))
+	for name := range retestChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Retest re-runs a single detector against the site, filling in only the fields it reports,
+// so a fix can be verified without a full scan
+func (s *Scanner) Retest(ctx context.Context, url, check string) (*SiteInfo, error) {
+	retest, ok := retestChecks[check]
+	if !ok {
+		return nil, fmt.Errorf("unknown check %q (available: %s)", check, strings.Join(Checks(), ", "))
+	}
+	if err := s.allowTarget(url); err != nil {
+		return nil, err
+	}
+	info := &SiteInfo{URL: url}
+	ctx, _ = s.checkOwnership(ctx, info, asciiURL(url))
+	if err := retest.run(ctx, s, asciiURL(url), info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// DescribeCheck summarizes the fields a detector reports, for comparing a retest with an earlier scan
+func DescribeCheck(info *SiteInfo, check string) string {
+	retest, ok := retestChecks[check]
+	if !ok {
+		return ""
+	}
+	return retest.describe(info)
+}
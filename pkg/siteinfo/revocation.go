@@ -0,0 +1,287 @@
+package siteinfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// maxCRLSize bounds the download of a certificate revocation list
+const maxCRLSize = 10 << 20
+
+// oidOCSPBasic identifies a basic OCSP response
+var oidOCSPBasic = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+// oidSHA1 identifies SHA-1, which OCSP certificate IDs are hashed with
+var oidSHA1 = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+
+// ocspSignatureAlgorithms maps the signature algorithms OCSP responders sign with to their x509 form
+var ocspSignatureAlgorithms = map[string]x509.SignatureAlgorithm{
+	"1.2.840.113549.1.1.5":  x509.SHA1WithRSA,
+	"1.2.840.113549.1.1.11": x509.SHA256WithRSA,
+	"1.2.840.113549.1.1.12": x509.SHA384WithRSA,
+	"1.2.840.113549.1.1.13": x509.SHA512WithRSA,
+	"1.2.840.10045.4.1":     x509.ECDSAWithSHA1,
+	"1.2.840.10045.4.3.2":   x509.ECDSAWithSHA256,
+	"1.2.840.10045.4.3.3":   x509.ECDSAWithSHA384,
+	"1.2.840.10045.4.3.4":   x509.ECDSAWithSHA512,
+	"1.3.101.112":           x509.PureEd25519,
+}
+
+// ocspCertID identifies the certificate an OCSP request or response is about
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+// ocspRequest is an OCSP request for a single certificate
+type ocspRequest struct {
+	TBSRequest struct {
+		RequestList []struct {
+			Cert ocspCertID
+		}
+	}
+}
+
+// ocspResponse is the envelope of an OCSP response
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response struct {
+		ResponseType asn1.ObjectIdentifier
+		Response     []byte
+	} `asn1:"explicit,tag:0,optional"`
+}
+
+// ocspBasicResponse is a basic OCSP response with the responder's signature
+type ocspBasicResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+// ocspResponseData holds the status of each certificate in a basic OCSP response
+type ocspResponseData struct {
+	Version     int `asn1:"optional,default:0,explicit,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []ocspSingleResponse
+	Extensions  []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// ocspSingleResponse is the status of one certificate. The status is good, revoked or
+// unknown by its context tag.
+type ocspSingleResponse struct {
+	CertID     ocspCertID
+	Status     asn1.RawValue
+	ThisUpdate time.Time        `asn1:"generalized"`
+	NextUpdate time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	Extensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// ocspStatuses names the certificate statuses of an OCSP response, by context tag
+var ocspStatuses = map[int]string{0: "Good", 1: "Revoked", 2: "Unknown"}
+
+// newOCSPCertID returns the ID of the certificate issued by issuer
+func newOCSPCertID(cert, issuer *x509.Certificate) (ocspCertID, error) {
+	var publicKey struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKey); err != nil {
+		return ocspCertID{}, err
+	}
+	nameHash := sha1.New()
+	nameHash.Write(issuer.RawSubject)
+	keyHash := sha1.New()
+	keyHash.Write(publicKey.PublicKey.RightAlign())
+	return ocspCertID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.NullRawValue},
+		NameHash:      nameHash.Sum(nil),
+		IssuerKeyHash: keyHash.Sum(nil),
+		SerialNumber:  cert.SerialNumber,
+	}, nil
+}
+
+// parseOCSPResponse returns the status an OCSP response gives the certificate: Good, Revoked
+// or Unknown. The response must be signed by the issuer, or by a responder the issuer
+// delegated to.
+func parseOCSPResponse(der []byte, cert, issuer *x509.Certificate) (string, error) {
+	var resp ocspResponse
+	if _, err := asn1.Unmarshal(der, &resp); err != nil {
+		return "", err
+	}
+	if resp.Status != 0 {
+		return "", fmt.Errorf("OCSP responder returned status %d", resp.Status)
+	}
+	if !resp.Response.ResponseType.Equal(oidOCSPBasic) {
+		return "", errors.New("unsupported OCSP response type")
+	}
+	var basic ocspBasicResponse
+	if _, err := asn1.Unmarshal(resp.Response.Response, &basic); err != nil {
+		return "", err
+	}
+	var data ocspResponseData
+	if _, err := asn1.Unmarshal(basic.TBSResponseData.FullBytes, &data); err != nil {
+		return "", err
+	}
+
+	// Verify the signature, against a delegated responder certificate when one is included
+	algorithm, ok := ocspSignatureAlgorithms[basic.SignatureAlgorithm.Algorithm.String()]
+	if !ok {
+		return "", fmt.Errorf("unsupported OCSP signature algorithm %s", basic.SignatureAlgorithm.Algorithm)
+	}
+	signer := issuer
+	if len(basic.Certificates) > 0 {
+		responder, err := x509.ParseCertificate(basic.Certificates[0].FullBytes)
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Equal(responder.Raw, issuer.Raw) {
+			if err := responder.CheckSignatureFrom(issuer); err != nil {
+				return "", fmt.Errorf("OCSP responder certificate not issued by the issuer: %w", err)
+			}
+			signer = responder
+		}
+	}
+	if err := signer.CheckSignature(algorithm, basic.TBSResponseData.FullBytes, basic.Signature.RightAlign()); err != nil {
+		return "", fmt.Errorf("invalid OCSP response signature: %w", err)
+	}
+
+	for _, single := range data.Responses {
+		if single.CertID.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			continue
+		}
+		if !single.NextUpdate.IsZero() && time.Now().After(single.NextUpdate) {
+			return "", errors.New("stale OCSP response")
+		}
+		if status, ok := ocspStatuses[single.Status.Tag]; ok && single.Status.Class == asn1.ClassContextSpecific {
+			return status, nil
+		}
+	}
+	return "", errors.New("OCSP response does not cover the certificate")
+}
+
+// queryOCSP asks the certificate's OCSP responder for its status
+func (s *Scanner) queryOCSP(ctx context.Context, cert, issuer *x509.Certificate) (string, error) {
+	id, err := newOCSPCertID(cert, issuer)
+	if err != nil {
+		return "", err
+	}
+	var request ocspRequest
+	request.TBSRequest.RequestList = append(request.TBSRequest.RequestList, struct{ Cert ocspCertID }{id})
+	der, err := asn1.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", cert.OCSPServer[0], bytes.NewReader(der))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OCSP responder returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	return parseOCSPResponse(body, cert, issuer)
+}
+
+// queryCRL looks the certificate up in its issuer's revocation list, for CAs that have
+// stopped running OCSP responders
+func (s *Scanner) queryCRL(ctx context.Context, cert, issuer *x509.Certificate) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", cert.CRLDistributionPoints[0], nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.do(s.client, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("CRL distribution point returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCRLSize))
+	if err != nil {
+		return "", err
+	}
+	crl, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return "", err
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return "", fmt.Errorf("invalid CRL signature: %w", err)
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return "Revoked", nil
+		}
+	}
+	return "Good", nil
+}
+
+// checkRevocation reports whether the server staples an OCSP response to the handshake, and
+// whether the certificate has been revoked: Good, Revoked or Unknown when neither the stapled
+// response, the CA's OCSP responder nor its revocation list could tell. A revoked certificate
+// still verifies until it expires, as verification does not check revocation.
+func (s *Scanner) checkRevocation(ctx context.Context, url string, state tls.ConnectionState) (string, string) {
+	stapling := "Not Stapled"
+	if len(state.OCSPResponse) > 0 {
+		stapling = "Stapled"
+	}
+	certs := state.PeerCertificates
+	leaf := certs[0]
+	var issuer *x509.Certificate
+	if len(certs) > 1 {
+		issuer = certs[1]
+	} else if len(leaf.IssuingCertificateURL) > 0 {
+		issuer, _ = s.fetchIssuer(ctx, leaf.IssuingCertificateURL[0])
+	}
+	if issuer == nil {
+		return stapling, "Unknown"
+	}
+
+	log := s.log(url)
+	if len(state.OCSPResponse) > 0 {
+		status, err := parseOCSPResponse(state.OCSPResponse, leaf, issuer)
+		if err == nil {
+			return stapling, status
+		}
+		log.Warn("Invalid stapled OCSP response", "error", err)
+	}
+	if len(leaf.OCSPServer) > 0 {
+		status, err := s.queryOCSP(ctx, leaf, issuer)
+		if err == nil {
+			return stapling, status
+		}
+		log.Warn("OCSP query failed", "error", err)
+	}
+	if len(leaf.CRLDistributionPoints) > 0 {
+		status, err := s.queryCRL(ctx, leaf, issuer)
+		if err == nil {
+			return stapling, status
+		}
+		log.Warn("CRL lookup failed", "error", err)
+	}
+	return stapling, "Unknown"
+}
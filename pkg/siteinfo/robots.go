@@ -0,0 +1,216 @@
+package siteinfo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// errRobotsDisallowed is returned for requests to paths the site's robots.txt disallows
+var errRobotsDisallowed = errors.New("disallowed by robots.txt")
+
+// robotsRule is an Allow or Disallow line of robots.txt
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules are the rules of the robots.txt group that applies to one user agent
+type robotsRules []robotsRule
+
+// parseRobots returns the rules for the user agent's product token, falling back to the
+// rules for every crawler (User-agent: *) when no group names it
+func parseRobots(body, userAgent string) robotsRules {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var named, wildcard robotsRules
+	var agents []string
+	inRules := false
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			// An empty Disallow allows everything
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{pattern: value, allow: field == "allow"}
+			for _, agent := range agents {
+				switch {
+				case agent == "*":
+					wildcard = append(wildcard, rule)
+				case token != "" && agent == token:
+					named = append(named, rule)
+				}
+			}
+		}
+	}
+	if named != nil {
+		return named
+	}
+	return wildcard
+}
+
+// allowed reports whether the path, including any query, may be requested. The longest
+// matching rule wins, and Allow wins a tie.
+func (r robotsRules) allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	allow, longest := true, -1
+	for _, rule := range r {
+		if !robotsPatternMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allow, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allow
+}
+
+// robotsPatternMatch matches a robots.txt path pattern, where * matches any characters and
+// a trailing $ anchors the end of the path
+func robotsPatternMatch(pattern, path string) bool {
+	expr := regexp.QuoteMeta(strings.TrimSuffix(pattern, "$"))
+	expr = "^" + strings.ReplaceAll(expr, `\*`, ".*")
+	if strings.HasSuffix(pattern, "$") {
+		expr += "$"
+	}
+	matched, _ := regexp.MatchString(expr, path)
+	return matched
+}
+
+// robotsPolicy holds the robots.txt rules for the site being scanned and records the
+// requests they blocked
+type robotsPolicy struct {
+	hosts map[string]bool
+	rules robotsRules
+
+	mu      sync.Mutex
+	blocked []string
+}
+
+// robotsPolicyKey is the context key of the site's robots policy
+type robotsPolicyKey struct{}
+
+// permits reports whether the request may be sent. The homepage and robots.txt itself are
+// always allowed, as are other hosts such as third-party asset servers.
+func (p *robotsPolicy) permits(u *neturl.URL) bool {
+	if !p.hosts[strings.ToLower(u.Hostname())] {
+		return true
+	}
+	path := u.EscapedPath()
+	if (path == "" || path == "/") && u.RawQuery == "" || path == "/robots.txt" {
+		return true
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if p.rules.allowed(path) {
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, blocked := range p.blocked {
+		if blocked == path {
+			return false
+		}
+	}
+	p.blocked = append(p.blocked, path)
+	return false
+}
+
+// blockedPaths returns the paths that were not requested because robots.txt disallows them
+func (p *robotsPolicy) blockedPaths() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.blocked...)
+}
+
+// robotsTransport refuses requests the robots policy in the request context disallows
+type robotsTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip sends the request unless robots.txt disallows it
+func (t *robotsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy, _ := req.Context().Value(robotsPolicyKey{}).(*robotsPolicy)
+	if policy != nil && !policy.permits(req.URL) {
+		return nil, fmt.Errorf("%s: %w", req.URL.Path, errRobotsDisallowed)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// checkRobots fetches robots.txt from the site's origin and reports "Not Found", "Allows
+// Indexing", "Blocks Indexing" when it disallows the whole site to every crawler, or
+// "Failed". It also returns the rules for the scanner's user agent; when robots.txt cannot
+// be fetched because of a server error, everything but the homepage is disallowed, as
+// search engines do.
+func (s *Scanner) checkRobots(ctx context.Context, finalURL string) (string, robotsRules) {
+	u, err := neturl.Parse(finalURL)
+	if err != nil {
+		return "Failed", nil
+	}
+	resp, body, err := s.fetchPage(ctx, u.Scheme+"://"+u.Host+"/robots.txt")
+	disallowAll := robotsRules{{pattern: "/"}}
+	switch {
+	case err != nil:
+		return "Failed", disallowAll
+	case resp.StatusCode >= 500:
+		return fmt.Sprintf("Failed (HTTP %d)", resp.StatusCode), disallowAll
+	case resp.StatusCode >= 400:
+		return "Not Found", nil
+	}
+
+	status := "Allows Indexing"
+	if !parseRobots(body, "*").allowed("/") {
+		status = "Blocks Indexing"
+	}
+	return status, parseRobots(body, s.userAgent())
+}
+
+// userAgent returns the User-Agent sent with requests
+func (s *Scanner) userAgent() string {
+	if s.opts.UserAgent != "" {
+		return s.opts.UserAgent
+	}
+	if s.opts.BrowserProfile {
+		return browserUserAgent
+	}
+	return "Go-http-client"
+}
+
+// withRobots returns a context whose requests to the site's hosts honour the rules
+func withRobots(ctx context.Context, rules robotsRules, urls ...string) (context.Context, *robotsPolicy) {
+	policy := &robotsPolicy{hosts: map[string]bool{}, rules: rules}
+	for _, url := range urls {
+		policy.hosts[strings.ToLower(hostOf(url))] = true
+	}
+	return context.WithValue(ctx, robotsPolicyKey{}, policy), policy
+}
@@ -0,0 +1,69 @@
+package siteinfo
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// Scan statuses, recording whether the homepage was scanned or why it could not be
+const (
+	ScanOK                = "OK"
+	ScanDNSFailure        = "DNS failure"
+	ScanTimeout           = "Timeout"
+	ScanTLSError          = "TLS error"
+	ScanConnectionRefused = "Connection refused"
+	ScanServerError       = "HTTP 5xx"
+	ScanFailed            = "Failed"
+)
+
+// ScanError is the error ScanAll returns for a site that could not be scanned
+type ScanError struct {
+	URL string
+	Err error
+}
+
+// Error formats the error as url: error
+func (e *ScanError) Error() string {
+	return e.URL + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error the scan failed with
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// scanStatus classifies the error a scan failed with
+func scanStatus(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var alertErr tls.AlertError
+	var recordErr tls.RecordHeaderError
+	switch {
+	case errors.As(err, &dnsErr):
+		return ScanDNSFailure
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ScanTimeout
+	case isCertificateError(err), errors.As(err, &alertErr), errors.As(err, &recordErr),
+		strings.Contains(err.Error(), "tls: "):
+		return ScanTLSError
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ScanConnectionRefused
+	}
+	return ScanFailed
+}
+
+// FailedSite returns the report row of a site that could not be scanned, so reports list
+// every input URL: its scan status describes the failure and ScanError holds the error
+func FailedSite(url string, err error) *SiteInfo {
+	var scanErr *ScanError
+	if errors.As(err, &scanErr) {
+		err = scanErr.Err
+	}
+	return &SiteInfo{URL: url, ScanStatus: scanStatus(err), ScanError: err.Error()}
+}
@@ -0,0 +1,970 @@
+package siteinfo
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/fingerprint"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/geoip"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/vuln"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Options configures a Scanner
+type Options struct {
+	// Timeout bounds each HTTP request as a whole. Defaults to 10 seconds, or no overall bound
+	// when a per-phase timeout is set.
+	Timeout time.Duration
+	// DNSTimeout bounds the hostname lookup of each connection. Zero leaves it to the overall timeout.
+	DNSTimeout time.Duration
+	// ConnectTimeout bounds each TCP connection attempt. Zero leaves it to the overall timeout.
+	ConnectTimeout time.Duration
+	// TLSTimeout bounds each TLS handshake. Defaults to 10 seconds.
+	TLSTimeout time.Duration
+	// HeaderTimeout bounds the wait for response headers once the request is sent.
+	// Zero leaves it to the overall timeout.
+	HeaderTimeout time.Duration
+	// BodyTimeout bounds the time spent reading each response body once its headers arrive.
+	// Zero leaves it to the overall timeout.
+	BodyTimeout time.Duration
+	// Retries is the number of attempts made when a request times out awaiting headers. Defaults to 5.
+	Retries int
+	// Samples is the number of TTFB measurements taken per site. Defaults to 3.
+	Samples int
+	// WarmupSamples is the number of requests made and discarded before sampling TTFB.
+	WarmupSamples int
+	// RateLimit is the maximum number of requests per second across all sites. Zero is unlimited.
+	RateLimit float64
+	// HostConcurrency caps the requests awaiting a response from each host. Zero is unlimited.
+	HostConcurrency int
+	// Concurrency is the number of sites ScanAll scans in parallel. Defaults to 1.
+	Concurrency int
+	// BaselineURL is a reference endpoint whose TTFB is measured periodically during the run.
+	// Results are annotated when it slows down, showing the scanner's own network degraded. Empty disables it.
+	BaselineURL string
+	// CalibrationInterval is how often the baseline is re-measured. Defaults to 1 minute.
+	CalibrationInterval time.Duration
+	// SkipWarmup disables the DNS and connection warmup ScanAll runs before measuring.
+	SkipWarmup bool
+	// UserAgent is sent with every request. Defaults to Go's user agent.
+	UserAgent string
+	// BrowserProfile sends the headers of desktop Chrome with every request, and its User-Agent
+	// unless UserAgent is set, so sites that filter bots do not appear to be down.
+	BrowserProfile bool
+	// SkipSearch disables the WordPress search results probe.
+	SkipSearch bool
+	// SkipErrorPage disables the 404 page check.
+	SkipErrorPage bool
+	// SkipCORS disables the CORS policy audit.
+	SkipCORS bool
+	// SkipDNS disables the collection of A, AAAA, CNAME, MX, NS, TXT and PTR records.
+	SkipDNS bool
+	// SkipMailAuth disables the SPF, DKIM and DMARC checks.
+	SkipMailAuth bool
+	// SkipWPJSON disables the REST API fallback used when the WordPress generator tag is stripped,
+	// and the login page probe used when the site shows no sign of WordPress at all.
+	SkipWPJSON bool
+	// SkipACME disables the Let's Encrypt HTTP-01 challenge path check.
+	SkipACME bool
+	// SkipContactForm disables the form plugin and contact form detection, which may fetch the contact page.
+	SkipContactForm bool
+	// SkipEcommerce disables the payment gateway and checkout checks on detected stores.
+	SkipEcommerce bool
+	// SkipIPv6 disables the IPv6 reachability check.
+	SkipIPv6 bool
+	// SkipCompression disables the gzip and Brotli compression check.
+	SkipCompression bool
+	// SkipRobots disables the robots.txt check.
+	SkipRobots bool
+	// RespectRobots skips the requests to paths the site's robots.txt disallows, such as the REST
+	// API, XML-RPC and sitemap probes. The homepage is always scanned.
+	RespectRobots bool
+	// SkipVary disables the Vary header and cookie cache bypass audit.
+	SkipVary bool
+	// SkipRevalidation disables the conditional request made with the homepage's ETag and
+	// Last-Modified validators.
+	SkipRevalidation bool
+	// SkipCanonical disables the HTTP to HTTPS and www canonicalization probes.
+	SkipCanonical bool
+	// SkipTLSEndpoints disables the comparison of certificates across the addresses a host resolves to.
+	SkipTLSEndpoints bool
+	// FetchAssets adds the size of every script, stylesheet and image to the page weight,
+	// which otherwise only counts the HTML.
+	FetchAssets bool
+	// CheckOpenRedirect enables the active open redirect probe.
+	CheckOpenRedirect bool
+	// CheckLoginTTFB measures the TTFB of /wp-login.php on WordPress sites.
+	CheckLoginTTFB bool
+	// CheckExposure enables the probe for exposed xmlrpc.php, wp-login.php, readme.html and debug artifacts.
+	CheckExposure bool
+	// CheckDomainExpiry looks up the registrar and expiry of each site's domain over RDAP.
+	CheckDomainExpiry bool
+	// CheckPropagation compares the hostname's A and AAAA records across public resolvers.
+	CheckPropagation bool
+	// CheckPurge compares the cached homepage with a cache-busting request to the origin to
+	// verify a CDN or page cache purge took effect.
+	CheckPurge bool
+	// CheckSmuggling flags server and proxy combinations historically associated with HTTP request smuggling.
+	CheckSmuggling bool
+	// CheckHotlink requests each asset the homepage loads with the page as Referer and Origin
+	// to find assets that hotlink protection or missing CORS headers block for visitors.
+	CheckHotlink bool
+	// CheckPluginUpdates compares each plugin's detected version with its latest release in the
+	// wordpress.org plugin directory.
+	CheckPluginUpdates bool
+	// CheckAbandonment flags plugins and themes closed in the wordpress.org directory or not
+	// updated there in two years.
+	CheckAbandonment bool
+	// CheckLicenses reads the license of each plugin and the theme from their readme.txt and
+	// style.css headers, falling back to their wordpress.org directory listing.
+	CheckLicenses bool
+	// CheckTLSAudit enables the probe of which TLS protocol versions the server accepts.
+	CheckTLSAudit bool
+	// CheckRevocation checks for a stapled OCSP response and whether the certificate has been
+	// revoked, through the stapled response, the CA's OCSP responder or its revocation list.
+	CheckRevocation bool
+	// CheckCT looks up the certificates Certificate Transparency logs hold for each domain on
+	// crt.sh, flagging CAs other than the one the site uses and ExpectedIssuers.
+	CheckCT bool
+	// CheckHSTSPreload looks each hostname up on the HSTS preload list at hstspreload.org.
+	CheckHSTSPreload bool
+	// CheckBlocklists looks each domain and its addresses up on the Spamhaus, SURBL and URIBL
+	// DNS blocklists, and each URL on Google Safe Browsing when SafeBrowsingKey is set.
+	CheckBlocklists bool
+	// SafeBrowsingKey is the Google Safe Browsing API key CheckBlocklists uses.
+	SafeBrowsingKey string
+	// Agents measure each site's TTFB from their regions, reported alongside the local samples.
+	Agents []Agent
+	// AgentToken is the bearer token the agents require.
+	AgentToken string
+	// ExpectedIssuers are the CAs, by organization, the domains' certificates may come from.
+	ExpectedIssuers []string
+	// VerificationToken is the ownership token site owners publish in a DNS TXT record
+	// (site-info-fetcher-verification=<token>) or at /.well-known/site-info-fetcher.txt.
+	VerificationToken string
+	// RequireVerification skips active checks on sites whose ownership is not verified: every
+	// probe beyond fetching the homepage and checking its certificate.
+	RequireVerification bool
+	// AllowTarget decides whether a site may be scanned at all, returning why not as an error.
+	// Every entry point that requests a site checks it first. Nil allows every site.
+	AllowTarget func(url string) error
+	// EOLCacheDir stores endoflife.date responses between runs. Empty disables the disk cache.
+	EOLCacheDir string
+	// EOLCacheTTL is how long cached endoflife.date responses are used before refetching. Defaults to 24 hours.
+	EOLCacheTTL time.Duration
+	// Offline uses cached endoflife.date data, or the bundled snapshot, without calling the API.
+	Offline bool
+	// Fingerprints identifies the frameworks, libraries, analytics and ecommerce platforms a site uses.
+	// Defaults to the built-in rules.
+	Fingerprints *fingerprint.Engine
+	// CustomFields extracts the fields of the user's detection rules from each homepage.
+	// Nil disables them.
+	CustomFields *fingerprint.Fields
+	// Vulnerabilities looks up known vulnerabilities in the detected WordPress core, plugins and theme.
+	// Nil disables the lookup.
+	Vulnerabilities *vuln.Client
+	// GeoIP looks up the hosting provider, ASN and country of each site's address.
+	// Nil disables the lookup.
+	GeoIP *geoip.Client
+	// ExpiryWarningDays flags certificates and domains expiring within this many days. Defaults to 30.
+	ExpiryWarningDays int
+	// Logger receives progress messages and warnings about each site. Nil discards them.
+	Logger *slog.Logger
+	// TracerProvider receives OpenTelemetry spans for each site's scan, with child spans for
+	// its HTTP requests, TLS check and endoflife.date lookups. Nil records no spans.
+	TracerProvider trace.TracerProvider
+	// Provenance records how each finding was determined, and with what confidence, in
+	// SiteInfo.Provenance.
+	Provenance bool
+	// Details records the homepage's raw response headers and a summary of the certificate
+	// chain in SiteInfo.ResponseHeaders and SiteInfo.CertificateChain, for per-site detail files.
+	Details bool
+	// CrawlPages samples this many pages from each site's sitemap and aggregates their TTFB,
+	// caching and mixed content in SiteInfo.Crawl. Zero disables the crawl.
+	CrawlPages int
+	// HealthWeights sets how much each area counts towards SiteInfo.HealthScore. Defaults to
+	// DefaultHealthWeights.
+	HealthWeights *HealthWeights
+	// Strict reports low-confidence inferred values, such as plugin versions taken from asset
+	// query strings, as Unknown rather than guessing them.
+	Strict bool
+	// OnScanned is called by ScanAll as each site finishes, with its result or error, so results
+	// can be saved before the whole batch completes. It is called from the worker goroutines.
+	OnScanned func(url string, info *SiteInfo, err error)
+	// OnStarted is called by ScanAll as each site's scan starts. It is called from the worker
+	// goroutines.
+	OnStarted func(url string)
+	// AuditLog receives a newline-delimited JSON record of every outbound request. Nil disables it.
+	AuditLog io.Writer
+	// Proxy routes all HTTP requests and TLS checks through an HTTP, HTTPS or SOCKS5 proxy.
+	// Nil uses the proxy selected by the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+	Proxy *neturl.URL
+	// Resolver answers every DNS lookup the scan makes, including those for its connections,
+	// e.g. one from NewResolver to check records against a specific server during a migration.
+	// Nil uses the system resolver. The propagation check still queries its public resolvers.
+	Resolver *net.Resolver
+	// TorProxy routes .onion sites, and only those, through Tor's SOCKS5 proxy, e.g.
+	// socks5h://127.0.0.1:9050. Other sites keep their usual route.
+	TorProxy *neturl.URL
+	// OnionTimeout bounds each HTTP request and TLS handshake with a .onion site, replacing
+	// Timeout and the per-phase timeouts, which are too tight for Tor. Defaults to 60 seconds.
+	OnionTimeout time.Duration
+	// Credentials give access to password-protected sites, keyed by hostname or site URL.
+	Credentials map[string]*Credentials
+}
+
+// Scanner fetches site information
+type Scanner struct {
+	opts        Options
+	client      *http.Client
+	onionClient *http.Client
+	audit       *auditLog
+	tracer      trace.Tracer
+	eol         *eolCache
+
+	envProxy        func(*neturl.URL) (*neturl.URL, error)
+	limiter         *rateLimiter
+	pluginDirectory pluginDirectory
+
+	sites     atomic.Int64
+	cpuStart  float64
+	bandwidth bandwidth
+
+	calibration *calibration
+}
+
+// New creates a Scanner with the given options
+func New(opts Options) *Scanner {
+	if opts.Timeout <= 0 && !opts.hasPhaseTimeouts() {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = 5
+	}
+	if opts.Samples < 1 {
+		opts.Samples = 3
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.OnionTimeout <= 0 {
+		opts.OnionTimeout = 60 * time.Second
+	}
+	if opts.ExpiryWarningDays <= 0 {
+		opts.ExpiryWarningDays = 30
+	}
+	if opts.EOLCacheTTL <= 0 {
+		opts.EOLCacheTTL = 24 * time.Hour
+	}
+	if opts.CalibrationInterval <= 0 {
+		opts.CalibrationInterval = time.Minute
+	}
+	if opts.HealthWeights == nil {
+		opts.HealthWeights = &DefaultHealthWeights
+	}
+	if opts.Fingerprints == nil {
+		opts.Fingerprints = fingerprint.Default()
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.New(slog.DiscardHandler)
+	}
+	tracerProvider := opts.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = noop.NewTracerProvider()
+	}
+	s := &Scanner{
+		opts:     opts,
+		audit:    newAuditLog(opts.AuditLog),
+		tracer:   tracerProvider.Tracer(tracerName),
+		eol:      newEOLCache(opts.EOLCacheDir, opts.EOLCacheTTL, opts.Offline),
+		envProxy: envProxyFunc(),
+		cpuStart: cpuSeconds(),
+		limiter:  newRateLimiter(opts.RateLimit, opts.HostConcurrency),
+		client: &http.Client{
+			Timeout: opts.Timeout,
+		},
+	}
+	s.client.Transport = s.wrapTransport(s.newTransport())
+	if opts.TorProxy != nil {
+		s.onionClient = &http.Client{Timeout: opts.OnionTimeout, Transport: s.wrapTransport(s.newOnionTransport())}
+	}
+	if opts.BaselineURL != "" {
+		s.calibration = &calibration{url: opts.BaselineURL, interval: opts.CalibrationInterval}
+	}
+	return s
+}
+
+// wrapTransport adds the configured body timeout, site credentials, browser headers,
+// User-Agent, audit logging, robots.txt rules, the ownership gate and tracing to a transport
+func (s *Scanner) wrapTransport(transport http.RoundTripper) http.RoundTripper {
+	if s.opts.BodyTimeout > 0 {
+		transport = &bodyTimeoutTransport{next: transport, timeout: s.opts.BodyTimeout}
+	}
+	if len(s.opts.Credentials) > 0 {
+		transport = newCredentialsTransport(transport, s.opts.Credentials)
+	}
+	if s.opts.BrowserProfile {
+		transport = &browserTransport{next: transport}
+	}
+	if s.opts.UserAgent != "" {
+		transport = &userAgentTransport{next: transport, userAgent: s.opts.UserAgent}
+	}
+	if s.audit != nil {
+		transport = &auditTransport{next: transport, log: s.audit}
+	}
+	if s.opts.RespectRobots {
+		transport = &robotsTransport{next: transport}
+	}
+	if s.opts.RequireVerification {
+		transport = &ownershipTransport{next: transport}
+	}
+	if s.opts.TracerProvider != nil {
+		transport = &tracingTransport{next: transport, tracer: s.tracer}
+	}
+	return transport
+}
+
+// log returns the logger for the scan of a site, with its URL attached to every record
+func (s *Scanner) log(url string) *slog.Logger {
+	return s.opts.Logger.With("url", url)
+}
+
+// allowTarget returns the error of Options.AllowTarget for a site that may not be scanned
+func (s *Scanner) allowTarget(url string) error {
+	if s.opts.AllowTarget == nil {
+		return nil
+	}
+	return s.opts.AllowTarget(url)
+}
+
+// Scan gets the site information for a given URL
+func (s *Scanner) Scan(ctx context.Context, url string) (*SiteInfo, error) {
+	ctx, span := s.tracer.Start(ctx, "scan", trace.WithAttributes(attribute.String("url.full", url)))
+	info, err := s.scan(ctx, url)
+	if err != nil {
+		span.SetAttributes(attribute.String("scan.status", scanStatus(err)))
+	} else {
+		span.SetAttributes(attribute.String("scan.status", info.ScanStatus))
+	}
+	endSpan(span, err)
+	return info, err
+}
+
+// scan gets the site information for Scan, inside its span
+func (s *Scanner) scan(ctx context.Context, url string) (*SiteInfo, error) {
+	if err := s.allowTarget(url); err != nil {
+		return nil, err
+	}
+	if isOnion(hostOf(url)) && s.onionClient == nil && s.opts.Proxy == nil {
+		return nil, errOnionWithoutTor
+	}
+	// Reports keep the URL as given, while every request goes to the ASCII form of an
+	// internationalized domain name
+	info := &SiteInfo{URL: url}
+	url = asciiURL(url)
+	s.sites.Add(1)
+
+	ttfbCtx, span := s.tracer.Start(ctx, "sample ttfb")
+	ttfs, err := s.sampleTTFB(ttfbCtx, url)
+	endSpan(span, err)
+	if err != nil {
+		if isCertificateError(err) {
+			return s.certificateFailure(ctx, info, url)
+		}
+		return nil, err
+	}
+
+	// Calculate the average TTFB and the spread of the samples
+	info.AverageTTFB, info.TTFBStats = ttfbStatistics(ttfs)
+
+	// Sort TTFBs in order of longest to shortest latency
+	sort.Slice(ttfs, func(i, j int) bool {
+		return ttfs[i] > ttfs[j]
+	})
+	info.TTFBs = ttfs
+
+	// Print TTFB tests and statistics in the terminal
+	samples := make([]float64, len(ttfs))
+	for i, ttfb := range ttfs {
+		samples[i] = Milliseconds(ttfb)
+	}
+	log := s.log(url)
+	log.Info("Fetching site info", "ttfb_samples_ms", samples, "average_ttfb_ms", Milliseconds(info.AverageTTFB),
+		"median_ttfb_ms", Milliseconds(info.TTFBStats.Median), "p95_ttfb_ms", Milliseconds(info.TTFBStats.P95),
+		"stddev_ttfb_ms", Milliseconds(info.TTFBStats.StdDev))
+
+	// Measure TTFB from the agents' regions
+	if len(s.opts.Agents) > 0 {
+		info.RegionalTTFB = s.measureRegions(ctx, url)
+		for _, region := range info.RegionalTTFB {
+			if region.Error != "" {
+				log.Warn("Regional TTFB failed", "region", region.Region, "error", region.Error)
+			}
+		}
+	}
+
+	fetchCtx, span := s.tracer.Start(ctx, "fetch")
+	resp, timing, err := s.fetchURL(fetchCtx, url)
+	endSpan(span, err)
+	if err != nil {
+		if isCertificateError(err) {
+			return s.certificateFailure(ctx, info, url)
+		}
+		return nil, fmt.Errorf("error fetching URL %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	received := time.Now()
+	info.Timing = timing
+
+	// Read the body
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body for URL %s: %w", url, err)
+	}
+	info.Timing.Total = time.Since(info.Timing.start)
+	info.Timing.Download = info.Timing.Total - info.Timing.TTFB
+
+	// Record the redirects followed, since the headers below belong to the final URL
+	info.RedirectChain = redirectChain(resp)
+	info.FinalURL = resp.Request.URL.String()
+	info.ExcessiveRedirects = len(info.RedirectChain) > maxRedirectHops
+	if info.ExcessiveRedirects {
+		log.Warn("Excessive redirect chain", "hops", len(info.RedirectChain))
+	}
+
+	// Verify ownership before any request beyond the homepage; the probes of an unverified
+	// site are skipped, and any request one would make to it is refused
+	ctx, active := s.checkOwnership(ctx, info, url, info.FinalURL)
+	if !active {
+		log.Info("Skipping active checks for unverified site")
+	}
+
+	// Read robots.txt, and honour it for every later request to the site when asked to
+	if (!s.opts.SkipRobots || s.opts.RespectRobots) && active {
+		var rules robotsRules
+		info.RobotsTxt, rules = s.checkRobots(ctx, info.FinalURL)
+		if s.opts.RespectRobots {
+			var policy *robotsPolicy
+			ctx, policy = withRobots(ctx, rules, url, info.FinalURL)
+			defer func() { info.RobotsBlocked = policy.blockedPaths() }()
+		}
+	}
+
+	// Check that every scheme and www variant redirects to the canonical URL
+	if !s.opts.SkipCanonical && active {
+		info.Canonicalization = s.checkCanonicalization(ctx, info.FinalURL)
+	}
+
+	if s.opts.Details {
+		info.ResponseHeaders = resp.Header.Clone()
+	}
+	info.Caching, info.WebServer, info.WebServerVersion, info.CacheControl, info.XPoweredBy = parseHeaders(resp.Header)
+	info.PHPVersion, info.PHPDetection = detectPHP(resp.Header)
+	info.HeaderAnomalies = checkHeaderAnomalies(resp.Header)
+	info.FrameProtection = checkFrameProtection(resp.Header)
+	info.SecurityHeaders = gradeSecurityHeaders(resp.Header, resp.TLS != nil)
+	info.HSTS = parseHSTS(resp.Header, resp.TLS != nil)
+	info.CSP = analyzeCSP(resp.Header)
+	info.Cookies = auditCookies(resp.Header)
+	if s.opts.CheckHSTSPreload {
+		info.HSTSPreload, err = s.checkHSTSPreload(ctx, url)
+		if err != nil {
+			log.Warn("HSTS preload list lookup failed", "error", err)
+		}
+	}
+	if s.opts.CheckSmuggling {
+		info.SmugglingIndicators = smugglingIndicators(resp.Header, resp.Proto)
+	}
+
+	// Find Vary headers and cookies that make the page cache ineffective
+	if !s.opts.SkipVary && active {
+		info.CacheKey = s.auditCacheKey(ctx, info.FinalURL, resp.Header)
+	}
+
+	// Check the origin answers a conditional request with 304 Not Modified
+	if !s.opts.SkipRevalidation && active {
+		info.Revalidation = s.checkRevalidation(ctx, info.FinalURL, resp.Header)
+	}
+
+	// Collect the DNS records of the hostname and its domain
+	if !s.opts.SkipDNS {
+		info.DNS = s.collectDNS(ctx, url)
+		info.IPAddresses = append(append([]string{}, info.DNS.A...), info.DNS.AAAA...)
+		if len(info.IPAddresses) > 0 {
+			info.PTR = s.reverseDNS(ctx, info.IPAddresses[0])
+		}
+	}
+
+	// Check that advertised IPv6 addresses actually serve the site
+	if !s.opts.SkipIPv6 && active {
+		info.IPv6 = s.checkIPv6(ctx, url)
+	}
+
+	// Check the domain's email authentication records
+	if !s.opts.SkipMailAuth {
+		info.MailAuth = s.checkMailAuth(ctx, url)
+	}
+
+	// Look up when the domain registration expires
+	if s.opts.CheckDomainExpiry {
+		registration, err := s.lookupDomainExpiry(ctx, url, s.opts.ExpiryWarningDays)
+		if err != nil {
+			log.Warn("RDAP lookup failed", "error", err)
+		}
+		info.Domain = registration
+	}
+
+	// Check that DNS changes have reached the major public resolvers
+	if s.opts.CheckPropagation {
+		info.Propagation = checkPropagation(ctx, url)
+	}
+
+	// Verify the cache serves the same content as the origin after a purge
+	if s.opts.CheckPurge && active {
+		info.Purge = s.checkPurge(ctx, url)
+	}
+
+	// Find which network and country the site is hosted in
+	if s.opts.GeoIP != nil {
+		var err error
+		info.HostingProvider, info.ASN, info.Country, err = s.lookupHosting(ctx, url)
+		if err != nil {
+			log.Warn("Hosting lookup failed", "error", err)
+		}
+	}
+
+	// Record the protocol negotiated over ALPN and whether HTTP/3 is offered
+	info.HTTPVersion = resp.Proto
+	info.HTTP3 = advertisesHTTP3(resp.Header)
+
+	// Identify the CDN; its edge Server header says nothing about the origin web server
+	info.CDN = s.detectCDN(ctx, url, resp.Header)
+	if isCDNServer(info.WebServer) {
+		info.WebServer, info.WebServerVersion = "", ""
+	}
+
+	// Identify the platform JAMstack and edge-rendered sites are deployed on
+	info.Platform = s.detectPlatform(ctx, url, resp.Header)
+
+	// Audit the cross-origin resource sharing policy
+	if !s.opts.SkipCORS && active {
+		var findings []corsFinding
+		info.CORSAllowOrigin, info.CORSCredentials, findings = s.checkCORS(ctx, url)
+		info.CORSIssues = corsIssues(findings)
+		info.SecurityHeaders.gradeCORS(findings)
+	}
+
+	// Probe for open redirects when the active check is enabled
+	if s.opts.CheckOpenRedirect && active {
+		info.OpenRedirects = s.checkOpenRedirect(ctx, url)
+	}
+
+	// Probe for publicly reachable login, XML-RPC and debug files when the hardening check is enabled
+	if s.opts.CheckExposure && active {
+		info.Exposures = s.checkExposure(ctx, url)
+	}
+
+	// Compare the server clock with ours
+	info.ServerDate, info.ClockSkew, info.ClockSkewFlag = checkClockSkew(resp.Header, received)
+	if info.ClockSkewFlag {
+		log.Warn("Significant clock skew", "skew", info.ClockSkew)
+	}
+
+	info.PageWeight, info.Assets = s.measurePageWeight(ctx, body, info.FinalURL, s.opts.FetchAssets && active)
+
+	// Find assets that do not load when requested as the page requests them
+	if s.opts.CheckHotlink && active {
+		info.HotlinkIssues = s.checkHotlink(ctx, body, info.FinalURL)
+	}
+
+	// Compare the compressed transfer size with the uncompressed page
+	if !s.opts.SkipCompression && active {
+		info.UncompressedSize = int64(len(body))
+		info.Compression, info.CompressedSize, err = s.checkCompression(ctx, url)
+		if err != nil {
+			log.Warn("Compression check failed", "error", err)
+		}
+	}
+
+	info.WordPressVersion = parseHTML(body)
+
+	// Fall back to the REST API when the generator tag is stripped. A headless site's REST
+	// API is on its WordPress backend rather than the frontend.
+	info.HeadlessFrontend, info.WordPressBackend = detectHeadless(resp.Header, body, info.FinalURL)
+	info.WordPressSignals = wordPressSignals(resp.Header, body)
+	if info.WordPressBackend != "" {
+		info.WordPressSignals = append(info.WordPressSignals, "headless backend")
+	}
+	if info.WordPressVersion == "" && !s.opts.SkipWPJSON && active {
+		var confirmed bool
+		confirmed, info.SiteName, info.SiteDescription, info.WordPressVersionRange = s.probeWPJSON(ctx, cmp.Or(info.WordPressBackend, url))
+		if confirmed {
+			info.WordPressSignals = addSignal(info.WordPressSignals, "wp-json")
+		}
+	}
+
+	// A site that strips every fingerprint may still serve the login form
+	if len(info.WordPressSignals) == 0 && !s.opts.SkipWPJSON && active && s.probeWPLogin(ctx, url) {
+		info.WordPressSignals = []string{"wp-login"}
+	}
+	info.IsWordPress = len(info.WordPressSignals) > 0
+	wordpress := info.IsWordPress
+
+	info.CMS, info.CMSVersion = detectCMS(resp.Header, body, wordpress, info.WordPressVersion)
+
+	// Behind a page cache the homepage may not reveal PHP or the database, but WordPress runs
+	// on PHP, and its REST API is answered by PHP itself
+	info.MySQLVersion, info.MySQLDetection = detectDatabase(resp.Header, body)
+	if wordpress && (info.PHPVersion == "" || info.MySQLVersion == "") && !s.opts.SkipWPJSON && active {
+		if headers := s.restAPIHeaders(ctx, cmp.Or(info.WordPressBackend, url)); headers != nil {
+			if version, from := restAPIVersion(headers, detectPHP); version != "" && info.PHPVersion == "" {
+				info.PHPVersion, info.PHPDetection = version, from
+			}
+			if version, from := restAPIVersion(headers, detectDatabaseHeaders); version != "" && info.MySQLVersion == "" {
+				info.MySQLVersion, info.MySQLDetection = version, from
+			}
+		}
+	}
+	if info.PHPVersion == "" && wordpress && info.PHPDetection == nil {
+		info.PHPDetection = &Provenance{SourceDerived, "WordPress runs on PHP", 0.95}
+	}
+	if info.MySQLVersion == "" {
+		info.MySQLVersion = Undetectable
+	}
+	info.Technologies = s.opts.Fingerprints.Detect(resp.Header, body)
+	if s.opts.CustomFields != nil {
+		info.CustomFields = s.opts.CustomFields.Extract(resp.Header, body)
+	}
+	if !s.opts.SkipEcommerce && active {
+		info.Ecommerce = s.checkEcommerce(ctx, body, url, info.Technologies)
+	}
+	info.Plugins = detectPlugins(body)
+	info.Theme, info.ThemeVersion = detectTheme(body)
+
+	// Compare the declared Permissions-Policy with the features the page uses
+	info.PermissionsPolicyIssues = checkPermissionsPolicy(resp.Header, body)
+
+	// Find resources an HTTPS page still loads over HTTP
+	info.MixedContent = checkMixedContent(body, info.FinalURL)
+
+	// Measure pages beyond the homepage, sampled from the sitemap
+	if s.opts.CrawlPages > 0 && active {
+		info.Crawl = s.crawlSitemap(ctx, info.FinalURL, s.opts.CrawlPages)
+		if info.Crawl.Sitemap == "" {
+			log.Info("No sitemap found to crawl")
+		}
+	}
+
+	// Check third-party scripts and stylesheets for subresource integrity
+	info.SRI = checkSRI(body, url)
+
+	// Identify the caching layers; the max-age heuristic misses most real setups
+	info.CachingLayers = detectCachingLayers(resp.Header, body)
+	if len(info.CachingLayers) > 0 {
+		info.Caching = true
+	}
+
+	// Find the contact form and the plugin behind it for lead generation audits
+	if !s.opts.SkipContactForm && active {
+		info.ContactForm, info.FormPlugins = s.findContactForm(ctx, body, url)
+	}
+
+	// Find where the media library is served from
+	info.MediaOffload = detectMediaOffload(body, url)
+	info.ImageCDN = s.detectImageCDN(ctx, body, url)
+
+	// Flag blank or fatal error responses so broken sites are not reported as merely slow
+	info.WSODSuspected = isWSOD(body)
+	if info.WSODSuspected {
+		log.Warn("WSOD suspected")
+	}
+
+	// Identify the firewall in front of the site, which may have served a block page instead
+	info.WAF, info.WAFBlocked = detectWAF(resp.Header, body)
+	if info.WAFBlocked {
+		log.Warn("Scan blocked by a web application firewall", "waf", info.WAF)
+	}
+
+	// Tell a live site from a maintenance, parked or error page, whose findings describe the
+	// holding page rather than the site
+	info.StatusCode = resp.StatusCode
+	info.ScanStatus = ScanOK
+	if resp.StatusCode >= 500 {
+		info.ScanStatus, info.ScanError = ScanServerError, resp.Status
+	}
+	info.SiteState, info.SiteStateReason = detectSiteState(resp.StatusCode, body)
+	if info.WAFBlocked {
+		info.SiteStateReason = "block page of " + strings.Join(info.WAF, ", ")
+	}
+	if info.SiteState != SiteLive {
+		log.Warn("Site is not live", "state", info.SiteState, "reason", info.SiteStateReason)
+	}
+
+	// Audit the search metadata and structured data of the homepage
+	info.SEO = auditSEO(body)
+	info.StructuredData = detectStructuredData(body)
+
+	// List the analytics tracking IDs, to verify tracking is rolled out across sites
+	info.Analytics = detectAnalytics(body)
+	if info.StructuredData.Invalid > 0 {
+		log.Warn("Invalid JSON-LD", "blocks", info.StructuredData.Invalid)
+	}
+
+	// Probe the search results template on WordPress sites
+	if !s.opts.SkipSearch && active {
+		info.SearchStatus = "N/A"
+		if wordpress {
+			info.SearchStatus = s.checkSearch(ctx, url)
+		}
+	}
+
+	// Measure the uncached backend through the login page
+	if s.opts.CheckLoginTTFB && wordpress && active {
+		info.LoginTTFB = s.measureLoginTTFB(ctx, url)
+	}
+
+	// Check how the site handles missing pages
+	if !s.opts.SkipErrorPage && active {
+		info.ErrorHandling = s.checkErrorPage(ctx, url)
+	}
+
+	// Check that Let's Encrypt can still reach the HTTP-01 challenge path
+	if !s.opts.SkipACME && active {
+		info.ACMEChallenge = s.checkACMEChallenge(ctx, url)
+	}
+
+	// Check SSL certificate. The homepage was already fetched over HTTPS, so a handshake that
+	// fails here is reported rather than failing the scan.
+	sslCtx, span := s.tracer.Start(ctx, "ssl check")
+	ssl, err := s.checkSSL(sslCtx, url)
+	endSpan(span, err)
+	if errors.Is(err, errCertificateExpired) {
+		return s.certificateFailure(ctx, info, url)
+	}
+	if err != nil {
+		log.Warn("TLS handshake failed", "error", err)
+		info.SSLIssues = []string{"handshake failed"}
+	} else {
+		if len(ssl.state.PeerCertificates) > 0 {
+			info.Certificate = describeCertificate(ssl.state.PeerCertificates[0], s.opts.ExpiryWarningDays)
+		}
+		info.TLSVersion = tls.VersionName(ssl.state.Version)
+		info.CipherSuite = tls.CipherSuiteName(ssl.state.CipherSuite)
+		info.ChainStatus = s.chainStatus(ctx, ssl.state.PeerCertificates)
+		if s.opts.Details {
+			info.CertificateChain = describeChain(ssl.state.PeerCertificates)
+		}
+		if info.ChainStatus == "Incomplete" {
+			log.Warn("Incomplete certificate chain")
+		}
+		if info.Certificate != nil && info.Certificate.ExpiringSoon {
+			log.Warn("Certificate expiring soon", "days", info.Certificate.DaysUntilExpiry)
+		}
+		if info.Certificate != nil && len(info.Certificate.Weaknesses) > 0 {
+			log.Warn("Certificate rejected by modern browsers", "weaknesses", info.Certificate.Weaknesses)
+		}
+		if info.Certificate != nil && info.Certificate.RenewalOverdue {
+			log.Warn("Let's Encrypt certificate not renewed after 60 days")
+		}
+		info.SSLValid = ssl.valid
+		info.CertificateHostnameMismatch = ssl.hostnameMismatch
+		if ssl.hostnameMismatch {
+			log.Warn("Certificate does not cover the hostname")
+		}
+		info.SSLIssues = certificateIssues(ssl, false, info.ChainStatus)
+
+		// A revoked certificate still verifies, so revocation is checked separately
+		if s.opts.CheckRevocation && len(ssl.state.PeerCertificates) > 0 {
+			info.OCSPStapling, info.RevocationStatus = s.checkRevocation(ctx, url, ssl.state)
+			if info.RevocationStatus == "Revoked" {
+				log.Warn("Certificate has been revoked")
+				info.SSLValid = false
+				info.SSLIssues = append(info.SSLIssues, "revoked")
+			}
+		}
+	}
+
+	// Look for certificates issued for the domain by CAs the site does not use
+	if s.opts.CheckCT {
+		var servedIssuer string
+		if info.Certificate != nil {
+			servedIssuer = info.Certificate.IssuerOrg
+		}
+		info.CT, err = s.lookupCT(ctx, url, servedIssuer, s.opts.ExpectedIssuers)
+		if err != nil {
+			log.Warn("Certificate Transparency lookup failed", "error", err)
+		} else if len(info.CT.UnexpectedIssuers) > 0 {
+			log.Warn("Certificates issued by unexpected CAs", "issuers", info.CT.UnexpectedIssuers)
+		}
+	}
+
+	// Check whether the site is flagged as malicious or compromised
+	if s.opts.CheckBlocklists {
+		info.Blocklists, err = s.checkBlocklists(ctx, url)
+		if err != nil {
+			log.Warn("Safe Browsing lookup failed", "error", err)
+		}
+		if info.Blocklists.Flagged {
+			log.Warn("Site is blocklisted", "listings", info.Blocklists.String())
+		}
+	}
+
+	// Compare the certificate and TLS configuration across load-balanced backends
+	if !s.opts.SkipTLSEndpoints && active {
+		info.TLSEndpoints, info.TLSEndpointMismatch = s.checkTLSEndpoints(ctx, url)
+		if info.TLSEndpointMismatch {
+			log.Warn("Inconsistent TLS configuration across addresses")
+		}
+	}
+
+	// Probe the accepted protocol versions when the TLS audit is enabled
+	if s.opts.CheckTLSAudit && active {
+		info.TLSAudit = s.auditTLS(ctx, url)
+		if info.TLSAudit.Deprecated {
+			log.Warn("Deprecated TLS protocols accepted")
+		}
+	}
+
+	// Look up known vulnerabilities in the detected versions
+	if s.opts.Vulnerabilities != nil {
+		info.Vulnerabilities, info.HighestSeverity, info.Remediations, err = s.checkVulnerabilities(ctx, info)
+		if err != nil {
+			log.Warn("Error looking up vulnerabilities", "error", err)
+		}
+	}
+
+	// Measure how far the plugins lag behind their latest releases
+	if s.opts.CheckPluginUpdates && wordpress && !s.opts.Offline {
+		info.PluginUpdateLag, err = s.checkPluginUpdates(ctx, info.Plugins)
+		if err != nil {
+			log.Warn("Error looking up plugin updates", "error", err)
+		}
+	}
+
+	// Flag the plugins and theme their authors have abandoned
+	if s.opts.CheckAbandonment && wordpress && !s.opts.Offline {
+		if err := s.checkAbandonment(ctx, info); err != nil {
+			log.Warn("Error looking up abandoned plugins", "error", err)
+		}
+	}
+
+	// Inventory the licenses of the plugins and theme
+	if s.opts.CheckLicenses && wordpress && active {
+		s.checkLicenses(ctx, url, info)
+	}
+
+	// Get support status, against the fork's own releases for WordPress forks
+	info.PHPStatus, info.MySQLStatus, info.WebServerStatus, info.WordPressStatus = s.getSupportStatus(ctx, info.PHPVersion, info.MySQLVersion, wordPressProduct(info), wordPressRelease(info), info.WebServer, info.WebServerVersion)
+	if !wordpress {
+		info.WordPressStatus = "N/A"
+	}
+
+	// Turn the findings into actions automation can act on
+	info.Remediations = append(info.Remediations, s.remediations(ctx, info)...)
+
+	// Annotate the result with the scanner's own network conditions
+	if s.calibration != nil {
+		s.annotateBaseline(ctx, info)
+	}
+
+	// Record how each finding was determined, and suppress the guesses in strict mode
+	if s.opts.Provenance || s.opts.Strict {
+		provenance := recordProvenance(info, resp.Header, body)
+		if s.opts.Strict {
+			applyStrict(info, provenance)
+		}
+		if s.opts.Provenance {
+			info.Provenance = provenance
+		}
+	}
+
+	// Sum the findings up in one score for reporting
+	info.HealthScore, info.HealthGrade = healthScore(info, *s.opts.HealthWeights)
+
+	// Show the final URL and redirects of internationalized domains in Unicode
+	info.FinalURL = unicodeURL(info.FinalURL)
+	for i := range info.RedirectChain {
+		info.RedirectChain[i].URL = unicodeURL(info.RedirectChain[i].URL)
+		info.RedirectChain[i].Location = unicodeURL(info.RedirectChain[i].Location)
+	}
+
+	return info, nil
+}
+
+// ScanAll scans each URL using a bounded pool of Options.Concurrency workers, after warming up
+// DNS and connections to every target. Results keep the order of the input URLs; failed sites
+// are omitted and their errors returned as *ScanError. When ctx is cancelled no further scans are started and
+// the scans in flight are abandoned, so only the sites completed before are returned.
+func (s *Scanner) ScanAll(ctx context.Context, urls []string) ([]*SiteInfo, []error) {
+	if !s.opts.SkipWarmup {
+		s.warmup(ctx, urls)
+	}
+
+	results := make([]*SiteInfo, len(urls))
+	errs := make([]error, len(urls))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < s.opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if s.opts.OnStarted != nil {
+					s.opts.OnStarted(urls[i])
+				}
+				info, err := s.Scan(ctx, urls[i])
+				if ctx.Err() != nil {
+					// Cancelled mid-scan: the site was not scanned rather than failed
+					continue
+				}
+				if s.opts.OnScanned != nil {
+					s.opts.OnScanned(urls[i], info, err)
+				}
+				if err != nil {
+					errs[i] = &ScanError{URL: urls[i], Err: err}
+					continue
+				}
+				results[i] = info
+			}
+		}()
+	}
+dispatch:
+	for i := range urls {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var siteInfos []*SiteInfo
+	var failures []error
+	for i := range urls {
+		if errs[i] != nil {
+			failures = append(failures, errs[i])
+			continue
+		}
+		if results[i] != nil {
+			siteInfos = append(siteInfos, results[i])
+		}
+	}
+	return siteInfos, failures
+}
@@ -0,0 +1,116 @@
+package siteinfo
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SecurityHeaders grades the security headers the site sends, in the manner of Mozilla Observatory
+type SecurityHeaders struct {
+	Score  int      `json:"score"`
+	Grade  string   `json:"grade"`
+	Issues []string `json:"issues"`
+}
+
+// minHSTSMaxAge is the shortest HSTS max-age, in seconds, that is not penalized (six months)
+const minHSTSMaxAge = 15768000
+
+// hstsMaxAgePattern extracts the max-age directive from a Strict-Transport-Security header
+var hstsMaxAgePattern = regexp.MustCompile(`(?i)max-age\s*=\s*"?(\d+)`)
+
+// securityGrades maps minimum scores to letter grades, best first
+var securityGrades = []struct {
+	minScore int
+	grade    string
+}{
+	{100, "A+"},
+	{90, "A"},
+	{80, "B"},
+	{70, "C"},
+	{60, "D"},
+	{0, "F"},
+}
+
+// deduct takes points off the score for the issue
+func (h *SecurityHeaders) deduct(points int, issue string) {
+	h.Score = max(h.Score-points, 0)
+	h.Issues = append(h.Issues, issue)
+}
+
+// grade sets the letter grade of the score
+func (h *SecurityHeaders) grade() {
+	for _, band := range securityGrades {
+		if h.Score >= band.minScore {
+			h.Grade = band.grade
+			return
+		}
+	}
+}
+
+// gradeCORS deducts for the CORS misconfigurations checkCORS found and grades the headers
+// again. Trusting any origin with credentials lets every site read a logged-in visitor's
+// responses; reflecting any origin or trusting null without credentials exposes public
+// responses only.
+func (h *SecurityHeaders) gradeCORS(findings []corsFinding) {
+	for _, finding := range findings {
+		if finding.credentials {
+			h.deduct(20, "CORS "+finding.String())
+		} else {
+			h.deduct(5, "CORS "+finding.String())
+		}
+	}
+	h.grade()
+}
+
+// gradeSecurityHeaders scores HSTS, Content-Security-Policy, X-Frame-Options, X-Content-Type-Options,
+// Referrer-Policy and Permissions-Policy, starting from 100 and deducting for each missing or weak
+// header. https reports whether the final response was served over TLS.
+func gradeSecurityHeaders(headers http.Header, https bool) *SecurityHeaders {
+	result := &SecurityHeaders{Score: 100}
+
+	hsts := headers.Get("Strict-Transport-Security")
+	switch {
+	case !https:
+		result.deduct(20, "Site not served over HTTPS")
+	case hsts == "":
+		result.deduct(20, "Strict-Transport-Security missing")
+	default:
+		match := hstsMaxAgePattern.FindStringSubmatch(hsts)
+		if match == nil {
+			result.deduct(20, "Strict-Transport-Security has no max-age")
+		} else if maxAge, _ := strconv.Atoi(match[1]); maxAge < minHSTSMaxAge {
+			result.deduct(10, "Strict-Transport-Security max-age under six months")
+		}
+	}
+
+	switch csp := analyzeCSP(headers); {
+	case csp == nil || headers.Get("Content-Security-Policy") == "":
+		result.deduct(25, "Content-Security-Policy missing")
+	case csp.Grade == "D" || csp.Grade == "F":
+		result.deduct(10, "Content-Security-Policy is easily bypassed (grade "+csp.Grade+")")
+	}
+
+	if checkFrameProtection(headers) == "None" {
+		result.deduct(20, "X-Frame-Options missing and no restrictive CSP frame-ancestors")
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(headers.Get("X-Content-Type-Options")), "nosniff") {
+		result.deduct(5, "X-Content-Type-Options not set to nosniff")
+	}
+
+	switch policy := strings.ToLower(strings.TrimSpace(headers.Get("Referrer-Policy"))); policy {
+	case "":
+		result.deduct(5, "Referrer-Policy missing")
+	case "unsafe-url", "no-referrer-when-downgrade":
+		result.deduct(5, "Referrer-Policy leaks full URLs ("+policy+")")
+	}
+
+	if headers.Get("Permissions-Policy") == "" {
+		result.deduct(5, "Permissions-Policy missing")
+	}
+
+	result.grade()
+	return result
+}
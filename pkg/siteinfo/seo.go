@@ -0,0 +1,98 @@
+package siteinfo
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// titlePattern matches the page title
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	// metaTagPattern matches meta tags
+	metaTagPattern = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+)
+
+// SEO holds the search metadata of the homepage and what is missing or duplicated in it
+type SEO struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Canonical   string   `json:"canonical"`
+	Robots      string   `json:"robots,omitempty"`
+	Hreflang    []string `json:"hreflang,omitempty"`
+	Issues      []string `json:"issues,omitempty"`
+}
+
+// metadataText decodes entities in a title or attribute value and collapses its whitespace
+func metadataText(text string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+// auditSEO extracts the title, meta description, canonical link, robots meta tag and hreflang
+// alternates of the page. Issues lists the missing and duplicated values, hreflang languages
+// given more than once, and a robots meta tag that keeps the page out of search results.
+// Only the document head is searched, as the title elements of inline SVG images in the
+// page are not page titles.
+func auditSEO(body string) *SEO {
+	if end := strings.Index(strings.ToLower(body), "</head>"); end >= 0 {
+		body = body[:end]
+	}
+	seo := &SEO{}
+	var issues []string
+	check := func(name string, values []string) string {
+		switch {
+		case len(values) == 0 || values[0] == "":
+			issues = append(issues, name+" missing")
+		case len(values) > 1:
+			issues = append(issues, "multiple "+name+"s")
+		}
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+
+	var titles []string
+	for _, match := range titlePattern.FindAllStringSubmatch(body, -1) {
+		titles = append(titles, metadataText(match[1]))
+	}
+	seo.Title = check("title", titles)
+
+	var descriptions, robots []string
+	for _, tag := range metaTagPattern.FindAllString(body, -1) {
+		attributes := tagAttributes(tag)
+		switch strings.ToLower(attributes["name"]) {
+		case "description":
+			descriptions = append(descriptions, metadataText(attributes["content"]))
+		case "robots":
+			robots = append(robots, strings.ToLower(metadataText(attributes["content"])))
+		}
+	}
+	seo.Description = check("meta description", descriptions)
+	seo.Robots = strings.Join(robots, ", ")
+	if strings.Contains(seo.Robots, "noindex") {
+		issues = append(issues, "noindex")
+	}
+
+	var canonicals []string
+	for _, tag := range stylesheetTagPattern.FindAllString(body, -1) {
+		attributes := tagAttributes(tag)
+		rel := strings.Fields(strings.ToLower(attributes["rel"]))
+		switch {
+		case slices.Contains(rel, "canonical"):
+			canonicals = append(canonicals, attributes["href"])
+		case slices.Contains(rel, "alternate") && attributes["hreflang"] != "":
+			lang := strings.ToLower(attributes["hreflang"])
+			if slices.Contains(seo.Hreflang, lang) {
+				issues = append(issues, "duplicate hreflang "+lang)
+				continue
+			}
+			seo.Hreflang = append(seo.Hreflang, lang)
+		}
+	}
+	seo.Canonical = check("canonical", canonicals)
+
+	seo.Issues = issues
+	return seo
+}
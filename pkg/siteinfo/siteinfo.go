@@ -0,0 +1,249 @@
+// Package siteinfo fetches information about WordPress sites: software versions,
+// caching, SSL validity, Time to First Byte and the support status of each component.
+package siteinfo
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/fingerprint"
+)
+
+// SiteInfo holds the information about the WordPress site
+type SiteInfo struct {
+	URL                         string                   `json:"url"`
+	ScanStatus                  string                   `json:"scan_status"`
+	ScanError                   string                   `json:"scan_error,omitempty"`
+	PHPVersion                  string                   `json:"php_version"`
+	PHPDetection                *Provenance              `json:"php_detection,omitempty"`
+	MySQLVersion                string                   `json:"mysql_version"`
+	MySQLDetection              *Provenance              `json:"mysql_detection,omitempty"`
+	WordPressVersion            string                   `json:"wordpress_version"`
+	IsWordPress                 bool                     `json:"is_wordpress"`
+	WordPressSignals            []string                 `json:"wordpress_signals,omitempty"`
+	Caching                     bool                     `json:"caching"`
+	CacheControl                string                   `json:"cache_control"`
+	WebServer                   string                   `json:"web_server"`
+	WebServerVersion            string                   `json:"web_server_version"`
+	SSLValid                    bool                     `json:"ssl_valid"`
+	SSLExpired                  bool                     `json:"ssl_expired"`
+	SSLIssues                   []string                 `json:"ssl_issues,omitempty"`
+	TTFBs                       []time.Duration          `json:"-"`
+	AverageTTFB                 time.Duration            `json:"-"`
+	TTFBStats                   TTFBStats                `json:"ttfb_stats"`
+	RegionalTTFB                []RegionalTTFB           `json:"regional_ttfb,omitempty"`
+	XPoweredBy                  string                   `json:"x_powered_by"`
+	PHPStatus                   string                   `json:"php_status"`
+	MySQLStatus                 string                   `json:"mysql_status"`
+	WebServerStatus             string                   `json:"web_server_status"`
+	WordPressStatus             string                   `json:"wordpress_status"`
+	SearchStatus                string                   `json:"search_status"`
+	ErrorHandling               string                   `json:"error_handling"`
+	WSODSuspected               bool                     `json:"wsod_suspected"`
+	StatusCode                  int                      `json:"status_code"`
+	SiteState                   string                   `json:"site_state"`
+	SiteStateReason             string                   `json:"site_state_reason,omitempty"`
+	WAF                         []string                 `json:"waf,omitempty"`
+	WAFBlocked                  bool                     `json:"waf_blocked"`
+	Blocklists                  *Blocklists              `json:"blocklists,omitempty"`
+	SEO                         *SEO                     `json:"seo,omitempty"`
+	StructuredData              *StructuredData          `json:"structured_data,omitempty"`
+	Analytics                   []AnalyticsTag           `json:"analytics"`
+	ServerDate                  time.Time                `json:"server_date,omitzero"`
+	ClockSkew                   time.Duration            `json:"-"`
+	ClockSkewFlag               bool                     `json:"clock_skew_significant"`
+	HeaderAnomalies             []string                 `json:"header_anomalies"`
+	CORSAllowOrigin             string                   `json:"cors_allow_origin"`
+	CORSCredentials             bool                     `json:"cors_allow_credentials"`
+	CORSIssues                  []string                 `json:"cors_issues"`
+	OpenRedirects               []string                 `json:"open_redirects"`
+	FrameProtection             string                   `json:"frame_protection"`
+	ClickjackingPoC             string                   `json:"clickjacking_poc,omitempty"`
+	OwnershipVerified           string                   `json:"ownership_verified,omitempty"`
+	Timing                      Timing                   `json:"timing"`
+	CertificateHostnameMismatch bool                     `json:"certificate_hostname_mismatch"`
+	Certificate                 *CertificateInfo         `json:"certificate,omitempty"`
+	ChainStatus                 string                   `json:"chain_status"`
+	OCSPStapling                string                   `json:"ocsp_stapling,omitempty"`
+	RevocationStatus            string                   `json:"revocation_status,omitempty"`
+	CT                          *CTLog                   `json:"certificate_transparency,omitempty"`
+	TLSVersion                  string                   `json:"tls_version"`
+	CipherSuite                 string                   `json:"cipher_suite"`
+	TLSAudit                    *TLSAudit                `json:"tls_audit,omitempty"`
+	TLSEndpoints                []TLSEndpoint            `json:"tls_endpoints,omitempty"`
+	TLSEndpointMismatch         bool                     `json:"tls_endpoint_mismatch"`
+	SecurityHeaders             *SecurityHeaders         `json:"security_headers,omitempty"`
+	HSTS                        *HSTS                    `json:"hsts,omitempty"`
+	HSTSPreload                 string                   `json:"hsts_preload,omitempty"`
+	Cookies                     *CookieAudit             `json:"cookies,omitempty"`
+	CDN                         string                   `json:"cdn"`
+	ACMEChallenge               string                   `json:"acme_challenge"`
+	CachingLayers               []string                 `json:"caching_layers"`
+	Plugins                     []Plugin                 `json:"plugins"`
+	CSP                         *CSPAnalysis             `json:"csp,omitempty"`
+	SRI                         *SRIReport               `json:"sri,omitempty"`
+	SiteName                    string                   `json:"site_name,omitempty"`
+	SiteDescription             string                   `json:"site_description,omitempty"`
+	WordPressVersionRange       string                   `json:"wordpress_version_range,omitempty"`
+	Exposures                   []Exposure               `json:"exposures,omitempty"`
+	PermissionsPolicyIssues     []string                 `json:"permissions_policy_issues"`
+	Theme                       string                   `json:"theme"`
+	ThemeVersion                string                   `json:"theme_version,omitempty"`
+	Vulnerabilities             int                      `json:"vulnerabilities"`
+	HighestSeverity             string                   `json:"highest_severity,omitempty"`
+	CMS                         string                   `json:"cms"`
+	CMSVersion                  string                   `json:"cms_version"`
+	BaselineTTFB                time.Duration            `json:"-"`
+	NetworkDegraded             bool                     `json:"network_degraded"`
+	Technologies                []fingerprint.Technology `json:"technologies"`
+	CustomFields                map[string]string        `json:"custom_fields,omitempty"`
+	DNS                         *DNSRecords              `json:"dns,omitempty"`
+	MailAuth                    *MailAuth                `json:"mail_auth,omitempty"`
+	LoginTTFB                   time.Duration            `json:"-"`
+	MediaOffload                string                   `json:"media_offload"`
+	Domain                      *DomainRegistration      `json:"domain,omitempty"`
+	ImageCDN                    string                   `json:"image_cdn"`
+	HostingProvider             string                   `json:"hosting_provider"`
+	ASN                         string                   `json:"asn"`
+	Country                     string                   `json:"country"`
+	ContactForm                 string                   `json:"contact_form,omitempty"`
+	FormPlugins                 []string                 `json:"form_plugins"`
+	IPAddresses                 []string                 `json:"ip_addresses"`
+	PTR                         string                   `json:"ptr"`
+	Ecommerce                   *Ecommerce               `json:"ecommerce,omitempty"`
+	IPv6                        string                   `json:"ipv6"`
+	PageWeight                  int64                    `json:"page_weight_bytes"`
+	HTTPVersion                 string                   `json:"http_version"`
+	HTTP3                       bool                     `json:"http3"`
+	Compression                 string                   `json:"compression,omitempty"`
+	CompressedSize              int64                    `json:"compressed_size_bytes,omitempty"`
+	UncompressedSize            int64                    `json:"uncompressed_size_bytes,omitempty"`
+	Percentiles                 *Percentiles             `json:"percentiles,omitempty"`
+	FinalURL                    string                   `json:"final_url"`
+	RedirectChain               []RedirectHop            `json:"redirect_chain"`
+	ExcessiveRedirects          bool                     `json:"excessive_redirects"`
+	Canonicalization            *Canonicalization        `json:"canonicalization,omitempty"`
+	Remediations                []Remediation            `json:"remediations"`
+	MixedContent                []string                 `json:"mixed_content"`
+	WPCLIScript                 string                   `json:"wp_cli_script,omitempty"`
+	Assets                      Assets                   `json:"assets"`
+	Propagation                 *Propagation             `json:"dns_propagation,omitempty"`
+	Purge                       *PurgeCheck              `json:"purge,omitempty"`
+	CacheKey                    *CacheKeyAudit           `json:"cache_key,omitempty"`
+	Revalidation                *Revalidation            `json:"revalidation,omitempty"`
+	SmugglingIndicators         []string                 `json:"smuggling_indicators,omitempty"`
+	RobotsTxt                   string                   `json:"robots_txt"`
+	RobotsBlocked               []string                 `json:"robots_blocked,omitempty"`
+	Provenance                  map[string]Provenance    `json:"provenance,omitempty"`
+	HotlinkIssues               []string                 `json:"hotlink_issues,omitempty"`
+	PluginUpdateLag             int                      `json:"plugin_update_lag"`
+	ThemeAbandoned              string                   `json:"theme_abandoned,omitempty"`
+	ThemeLicense                string                   `json:"theme_license,omitempty"`
+	HeadlessFrontend            string                   `json:"headless_frontend,omitempty"`
+	WordPressBackend            string                   `json:"wordpress_backend,omitempty"`
+	Platform                    string                   `json:"platform"`
+	ResponseHeaders             map[string][]string      `json:"response_headers,omitempty"`
+	CertificateChain            []ChainCertificate       `json:"certificate_chain,omitempty"`
+	DetailFile                  string                   `json:"detail_file,omitempty"`
+	Crawl                       *Crawl                   `json:"crawl,omitempty"`
+	HealthScore                 int                      `json:"health_score"`
+	HealthGrade                 string                   `json:"health_grade"`
+}
+
+// Percentiles ranks a site's metrics against previous scans: the percentage of sites
+// scanned before with a lower TTFB or a lighter page
+type Percentiles struct {
+	TTFB       float64 `json:"ttfb"`
+	PageWeight float64 `json:"page_weight"`
+}
+
+// Timing breaks a request down into its connection phases
+type Timing struct {
+	DNSLookup    time.Duration
+	TCPConnect   time.Duration
+	TLSHandshake time.Duration
+	TTFB         time.Duration
+	Download     time.Duration
+	Total        time.Duration
+
+	start time.Time
+}
+
+// MarshalJSON encodes the timing phases in milliseconds
+func (t Timing) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		DNSLookup    float64 `json:"dns_lookup_ms"`
+		TCPConnect   float64 `json:"tcp_connect_ms"`
+		TLSHandshake float64 `json:"tls_handshake_ms"`
+		TTFB         float64 `json:"ttfb_ms"`
+		Download     float64 `json:"download_ms"`
+		Total        float64 `json:"total_ms"`
+	}{
+		DNSLookup:    Milliseconds(t.DNSLookup),
+		TCPConnect:   Milliseconds(t.TCPConnect),
+		TLSHandshake: Milliseconds(t.TLSHandshake),
+		TTFB:         Milliseconds(t.TTFB),
+		Download:     Milliseconds(t.Download),
+		Total:        Milliseconds(t.Total),
+	})
+}
+
+// Milliseconds converts a duration to fractional milliseconds
+func Milliseconds(d time.Duration) float64 {
+	return d.Seconds() * 1000
+}
+
+// MarshalJSON encodes the site information with durations in milliseconds
+func (info *SiteInfo) MarshalJSON() ([]byte, error) {
+	type siteInfoJSON SiteInfo
+	ttfbs := make([]float64, len(info.TTFBs))
+	for i, ttfb := range info.TTFBs {
+		ttfbs[i] = Milliseconds(ttfb)
+	}
+	return json.Marshal(struct {
+		*siteInfoJSON
+		TTFBs       []float64 `json:"ttfbs_ms"`
+		AverageTTFB float64   `json:"average_ttfb_ms"`
+		ClockSkew   float64   `json:"clock_skew_ms"`
+		Baseline    float64   `json:"baseline_ttfb_ms,omitempty"`
+		LoginTTFB   float64   `json:"login_ttfb_ms,omitempty"`
+	}{
+		siteInfoJSON: (*siteInfoJSON)(info),
+		TTFBs:        ttfbs,
+		AverageTTFB:  Milliseconds(info.AverageTTFB),
+		ClockSkew:    Milliseconds(info.ClockSkew),
+		Baseline:     Milliseconds(info.BaselineTTFB),
+		LoginTTFB:    Milliseconds(info.LoginTTFB),
+	})
+}
+
+// fromMilliseconds converts fractional milliseconds to a duration
+func fromMilliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// UnmarshalJSON decodes site information written by MarshalJSON, so earlier reports can
+// be compared with new results
+func (info *SiteInfo) UnmarshalJSON(data []byte) error {
+	type siteInfoJSON SiteInfo
+	decoded := struct {
+		*siteInfoJSON
+		TTFBs       []float64 `json:"ttfbs_ms"`
+		AverageTTFB float64   `json:"average_ttfb_ms"`
+		ClockSkew   float64   `json:"clock_skew_ms"`
+		Baseline    float64   `json:"baseline_ttfb_ms"`
+		LoginTTFB   float64   `json:"login_ttfb_ms"`
+	}{siteInfoJSON: (*siteInfoJSON)(info)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	info.TTFBs = nil
+	for _, ttfb := range decoded.TTFBs {
+		info.TTFBs = append(info.TTFBs, fromMilliseconds(ttfb))
+	}
+	info.AverageTTFB = fromMilliseconds(decoded.AverageTTFB)
+	info.ClockSkew = fromMilliseconds(decoded.ClockSkew)
+	info.BaselineTTFB = fromMilliseconds(decoded.Baseline)
+	info.LoginTTFB = fromMilliseconds(decoded.LoginTTFB)
+	return nil
+}
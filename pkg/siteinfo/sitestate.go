@@ -0,0 +1,127 @@
+package siteinfo
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Site states, from what the homepage shows visitors
+const (
+	SiteLive        = "live"
+	SiteMaintenance = "maintenance"
+	SiteParked      = "parked"
+	SiteError       = "error"
+)
+
+// headingPattern matches the page title and top-level headings, where a holding page states
+// what it is. Searching them rather than the whole page keeps a live site mentioning
+// "coming soon" in its content from matching.
+var headingPattern = regexp.MustCompile(`(?is)<(title|h1)[^>]*>(.*?)</(?:title|h1)>`)
+
+// headingSeparatorPattern splits a title into its parts, such as the page and the site name in
+// "Under Maintenance | Acme"
+var headingSeparatorPattern = regexp.MustCompile(`[|–—·»:]| - `)
+
+// nonWordPattern matches the punctuation dropped from a heading before it is compared
+var nonWordPattern = regexp.MustCompile(`[^\pL\pN]+`)
+
+// maintenanceMarkers are strings maintenance and coming soon plugins leave anywhere in the page
+var maintenanceMarkers = []string{
+	"Briefly unavailable for scheduled maintenance",
+	"wp-maintenance-mode",
+	"seedprod-coming-soon",
+	"coming-soon-page",
+	"under-construction-page",
+	"elementor-maintenance-mode",
+}
+
+// maintenanceHeadings match a whole title part or heading of a maintenance, coming soon or
+// under construction page, so a live business such as "Acme Pool Maintenance" does not
+var maintenanceHeadings = []*regexp.Regexp{
+	regexp.MustCompile(`^(we re |we are |(this |our )?(web)?site (is )?)?((currently |temporarily )?(under|down for|in) )?(scheduled )?maintenance( mode)?$`),
+	regexp.MustCompile(`^(we re |we are |(this |our )?(web)?site (is )?)?(coming|launching) soon$`),
+	regexp.MustCompile(`^(we re |we are |(this |our )?(web)?site (is )?)?under construction$`),
+	regexp.MustCompile(`^(we ll |we will )?be back soon$`),
+}
+
+// parkingMarkers are strings domain parking and for sale pages contain
+var parkingMarkers = []string{
+	"this domain is for sale",
+	"this domain may be for sale",
+	"buy this domain",
+	"domain is parked",
+	"this web page is parked",
+	"parked free",
+	"sedoparking",
+	"parkingcrew",
+	"bodis.com",
+	"afternic",
+	"dan.com/buy-domain",
+}
+
+// notFoundHeadings match a whole title part or heading of a not found page, so a page such as
+// "Lost & Not Found Records" does not
+var notFoundHeadings = []*regexp.Regexp{
+	regexp.MustCompile(`^(error )?404( error)?( (page |file )?not found)?$`),
+	regexp.MustCompile(`^(page |file |post |content )?not found( 404)?$`),
+	regexp.MustCompile(`^(oops )?that page can ?t be found$`),
+}
+
+// matchHeading returns the first title part or heading matching one of the patterns
+func matchHeading(body string, patterns []*regexp.Regexp) (string, bool) {
+	for _, match := range headingPattern.FindAllStringSubmatch(body, -1) {
+		for _, part := range headingSeparatorPattern.Split(html.UnescapeString(visibleText(match[2])), -1) {
+			words := strings.TrimSpace(nonWordPattern.ReplaceAllString(strings.ToLower(part), " "))
+			for _, pattern := range patterns {
+				if pattern.MatchString(words) {
+					return strings.Join(strings.Fields(part), " "), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// containsAny reports whether text contains any of the phrases, returning the first found
+func containsAny(text string, phrases []string) (string, bool) {
+	for _, phrase := range phrases {
+		if strings.Contains(text, strings.ToLower(phrase)) {
+			return phrase, true
+		}
+	}
+	return "", false
+}
+
+// detectSiteState classifies what the homepage shows visitors: a live site, a maintenance or
+// coming soon page, a parked domain, or an error, and the reason for the classification.
+// Sites in any state but live are still scanned, but their findings describe the holding or
+// error page rather than the site.
+func detectSiteState(statusCode int, body string) (string, string) {
+	lower := strings.ToLower(body)
+
+	if marker, ok := containsAny(lower, maintenanceMarkers); ok {
+		return SiteMaintenance, "maintenance page (" + marker + ")"
+	}
+	if heading, ok := matchHeading(body, maintenanceHeadings); ok && (statusCode == http.StatusServiceUnavailable || statusCode < 400) {
+		return SiteMaintenance, fmt.Sprintf("page titled %q", heading)
+	}
+	if statusCode >= 400 {
+		return SiteError, fmt.Sprintf("HTTP %d", statusCode)
+	}
+	if marker, ok := containsAny(lower, parkingMarkers); ok {
+		return SiteParked, "parking page (" + marker + ")"
+	}
+	if hasPHPFatalError(body) {
+		return SiteError, "PHP fatal error"
+	}
+	if isWSOD(body) {
+		return SiteError, "blank page"
+	}
+	if heading, ok := matchHeading(body, notFoundHeadings); ok {
+		return SiteError, fmt.Sprintf("soft 404: HTTP %d page titled %q", statusCode, heading)
+	}
+	return SiteLive, ""
+}
@@ -0,0 +1,63 @@
+package siteinfo
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/vuln"
+)
+
+// smugglingAdvisories are server and proxy releases with published request smuggling issues,
+// keyed by the lowercase product token they advertise in the Server or Via header. Each entry
+// is the oldest release fixing the issue, so patched releases of other branches are not flagged.
+var smugglingAdvisories = map[string]struct {
+	fixed    string
+	advisory string
+}{
+	"apache":   {"2.4.56", "CVE-2023-25690 mod_proxy request splitting"},
+	"nginx":    {"1.17.7", "CVE-2019-20372 error_page request smuggling"},
+	"ats":      {"7.1.4", "CVE-2018-8004 request smuggling"},
+	"varnish":  {"6.0.8", "CVE-2021-36740 HTTP/2 request smuggling"},
+	"gunicorn": {"22.0.0", "CVE-2024-1135 Transfer-Encoding validation"},
+	"waitress": {"2.1.1", "CVE-2022-24761 request smuggling"},
+}
+
+// productTokenPattern matches product/version tokens such as nginx/1.18.0 or Varnish/6.0
+var productTokenPattern = regexp.MustCompile(`([A-Za-z][\w.-]*)/(\d+(?:\.\d+)*)`)
+
+// frontEndHeaders reveal a CDN or reverse proxy in front of the origin
+var frontEndHeaders = []string{"Via", "X-Cache", "CF-Ray", "X-Served-By", "X-Amz-Cf-Id", "X-Varnish"}
+
+// smugglingIndicators passively flags server and proxy combinations historically associated with
+// HTTP request smuggling. The findings are informational: they show where a front-end and
+// back-end may parse requests differently, not that the site is exploitable.
+func smugglingIndicators(header http.Header, proto string) []string {
+	var indicators []string
+
+	var tokens []string
+	tokens = append(tokens, header.Values("Server")...)
+	tokens = append(tokens, header.Values("Via")...)
+	for _, match := range productTokenPattern.FindAllStringSubmatch(strings.Join(tokens, " "), -1) {
+		product := strings.ToLower(match[1])
+		advisory, ok := smugglingAdvisories[product]
+		if ok && vuln.CompareVersions(match[2], advisory.fixed) < 0 {
+			indicators = append(indicators, match[1]+"/"+match[2]+" predates "+advisory.fixed+" ("+advisory.advisory+")")
+		}
+	}
+
+	var frontEnd string
+	for _, name := range frontEndHeaders {
+		if header.Get(name) != "" {
+			frontEnd = name
+			break
+		}
+	}
+	if frontEnd != "" {
+		indicators = append(indicators, "front-end proxy chain ("+frontEnd+" header), where front-end and back-end may parse request boundaries differently")
+		if strings.HasPrefix(proto, "HTTP/2") {
+			indicators = append(indicators, "HTTP/2 front-end likely downgrading to HTTP/1.1 towards the origin (H2.CL/H2.TE risk)")
+		}
+	}
+	return indicators
+}
@@ -0,0 +1,77 @@
+package siteinfo
+
+import (
+	neturl "net/url"
+	"regexp"
+	"strings"
+)
+
+// SRIReport summarizes subresource integrity usage on third-party scripts and stylesheets
+type SRIReport struct {
+	ExternalResources int      `json:"external_resources"`
+	WithIntegrity     int      `json:"with_integrity"`
+	ScriptsMissingSRI []string `json:"scripts_missing_sri"`
+}
+
+var (
+	// scriptTagPattern matches script tags
+	scriptTagPattern = regexp.MustCompile(`(?is)<script\b[^>]*>`)
+	// stylesheetTagPattern matches link tags
+	stylesheetTagPattern = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	// attributePattern extracts a tag's attributes and their values
+	attributePattern = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// tagAttributes returns the attributes of an HTML tag, keyed by lowercase name
+func tagAttributes(tag string) map[string]string {
+	attributes := map[string]string{}
+	for _, match := range attributePattern.FindAllStringSubmatch(tag, -1) {
+		attributes[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+	}
+	return attributes
+}
+
+// thirdPartyHost returns the host of a resource URL if it is served from a different
+// host than the page, or "" for same-origin and relative URLs
+func thirdPartyHost(resource, pageHost string) string {
+	if strings.HasPrefix(resource, "//") {
+		resource = "https:" + resource
+	}
+	u, err := neturl.Parse(resource)
+	if err != nil || u.Host == "" || strings.EqualFold(u.Hostname(), pageHost) {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// checkSRI reports which third-party scripts and stylesheets carry an integrity attribute.
+// Third-party scripts without one are flagged: a compromised CDN can run arbitrary code on the site.
+func checkSRI(body, url string) *SRIReport {
+	pageHost := hostOf(url)
+	report := &SRIReport{}
+
+	for _, tag := range scriptTagPattern.FindAllString(body, -1) {
+		attributes := tagAttributes(tag)
+		src := attributes["src"]
+		if thirdPartyHost(src, pageHost) == "" {
+			continue
+		}
+		report.ExternalResources++
+		if attributes["integrity"] != "" {
+			report.WithIntegrity++
+		} else {
+			report.ScriptsMissingSRI = append(report.ScriptsMissingSRI, src)
+		}
+	}
+	for _, tag := range stylesheetTagPattern.FindAllString(body, -1) {
+		attributes := tagAttributes(tag)
+		if !strings.Contains(strings.ToLower(attributes["rel"]), "stylesheet") || thirdPartyHost(attributes["href"], pageHost) == "" {
+			continue
+		}
+		report.ExternalResources++
+		if attributes["integrity"] != "" {
+			report.WithIntegrity++
+		}
+	}
+	return report
+}
@@ -0,0 +1,160 @@
+package siteinfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// errCertificateExpired is returned by checkSSL when the certificate has expired
+var errCertificateExpired = errors.New("expired")
+
+// sslResult holds the outcome of the certificate check
+type sslResult struct {
+	valid            bool
+	hostnameMismatch bool
+	state            tls.ConnectionState
+}
+
+// dialTLS completes a TLS handshake with addr, through the proxy when one applies, recording
+// it in the audit log
+func (s *Scanner) dialTLS(ctx context.Context, addr string, config *tls.Config) (*tls.Conn, error) {
+	start := time.Now()
+	var conn *tls.Conn
+	raw, err := s.dialProxied(ctx, addr)
+	if err == nil {
+		conn = tls.Client(raw, config)
+		handshakeCtx := ctx
+		tlsTimeout := s.opts.TLSTimeout
+		if host, _, _ := net.SplitHostPort(addr); s.opts.TorProxy != nil && isOnion(host) {
+			tlsTimeout = s.opts.OnionTimeout
+		}
+		if tlsTimeout > 0 {
+			var cancel context.CancelFunc
+			handshakeCtx, cancel = context.WithTimeout(ctx, tlsTimeout)
+			defer cancel()
+		}
+		if err = conn.HandshakeContext(handshakeCtx); err != nil {
+			raw.Close()
+		}
+	}
+	if s.audit != nil {
+		entry := AuditEntry{Timestamp: start, Method: "TLS", URL: "tls://" + addr, DurationMS: Milliseconds(time.Since(start))}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		s.audit.record(entry)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// checkSSL checks if the site has a valid SSL certificate. The handshake accepts any
+// certificate so that expiry and hostname mismatches can be reported separately.
+func (s *Scanner) checkSSL(ctx context.Context, url string) (sslResult, error) {
+	host := hostOf(url)
+	conn, err := s.dialTLS(ctx, net.JoinHostPort(host, "443"), &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	if err != nil {
+		return sslResult{}, err
+	}
+	defer conn.Close()
+
+	result := sslResult{state: conn.ConnectionState()}
+	certs := result.state.PeerCertificates
+	if len(certs) == 0 {
+		return result, nil
+	}
+
+	// Check the certificate
+	cert := certs[0]
+	result.hostnameMismatch = cert.VerifyHostname(host) != nil
+	if time.Now().After(cert.NotAfter) {
+		return result, errCertificateExpired
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, intermediate := range certs[1:] {
+		intermediates.AddCert(intermediate)
+	}
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+	result.valid = err == nil
+	return result, nil
+}
+
+// isCertificateError reports whether a request failed because the server's certificate did not
+// verify, rather than because the site could not be reached
+func isCertificateError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	return errors.As(err, &verifyErr)
+}
+
+// isSelfSigned reports whether the certificate is signed by its own key
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// certificateIssues names each reason the certificate fails verification, so an expired or
+// self-signed certificate, a hostname mismatch, a missing intermediate and an untrusted CA are
+// told apart rather than all reported as invalid. chainStatus is the chain's status as
+// returned by chainStatus.
+func certificateIssues(ssl sslResult, expired bool, chainStatus string) []string {
+	certs := ssl.state.PeerCertificates
+	if ssl.valid || len(certs) == 0 {
+		return nil
+	}
+	var issues []string
+	if expired {
+		issues = append(issues, "expired")
+	}
+	if ssl.hostnameMismatch {
+		issues = append(issues, "hostname mismatch")
+	}
+	switch {
+	case isSelfSigned(certs[0]):
+		issues = append(issues, "self-signed")
+	case chainStatus == "Incomplete":
+		issues = append(issues, "missing intermediate")
+	case chainStatus == "Untrusted":
+		issues = append(issues, "untrusted CA")
+	}
+	return issues
+}
+
+// certificateFailure returns the result for a site whose certificate does not verify. Its
+// pages cannot be fetched, so only the certificate and what is wrong with it are reported.
+func (s *Scanner) certificateFailure(ctx context.Context, info *SiteInfo, url string) (*SiteInfo, error) {
+	ssl, err := s.checkSSL(ctx, url)
+	expired := errors.Is(err, errCertificateExpired)
+	if err != nil && !expired {
+		return nil, err
+	}
+	failed := &SiteInfo{
+		URL:                         info.URL,
+		ScanStatus:                  ScanTLSError,
+		SSLExpired:                  expired,
+		SSLValid:                    ssl.valid,
+		CertificateHostnameMismatch: ssl.hostnameMismatch,
+		TLSVersion:                  tls.VersionName(ssl.state.Version),
+		CipherSuite:                 tls.CipherSuiteName(ssl.state.CipherSuite),
+	}
+	if certs := ssl.state.PeerCertificates; len(certs) > 0 {
+		failed.Certificate = describeCertificate(certs[0], s.opts.ExpiryWarningDays)
+		failed.ChainStatus = s.chainStatus(ctx, certs)
+		if s.opts.Details {
+			failed.CertificateChain = describeChain(certs)
+		}
+	}
+	failed.SSLIssues = certificateIssues(ssl, expired, failed.ChainStatus)
+	failed.ScanError = "certificate does not verify: " + strings.Join(failed.SSLIssues, ", ")
+	s.log(url).Warn("Certificate does not verify", "issues", failed.SSLIssues)
+	failed.Remediations = s.remediations(ctx, failed)
+	failed.HealthScore, failed.HealthGrade = healthScore(failed, *s.opts.HealthWeights)
+	return failed, nil
+}
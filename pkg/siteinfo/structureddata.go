@@ -0,0 +1,71 @@
+package siteinfo
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// jsonLDPattern matches JSON-LD script blocks and their content
+var jsonLDPattern = regexp.MustCompile(`(?is)<script\b[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+
+// StructuredData holds the schema.org JSON-LD blocks of the page
+type StructuredData struct {
+	Blocks int `json:"blocks"`
+	// Types lists the schema.org types of the top-level items, e.g. Organization or Product
+	Types []string `json:"types"`
+	// Invalid counts the blocks that are not valid JSON, which search engines ignore
+	Invalid int `json:"invalid"`
+}
+
+// jsonLDTypes returns the @type of a JSON-LD item, and of the items of its @graph. A type may
+// be a single name or a list, and may be given as a schema.org URL.
+func jsonLDTypes(item any) []string {
+	switch item := item.(type) {
+	case []any:
+		var types []string
+		for _, element := range item {
+			types = append(types, jsonLDTypes(element)...)
+		}
+		return types
+	case map[string]any:
+		var types []string
+		switch value := item["@type"].(type) {
+		case string:
+			types = append(types, value)
+		case []any:
+			for _, name := range value {
+				if name, ok := name.(string); ok {
+					types = append(types, name)
+				}
+			}
+		}
+		for i, name := range types {
+			name = strings.TrimPrefix(strings.TrimPrefix(name, "https://schema.org/"), "http://schema.org/")
+			types[i] = strings.TrimPrefix(name, "schema:")
+		}
+		return append(types, jsonLDTypes(item["@graph"])...)
+	}
+	return nil
+}
+
+// detectStructuredData parses the JSON-LD blocks of the page, listing the schema.org types
+// present and counting the blocks that are not valid JSON
+func detectStructuredData(body string) *StructuredData {
+	data := &StructuredData{Types: []string{}}
+	for _, match := range jsonLDPattern.FindAllStringSubmatch(body, -1) {
+		data.Blocks++
+		var item any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(match[1])), &item); err != nil {
+			data.Invalid++
+			continue
+		}
+		for _, name := range jsonLDTypes(item) {
+			if !slices.Contains(data.Types, name) {
+				data.Types = append(data.Types, name)
+			}
+		}
+	}
+	return data
+}
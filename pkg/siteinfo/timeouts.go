@@ -0,0 +1,116 @@
+package siteinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// errBodyTimeout is returned when reading a response body takes longer than the body timeout
+var errBodyTimeout = errors.New("timeout reading response body")
+
+// hasPhaseTimeouts reports whether any per-phase timeout is configured
+func (opts *Options) hasPhaseTimeouts() bool {
+	return opts.DNSTimeout > 0 || opts.ConnectTimeout > 0 || opts.TLSTimeout > 0 ||
+		opts.HeaderTimeout > 0 || opts.BodyTimeout > 0
+}
+
+// newTransport returns a transport enforcing the configured DNS, connect, TLS handshake and
+// response header timeouts, and routing through the configured proxy
+func (s *Scanner) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = s.dialContext
+	if s.opts.Proxy != nil {
+		transport.Proxy = http.ProxyURL(s.opts.Proxy)
+	}
+	if s.opts.TLSTimeout > 0 {
+		transport.TLSHandshakeTimeout = s.opts.TLSTimeout
+	}
+	transport.ResponseHeaderTimeout = s.opts.HeaderTimeout
+	return transport
+}
+
+// dialTimeouts connects to addr within the connect timeout, resolving its hostname within the
+// DNS timeout when one is set. The lookup runs on the request context, so it is still traced.
+func (s *Scanner) dialTimeouts(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.opts.ConnectTimeout, KeepAlive: 30 * time.Second, Resolver: s.opts.Resolver}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || s.opts.DNSTimeout <= 0 || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, s.opts.DNSTimeout)
+	defer cancel()
+	ipNetwork := "ip"
+	switch network {
+	case "tcp4":
+		ipNetwork = "ip4"
+	case "tcp6":
+		ipNetwork = "ip6"
+	}
+	ips, err := s.resolver().LookupIP(lookupCtx, ipNetwork, host)
+	if err != nil {
+		return nil, fmt.Errorf("DNS lookup of %s: %w", host, err)
+	}
+
+	var dialErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		if dialErr == nil {
+			dialErr = err
+		}
+	}
+	return nil, dialErr
+}
+
+// bodyTimeoutTransport bounds the time spent reading each response body once its headers arrive
+type bodyTimeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+// RoundTrip sends the request and cancels it if the body is not read within the timeout
+func (t *bodyTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+	timer := time.AfterFunc(t.timeout, func() { cancel(errBodyTimeout) })
+	resp.Body = &timedBody{ReadCloser: resp.Body, ctx: ctx, stop: func() {
+		timer.Stop()
+		cancel(nil)
+	}}
+	return resp, nil
+}
+
+// timedBody is a response body whose reads fail once the body timeout has passed
+type timedBody struct {
+	io.ReadCloser
+	ctx  context.Context
+	stop func()
+}
+
+// Read reads from the body, reporting a body timeout instead of the cancellation it caused
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && errors.Is(context.Cause(b.ctx), errBodyTimeout) {
+		err = errBodyTimeout
+	}
+	return n, err
+}
+
+// Close closes the body and releases its timer
+func (b *timedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.stop()
+	return err
+}
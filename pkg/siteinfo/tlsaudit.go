@@ -0,0 +1,54 @@
+package siteinfo
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+// TLSAudit reports which TLS protocol versions the server accepts
+type TLSAudit struct {
+	Protocols  []string `json:"protocols"`
+	Deprecated bool     `json:"deprecated"`
+}
+
+// tlsProtocols lists the protocol versions probed, oldest first
+var tlsProtocols = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// allCipherSuites returns every cipher suite Go implements, including insecure ones,
+// so that servers limited to legacy suites still complete the handshake
+func allCipherSuites() []uint16 {
+	var ids []uint16
+	for _, suite := range tls.CipherSuites() {
+		ids = append(ids, suite.ID)
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		ids = append(ids, suite.ID)
+	}
+	return ids
+}
+
+// auditTLS dials the host once per protocol version, constraining the handshake to
+// that version, and reports the versions accepted. TLS 1.0 and 1.1 are deprecated.
+func (s *Scanner) auditTLS(ctx context.Context, url string) *TLSAudit {
+	host := hostOf(url)
+	audit := &TLSAudit{}
+	for _, version := range tlsProtocols {
+		conn, err := s.dialTLS(ctx, net.JoinHostPort(host, "443"), &tls.Config{
+			ServerName:         host,
+			InsecureSkipVerify: true,
+			MinVersion:         version,
+			MaxVersion:         version,
+			CipherSuites:       allCipherSuites(),
+		})
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		audit.Protocols = append(audit.Protocols, tls.VersionName(version))
+		if version < tls.VersionTLS12 {
+			audit.Deprecated = true
+		}
+	}
+	return audit
+}
@@ -0,0 +1,104 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// TTFBStats summarizes the TTFB samples of a site, so flaky hosts with a wide spread stand out
+// from consistently slow ones
+type TTFBStats struct {
+	Min    time.Duration
+	Median time.Duration
+	P95    time.Duration
+	StdDev time.Duration
+}
+
+// ttfbStatsJSON is the JSON form of TTFBStats, in milliseconds
+type ttfbStatsJSON struct {
+	Min    float64 `json:"min_ms"`
+	Median float64 `json:"median_ms"`
+	P95    float64 `json:"p95_ms"`
+	StdDev float64 `json:"stddev_ms"`
+}
+
+// MarshalJSON encodes the statistics in milliseconds
+func (t TTFBStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ttfbStatsJSON{
+		Min:    Milliseconds(t.Min),
+		Median: Milliseconds(t.Median),
+		P95:    Milliseconds(t.P95),
+		StdDev: Milliseconds(t.StdDev),
+	})
+}
+
+// UnmarshalJSON decodes statistics written by MarshalJSON
+func (t *TTFBStats) UnmarshalJSON(data []byte) error {
+	var decoded ttfbStatsJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*t = TTFBStats{
+		Min:    fromMilliseconds(decoded.Min),
+		Median: fromMilliseconds(decoded.Median),
+		P95:    fromMilliseconds(decoded.P95),
+		StdDev: fromMilliseconds(decoded.StdDev),
+	}
+	return nil
+}
+
+// sampleTTFB makes the warm-up requests, whose timings are discarded since they prime the
+// origin's caches and connections, then measures the configured number of TTFB samples
+func (s *Scanner) sampleTTFB(ctx context.Context, url string) ([]time.Duration, error) {
+	var ttfs []time.Duration
+	for i := 0; i < s.opts.WarmupSamples+s.opts.Samples; i++ {
+		resp, timing, err := s.fetchURL(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching URL %s: %w", url, err)
+		}
+		resp.Body.Close()
+		if i >= s.opts.WarmupSamples {
+			ttfs = append(ttfs, timing.TTFB)
+		}
+	}
+	return ttfs, nil
+}
+
+// ttfbStatistics returns the mean and the statistics of the samples
+func ttfbStatistics(samples []time.Duration) (time.Duration, TTFBStats) {
+	if len(samples) == 0 {
+		return 0, TTFBStats{}
+	}
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, sample := range sorted {
+		total += sample
+	}
+	mean := total / time.Duration(len(sorted))
+
+	var variance float64
+	for _, sample := range sorted {
+		diff := float64(sample - mean)
+		variance += diff * diff
+	}
+	variance /= float64(len(sorted))
+
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	// Nearest-rank 95th percentile
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	return mean, TTFBStats{
+		Min:    sorted[0],
+		Median: median,
+		P95:    sorted[rank],
+		StdDev: time.Duration(math.Sqrt(variance)),
+	}
+}
@@ -0,0 +1,60 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// Uptime is the result of a lightweight availability check
+type Uptime struct {
+	URL        string        `json:"url"`
+	CheckedAt  time.Time     `json:"checked_at"`
+	Up         bool          `json:"up"`
+	StatusCode int           `json:"status_code,omitempty"`
+	TTFB       time.Duration `json:"-"`
+	RemoteAddr string        `json:"remote_addr,omitempty"`
+	Header     http.Header   `json:"-"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes the uptime check with the TTFB in milliseconds
+func (u *Uptime) MarshalJSON() ([]byte, error) {
+	type uptimeJSON Uptime
+	return json.Marshal(struct {
+		*uptimeJSON
+		TTFB float64 `json:"ttfb_ms"`
+	}{
+		uptimeJSON: (*uptimeJSON)(u),
+		TTFB:       Milliseconds(u.TTFB),
+	})
+}
+
+// CheckUptime fetches the URL once and reports whether it answered without a server error.
+// It is much lighter than Scan and suited to frequent monitoring.
+func (s *Scanner) CheckUptime(ctx context.Context, url string) *Uptime {
+	uptime := &Uptime{URL: url, CheckedAt: time.Now()}
+	if err := s.allowTarget(url); err != nil {
+		uptime.Error = err.Error()
+		return uptime
+	}
+	// Record the address that answered, which shows when a DNS cutover has reached the scanner
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(conn httptrace.GotConnInfo) {
+			uptime.RemoteAddr = conn.Conn.RemoteAddr().String()
+		},
+	})
+	resp, timing, err := s.fetchURL(ctx, asciiURL(url))
+	if err != nil {
+		uptime.Error = err.Error()
+		return uptime
+	}
+	resp.Body.Close()
+	uptime.StatusCode = resp.StatusCode
+	uptime.TTFB = timing.TTFB
+	uptime.Header = resp.Header
+	uptime.Up = resp.StatusCode < 500
+	return uptime
+}
@@ -0,0 +1,105 @@
+package siteinfo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"sync/atomic"
+)
+
+// Usage is the scanner's own resource consumption, for sizing machines for large portfolios
+type Usage struct {
+	// Sites is the number of sites scanned
+	Sites int `json:"sites"`
+	// CPUSeconds is the CPU time used by the process since the scanner was created
+	CPUSeconds float64 `json:"cpu_seconds"`
+	// MemoryBytes is the memory the process has obtained from the operating system
+	MemoryBytes uint64 `json:"memory_bytes"`
+	// BytesReceived and BytesSent count the traffic on every connection the scanner opened,
+	// including headers and TLS overhead but not DNS lookups
+	BytesReceived int64 `json:"bytes_received"`
+	BytesSent     int64 `json:"bytes_sent"`
+	// PerThousandSites scales the CPU time and traffic to 1000 sites. Nil when no site was scanned.
+	PerThousandSites *UsageRate `json:"per_1000_sites,omitempty"`
+}
+
+// UsageRate is the CPU time and traffic of scanning a number of sites
+type UsageRate struct {
+	CPUSeconds    float64 `json:"cpu_seconds"`
+	BytesReceived int64   `json:"bytes_received"`
+	BytesSent     int64   `json:"bytes_sent"`
+}
+
+// megabytes converts a byte count to MB
+func megabytes(bytes int64) float64 {
+	return float64(bytes) / (1 << 20)
+}
+
+// String summarizes the usage, e.g. "CPU 12.3s, memory 45.6 MB, received 120.5 MB, sent 1.2 MB"
+func (u Usage) String() string {
+	summary := fmt.Sprintf("CPU %.1fs, memory %.1f MB, received %.1f MB, sent %.1f MB",
+		u.CPUSeconds, megabytes(int64(u.MemoryBytes)), megabytes(u.BytesReceived), megabytes(u.BytesSent))
+	if u.PerThousandSites != nil {
+		summary += fmt.Sprintf(" (per 1000 sites: CPU %.1fs, received %.1f MB, sent %.1f MB)",
+			u.PerThousandSites.CPUSeconds, megabytes(u.PerThousandSites.BytesReceived), megabytes(u.PerThousandSites.BytesSent))
+	}
+	return summary
+}
+
+// bandwidth counts the bytes read and written on the scanner's connections
+type bandwidth struct {
+	received atomic.Int64
+	sent     atomic.Int64
+}
+
+// countingConn is a connection that adds its traffic to the scanner's bandwidth
+type countingConn struct {
+	net.Conn
+	bandwidth *bandwidth
+}
+
+// Read reads from the connection, counting the bytes received
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.bandwidth.received.Add(int64(n))
+	return n, err
+}
+
+// Write writes to the connection, counting the bytes sent
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.bandwidth.sent.Add(int64(n))
+	return n, err
+}
+
+// dialContext opens a connection with the configured timeouts whose traffic is counted
+func (s *Scanner) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := s.dialTimeouts(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: conn, bandwidth: &s.bandwidth}, nil
+}
+
+// Usage returns the resources used since the scanner was created
+func (s *Scanner) Usage() Usage {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	usage := Usage{
+		Sites:         int(s.sites.Load()),
+		CPUSeconds:    cpuSeconds() - s.cpuStart,
+		MemoryBytes:   mem.Sys,
+		BytesReceived: s.bandwidth.received.Load(),
+		BytesSent:     s.bandwidth.sent.Load(),
+	}
+	if usage.Sites > 0 {
+		scale := 1000 / float64(usage.Sites)
+		usage.PerThousandSites = &UsageRate{
+			CPUSeconds:    usage.CPUSeconds * scale,
+			BytesReceived: int64(float64(usage.BytesReceived) * scale),
+			BytesSent:     int64(float64(usage.BytesSent) * scale),
+		}
+	}
+	return usage
+}
@@ -0,0 +1,51 @@
+package siteinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/vuln"
+)
+
+// checkVulnerabilities looks up the known vulnerabilities in the detected WordPress core,
+// plugin and theme versions, returning their count, the highest severity (None if there are
+// none) and an update remediation for each vulnerable component. Components whose lookup
+// fails are skipped, and the findings for the others are returned with the error.
+func (s *Scanner) checkVulnerabilities(ctx context.Context, info *SiteInfo) (int, string, []Remediation, error) {
+	db := s.opts.Vulnerabilities
+	var found []vuln.Vulnerability
+	var remediations []Remediation
+
+	lookup := func(kind vuln.Kind, component, slug, version string) error {
+		vulnerabilities, err := db.Lookup(ctx, kind, slug, version)
+		found = append(found, vulnerabilities...)
+		if len(vulnerabilities) > 0 {
+			remediations = append(remediations, Remediation{
+				Action:           "update",
+				Component:        component,
+				Name:             slug,
+				CurrentVersion:   version,
+				SuggestedVersion: vuln.FixedVersion(vulnerabilities),
+				Reason:           fmt.Sprintf("%d known vulnerabilities (%s)", len(vulnerabilities), vuln.HighestSeverity(vulnerabilities)),
+			})
+		}
+		return err
+	}
+
+	// A failed lookup leaves the other components to check, reporting what was found with the error
+	var errs []error
+	errs = append(errs, lookup(vuln.Core, "wordpress", "", info.WordPressVersion))
+	for _, plugin := range info.Plugins {
+		errs = append(errs, lookup(vuln.Plugin, "plugin", plugin.Slug, plugin.Version))
+	}
+	errs = append(errs, lookup(vuln.Theme, "theme", info.Theme, info.ThemeVersion))
+	err := errors.Join(errs...)
+	if len(found) == 0 {
+		if err != nil {
+			return 0, "", nil, err
+		}
+		return 0, "None", nil, nil
+	}
+	return len(found), vuln.HighestSeverity(found), remediations, err
+}
@@ -0,0 +1,109 @@
+package siteinfo
+
+import (
+	"net/http"
+	"strings"
+)
+
+// wafSignature identifies a web application firewall by its response headers, Server header,
+// cookie name prefixes, the markup it adds to pages it lets through, and its block pages
+type wafSignature struct {
+	name       string
+	headers    []string
+	servers    []string
+	cookies    []string
+	markers    []string
+	blockPages []string
+}
+
+// wafSignatures lists the web application firewalls that can be detected
+var wafSignatures = []wafSignature{
+	{
+		name:       "Cloudflare",
+		headers:    []string{"CF-Mitigated"},
+		cookies:    []string{"cf_clearance", "__cf_bm"},
+		markers:    []string{"/cdn-cgi/challenge-platform/"},
+		blockPages: []string{"Attention Required! | Cloudflare", "Sorry, you have been blocked", "cf-error-details", "<title>Just a moment...</title>"},
+	},
+	{
+		name:       "Sucuri",
+		headers:    []string{"X-Sucuri-ID", "X-Sucuri-Block"},
+		servers:    []string{"sucuri/cloudproxy"},
+		cookies:    []string{"sucuri_cloudproxy_"},
+		blockPages: []string{"Sucuri WebSite Firewall - Access Denied", "cloudproxy@sucuri.net"},
+	},
+	{
+		name:       "Wordfence",
+		cookies:    []string{"wfwaf-authcookie-", "wfvt_"},
+		markers:    []string{"wordfence_lh", "wfLogHumanRan"},
+		blockPages: []string{"Generated by Wordfence", "Your access to this site has been limited by the site owner"},
+	},
+	{
+		name:       "ModSecurity",
+		headers:    []string{"X-Mod-Security"},
+		servers:    []string{"mod_security"},
+		blockPages: []string{"This error was generated by Mod_Security"},
+	},
+	{
+		name:       "Imperva",
+		headers:    []string{"X-Iinfo"},
+		cookies:    []string{"incap_ses_", "visid_incap_", "nlbi_"},
+		markers:    []string{"_Incapsula_Resource"},
+		blockPages: []string{"Incapsula incident ID", "Powered By Incapsula"},
+	},
+	{
+		name:    "AWS WAF",
+		headers: []string{"X-Amzn-Waf-Action"},
+		cookies: []string{"aws-waf-token"},
+		markers: []string{"awswaf.com", "AwsWafIntegration"},
+	},
+	{
+		name:       "F5 BIG-IP ASM",
+		cookies:    []string{"TS01"},
+		blockPages: []string{"The requested URL was rejected. Please consult with your administrator."},
+	},
+}
+
+// detectWAF identifies the web application firewalls in front of the site from the homepage
+// response, and whether the response is one of their block or challenge pages rather than the
+// site. A blocked scan sees only the block page, which explains the detections that come back
+// empty. Imperva is also recognised by its X-CDN header.
+func detectWAF(headers http.Header, body string) ([]string, bool) {
+	server := strings.ToLower(headers.Get("Server"))
+	var cookies []string
+	for _, cookie := range (&http.Response{Header: headers}).Cookies() {
+		cookies = append(cookies, cookie.Name)
+	}
+
+	var wafs []string
+	var blocked bool
+	for _, waf := range wafSignatures {
+		found := false
+		for _, name := range waf.headers {
+			found = found || headers.Get(name) != ""
+		}
+		for _, prefix := range waf.servers {
+			found = found || strings.Contains(server, prefix)
+		}
+		for _, prefix := range waf.cookies {
+			for _, cookie := range cookies {
+				found = found || strings.HasPrefix(cookie, prefix)
+			}
+		}
+		for _, marker := range waf.markers {
+			found = found || strings.Contains(body, marker)
+		}
+		for _, page := range waf.blockPages {
+			if strings.Contains(body, page) {
+				found, blocked = true, true
+			}
+		}
+		if waf.name == "Imperva" && strings.EqualFold(headers.Get("X-CDN"), "Incapsula") {
+			found = true
+		}
+		if found {
+			wafs = append(wafs, waf.name)
+		}
+	}
+	return wafs, blocked
+}
@@ -0,0 +1,51 @@
+package siteinfo
+
+import (
+	"cmp"
+	"context"
+	"net"
+	"strings"
+	"sync"
+)
+
+// maxWarmupConnections bounds the concurrent lookups and connections made by the warmup phase
+const maxWarmupConnections = 32
+
+// warmup resolves and connects to every target concurrently before any measurements start,
+// so TTFB samples across a long list are not skewed by cold DNS caches on the scanning machine.
+// Failures are ignored; the scan reports them. Sites Options.AllowTarget refuses are not touched.
+func (s *Scanner) warmup(ctx context.Context, urls []string) {
+	s.opts.Logger.Debug("Warming up DNS and connections", "sites", len(urls))
+	slots := make(chan struct{}, maxWarmupConnections)
+	var wg sync.WaitGroup
+	for _, url := range urls {
+		if s.allowTarget(url) != nil {
+			continue
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			host := hostOf(url)
+			port := "80"
+			if strings.HasPrefix(url, "https://") {
+				port = "443"
+			}
+			// Proxied sites are resolved and connected to by the proxy
+			if proxyURL, err := s.proxyFor(net.JoinHostPort(host, port)); err != nil || proxyURL != nil {
+				return
+			}
+			if _, err := s.resolver().LookupHost(ctx, host); err != nil {
+				return
+			}
+			dialer := &net.Dialer{Timeout: cmp.Or(s.opts.ConnectTimeout, s.opts.Timeout), Resolver: s.opts.Resolver}
+			conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+			if err == nil {
+				conn.Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,49 @@
+package siteinfo
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// wordPressAPIRel is the link relation WordPress advertises its REST API with, in a Link
+// header and a <link> tag on every page
+const wordPressAPIRel = "https://api.w.org/"
+
+// wordPressLoginMarkers are strings only the login form of wp-login.php renders
+var wordPressLoginMarkers = []string{`id="loginform"`, `name="wp-submit"`}
+
+// wordPressSignals returns the fingerprints of WordPress in the homepage: a WordPress
+// generator tag, /wp-content/ or /wp-includes/ asset paths, and the REST API link
+func wordPressSignals(headers http.Header, body string) []string {
+	var signals []string
+	if strings.HasPrefix(generatorTag(body), "WordPress") {
+		signals = append(signals, "generator")
+	}
+	if strings.Contains(body, "/wp-content/") || strings.Contains(body, "/wp-includes/") {
+		signals = append(signals, "wp-content")
+	}
+	if strings.Contains(strings.Join(headers.Values("Link"), ","), wordPressAPIRel) || strings.Contains(body, `rel="`+wordPressAPIRel+`"`) {
+		signals = append(signals, "wp-json")
+	}
+	return signals
+}
+
+// addSignal appends the signal unless it is already recorded
+func addSignal(signals []string, signal string) []string {
+	if slices.Contains(signals, signal) {
+		return signals
+	}
+	return append(signals, signal)
+}
+
+// probeWPLogin reports whether the site serves the WordPress login form at /wp-login.php, the
+// last sign of WordPress on a site that strips every fingerprint from its pages
+func (s *Scanner) probeWPLogin(ctx context.Context, url string) bool {
+	resp, body, err := s.fetchPage(ctx, strings.TrimRight(url, "/")+"/wp-login.php")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return false
+	}
+	return slices.ContainsFunc(wordPressLoginMarkers, func(marker string) bool { return strings.Contains(body, marker) })
+}
@@ -0,0 +1,72 @@
+package siteinfo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// wpJSONIndex is the subset of the REST API index at /wp-json/ used for detection
+type wpJSONIndex struct {
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Namespaces  []string                   `json:"namespaces"`
+	Routes      map[string]json.RawMessage `json:"routes"`
+}
+
+// wpRouteVersions maps REST API route prefixes to the WordPress release that introduced them,
+// newest first, so the first route found gives the minimum version
+var wpRouteVersions = []struct {
+	prefix  string
+	version string
+}{
+	{"/wp/v2/font-families", "6.5"},
+	{"/wp/v2/navigation", "5.9"},
+	{"/wp/v2/global-styles", "5.9"},
+	{"/wp/v2/widgets", "5.8"},
+	{"/wp-site-health/v1", "5.6"},
+	{"/wp/v2/block-types", "5.5"},
+	{"/wp/v2", "4.7"},
+	{"/oembed/1.0", "4.4"},
+}
+
+// fetchWPJSON fetches and decodes a REST API index, returning nil if it is not WordPress JSON
+func (s *Scanner) fetchWPJSON(ctx context.Context, url string) *wpJSONIndex {
+	resp, body, err := s.fetchPage(ctx, url)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	var index wpJSONIndex
+	if err := json.Unmarshal([]byte(body), &index); err != nil || (len(index.Namespaces) == 0 && len(index.Routes) == 0) {
+		return nil
+	}
+	return &index
+}
+
+// inferWPVersion returns the minimum WordPress version that serves the routes, e.g. ">= 5.9"
+func inferWPVersion(index *wpJSONIndex) string {
+	for _, candidate := range wpRouteVersions {
+		for route := range index.Routes {
+			if strings.HasPrefix(route, candidate.prefix) {
+				return ">= " + candidate.version
+			}
+		}
+	}
+	return ""
+}
+
+// probeWPJSON queries /wp-json/ and, if that is blocked, /wp-json/wp/v2/ to confirm the site
+// runs WordPress when the generator tag is stripped. It returns the site name and description
+// and the version range the available routes imply.
+func (s *Scanner) probeWPJSON(ctx context.Context, url string) (bool, string, string, string) {
+	base := strings.TrimRight(url, "/")
+	index := s.fetchWPJSON(ctx, base+"/wp-json/")
+	if index == nil {
+		index = s.fetchWPJSON(ctx, base+"/wp-json/wp/v2/")
+	}
+	if index == nil {
+		return false, "", "", ""
+	}
+	return true, index.Name, index.Description, inferWPVersion(index)
+}
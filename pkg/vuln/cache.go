@@ -0,0 +1,40 @@
+package vuln
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachePath returns the disk cache file for the component
+func (c *Client) cachePath(kind Kind, key string) string {
+	return filepath.Join(c.opts.CacheDir, "wpscan-"+string(kind)+"-"+filepath.Base(key)+".json")
+}
+
+// readDisk reads a fresh API response for the component from the disk cache
+func (c *Client) readDisk(kind Kind, key string) ([]byte, error) {
+	if c.opts.CacheDir == "" {
+		return nil, errors.New("disk cache disabled")
+	}
+	path := c.cachePath(kind, key)
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(stat.ModTime()) >= c.opts.CacheTTL {
+		return nil, errors.New("cache entry expired")
+	}
+	return os.ReadFile(path)
+}
+
+// writeDisk stores the API response in the disk cache; failures only cost a refetch next run
+func (c *Client) writeDisk(kind Kind, key string, data []byte) {
+	if c.opts.CacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(c.opts.CacheDir, 0755); err != nil {
+		return
+	}
+	os.WriteFile(c.cachePath(kind, key), data, 0644)
+}
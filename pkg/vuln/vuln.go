@@ -0,0 +1,358 @@
+// Package vuln looks up known vulnerabilities in WordPress core, plugins and themes using the
+// WPScan API or a local feed in the same format. API responses are cached on disk and requests
+// are rate limited to stay within the API quota.
+package vuln
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is the type of WordPress component being looked up
+type Kind string
+
+// The component kinds, named after the WPScan API endpoints
+const (
+	Core   Kind = "wordpresses"
+	Plugin Kind = "plugins"
+	Theme  Kind = "themes"
+)
+
+// Vulnerability is a known vulnerability affecting a component
+type Vulnerability struct {
+	Title    string `json:"title"`
+	FixedIn  string `json:"fixed_in"`
+	Severity string `json:"severity"`
+}
+
+// Options configures a Client
+type Options struct {
+	// Token is the WPScan API token. Either Token or FeedPath is required.
+	Token string
+	// FeedPath is a local JSON feed keyed by kind, then slug (or WordPress version), in the WPScan API format.
+	// When set, the API is not called.
+	FeedPath string
+	// CacheDir stores API responses between runs. Empty disables the disk cache.
+	CacheDir string
+	// CacheTTL is how long cached API responses are used. Defaults to 24 hours.
+	CacheTTL time.Duration
+	// Interval is the minimum time between API requests. Defaults to 1 second.
+	Interval time.Duration
+	// HTTPClient sends the API requests. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// apiComponent is a WPScan API entry for one component
+type apiComponent struct {
+	Vulnerabilities []struct {
+		Title   string  `json:"title"`
+		FixedIn *string `json:"fixed_in"`
+		CVSS    *struct {
+			Score string `json:"score"`
+		} `json:"cvss"`
+	} `json:"vulnerabilities"`
+}
+
+// lookupResult holds the API entry fetched for a component during this run. done is closed
+// once the lookup finishes; failed lookups are dropped so the next one tries again.
+type lookupResult struct {
+	done      chan struct{}
+	component *apiComponent
+	err       error
+}
+
+// rateLimitRetries is how many times a request answered 429 Too Many Requests is retried
+const rateLimitRetries = 3
+
+// maxRetryAfter is the longest Retry-After waited for; a longer one fails the lookup
+const maxRetryAfter = time.Minute
+
+// Client looks up vulnerabilities, fetching each component at most once per run
+type Client struct {
+	opts Options
+	feed map[Kind]map[string]json.RawMessage
+
+	mu      sync.Mutex
+	results map[string]*lookupResult
+	last    time.Time
+	limit   sync.Mutex
+}
+
+// apiURL is the WPScan API base URL
+const apiURL = "https://wpscan.com/api/v3/"
+
+// New creates a Client, loading the local feed if one is configured
+func New(opts Options) (*Client, error) {
+	if opts.Token == "" && opts.FeedPath == "" {
+		return nil, errors.New("a WPScan API token or a local feed is required")
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = 24 * time.Hour
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	c := &Client{opts: opts, results: map[string]*lookupResult{}}
+	if opts.FeedPath != "" {
+		data, err := os.ReadFile(opts.FeedPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &c.feed); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", opts.FeedPath, err)
+		}
+	}
+	return c, nil
+}
+
+// Lookup returns the vulnerabilities affecting the component at the given version. Core is
+// looked up by version; plugins and themes by slug, keeping vulnerabilities fixed after
+// version. An empty plugin or theme version keeps every vulnerability without a fix.
+func (c *Client) Lookup(ctx context.Context, kind Kind, slug, version string) ([]Vulnerability, error) {
+	key := slug
+	if kind == Core {
+		key = strings.ReplaceAll(version, ".", "")
+	}
+	if key == "" {
+		return nil, nil
+	}
+
+	component, err := c.component(ctx, kind, key)
+	if err != nil || component == nil {
+		return nil, err
+	}
+
+	var vulnerabilities []Vulnerability
+	for _, v := range component.Vulnerabilities {
+		fixedIn := ""
+		if v.FixedIn != nil {
+			fixedIn = *v.FixedIn
+		}
+		if kind != Core && fixedIn != "" && (version == "" || CompareVersions(version, fixedIn) >= 0) {
+			continue
+		}
+		vulnerability := Vulnerability{Title: v.Title, FixedIn: fixedIn, Severity: "Unknown"}
+		if v.CVSS != nil {
+			if score, err := strconv.ParseFloat(v.CVSS.Score, 64); err == nil {
+				vulnerability.Severity = severity(score)
+			}
+		}
+		vulnerabilities = append(vulnerabilities, vulnerability)
+	}
+	return vulnerabilities, nil
+}
+
+// component returns the feed or API entry for the component. Each is fetched once per run
+// when the lookup succeeds; a lookup that failed, or was cancelled with its scan, is not
+// remembered, so later scans look the component up again.
+func (c *Client) component(ctx context.Context, kind Kind, key string) (*apiComponent, error) {
+	if c.feed != nil {
+		return decodeComponent(c.feed[kind][key])
+	}
+
+	id := string(kind) + "/" + key
+	for {
+		c.mu.Lock()
+		result, ok := c.results[id]
+		if !ok {
+			result = &lookupResult{done: make(chan struct{})}
+			c.results[id] = result
+			c.mu.Unlock()
+
+			result.component, result.err = c.load(ctx, kind, key)
+			if result.err != nil {
+				c.mu.Lock()
+				delete(c.results, id)
+				c.mu.Unlock()
+			}
+			close(result.done)
+			return result.component, result.err
+		}
+		c.mu.Unlock()
+
+		// Wait for the scan already looking it up, and try again ourselves if it failed
+		select {
+		case <-result.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if result.err == nil {
+			return result.component, nil
+		}
+	}
+}
+
+// load resolves the component from the disk cache or the API
+func (c *Client) load(ctx context.Context, kind Kind, key string) (*apiComponent, error) {
+	data, err := c.readDisk(kind, key)
+	if err != nil {
+		data, err = c.fetch(ctx, kind, key)
+		if err != nil {
+			return nil, err
+		}
+		c.writeDisk(kind, key, data)
+	}
+	return decodeComponent(data)
+}
+
+// decodeComponent decodes a WPScan API response, which wraps the entry in an object keyed
+// by the slug or version. Missing data decodes to nil.
+func decodeComponent(data []byte) (*apiComponent, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var entries map[string]*apiComponent
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		return entry, nil
+	}
+	return nil, nil
+}
+
+// fetch calls the WPScan API, waiting for the rate limit. A 404 means the component is
+// unknown to WPScan and is cached as an empty entry. A 429 holds back every request for the
+// time given in Retry-After, up to a minute, or an interval doubling with each attempt, before
+// retrying.
+func (c *Client) fetch(ctx context.Context, kind Kind, key string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx); err != nil {
+			return nil, err
+		}
+		data, status, delay, err := c.get(ctx, kind, key)
+		if status != http.StatusTooManyRequests || attempt == rateLimitRetries || delay > maxRetryAfter {
+			return data, err
+		}
+		if delay <= 0 {
+			delay = c.opts.Interval << (attempt + 1)
+		}
+		// Push the last request time forward, so the next request from any scan waits out the delay
+		c.limit.Lock()
+		c.last = time.Now().Add(delay - c.opts.Interval)
+		c.limit.Unlock()
+	}
+}
+
+// wait blocks until the rate limit allows another API request
+func (c *Client) wait(ctx context.Context) error {
+	c.limit.Lock()
+	defer c.limit.Unlock()
+	if wait := c.opts.Interval - time.Since(c.last); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	c.last = time.Now()
+	return nil
+}
+
+// get sends one API request, returning the response's status code and, for a 429, the
+// delay its Retry-After header asks for
+func (c *Client) get(ctx context.Context, kind Kind, key string) ([]byte, int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL+string(kind)+"/"+key, nil)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	req.Header.Set("Authorization", "Token token="+c.opts.Token)
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var data json.RawMessage
+		if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+			return nil, resp.StatusCode, 0, err
+		}
+		return data, resp.StatusCode, 0, nil
+	case http.StatusNotFound:
+		return []byte("{}"), resp.StatusCode, 0, nil
+	}
+	err = fmt.Errorf("WPScan API returned HTTP %d for %s/%s", resp.StatusCode, kind, key)
+	var delay time.Duration
+	if seconds, convErr := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); convErr == nil && seconds > 0 {
+		delay = time.Duration(seconds) * time.Second
+	}
+	return nil, resp.StatusCode, delay, err
+}
+
+// severity maps a CVSS score to its qualitative rating
+func severity(score float64) string {
+	switch {
+	case score >= 9:
+		return "Critical"
+	case score >= 7:
+		return "High"
+	case score >= 4:
+		return "Medium"
+	case score > 0:
+		return "Low"
+	}
+	return "None"
+}
+
+// severityRank orders severities from least to most severe
+var severityRank = map[string]int{"": 0, "None": 1, "Unknown": 2, "Low": 3, "Medium": 4, "High": 5, "Critical": 6}
+
+// HighestSeverity returns the most severe rating among the vulnerabilities, or "" if there are none
+func HighestSeverity(vulnerabilities []Vulnerability) string {
+	highest := ""
+	for _, v := range vulnerabilities {
+		if severityRank[v.Severity] > severityRank[highest] {
+			highest = v.Severity
+		}
+	}
+	return highest
+}
+
+// FixedVersion returns the lowest version that fixes every vulnerability, or "" if any of
+// them has no fix yet
+func FixedVersion(vulnerabilities []Vulnerability) string {
+	fixed := ""
+	for _, v := range vulnerabilities {
+		if v.FixedIn == "" {
+			return ""
+		}
+		if CompareVersions(v.FixedIn, fixed) > 0 {
+			fixed = v.FixedIn
+		}
+	}
+	return fixed
+}
+
+// CompareVersions compares dotted version strings numerically, returning -1, 0 or 1
+func CompareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
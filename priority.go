@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// priorityLevels maps named priorities to their rank; lower ranks are scanned first
+var priorityLevels = map[string]int{
+	"critical": 0,
+	"high":     1,
+	"medium":   2,
+	"normal":   2,
+	"low":      3,
+}
+
+// lowestPriority is the rank of sites with a missing or unrecognised priority
+const lowestPriority = int(^uint(0) >> 1)
+
+// parsePriority ranks a priority value: a named level (critical, high, normal, low) or a
+// number such as 1 or P1, where lower numbers are scanned first
+func parsePriority(value string) int {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if rank, ok := priorityLevels[value]; ok {
+		return rank
+	}
+	if rank, err := strconv.Atoi(strings.TrimPrefix(value, "p")); err == nil {
+		return rank
+	}
+	return lowestPriority
+}
+
+// sortByPriority orders the URLs by their priorities, keeping the input order for sites
+// with the same priority
+func sortByPriority(urls, priorities []string) {
+	ranks := make([]int, len(priorities))
+	for i, priority := range priorities {
+		ranks[i] = parsePriority(priority)
+	}
+	indexes := make([]int, len(urls))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return ranks[indexes[a]] < ranks[indexes[b]]
+	})
+	sorted := make([]string, len(urls))
+	for i, index := range indexes {
+		sorted[i] = urls[index]
+	}
+	copy(urls, sorted)
+}
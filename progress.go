@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressWindow is the number of recent completions the ETA's scan rate is measured over, so
+// the estimate follows slow or fast stretches of the input rather than the run's average
+const progressWindow = 20
+
+// progressBarWidth is the width of the bar in characters
+const progressBarWidth = 30
+
+// quiet silences the progress bar and logs only errors, so cron jobs only report problems
+var quiet bool
+
+// isTerminal reports whether the file is an interactive terminal rather than a pipe, file or
+// cron's mail
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// progress draws a progress bar for batch runs, redrawn in place: the sites completed of the
+// total, the failures so far and the time remaining at the recent scan rate
+type progress struct {
+	out     io.Writer
+	total   int
+	started time.Time
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	mu        sync.Mutex
+	completed int
+	failed    int
+	recent    []time.Time
+}
+
+// startProgress draws the progress bar for total sites and redraws it every second, so the
+// elapsed time and ETA move on while long scans are in flight
+func startProgress(out io.Writer, total int) *progress {
+	p := &progress{out: out, total: total, started: time.Now(), done: make(chan struct{})}
+	p.draw()
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.draw()
+			}
+		}
+	}()
+	return p
+}
+
+// record counts a finished site, failed when err is set
+func (p *progress) record(err error) {
+	p.mu.Lock()
+	p.completed++
+	if err != nil {
+		p.failed++
+	}
+	p.recent = append(p.recent, time.Now())
+	if len(p.recent) > progressWindow {
+		p.recent = p.recent[1:]
+	}
+	p.mu.Unlock()
+	p.draw()
+}
+
+// finish draws the final state of the bar and ends its line, so later messages start below it
+func (p *progress) finish() {
+	close(p.done)
+	p.wg.Wait()
+	p.draw()
+	fmt.Fprintln(p.out)
+}
+
+// Write prints log output above the bar: it clears the bar, writes the data and redraws the bar
+// below it
+func (p *progress) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprint(p.out, "\r\x1b[K")
+	n, err := p.out.Write(data)
+	p.drawLocked()
+	return n, err
+}
+
+// draw redraws the bar over the previous one
+func (p *progress) draw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.drawLocked()
+}
+
+// drawLocked draws the bar with p.mu held
+func (p *progress) drawLocked() {
+	filled := progressBarWidth
+	if p.total > 0 {
+		filled = p.completed * progressBarWidth / p.total
+	}
+	line := fmt.Sprintf("[%s%s] %d/%d sites", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), p.completed, p.total)
+	if p.failed > 0 {
+		line += fmt.Sprintf(", %d failed", p.failed)
+	}
+	line += ", elapsed " + time.Since(p.started).Round(time.Second).String()
+	if eta, ok := p.eta(); ok {
+		line += ", ETA " + eta.Round(time.Second).String()
+	}
+	// Clear the rest of the line, in case the previous one was longer
+	fmt.Fprintf(p.out, "\r%s\x1b[K", line)
+}
+
+// eta estimates the time until every site is scanned from the rate of the recent completions,
+// which accounts for the scans running concurrently. Until the window fills, the rate is
+// measured from the start of the run.
+func (p *progress) eta() (time.Duration, bool) {
+	remaining := p.total - p.completed
+	if p.completed == 0 || remaining <= 0 {
+		return 0, false
+	}
+	first, count := p.started, p.completed
+	if p.completed > progressWindow {
+		first, count = p.recent[0], len(p.recent)-1
+	}
+	elapsed := p.recent[len(p.recent)-1].Sub(first)
+	if elapsed <= 0 {
+		return 0, false
+	}
+	perSite := elapsed / time.Duration(count)
+	// Count the time since the last completion as progress towards the next one
+	eta := perSite*time.Duration(remaining) - time.Since(p.recent[len(p.recent)-1])
+	return max(eta, 0), true
+}
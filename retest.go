@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// loadReport returns the sites of an earlier JSON report
+func loadReport(filePath string) ([]*siteinfo.SiteInfo, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var siteInfos []*siteinfo.SiteInfo
+	if err := json.Unmarshal(data, &siteInfos); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filePath, err)
+	}
+	return siteInfos, nil
+}
+
+// loadReportEntry returns the site's entry from an earlier JSON report, or nil if it is not there
+func loadReportEntry(filePath, url string) (*siteinfo.SiteInfo, error) {
+	siteInfos, err := loadReport(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range siteInfos {
+		if info.URL == url {
+			return info, nil
+		}
+	}
+	return nil, nil
+}
+
+// runRetest re-runs one detector against one site and prints its result, after the result
+// recorded in an earlier JSON report when one is given
+func runRetest(args []string) {
+	fs := flag.NewFlagSet("retest", flag.ExitOnError)
+	reportPath := fs.String("report", "", "earlier JSON report to show the site's previous result from")
+	scan := registerScanFlags(fs)
+	registerErrorFormat(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: site-info-fetcher retest [flags] <url> <check>\n\nChecks: %v\n\nFlags:\n", siteinfo.Checks())
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	url, check := fs.Arg(0), fs.Arg(1)
+	if !slices.Contains(siteinfo.Checks(), check) {
+		fail(codeUnknownCheck, fmt.Errorf("%q; available checks: %v", check, siteinfo.Checks()))
+	}
+
+	opts, closeAudit, err := scan.options()
+	if err != nil {
+		fail(codeScannerConfig, err)
+	}
+	defer closeAudit()
+	opts.Logger = nil
+	if err := opts.AllowTarget(url); err != nil {
+		fail(codeTargetRefused, err)
+	}
+
+	if *reportPath != "" {
+		before, err := loadReportEntry(*reportPath, url)
+		switch {
+		case err != nil:
+			fail(codeReportRead, err)
+		case before == nil:
+			fmt.Printf("Before: %s is not in %s\n", url, *reportPath)
+		default:
+			fmt.Printf("Before: %s\n", siteinfo.DescribeCheck(before, check))
+		}
+	}
+
+	after, err := siteinfo.New(opts).Retest(context.Background(), url, check)
+	if err != nil {
+		fail(codeRetest, fmt.Errorf("%s: %w", url, err))
+	}
+	fmt.Printf("After:  %s\n", siteinfo.DescribeCheck(after, check))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+)
+
+// parseSampleSize parses a -sample value, a number of sites such as 500 or a percentage of
+// the portfolio such as 5%, into a number of sites between 1 and total
+func parseSampleSize(value string, total int) (int, error) {
+	var size int
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p <= 0 || p > 100 {
+			return 0, fmt.Errorf("invalid sample %q: use a number of sites such as 500 or a percentage such as 5%%", value)
+		}
+		size = int(math.Ceil(float64(total) * p / 100))
+	} else {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid sample %q: use a number of sites such as 500 or a percentage such as 5%%", value)
+		}
+		size = n
+	}
+	return min(max(size, 1), total), nil
+}
+
+// stratifiedSample draws a random sample of about size sites, allocating it across the groups
+// in proportion to their sizes, with at least one site from every group, so each client or
+// group is represented. The sampled URLs keep their input order. Each is weighted by the
+// number of portfolio sites it stands for, its group's size over the group's sample size, so
+// statistics over the sample can be extrapolated to the whole portfolio.
+func stratifiedSample(urls []string, groups map[string]string, size int, rng *rand.Rand) ([]string, map[string]float64) {
+	var names []string
+	members := map[string][]string{}
+	for _, url := range urls {
+		group := groups[url]
+		if members[group] == nil {
+			names = append(names, group)
+		}
+		members[group] = append(members[group], url)
+	}
+
+	// Allocate the sample by the largest remainder method, then give empty groups one site
+	allocation := map[string]int{}
+	remainders := map[string]float64{}
+	allocated := 0
+	for _, name := range names {
+		share := float64(size) * float64(len(members[name])) / float64(len(urls))
+		allocation[name] = int(share)
+		remainders[name] = share - float64(allocation[name])
+		allocated += allocation[name]
+	}
+	byRemainder := append([]string{}, names...)
+	sort.SliceStable(byRemainder, func(i, j int) bool { return remainders[byRemainder[i]] > remainders[byRemainder[j]] })
+	for _, name := range byRemainder[:min(size-allocated, len(byRemainder))] {
+		allocation[name]++
+	}
+	for _, name := range names {
+		allocation[name] = min(max(allocation[name], 1), len(members[name]))
+	}
+
+	// Draw each group's sites at random
+	chosen := map[string]float64{}
+	for _, name := range names {
+		group := members[name]
+		weight := float64(len(group)) / float64(allocation[name])
+		for _, i := range rng.Perm(len(group))[:allocation[name]] {
+			chosen[group[i]] = weight
+		}
+	}
+	var sample []string
+	for _, url := range urls {
+		if _, ok := chosen[url]; ok {
+			sample = append(sample, url)
+		}
+	}
+	return sample, chosen
+}
+
+// logEstimate logs the portfolio statistics extrapolated from the sample
+func logEstimate(summary *report.Summary, portfolio int) {
+	attrs := []any{"sampled", summary.SampledSites, "portfolio", portfolio,
+		"supported_php_percent", math.Round(summary.SupportedPHPPercent*10) / 10,
+		"average_ttfb_ms", math.Round(summary.AverageTTFB*10) / 10}
+	for _, histogram := range []struct {
+		name   string
+		counts map[string]int
+	}{
+		{"php_versions", summary.PHPVersions},
+		{"wordpress_versions", summary.WordPressVersions},
+		{"web_servers", summary.WebServers},
+		{"certificate_expiry", summary.CertificateExpiry},
+	} {
+		var values []string
+		for value := range histogram.counts {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for i, value := range values {
+			values[i] = fmt.Sprintf("%s ~%d", value, histogram.counts[value])
+		}
+		if len(values) > 0 {
+			attrs = append(attrs, histogram.name, strings.Join(values, ", "))
+		}
+	}
+	slog.Info("Estimated portfolio statistics", attrs...)
+}
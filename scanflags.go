@@ -0,0 +1,446 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/fingerprint"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/geoip"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/vuln"
+)
+
+// scanFlags holds the flags that configure the scanner, shared by every command that scans
+type scanFlags struct {
+	concurrency         *int
+	rateLimit           *float64
+	hostConcurrency     *int
+	auditLogPath        *string
+	otlpEndpoint        *string
+	timeout             *time.Duration
+	dnsTimeout          *time.Duration
+	connectTimeout      *time.Duration
+	tlsTimeout          *time.Duration
+	headerTimeout       *time.Duration
+	bodyTimeout         *time.Duration
+	retries             *int
+	samples             *int
+	warmupSamples       *int
+	baselineURL         *string
+	calibrationInterval *time.Duration
+	warmup              *bool
+	userAgent           *string
+	requestProfile      *string
+	proxy               *string
+	resolver            *string
+	torProxy            *string
+	onionTimeout        *time.Duration
+	credentials         *string
+	eolCacheDir         *string
+	eolCacheTTL         *time.Duration
+	offline             *bool
+	checkSearch         *bool
+	checkErrorPage      *bool
+	checkCORS           *bool
+	checkDNS            *bool
+	checkMailAuth       *bool
+	checkWPJSON         *bool
+	checkACME           *bool
+	checkTLSEndpoints   *bool
+	checkContactForm    *bool
+	checkCanonical      *bool
+	checkVary           *bool
+	checkRevalidation   *bool
+	checkRobots         *bool
+	respectRobots       *bool
+	provenance          *bool
+	strict              *bool
+	checkCompression    *bool
+	checkIPv6           *bool
+	checkEcommerce      *bool
+	checkOpenRedirect   *bool
+	fetchAssets         *bool
+	crawl               *int
+	healthWeights       *string
+	checkHotlink        *bool
+	checkPluginUpdates  *bool
+	checkAbandonment    *bool
+	checkLicenses       *bool
+	expiryWarningDays   *int
+	checkLoginTTFB      *bool
+	checkDomainExpiry   *bool
+	checkExposure       *bool
+	checkTLSAudit       *bool
+	checkRevocation     *bool
+	checkCT             *bool
+	checkHSTSPreload    *bool
+	ctIssuers           *string
+	checkBlocklists     *bool
+	agents              *string
+	agentToken          *string
+	safeBrowsingKey     *string
+	checkPropagation    *bool
+	checkPurge          *bool
+	checkSmuggling      *bool
+	fingerprintRules    *string
+	detectRules         *string
+	wpscanToken         *string
+	vulnFeed            *string
+	geoip               *bool
+	geoipASNDB          *string
+	geoipCountryDB      *string
+	ipinfoToken         *string
+	verifyToken         *string
+	requireVerification *bool
+	include             stringList
+	exclude             stringList
+	blocklist           *string
+	targets             *targetFilter
+}
+
+// registerScanFlags defines the scanner flags on fs
+func registerScanFlags(fs *flag.FlagSet) *scanFlags {
+	f := &scanFlags{
+		concurrency:         fs.Int("concurrency", 1, "number of sites to scan in parallel"),
+		rateLimit:           fs.Float64("rate-limit", 0, "maximum requests per second across all sites (0 is unlimited)"),
+		hostConcurrency:     fs.Int("host-concurrency", 0, "maximum requests in flight to each host (0 is unlimited)"),
+		auditLogPath:        fs.String("audit-log", "", "append an NDJSON audit log of every outbound request to this file"),
+		otlpEndpoint:        fs.String("otlp-endpoint", "", "send OpenTelemetry traces of each scan to this OTLP/HTTP collector, e.g. http://localhost:4318 (defaults to OTEL_EXPORTER_OTLP_ENDPOINT)"),
+		timeout:             fs.Duration("timeout", 10*time.Second, "timeout for each HTTP request as a whole (0 disables it when a phase timeout is set)"),
+		dnsTimeout:          fs.Duration("dns-timeout", 0, "timeout for each hostname lookup"),
+		connectTimeout:      fs.Duration("connect-timeout", 0, "timeout for each TCP connection attempt"),
+		tlsTimeout:          fs.Duration("tls-timeout", 10*time.Second, "timeout for each TLS handshake"),
+		headerTimeout:       fs.Duration("header-timeout", 0, "timeout awaiting response headers once a request is sent"),
+		bodyTimeout:         fs.Duration("body-timeout", 0, "timeout reading each response body once its headers arrive"),
+		retries:             fs.Int("retries", 5, "attempts for requests that time out awaiting headers"),
+		samples:             fs.Int("samples", 3, "number of TTFB measurements taken per site"),
+		warmupSamples:       fs.Int("warmup-samples", 0, "requests made and discarded before measuring TTFB"),
+		baselineURL:         fs.String("baseline-url", "", "reference URL measured periodically to detect degraded network conditions on the scanner"),
+		calibrationInterval: fs.Duration("calibration-interval", time.Minute, "how often the baseline URL is re-measured"),
+		warmup:              fs.Bool("warmup", true, "resolve and connect to every site before measuring"),
+		userAgent:           fs.String("user-agent", "", "User-Agent header sent with every request"),
+		requestProfile:      fs.String("request-profile", "default", "request headers to send: default, or browser to send desktop Chrome's headers to sites that filter bots"),
+		credentials:         fs.String("credentials", "", "YAML file of basic auth credentials, cookies and headers for password-protected sites, keyed by hostname"),
+		proxy:               fs.String("proxy", "", "route all scanning traffic through this HTTP or SOCKS5 proxy, e.g. socks5://127.0.0.1:1080 (defaults to HTTPS_PROXY)"),
+		resolver:            fs.String("resolver", "", "DNS server for every lookup, as host:port (e.g. 1.1.1.1:53) or a DNS-over-HTTPS URL, instead of the system resolver"),
+		torProxy:            fs.String("tor-proxy", "", "route .onion sites through Tor's SOCKS5 proxy, e.g. socks5h://127.0.0.1:9050"),
+		onionTimeout:        fs.Duration("onion-timeout", 60*time.Second, "timeout for each request to a .onion site, replacing -timeout and the phase timeouts"),
+		eolCacheDir:         fs.String("eol-cache-dir", defaultCacheDir(), "directory caching endoflife.date responses between runs (empty disables)"),
+		eolCacheTTL:         fs.Duration("eol-cache-ttl", 24*time.Hour, "how long cached endoflife.date responses are reused"),
+		offline:             fs.Bool("offline", false, "use cached or bundled endoflife.date data instead of calling the API"),
+		checkSearch:         fs.Bool("check-search", true, "probe the WordPress search results template"),
+		checkErrorPage:      fs.Bool("check-error-page", true, "check how the site handles missing pages"),
+		checkCORS:           fs.Bool("check-cors", true, "audit the CORS policy"),
+		checkDNS:            fs.Bool("check-dns", true, "collect A, AAAA, CNAME, MX, NS, TXT and PTR records"),
+		checkMailAuth:       fs.Bool("check-mail", true, "check the domain's SPF, DKIM and DMARC records"),
+		checkWPJSON:         fs.Bool("check-wp-json", true, "query the REST API when the WordPress generator tag is stripped, and the login page when the site shows no sign of WordPress"),
+		checkACME:           fs.Bool("check-acme", true, "check that the ACME HTTP-01 challenge path is reachable"),
+		checkTLSEndpoints:   fs.Bool("check-tls-endpoints", true, "compare certificates across every address a host resolves to"),
+		checkIPv6:           fs.Bool("check-ipv6", true, "check that sites advertising AAAA records are reachable over IPv6"),
+		checkCompression:    fs.Bool("check-compression", true, "check whether HTML is served with gzip or Brotli compression"),
+		checkCanonical:      fs.Bool("check-canonical", true, "check that http, https, www and bare hostnames 301 to the canonical URL"),
+		checkVary:           fs.Bool("check-vary", true, "audit Vary headers and cookies that bypass or fragment the page cache"),
+		checkRevalidation:   fs.Bool("check-revalidation", true, "check whether the homepage is answered 304 Not Modified when requested with its ETag and Last-Modified validators"),
+		checkRobots:         fs.Bool("check-robots", true, "report whether robots.txt exists and whether it blocks indexing of the whole site"),
+		provenance:          fs.Bool("provenance", false, "record in the JSON output how each finding was determined and a confidence score"),
+		strict:              fs.Bool("strict", false, "report low-confidence inferred values as Unknown instead of guessing them"),
+		respectRobots:       fs.Bool("respect-robots", false, "skip requests to paths robots.txt disallows, such as the REST API, XML-RPC and sitemap probes"),
+		checkContactForm:    fs.Bool("check-contact-form", true, "detect form plugins and find the contact form on the homepage or contact page"),
+		checkEcommerce:      fs.Bool("check-ecommerce", true, "report payment gateways and checkout HTTPS consistency on detected stores"),
+		fetchAssets:         fs.Bool("fetch-assets", false, "fetch the size of every script, stylesheet and image to report the total page weight"),
+		crawl:               fs.Int("crawl", 0, "sample this many pages per site from its sitemap and aggregate their TTFB, caching and mixed content"),
+		healthWeights:       fs.String("health-weights", "", "weights of the health score areas as area=weight pairs, e.g. support=30,ssl=25,security=20,caching=10,ttfb=15"),
+		checkPluginUpdates:  fs.Bool("check-plugin-updates", false, "compare each plugin's version with its latest release on wordpress.org"),
+		checkAbandonment:    fs.Bool("check-abandonment", false, "flag plugins and themes closed on wordpress.org or not updated there in two years"),
+		checkLicenses:       fs.Bool("check-licenses", false, "report the license of each plugin and the theme, from their headers or wordpress.org"),
+		checkHotlink:        fs.Bool("check-hotlink", false, "request each asset with the page as Referer and Origin to find assets blocked by hotlink protection or CORS"),
+		checkOpenRedirect:   fs.Bool("check-open-redirect", false, "actively probe the homepage for open redirect parameters"),
+		expiryWarningDays:   fs.Int("cert-expiry-warning", 30, "flag certificates and domains expiring within this many days"),
+		checkDomainExpiry:   fs.Bool("check-domain-expiry", false, "look up each domain's registrar and expiry date over RDAP"),
+		checkLoginTTFB:      fs.Bool("check-login-ttfb", false, "measure the TTFB of /wp-login.php separately from the homepage"),
+		checkExposure:       fs.Bool("check-exposure", false, "probe whether xmlrpc.php, wp-login.php, readme.html and debug artifacts are publicly reachable"),
+		checkPropagation:    fs.Bool("check-propagation", false, "compare each hostname's A and AAAA records across Google, Cloudflare, Quad9 and OpenDNS"),
+		checkPurge:          fs.Bool("check-purge", false, "verify with a cache-busting request that the CDN or page cache serves fresh content after a purge"),
+		checkSmuggling:      fs.Bool("check-smuggling", false, "flag server and proxy combinations historically associated with HTTP request smuggling"),
+		checkTLSAudit:       fs.Bool("check-tls-audit", false, "probe which TLS protocol versions each server accepts"),
+		checkRevocation:     fs.Bool("check-revocation", false, "check for OCSP stapling and whether each certificate has been revoked"),
+		checkHSTSPreload:    fs.Bool("check-hsts-preload", false, "look up each hostname on the HSTS preload list at hstspreload.org"),
+		checkCT:             fs.Bool("check-ct", false, "look up the certificates Certificate Transparency logs hold for each domain on crt.sh"),
+		ctIssuers:           fs.String("ct-issuers", "", "comma-separated CAs expected to issue the domains' certificates with -check-ct, e.g. \"Let's Encrypt,DigiCert\""),
+		checkBlocklists:     fs.Bool("check-blocklists", false, "look up each domain and its addresses on the Spamhaus, SURBL and URIBL DNS blocklists, and on Google Safe Browsing with -safe-browsing-key"),
+		safeBrowsingKey:     fs.String("safe-browsing-key", "", "Google Safe Browsing API key for -check-blocklists (or set SAFE_BROWSING_API_KEY)"),
+		agents:              fs.String("agents", "", "comma-separated region=URL agents that also measure each site's TTFB, e.g. \"eu=https://eu.example.com:9090,ap=https://ap.example.com:9090\""),
+		agentToken:          fs.String("agent-token", "", "bearer token the -agents require (or set "+agentTokenEnv+")"),
+		fingerprintRules:    fs.String("fingerprint-rules", "", "JSON file of technology fingerprint rules extending the built-in set"),
+		detectRules:         fs.String("detect-rules", "", "YAML file of header and HTML regex rules extracting custom fields, written as extra output columns"),
+		wpscanToken:         fs.String("wpscan-token", "", "look up known vulnerabilities with this WPScan API token (or set WPSCAN_API_TOKEN)"),
+		vulnFeed:            fs.String("vuln-feed", "", "look up known vulnerabilities in this local JSON feed instead of the WPScan API"),
+		geoip:               fs.Bool("geoip", false, "look up each site's hosting provider, ASN and country with the ipinfo.io API"),
+		geoipASNDB:          fs.String("geoip-asn-db", "", "look up hosting providers and ASNs in this MaxMind GeoLite2-ASN database instead of the API"),
+		geoipCountryDB:      fs.String("geoip-country-db", "", "look up countries in this MaxMind GeoLite2-Country database instead of the API"),
+		ipinfoToken:         fs.String("ipinfo-token", "", "ipinfo.io API token for -geoip (or set IPINFO_TOKEN)"),
+		verifyToken:         fs.String("verify-token", "", "ownership token sites publish via DNS TXT or /.well-known/site-info-fetcher.txt"),
+		requireVerification: fs.Bool("require-verification", false, "skip active checks on sites whose ownership is not verified"),
+		blocklist:           fs.String("blocklist", "", "file of domains never to scan, one per line"),
+	}
+	fs.Var(&f.include, "include", "only scan hosts matching this glob or /regex/ (repeatable)")
+	fs.Var(&f.exclude, "exclude", "skip hosts matching this glob or /regex/ (repeatable)")
+	return f
+}
+
+// targetFilter builds the filter of the -include, -exclude and -blocklist flags, loading the
+// blocklist only once however often it is called
+func (f *scanFlags) targetFilter() (*targetFilter, error) {
+	if f.targets == nil {
+		targets, err := newTargetFilter(f.include, f.exclude, *f.blocklist)
+		if err != nil {
+			return nil, err
+		}
+		f.targets = targets
+	}
+	return f.targets, nil
+}
+
+// options builds the scanner options from the flags. The returned function flushes traces and
+// closes the audit log and GeoIP databases, and must be called once scanning is finished; fail
+// calls it too, so runs that exit with an error still flush and close them.
+func (f *scanFlags) options() (siteinfo.Options, func(), error) {
+	opts := siteinfo.Options{
+		Timeout:             *f.timeout,
+		DNSTimeout:          *f.dnsTimeout,
+		ConnectTimeout:      *f.connectTimeout,
+		TLSTimeout:          *f.tlsTimeout,
+		HeaderTimeout:       *f.headerTimeout,
+		BodyTimeout:         *f.bodyTimeout,
+		Retries:             *f.retries,
+		Samples:             *f.samples,
+		WarmupSamples:       *f.warmupSamples,
+		Concurrency:         *f.concurrency,
+		RateLimit:           *f.rateLimit,
+		HostConcurrency:     *f.hostConcurrency,
+		BaselineURL:         *f.baselineURL,
+		CalibrationInterval: *f.calibrationInterval,
+		SkipWarmup:          !*f.warmup,
+		UserAgent:           *f.userAgent,
+		BrowserProfile:      *f.requestProfile == "browser",
+		SkipSearch:          !*f.checkSearch,
+		SkipErrorPage:       !*f.checkErrorPage,
+		SkipCORS:            !*f.checkCORS,
+		SkipDNS:             !*f.checkDNS,
+		SkipMailAuth:        !*f.checkMailAuth,
+		SkipWPJSON:          !*f.checkWPJSON,
+		SkipACME:            !*f.checkACME,
+		SkipTLSEndpoints:    !*f.checkTLSEndpoints,
+		SkipIPv6:            !*f.checkIPv6,
+		SkipContactForm:     !*f.checkContactForm,
+		SkipCanonical:       !*f.checkCanonical,
+		SkipVary:            !*f.checkVary,
+		SkipRevalidation:    !*f.checkRevalidation,
+		SkipRobots:          !*f.checkRobots,
+		RespectRobots:       *f.respectRobots,
+		Provenance:          *f.provenance,
+		Strict:              *f.strict,
+		SkipCompression:     !*f.checkCompression,
+		SkipEcommerce:       !*f.checkEcommerce,
+		FetchAssets:         *f.fetchAssets,
+		CrawlPages:          *f.crawl,
+		CheckHotlink:        *f.checkHotlink,
+		CheckPluginUpdates:  *f.checkPluginUpdates,
+		CheckAbandonment:    *f.checkAbandonment,
+		CheckLicenses:       *f.checkLicenses,
+		CheckOpenRedirect:   *f.checkOpenRedirect,
+		CheckLoginTTFB:      *f.checkLoginTTFB,
+		CheckDomainExpiry:   *f.checkDomainExpiry,
+		CheckExposure:       *f.checkExposure,
+		CheckTLSAudit:       *f.checkTLSAudit,
+		CheckRevocation:     *f.checkRevocation,
+		CheckCT:             *f.checkCT,
+		CheckHSTSPreload:    *f.checkHSTSPreload,
+		CheckBlocklists:     *f.checkBlocklists,
+		SafeBrowsingKey:     cmp.Or(*f.safeBrowsingKey, os.Getenv("SAFE_BROWSING_API_KEY")),
+		AgentToken:          cmp.Or(*f.agentToken, os.Getenv(agentTokenEnv)),
+		CheckPropagation:    *f.checkPropagation,
+		CheckPurge:          *f.checkPurge,
+		CheckSmuggling:      *f.checkSmuggling,
+		VerificationToken:   *f.verifyToken,
+		RequireVerification: *f.requireVerification,
+		EOLCacheDir:         *f.eolCacheDir,
+		EOLCacheTTL:         *f.eolCacheTTL,
+		Offline:             *f.offline,
+		ExpiryWarningDays:   *f.expiryWarningDays,
+		Logger:              slog.Default(),
+	}
+
+	// Refuse sites outside the include, exclude and blocklist rules in every command
+	targets, err := f.targetFilter()
+	if err != nil {
+		return opts, nil, fmt.Errorf("invalid target filters: %w", err)
+	}
+	opts.AllowTarget = targets.check
+
+	switch *f.requestProfile {
+	case "default", "browser":
+	default:
+		return opts, nil, fmt.Errorf("unknown request profile %q: use default or browser", *f.requestProfile)
+	}
+
+	for _, issuer := range strings.Split(*f.ctIssuers, ",") {
+		if issuer = strings.TrimSpace(issuer); issuer != "" {
+			opts.ExpectedIssuers = append(opts.ExpectedIssuers, issuer)
+		}
+	}
+
+	for _, agent := range strings.Split(*f.agents, ",") {
+		if agent = strings.TrimSpace(agent); agent == "" {
+			continue
+		}
+		region, url, ok := strings.Cut(agent, "=")
+		if !ok || region == "" || url == "" {
+			return opts, nil, fmt.Errorf("invalid agent %q: use region=URL, e.g. eu=https://eu.example.com:9090", agent)
+		}
+		opts.Agents = append(opts.Agents, siteinfo.Agent{Region: region, URL: url})
+	}
+
+	if *f.healthWeights != "" {
+		weights, err := siteinfo.ParseHealthWeights(*f.healthWeights)
+		if err != nil {
+			return opts, nil, fmt.Errorf("invalid health weights: %w", err)
+		}
+		opts.HealthWeights = &weights
+	}
+
+	// Route the scan, and the API lookups below, through the proxy
+	apiClient := &http.Client{Timeout: *f.timeout}
+	if *f.proxy != "" {
+		proxyURL, err := siteinfo.ParseProxy(*f.proxy)
+		if err != nil {
+			return opts, nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		opts.Proxy = proxyURL
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+		apiClient.Transport = transport
+	}
+	if *f.resolver != "" {
+		resolver, err := siteinfo.NewResolver(*f.resolver)
+		if err != nil {
+			return opts, nil, fmt.Errorf("invalid resolver: %w", err)
+		}
+		opts.Resolver = resolver
+	}
+	if *f.torProxy != "" {
+		torURL, err := siteinfo.ParseTorProxy(*f.torProxy)
+		if err != nil {
+			return opts, nil, fmt.Errorf("invalid Tor proxy: %w", err)
+		}
+		opts.TorProxy = torURL
+		opts.OnionTimeout = *f.onionTimeout
+	}
+
+	// Load the credentials of password-protected staging sites
+	if *f.credentials != "" {
+		credentials, err := loadCredentials(*f.credentials)
+		if err != nil {
+			return opts, nil, fmt.Errorf("error loading credentials: %w", err)
+		}
+		opts.Credentials = credentials
+	}
+
+	// Extend technology detection with the user's own rules
+	if *f.fingerprintRules != "" {
+		fingerprints, err := fingerprint.Load(*f.fingerprintRules)
+		if err != nil {
+			return opts, nil, fmt.Errorf("error loading fingerprint rules: %w", err)
+		}
+		opts.Fingerprints = fingerprints
+	}
+
+	// Extract the fields of the user's own detection rules
+	if *f.detectRules != "" {
+		fields, err := fingerprint.LoadFields(*f.detectRules)
+		if err != nil {
+			return opts, nil, fmt.Errorf("error loading detection rules: %w", err)
+		}
+		opts.CustomFields = fields
+	}
+
+	// Look up known vulnerabilities when a WPScan token or local feed is given
+	token := *f.wpscanToken
+	if token == "" {
+		token = os.Getenv("WPSCAN_API_TOKEN")
+	}
+	if token != "" || *f.vulnFeed != "" {
+		var vulnCacheDir string
+		if *f.eolCacheDir != "" {
+			vulnCacheDir = filepath.Join(*f.eolCacheDir, "vuln")
+		}
+		vulnerabilities, err := vuln.New(vuln.Options{
+			Token:      token,
+			FeedPath:   *f.vulnFeed,
+			CacheDir:   vulnCacheDir,
+			HTTPClient: apiClient,
+		})
+		if err != nil {
+			return opts, nil, fmt.Errorf("error loading vulnerability feed: %w", err)
+		}
+		opts.Vulnerabilities = vulnerabilities
+	}
+
+	// Enrich each site with its hosting network from local databases or the API
+	ipinfoToken := *f.ipinfoToken
+	if ipinfoToken == "" {
+		ipinfoToken = os.Getenv("IPINFO_TOKEN")
+	}
+	closeGeoIP := func() {}
+	if *f.geoip || *f.geoipASNDB != "" || *f.geoipCountryDB != "" {
+		geo, err := geoip.New(geoip.Options{
+			ASNDatabase:     *f.geoipASNDB,
+			CountryDatabase: *f.geoipCountryDB,
+			Token:           ipinfoToken,
+			HTTPClient:      apiClient,
+		})
+		if err != nil {
+			return opts, nil, fmt.Errorf("error loading GeoIP databases: %w", err)
+		}
+		closeGeoIP = func() { geo.Close() }
+		opts.GeoIP = geo
+	}
+
+	// Record every outbound request for compliance audits
+	closeAudit := closeGeoIP
+	if *f.auditLogPath != "" {
+		auditFile, err := os.OpenFile(*f.auditLogPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			closeGeoIP()
+			return opts, nil, fmt.Errorf("error opening audit log: %w", err)
+		}
+		closeAudit = func() {
+			auditFile.Close()
+			closeGeoIP()
+		}
+		opts.AuditLog = auditFile
+	}
+
+	// Trace each scan to the OpenTelemetry collector, flushing the last spans on close
+	provider, shutdownTracing, err := newTracerProvider(*f.otlpEndpoint)
+	if err != nil {
+		closeAudit()
+		return opts, nil, fmt.Errorf("error setting up tracing: %w", err)
+	}
+	if provider != nil {
+		opts.TracerProvider = provider
+		closeFiles := closeAudit
+		closeAudit = func() {
+			shutdownTracing()
+			closeFiles()
+		}
+	}
+	return opts, onExit(closeAudit), nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseScheduleField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     []int
+	}{
+		{"*", 0, 5, []int{0, 1, 2, 3, 4, 5}},
+		{"3", 0, 59, []int{3}},
+		{"1-4", 0, 59, []int{1, 2, 3, 4}},
+		{"1,5,9", 0, 59, []int{1, 5, 9}},
+		{"*/15", 0, 59, []int{0, 15, 30, 45}},
+		{"10-20/5", 0, 59, []int{10, 15, 20}},
+		{"50/5", 0, 59, []int{50, 55}},
+		{"1-2,20-21", 1, 31, []int{1, 2, 20, 21}},
+		{"5-7", 0, 7, []int{5, 6, 7}},
+	}
+	for _, test := range tests {
+		set, err := parseScheduleField(test.field, test.min, test.max)
+		if err != nil {
+			t.Errorf("parseScheduleField(%q): %v", test.field, err)
+			continue
+		}
+		if got := slices.Sorted(maps.Keys(set)); !slices.Equal(got, test.want) {
+			t.Errorf("parseScheduleField(%q) = %v, want %v", test.field, got, test.want)
+		}
+	}
+
+	for _, field := range []string{"", "x", "*/0", "*/x", "60", "5-3", "1-x", "-1"} {
+		if _, err := parseScheduleField(field, 0, 59); err == nil {
+			t.Errorf("parseScheduleField(%q) succeeded, want an error", field)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// Monday 1 January 2024, 10:07
+	from := time.Date(2024, time.January, 1, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/5 * * * *", time.Date(2024, time.January, 1, 10, 10, 0, 0, time.UTC)},
+		{"0 2 * * 1-5", time.Date(2024, time.January, 2, 2, 0, 0, 0, time.UTC)},
+		{"30 9 * * 0", time.Date(2024, time.January, 7, 9, 30, 0, 0, time.UTC)},
+		{"30 9 * * 7", time.Date(2024, time.January, 7, 9, 30, 0, 0, time.UTC)},
+		{"0 0 15 * *", time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		// Either the 20th or a Friday
+		{"0 0 20 * 5", time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 3 * 5", time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		// A day of month starting with * leaves only the day of week
+		{"0 0 */1 * 5", time.Date(2024, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1-31/2 * 5", time.Date(2024, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		s, err := parseSchedule(test.expr)
+		if err != nil {
+			t.Errorf("parseSchedule(%q): %v", test.expr, err)
+			continue
+		}
+		if got := s.next(from); !got.Equal(test.want) {
+			t.Errorf("next(%q) = %v, want %v", test.expr, got, test.want)
+		}
+	}
+
+	for _, expr := range []string{"* * * *", "* * * * * *", "60 * * * *", "* * 0 * *", "* * * 13 *", "* * * * 8"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("parseSchedule(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// apiTokenEnv sets the API token when -token is not given
+const apiTokenEnv = "SITE_INFO_API_TOKEN"
+
+// jobRetention is how long finished jobs and their results are kept
+const jobRetention = 24 * time.Hour
+
+// maxJobURLs caps the number of URLs submitted in one job
+const maxJobURLs = 10000
+
+// Job statuses
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+)
+
+// scanRequest is the body of POST /scan: a single URL or a batch
+type scanRequest struct {
+	URL  string   `json:"url"`
+	URLs []string `json:"urls"`
+}
+
+// jobError is a site of a job that could not be scanned
+type jobError struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// job is a submitted scan and its progress
+type job struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	SubmittedAt time.Time  `json:"submitted_at"`
+	StartedAt   time.Time  `json:"started_at,omitzero"`
+	FinishedAt  time.Time  `json:"finished_at,omitzero"`
+	URLs        []string   `json:"urls"`
+	Completed   int        `json:"completed"`
+	Errors      []jobError `json:"errors"`
+
+	results []*siteinfo.SiteInfo
+}
+
+// apiServer runs submitted scans one job at a time and serves their status and results
+type apiServer struct {
+	opts  siteinfo.Options
+	token string
+	queue chan *job
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// newJobID returns a random job identifier
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handler returns the API's routes, behind the bearer token when one is set
+func (a *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scan", a.submit)
+	mux.HandleFunc("GET /scans/{id}", a.status)
+	mux.HandleFunc("GET /scans/{id}/results", a.results)
+	return bearerAuth(a.token, mux)
+}
+
+// bearerAuth requires requests to the handler to send the token in the Authorization header,
+// unless the token is empty
+func bearerAuth(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// submit queues a scan of the URL or batch in the request body and answers with the job
+func (a *apiServer) submit(w http.ResponseWriter, r *http.Request) {
+	var req scanRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	var urls []string
+	for _, url := range append([]string{req.URL}, req.URLs...) {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	switch {
+	case len(urls) == 0:
+		writeAPIError(w, http.StatusBadRequest, `give a "url" or a "urls" list`)
+		return
+	case len(urls) > maxJobURLs:
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("at most %d URLs can be scanned in one job", maxJobURLs))
+		return
+	}
+	for _, url := range urls {
+		if err := a.opts.AllowTarget(url); err != nil {
+			writeAPIError(w, http.StatusForbidden, err.Error())
+			return
+		}
+	}
+
+	j := &job{ID: newJobID(), Status: jobQueued, SubmittedAt: time.Now(), URLs: urls, Errors: []jobError{}}
+	a.mu.Lock()
+	a.expire()
+	a.jobs[j.ID] = j
+	a.mu.Unlock()
+
+	select {
+	case a.queue <- j:
+	default:
+		a.mu.Lock()
+		delete(a.jobs, j.ID)
+		a.mu.Unlock()
+		writeAPIError(w, http.StatusServiceUnavailable, "too many queued jobs, try again later")
+		return
+	}
+	w.Header().Set("Location", "/scans/"+j.ID)
+	a.writeJob(w, http.StatusAccepted, j)
+}
+
+// status answers with the job's status and progress
+func (a *apiServer) status(w http.ResponseWriter, r *http.Request) {
+	j := a.job(r.PathValue("id"))
+	if j == nil {
+		writeAPIError(w, http.StatusNotFound, "no such job")
+		return
+	}
+	a.writeJob(w, http.StatusOK, j)
+}
+
+// results answers with the site information of a finished job, in the JSON report format
+func (a *apiServer) results(w http.ResponseWriter, r *http.Request) {
+	j := a.job(r.PathValue("id"))
+	if j == nil {
+		writeAPIError(w, http.StatusNotFound, "no such job")
+		return
+	}
+	a.mu.Lock()
+	status, results := j.Status, j.results
+	a.mu.Unlock()
+	if status != jobDone {
+		writeAPIError(w, http.StatusConflict, "job is "+status+"; poll /scans/"+j.ID+" until it is done")
+		return
+	}
+	if results == nil {
+		results = []*siteinfo.SiteInfo{}
+	}
+	writeAPIJSON(w, http.StatusOK, results)
+}
+
+// job returns the job with the ID, or nil
+func (a *apiServer) job(id string) *job {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.jobs[id]
+}
+
+// writeJob writes a snapshot of the job
+func (a *apiServer) writeJob(w http.ResponseWriter, code int, j *job) {
+	a.mu.Lock()
+	snapshot := *j
+	snapshot.Errors = append([]jobError{}, j.Errors...)
+	a.mu.Unlock()
+	writeAPIJSON(w, code, &snapshot)
+}
+
+// expire forgets jobs that finished more than jobRetention ago. The caller holds a.mu.
+func (a *apiServer) expire() {
+	for id, j := range a.jobs {
+		if j.Status == jobDone && time.Since(j.FinishedAt) > jobRetention {
+			delete(a.jobs, id)
+		}
+	}
+}
+
+// work runs queued jobs one at a time until ctx is cancelled
+func (a *apiServer) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-a.queue:
+			a.run(ctx, j)
+		}
+	}
+}
+
+// run scans the job's sites, recording progress as each site finishes
+func (a *apiServer) run(ctx context.Context, j *job) {
+	a.mu.Lock()
+	j.Status, j.StartedAt = jobRunning, time.Now()
+	a.mu.Unlock()
+
+	opts := a.opts
+	opts.OnScanned = func(url string, info *siteinfo.SiteInfo, err error) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		j.Completed++
+		if err != nil {
+			j.Errors = append(j.Errors, jobError{URL: url, Error: err.Error()})
+		}
+	}
+	// A new scanner per job refreshes the endoflife.date data in a long-running server
+	results, _ := siteinfo.New(opts).ScanAll(ctx, j.URLs)
+
+	a.mu.Lock()
+	j.Status, j.FinishedAt, j.results = jobDone, time.Now(), results
+	a.mu.Unlock()
+}
+
+// writeAPIJSON writes the value as a JSON response
+func writeAPIJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeAPIError writes an error response
+func writeAPIError(w http.ResponseWriter, code int, message string) {
+	writeAPIJSON(w, code, map[string]string{"error": message})
+}
+
+// runServe runs the serve command: an HTTP API that scans submitted URLs and serves the
+// results as JSON, for services that would otherwise shell out to the binary
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	token := fs.String("token", os.Getenv(apiTokenEnv), "bearer token clients must send in the Authorization header (or set "+apiTokenEnv+")")
+	queueSize := fs.Int("queue", 100, "maximum number of jobs waiting to run")
+	scan := registerScanFlags(fs)
+	registerErrorFormat(fs)
+	registerLogFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+
+	opts, closeAudit, err := scan.options()
+	if err != nil {
+		fail(codeScannerConfig, err)
+	}
+	defer closeAudit()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fail(codeServe, err)
+	}
+	api := &apiServer{opts: opts, token: *token, queue: make(chan *job, max(*queueSize, 1)), jobs: map[string]*job{}}
+	server := &http.Server{Handler: api.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go api.work(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if *token == "" {
+		slog.Warn("No -token set; anyone who can reach the server can start scans")
+	}
+	slog.Info("Serving the scan API", "url", "http://"+listener.Addr().String())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fail(codeServe, err)
+	}
+	slog.Info("Server stopped")
+}
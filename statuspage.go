@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// statusPageDays is the default window of history shown on the status page
+const statusPageDays = 90
+
+// readJSONLines decodes each line of a JSON Lines file written by the daemon. A missing file
+// has no lines, and lines that do not decode, such as one truncated by a crash, are skipped.
+func readJSONLines[T any](filePath string, each func(T)) error {
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var value T
+		if json.Unmarshal(scanner.Bytes(), &value) == nil {
+			each(value)
+		}
+	}
+	return scanner.Err()
+}
+
+// buildStatusPage builds the status page of every site in the schedule's groups from the
+// daemon's history: the uptime groups' availability checks, and the site_down and
+// site_recovered alerts, which every group raises, as incidents
+func buildStatusPage(cfg *daemonConfig, title string, days int, now time.Time) (*report.StatusPage, error) {
+	since := now.AddDate(0, 0, -days)
+	page := &report.StatusPage{Title: title, GeneratedAt: now, Days: days}
+	key := func(group, url string) string { return group + "\x00" + url }
+	listed := map[string]bool{}
+	for _, group := range cfg.Groups {
+		g := daemonGroup{siteGroup: group}
+		urls, err := g.urls()
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", group.Name, err)
+		}
+		for _, url := range urls {
+			if !listed[key(group.Name, url)] {
+				listed[key(group.Name, url)] = true
+				page.Sites = append(page.Sites, report.StatusSite{URL: url, Group: group.Name, Up: true})
+			}
+		}
+	}
+	sites := map[string]*report.StatusSite{}
+	for i := range page.Sites {
+		sites[key(page.Sites[i].Group, page.Sites[i].URL)] = &page.Sites[i]
+	}
+
+	// Tally the availability checks of the uptime groups by day
+	for _, group := range cfg.Groups {
+		if group.Mode != "uptime" {
+			continue
+		}
+		daily := map[string]map[string][2]int{}
+		err := readJSONLines(filepath.Join(cfg.OutputDir, group.Name+"_uptime.jsonl"), func(check siteinfo.Uptime) {
+			site := sites[key(group.Name, check.URL)]
+			if site == nil || check.CheckedAt.Before(since) {
+				return
+			}
+			if check.CheckedAt.After(site.LastChecked) {
+				site.LastChecked, site.Up = check.CheckedAt, check.Up
+			}
+			if daily[check.URL] == nil {
+				daily[check.URL] = map[string][2]int{}
+			}
+			date := check.CheckedAt.UTC().Format(time.DateOnly)
+			tally := daily[check.URL][date]
+			tally[0]++
+			if check.Up {
+				tally[1]++
+			}
+			daily[check.URL][date] = tally
+		})
+		if err != nil {
+			return nil, err
+		}
+		for url, tallies := range daily {
+			site := sites[key(group.Name, url)]
+			var checks, up int
+			for day := since.UTC(); !day.After(now.UTC()); day = day.AddDate(0, 0, 1) {
+				date := day.Format(time.DateOnly)
+				tally := tallies[date]
+				status := report.StatusDay{Date: date, Checks: tally[0]}
+				if tally[0] > 0 {
+					status.UptimePercent = float64(tally[1]) / float64(tally[0]) * 100
+				}
+				site.Daily = append(site.Daily, status)
+				checks += tally[0]
+				up += tally[1]
+			}
+			site.Checks = checks
+			if checks > 0 {
+				site.UptimePercent = float64(up) / float64(checks) * 100
+			}
+		}
+	}
+
+	// Pair each site_down alert with the site_recovered alert that ends it
+	err := readJSONLines(cfg.Alerts, func(a alert) {
+		site := sites[key(a.Group, a.URL)]
+		if site == nil {
+			return
+		}
+		switch a.Kind {
+		case alertDown:
+			site.Incidents = append(site.Incidents, report.Incident{Started: a.RaisedAt, Reason: a.Detail})
+		case alertRecovered:
+			if n := len(site.Incidents); n > 0 && site.Incidents[n-1].Resolved.IsZero() {
+				site.Incidents[n-1].Resolved = a.RaisedAt
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range page.Sites {
+		site := &page.Sites[i]
+		var recent []report.Incident
+		for _, incident := range site.Incidents {
+			if incident.Resolved.IsZero() {
+				site.Up = false
+			}
+			if incident.Resolved.IsZero() || incident.Resolved.After(since) {
+				recent = append(recent, incident)
+			}
+		}
+		// Newest first, as visitors look for the current incident
+		for l, r := 0, len(recent)-1; l < r; l, r = l+1, r-1 {
+			recent[l], recent[r] = recent[r], recent[l]
+		}
+		site.Incidents = recent
+	}
+	return page, nil
+}
+
+// updateStatusPage regenerates the status page configured in the schedule file, so it shows
+// the latest run of each group
+func (d *daemon) updateStatusPage() {
+	if d.cfg.StatusPage == "" {
+		return
+	}
+	d.statusMu.Lock()
+	defer d.statusMu.Unlock()
+	page, err := buildStatusPage(d.cfg, cmp.Or(d.cfg.StatusTitle, "Site status"), statusPageDays, time.Now())
+	if err == nil {
+		err = report.WriteStatusPage(d.cfg.StatusPage, page)
+	}
+	if err != nil {
+		slog.Error("Error updating status page", "error", err)
+	}
+}
+
+// runStatusPage runs the status-page command: it generates a static status page from the
+// daemon's history, to be published to S3, GitHub Pages or any static host
+func runStatusPage(args []string) {
+	fs := flag.NewFlagSet("status-page", flag.ExitOnError)
+	schedulePath := fs.String("schedule", "", "path to the daemon's YAML schedule file, whose uptime logs and alerts are read")
+	outputDir := fs.String("output", "status", "directory to write index.html and status.json to")
+	title := fs.String("title", "", "heading of the status page (default the schedule's status_title, or Site status)")
+	days := fs.Int("days", statusPageDays, "days of history to show")
+	registerErrorFormat(fs)
+	registerLogFlags(fs)
+	fs.Parse(args)
+	setupLogging()
+	if *schedulePath == "" {
+		fail(codeSchedule, errors.New("the status-page command requires -schedule"))
+	}
+
+	cfg, err := loadDaemonConfig(*schedulePath)
+	if err != nil {
+		fail(codeSchedule, err)
+	}
+	page, err := buildStatusPage(cfg, cmp.Or(*title, cfg.StatusTitle, "Site status"), *days, time.Now())
+	if err != nil {
+		fail(codeReportRead, err)
+	}
+	if err := report.WriteStatusPage(*outputDir, page); err != nil {
+		fail(codeWriteReport, err)
+	}
+	slog.Info("Status page written", "sites", len(page.Sites), "path", filepath.Join(*outputDir, "index.html"))
+}
@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// The terminal control requests reading and setting the terminal's mode
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
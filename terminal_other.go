@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw reports that raw terminal mode is not supported on this platform
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("the terminal UI is not supported on this platform")
+}
+
+// terminalSize returns the default terminal size of 80 by 24 characters
+func terminalSize(f *os.File) (int, int) {
+	return 80, 24
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// tracingShutdownTimeout bounds flushing the remaining spans to the collector on exit
+const tracingShutdownTimeout = 5 * time.Second
+
+// newTracerProvider returns a tracer provider exporting spans over OTLP/HTTP to the endpoint,
+// e.g. http://localhost:4318, with a function that flushes and stops it. Without an endpoint,
+// the standard OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_TRACES_ENDPOINT variables
+// configure the exporter, and when neither is set tracing is off and the provider is nil.
+func newTracerProvider(endpoint string) (*sdktrace.TracerProvider, func(), error) {
+	var exporterOpts []otlptracehttp.Option
+	switch {
+	case endpoint != "":
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Like OTEL_EXPORTER_OTLP_ENDPOINT, the flag is the collector's base URL
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/traces"
+		}
+		exporterOpts = append(exporterOpts, otlptracehttp.WithEndpointURL(u.String()))
+	case os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "":
+		return nil, func() {}, nil
+	}
+
+	ctx := context.Background()
+	exporter, err := otlptracehttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "site-info-fetcher")),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	shutdown := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			slog.Warn("Could not flush traces", "error", err)
+		}
+	}
+	return provider, shutdown, nil
+}
@@ -0,0 +1,617 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// The states of a site in the terminal UI
+const (
+	tuiQueued   = "queued"
+	tuiScanning = "scanning"
+	tuiDone     = "done"
+	tuiFailed   = "failed"
+)
+
+// tuiStateOrder sorts the states from least to most advanced
+var tuiStateOrder = map[string]int{tuiQueued: 0, tuiScanning: 1, tuiDone: 2, tuiFailed: 3}
+
+// tuiRow is a site in the terminal UI's table
+type tuiRow struct {
+	index   int
+	url     string
+	state   string
+	started time.Time
+	elapsed time.Duration
+	info    *siteinfo.SiteInfo
+	err     error
+}
+
+// tuiColumn is a column of the results table: the value shown, the key it sorts by when
+// numeric rather than the value, and whether the value is a problem to show in red
+type tuiColumn struct {
+	header string
+	width  int
+	value  func(row *tuiRow) string
+	key    func(row *tuiRow) float64
+	alert  func(row *tuiRow) bool
+}
+
+// unsorted sorts the rows without a value after those with one
+var unsorted = math.Inf(1)
+
+// tuiColumns are the columns of the results table. The URL column, of width 0, takes the
+// width the others leave.
+var tuiColumns = []tuiColumn{
+	{header: "#", width: 4,
+		value: func(row *tuiRow) string { return fmt.Sprint(row.index + 1) },
+		key:   func(row *tuiRow) float64 { return float64(row.index) }},
+	{header: "URL", width: 0,
+		value: func(row *tuiRow) string { return row.url }},
+	{header: "Status", width: 13,
+		value: func(row *tuiRow) string {
+			if row.state == tuiScanning {
+				return fmt.Sprintf("scanning %ds", int(time.Since(row.started).Seconds()))
+			}
+			return row.state
+		},
+		key:   func(row *tuiRow) float64 { return float64(tuiStateOrder[row.state]) },
+		alert: func(row *tuiRow) bool { return row.state == tuiFailed }},
+	{header: "TTFB ms", width: 8,
+		value: func(row *tuiRow) string {
+			if row.info == nil || row.info.AverageTTFB == 0 {
+				return ""
+			}
+			return fmt.Sprintf("%.0f", siteinfo.Milliseconds(row.info.AverageTTFB))
+		},
+		key: func(row *tuiRow) float64 {
+			if row.info == nil || row.info.AverageTTFB == 0 {
+				return unsorted
+			}
+			return siteinfo.Milliseconds(row.info.AverageTTFB)
+		}},
+	{header: "WordPress", width: 10,
+		value: func(row *tuiRow) string {
+			if row.info == nil {
+				return ""
+			}
+			return row.info.WordPressVersion
+		},
+		alert: func(row *tuiRow) bool { return row.info != nil && row.info.WordPressStatus == "Outdated" }},
+	{header: "PHP", width: 8,
+		value: func(row *tuiRow) string {
+			if row.info == nil {
+				return ""
+			}
+			return row.info.PHPVersion
+		},
+		alert: func(row *tuiRow) bool { return row.info != nil && row.info.PHPStatus == "Outdated" }},
+	{header: "SSL", width: 8,
+		value: func(row *tuiRow) string {
+			switch {
+			case row.info == nil:
+				return ""
+			case row.info.SSLExpired:
+				return "expired"
+			case !row.info.SSLValid:
+				return "invalid"
+			case row.info.Certificate != nil:
+				return fmt.Sprintf("%dd", row.info.Certificate.DaysUntilExpiry)
+			}
+			return "valid"
+		},
+		key: func(row *tuiRow) float64 {
+			switch {
+			case row.info == nil:
+				return unsorted
+			case row.info.SSLExpired || !row.info.SSLValid:
+				return -1
+			case row.info.Certificate != nil:
+				return float64(row.info.Certificate.DaysUntilExpiry)
+			}
+			return unsorted - 1
+		},
+		alert: func(row *tuiRow) bool {
+			return row.info != nil && (row.info.SSLExpired || !row.info.SSLValid ||
+				row.info.Certificate != nil && row.info.Certificate.ExpiringSoon)
+		}},
+	{header: "Health", width: 6,
+		value: func(row *tuiRow) string {
+			if row.info == nil || row.info.HealthGrade == "" {
+				return ""
+			}
+			return fmt.Sprintf("%d %s", row.info.HealthScore, row.info.HealthGrade)
+		},
+		key: func(row *tuiRow) float64 {
+			if row.info == nil || row.info.HealthGrade == "" {
+				return unsorted
+			}
+			return float64(row.info.HealthScore)
+		},
+		alert: func(row *tuiRow) bool { return row.info != nil && row.info.HealthGrade == "F" }},
+}
+
+// tui is the interactive terminal UI of -tui: a live table of the sites being scanned, which
+// can be sorted, and in which a site's findings can be inspected or the site scanned again
+type tui struct {
+	out     *os.File
+	rows    []*tuiRow
+	updates chan struct{}
+
+	mu      sync.Mutex
+	lastLog string
+	rescans sync.WaitGroup
+
+	// The view, only touched by the UI loop
+	sortBy     int
+	descending bool
+	selected   *tuiRow
+	offset     int
+	detail     *tuiRow
+	scroll     int
+}
+
+// newTUI prepares the terminal UI for the sites, drawn on out, which needs an interactive
+// terminal
+func newTUI(out *os.File, urls []string) (*tui, error) {
+	if !isTerminal(os.Stdin) || !isTerminal(out) {
+		return nil, errors.New("-tui needs an interactive terminal for both input and output")
+	}
+	t := &tui{out: out, updates: make(chan struct{}, 1)}
+	for i, url := range urls {
+		t.rows = append(t.rows, &tuiRow{index: i, url: url, state: tuiQueued})
+	}
+	return t, nil
+}
+
+// notify asks the UI loop to redraw
+func (t *tui) notify() {
+	select {
+	case t.updates <- struct{}{}:
+	default:
+	}
+}
+
+// started marks the site's scan as started
+func (t *tui) started(url string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, row := range t.rows {
+		if row.url == url && row.state == tuiQueued {
+			row.state, row.started = tuiScanning, time.Now()
+			break
+		}
+	}
+	t.notify()
+}
+
+// scanned records the site's result
+func (t *tui) scanned(url string, info *siteinfo.SiteInfo, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, row := range t.rows {
+		if row.url == url && row.state == tuiScanning {
+			row.finish(info, err)
+			break
+		}
+	}
+	t.notify()
+}
+
+// finish records the result of the row's scan
+func (row *tuiRow) finish(info *siteinfo.SiteInfo, err error) {
+	row.elapsed = time.Since(row.started)
+	row.info, row.err = info, err
+	row.state = tuiDone
+	if err != nil {
+		row.state = tuiFailed
+	}
+}
+
+// Write shows the last line of the scanner's log below the table, as the UI owns the screen
+func (t *tui) Write(data []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastLog = strings.TrimSpace(string(data))
+	t.notify()
+	return len(data), nil
+}
+
+// run scans the sites while showing the UI, until the user quits. Quitting before every site
+// is scanned calls stop, which cancels ctx and so the scans still in flight, and quitting
+// abandons any site being scanned again. The results are returned in input order, with any
+// sites scanned again replaced by their latest result.
+func (t *tui) run(ctx context.Context, stop func(), scanner *siteinfo.Scanner) ([]*siteinfo.SiteInfo, []error, error) {
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Draw on the alternate screen with the cursor hidden, restoring both on exit
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	urls := make([]string, len(t.rows))
+	for i, row := range t.rows {
+		urls[i] = row.url
+	}
+	scanDone := make(chan struct{})
+	go func() {
+		scanner.ScanAll(ctx, urls)
+		close(scanDone)
+	}()
+	// Rescans are cancelled on quitting, keeping the sites' earlier results
+	rescanCtx, cancelRescans := context.WithCancel(ctx)
+	defer cancelRescans()
+	keys := readKeys()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	finished := scanDone
+loop:
+	for {
+		t.render(finished == nil)
+		select {
+		case <-t.updates:
+		case <-ticker.C:
+		case <-finished:
+			finished = nil
+		case <-ctx.Done():
+			break loop
+		case key, ok := <-keys:
+			if !ok {
+				keys = nil
+				continue
+			}
+			if t.handle(rescanCtx, key, scanner) {
+				break loop
+			}
+		}
+	}
+	if finished != nil {
+		stop()
+	}
+	cancelRescans()
+	t.rescans.Wait()
+	<-scanDone
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var infos []*siteinfo.SiteInfo
+	var errs []error
+	for _, row := range t.rows {
+		switch {
+		case row.info != nil:
+			infos = append(infos, row.info)
+		case row.err != nil:
+			errs = append(errs, &siteinfo.ScanError{URL: row.url, Err: row.err})
+		}
+	}
+	return infos, errs, nil
+}
+
+// handle acts on a key press, reporting whether the user quit
+func (t *tui) handle(ctx context.Context, key string, scanner *siteinfo.Scanner) bool {
+	if key == "ctrl-c" {
+		return true
+	}
+	_, height := terminalSize(t.out)
+	page := max(height-4, 1)
+
+	// The detail view of a site
+	if t.detail != nil {
+		switch key {
+		case "up", "k":
+			t.scroll--
+		case "down", "j":
+			t.scroll++
+		case "pgup":
+			t.scroll -= page
+		case "pgdn", " ":
+			t.scroll += page
+		case "home":
+			t.scroll = 0
+		case "r":
+			t.rescan(ctx, t.detail, scanner)
+		case "esc", "q", "enter":
+			t.detail = nil
+		}
+		t.scroll = max(t.scroll, 0)
+		return false
+	}
+
+	// The table of sites
+	rows := t.sorted()
+	current := 0
+	for i, row := range rows {
+		if row == t.selected {
+			current = i
+		}
+	}
+	switch key {
+	case "up", "k":
+		current--
+	case "down", "j":
+		current++
+	case "pgup":
+		current -= page
+	case "pgdn", " ":
+		current += page
+	case "home":
+		current = 0
+	case "end":
+		current = len(rows) - 1
+	case "enter", "d":
+		t.detail, t.scroll = t.selected, 0
+	case "r":
+		t.rescan(ctx, t.selected, scanner)
+	case "s":
+		t.sortBy = (t.sortBy + 1) % len(tuiColumns)
+	case "o":
+		t.descending = !t.descending
+	case "q":
+		return true
+	}
+	if len(rows) > 0 && t.detail == nil {
+		t.selected = rows[min(max(current, 0), len(rows)-1)]
+	}
+	return false
+}
+
+// rescan scans the site again in the background, unless it is being scanned. The scan goes
+// through ScanAll, so its result reaches Options.OnScanned and replaces the site's earlier one
+// in the checkpoint, the streamed outputs and the webhook, as well as in the table.
+func (t *tui) rescan(ctx context.Context, row *tuiRow, scanner *siteinfo.Scanner) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if row == nil || row.state == tuiScanning || row.state == tuiQueued {
+		return
+	}
+	row.state, row.started = tuiScanning, time.Now()
+	t.rescans.Add(1)
+	go func() {
+		defer t.rescans.Done()
+		scanner.ScanAll(ctx, []string{row.url})
+		t.notify()
+	}()
+}
+
+// sorted returns the rows in the chosen order, by the sort column's key or its value. Rows
+// with equal values keep their input order.
+func (t *tui) sorted() []*tuiRow {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rows := append([]*tuiRow(nil), t.rows...)
+	column := tuiColumns[t.sortBy]
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if t.descending {
+			a, b = b, a
+		}
+		if column.key != nil {
+			return column.key(a) < column.key(b)
+		}
+		return column.value(a) < column.value(b)
+	})
+	if t.selected == nil && len(rows) > 0 {
+		t.selected = rows[0]
+	}
+	return rows
+}
+
+// render redraws the screen: the table, or the findings of the site being inspected
+func (t *tui) render(finished bool) {
+	width, height := terminalSize(t.out)
+	var lines []string
+	if t.detail != nil {
+		lines = t.renderDetail(width, height)
+	} else {
+		lines = t.renderTable(width, height, finished)
+	}
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line)
+		b.WriteString("\x1b[K")
+	}
+	b.WriteString("\x1b[J")
+	fmt.Fprint(t.out, b.String())
+}
+
+// renderTable returns the lines of the table view
+func (t *tui) renderTable(width, height int, finished bool) []string {
+	rows := t.sorted()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counts := map[string]int{}
+	for _, row := range t.rows {
+		counts[row.state]++
+	}
+	order := "ascending"
+	if t.descending {
+		order = "descending"
+	}
+	title := fmt.Sprintf("site-info-fetcher  %d/%d scanned, %d failed, %d scanning  sorted by %s, %s",
+		counts[tuiDone]+counts[tuiFailed], len(t.rows), counts[tuiFailed], counts[tuiScanning], tuiColumns[t.sortBy].header, order)
+	if finished {
+		title += "  (scan complete)"
+	}
+	lines := []string{"\x1b[1m" + fit(title, width) + "\x1b[0m"}
+
+	// The URL column takes the width the others leave
+	widths := make([]int, len(tuiColumns))
+	fixed := 0
+	for i, column := range tuiColumns {
+		widths[i] = column.width
+		fixed += column.width + 1
+	}
+	for i, column := range tuiColumns {
+		if column.width == 0 {
+			widths[i] = max(width-fixed, 20)
+		}
+	}
+	var header []string
+	for i, column := range tuiColumns {
+		header = append(header, fit(column.header, widths[i]))
+	}
+	lines = append(lines, "\x1b[7m"+fit(strings.Join(header, " "), width)+"\x1b[0m")
+
+	// Scroll the table to keep the selected row in view
+	visible := max(height-4, 1)
+	current := 0
+	for i, row := range rows {
+		if row == t.selected {
+			current = i
+		}
+	}
+	if current < t.offset {
+		t.offset = current
+	}
+	if current >= t.offset+visible {
+		t.offset = current - visible + 1
+	}
+	t.offset = min(t.offset, max(len(rows)-visible, 0))
+	for _, row := range rows[t.offset:min(t.offset+visible, len(rows))] {
+		var cells []string
+		for i, column := range tuiColumns {
+			cell := fit(column.value(row), widths[i])
+			if column.alert != nil && column.alert(row) {
+				cell = "\x1b[31m" + cell + "\x1b[39m"
+			}
+			cells = append(cells, cell)
+		}
+		line := strings.Join(cells, " ")
+		if row == t.selected {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, "\x1b[2m"+fit(t.lastLog, width)+"\x1b[0m")
+	lines = append(lines, fit("↑↓ select  enter details  r scan again  s sort column  o order  q quit and write the reports", width))
+	return lines
+}
+
+// renderDetail returns the lines of the detail view: the error or the full findings of the
+// site being inspected
+func (t *tui) renderDetail(width, height int) []string {
+	t.mu.Lock()
+	row := *t.detail
+	t.mu.Unlock()
+
+	var body []string
+	switch {
+	case row.state == tuiScanning:
+		body = []string{"Scanning..."}
+	case row.err != nil:
+		body = []string{"Error: " + row.err.Error()}
+	case row.info != nil:
+		data, _ := json.MarshalIndent(row.info, "", "  ")
+		body = strings.Split(string(data), "\n")
+	default:
+		body = []string{"Not scanned yet"}
+	}
+	visible := max(height-2, 1)
+	t.scroll = min(t.scroll, max(len(body)-visible, 0))
+
+	title := fmt.Sprintf("%s  %s", row.url, row.state)
+	if row.state != tuiScanning && row.elapsed > 0 {
+		title += fmt.Sprintf(" in %s", row.elapsed.Round(100*time.Millisecond))
+	}
+	lines := []string{"\x1b[1m" + fit(title, width) + "\x1b[0m"}
+	for _, line := range body[t.scroll:min(t.scroll+visible, len(body))] {
+		lines = append(lines, fit(line, width))
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	return append(lines, fit("↑↓ scroll  r scan again  esc back", width))
+}
+
+// fit pads or truncates the text to exactly width characters
+func fit(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:width])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
+
+// readKeys reads key presses from stdin in raw mode, naming the special keys
+func readKeys() <-chan string {
+	keys := make(chan string)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			for _, key := range parseKeys(buf[:n]) {
+				keys <- key
+			}
+		}
+	}()
+	return keys
+}
+
+// escapeKeys names the escape sequences terminals send for the special keys, after ESC [ or
+// ESC O
+var escapeKeys = map[string]string{
+	"A": "up", "B": "down", "H": "home", "F": "end",
+	"5~": "pgup", "6~": "pgdn", "1~": "home", "4~": "end",
+}
+
+// parseKeys splits the bytes read from the terminal into keys
+func parseKeys(data []byte) []string {
+	var keys []string
+	for i := 0; i < len(data); i++ {
+		switch b := data[i]; {
+		case b == 0x1b && i+2 < len(data) && (data[i+1] == '[' || data[i+1] == 'O'):
+			// A sequence ends with a letter or ~
+			end := i + 2
+			for end < len(data) && (data[end] >= '0' && data[end] <= '9' || data[end] == ';') {
+				end++
+			}
+			if end < len(data) {
+				if key, ok := escapeKeys[string(data[i+2:end+1])]; ok {
+					keys = append(keys, key)
+				}
+			}
+			i = end
+		case b == 0x1b:
+			keys = append(keys, "esc")
+		case b == 3:
+			keys = append(keys, "ctrl-c")
+		case b == '\r' || b == '\n':
+			keys = append(keys, "enter")
+		case b == 127 || b == 8:
+			keys = append(keys, "esc")
+		default:
+			keys = append(keys, string(b))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// clearScreen moves the cursor home and clears the terminal
+const clearScreen = "\033[H\033[2J"
+
+// watchHistory is the number of recent checks shown in the watch view
+const watchHistory = 10
+
+// renderWatch draws the watch view: the latest status, TTFB, answering address and response
+// headers, followed by the most recent checks so changes during a deployment stand out
+func renderWatch(w io.Writer, url string, interval time.Duration, history []*siteinfo.Uptime) {
+	latest := history[len(history)-1]
+
+	fmt.Fprint(w, clearScreen)
+	fmt.Fprintf(w, "Watching %s every %s (Ctrl+C to stop)\n\n", url, interval)
+	fmt.Fprintf(w, "Checked:  %s\n", latest.CheckedAt.Format("15:04:05"))
+	if latest.Error != "" {
+		fmt.Fprintf(w, "Status:   DOWN (%s)\n", latest.Error)
+	} else {
+		state := "UP"
+		if !latest.Up {
+			state = "DOWN"
+		}
+		fmt.Fprintf(w, "Status:   %s (HTTP %d)\n", state, latest.StatusCode)
+		fmt.Fprintf(w, "TTFB:     %.3fms\n", siteinfo.Milliseconds(latest.TTFB))
+		fmt.Fprintf(w, "Address:  %s\n", latest.RemoteAddr)
+	}
+
+	if len(latest.Header) > 0 {
+		fmt.Fprintln(w, "\nHeaders:")
+		names := make([]string, 0, len(latest.Header))
+		for name := range latest.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s: %s\n", name, strings.Join(latest.Header[name], ", "))
+		}
+	}
+
+	fmt.Fprintln(w, "\nRecent checks:")
+	for i := len(history) - 1; i >= 0; i-- {
+		check := history[i]
+		if check.Error != "" {
+			fmt.Fprintf(w, "  %s  error  %s\n", check.CheckedAt.Format("15:04:05"), check.Error)
+			continue
+		}
+		fmt.Fprintf(w, "  %s  %d  %10.3fms  %s\n", check.CheckedAt.Format("15:04:05"), check.StatusCode,
+			siteinfo.Milliseconds(check.TTFB), check.RemoteAddr)
+	}
+}
+
+// runWatch re-checks a single site on an interval and keeps a live view of it in the terminal
+// until interrupted, which is handy during deployments and DNS cutovers
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := fs.Duration("interval", 5*time.Second, "time between checks")
+	scan := registerScanFlags(fs)
+	registerErrorFormat(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: site-info-fetcher watch [flags] <url>\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 || *interval <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	url := fs.Arg(0)
+
+	opts, closeAudit, err := scan.options()
+	if err != nil {
+		fail(codeScannerConfig, err)
+	}
+	defer closeAudit()
+	opts.Logger = nil
+	if err := opts.AllowTarget(url); err != nil {
+		fail(codeTargetRefused, err)
+	}
+	// A single attempt per check keeps the view current while the site is down
+	opts.Retries = 1
+	scanner := siteinfo.New(opts)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	var history []*siteinfo.Uptime
+	for {
+		uptime := scanner.CheckUptime(ctx, url)
+		if ctx.Err() != nil {
+			fmt.Println()
+			return
+		}
+		history = append(history, uptime)
+		if len(history) > watchHistory {
+			history = history[1:]
+		}
+		renderWatch(os.Stdout, url, *interval, history)
+
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return
+		case <-ticker.C:
+		}
+	}
+}
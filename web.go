@@ -0,0 +1,117 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/report"
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// dashboardTitle is the default heading of the dashboard
+const dashboardTitle = "Site dashboard"
+
+// listenDashboard listens on the dashboard's address, before a scan starts so a port in use
+// does not cost a whole run
+func listenDashboard(addr string) net.Listener {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fail(codeWeb, err)
+	}
+	return listener
+}
+
+// serveDashboard serves the dashboard on the listener until interrupted
+func serveDashboard(listener net.Listener, dashboard *report.Dashboard) {
+	server := &http.Server{Handler: dashboard.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Serving the dashboard until interrupted", "url", "http://"+listener.Addr().String(), "sites", len(dashboard.Sites))
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fail(codeWeb, err)
+	}
+	slog.Info("Dashboard stopped")
+}
+
+// runDashboard runs the dashboard command: it serves the results of earlier runs, from JSON
+// reports or the latest scan of each site in the SQLite database, without scanning
+func runDashboard(args []string) {
+	fs := flag.NewFlagSet("dashboard", flag.ExitOnError)
+	addr := fs.String("web", "localhost:8080", "address to serve the dashboard on")
+	dbPath := fs.String("db", "", "SQLite database written with -db, whose latest scan of each site is listed and whose history is shown on each site's page")
+	title := fs.String("title", dashboardTitle, "heading of the dashboard")
+	registerErrorFormat(fs)
+	registerLogFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: site-info-fetcher dashboard [flags] [report.json ...]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	setupLogging()
+	if fs.NArg() == 0 && *dbPath == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	// The reports replace the database's results, and later reports earlier ones, so each
+	// site is listed with its latest result
+	dashboard := &report.Dashboard{Title: cmp.Or(*title, dashboardTitle), DB: *dbPath}
+	latest := func(filePath string) {
+		if stat, err := os.Stat(filePath); err == nil && stat.ModTime().After(dashboard.GeneratedAt) {
+			dashboard.GeneratedAt = stat.ModTime()
+		}
+	}
+	positions := map[string]int{}
+	add := func(info *siteinfo.SiteInfo) {
+		if i, ok := positions[info.URL]; ok {
+			dashboard.Sites[i] = info
+			return
+		}
+		positions[info.URL] = len(dashboard.Sites)
+		dashboard.Sites = append(dashboard.Sites, info)
+	}
+	if *dbPath != "" {
+		if _, err := os.Stat(*dbPath); err != nil {
+			fail(codeHistory, err)
+		}
+		recent, err := report.RecentScans(*dbPath, time.Time{})
+		if err != nil {
+			fail(codeHistory, err)
+		}
+		for _, url := range slices.Sorted(maps.Keys(recent)) {
+			add(recent[url])
+		}
+		latest(*dbPath)
+	}
+	for _, filePath := range fs.Args() {
+		siteInfos, err := loadReport(filePath)
+		if err != nil {
+			fail(codeReportRead, err)
+		}
+		for _, info := range siteInfos {
+			add(info)
+		}
+		latest(filePath)
+	}
+
+	serveDashboard(listenDashboard(*addr), dashboard)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dr-robert-li/site-info-fetcher/pkg/siteinfo"
+)
+
+// webhookSecretEnv sets the webhook signing secret when -webhook-secret is not given
+const webhookSecretEnv = "SITE_INFO_WEBHOOK_SECRET"
+
+// webhookSignatureHeader carries the HMAC-SHA256 of the request body, as sha256=<hex>
+const webhookSignatureHeader = "X-Site-Info-Signature-256"
+
+// webhookAttempts is the number of times a delivery is tried before it is given up
+const webhookAttempts = 4
+
+// webhookTimeout bounds each delivery attempt
+const webhookTimeout = 30 * time.Second
+
+// webhook POSTs each scanned site to a URL as JSON while the batch is still running.
+// Deliveries run in the background, in scan order, so a slow endpoint does not hold up the
+// scan; the queue applies back-pressure once it is full.
+type webhook struct {
+	url    string
+	secret string
+	client *http.Client
+	queue  chan *siteinfo.SiteInfo
+	done   sync.WaitGroup
+	failed int
+}
+
+// newWebhook starts delivering to the URL, signing each body with the secret when one is set
+func newWebhook(url, secret string) *webhook {
+	w := &webhook{url: url, secret: secret, client: &http.Client{Timeout: webhookTimeout}, queue: make(chan *siteinfo.SiteInfo, 100)}
+	w.done.Add(1)
+	go func() {
+		defer w.done.Done()
+		for info := range w.queue {
+			if err := w.deliver(info); err != nil {
+				w.failed++
+				slog.Error("Webhook delivery failed", "url", info.URL, "error", err)
+			}
+		}
+	}()
+	return w
+}
+
+// record queues a successfully scanned site for delivery
+func (w *webhook) record(url string, info *siteinfo.SiteInfo, err error) {
+	if err == nil {
+		w.queue <- info
+	}
+}
+
+// close waits for the queued deliveries to finish and returns the number that failed
+func (w *webhook) close() int {
+	close(w.queue)
+	w.done.Wait()
+	return w.failed
+}
+
+// deliver POSTs the site, retrying network errors, rate limiting and server errors with
+// exponential backoff
+func (w *webhook) deliver(info *siteinfo.SiteInfo) error {
+	body, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err = w.post(body)
+		if err == nil {
+			return nil
+		}
+		var rejected webhookRejected
+		if errors.As(err, &rejected) && !rejected.retryable() {
+			return err
+		}
+		if attempt == webhookAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post sends one delivery attempt
+func (w *webhook) post(body []byte) error {
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		req.Header.Set(webhookSignatureHeader, "sha256="+signWebhook(w.secret, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return webhookRejected(resp.StatusCode)
+	}
+	return nil
+}
+
+// signWebhook returns the hex HMAC-SHA256 of the body with the secret, which receivers
+// recompute to check the delivery came from this tool and was not altered
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRejected is the status code of a delivery the endpoint did not accept
+type webhookRejected int
+
+// Error describes the rejection
+func (code webhookRejected) Error() string {
+	return fmt.Sprintf("endpoint answered HTTP %d", int(code))
+}
+
+// retryable reports whether the endpoint may accept the delivery later
+func (code webhookRejected) retryable() bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// xlsxSheet is a worksheet of the workbook and the part holding it
+type xlsxSheet struct {
+	name, part string
+}
+
+// readXLSX reads the rows of the named worksheet of an Excel workbook, or of its first sheet
+// when no name is given. Cells are returned as the text Excel shows for strings and the raw
+// value for numbers, with empty cells filled in so the row indexes match the sheet's columns.
+func readXLSX(filePath, sheet string) ([][]string, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an Excel workbook: %w", filePath, err)
+	}
+	defer archive.Close()
+	parts := map[string]*zip.File{}
+	for _, file := range archive.File {
+		parts[file.Name] = file
+	}
+
+	sheets, err := readXLSXSheets(parts)
+	if err != nil {
+		return nil, err
+	}
+	part := ""
+	var names []string
+	for _, s := range sheets {
+		names = append(names, s.name)
+		if sheet == "" || strings.EqualFold(s.name, sheet) {
+			part = s.part
+			break
+		}
+	}
+	if part == "" {
+		return nil, fmt.Errorf("no sheet %q in %s; it has %s", sheet, filePath, strings.Join(names, ", "))
+	}
+
+	var shared []string
+	if parts["xl/sharedStrings.xml"] != nil {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := decodeXLSXPart(parts, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			shared = append(shared, item.String())
+		}
+	}
+
+	var worksheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeXLSXPart(parts, part, &worksheet); err != nil {
+		return nil, err
+	}
+	var records [][]string
+	for _, row := range worksheet.Rows {
+		var record []string
+		for _, cell := range row.Cells {
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				var index int
+				if _, err := fmt.Sscan(cell.Value, &index); err != nil || index < 0 || index >= len(shared) {
+					return nil, fmt.Errorf("cell %s refers to a missing shared string", cell.Ref)
+				}
+				value = shared[index]
+			case "inlineStr":
+				value = cell.Inline.String()
+			}
+			column, ok := xlsxColumnIndex(cell.Ref)
+			if !ok {
+				column = len(record)
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+			record[column] = value
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readXLSXSheets reads the workbook's sheet names and resolves the parts holding them through
+// the workbook's relationships
+func readXLSXSheets(parts map[string]*zip.File) ([]xlsxSheet, error) {
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeXLSXPart(parts, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXLSXPart(parts, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	for _, rel := range rels.Relationships {
+		// Targets are relative to xl/ unless they are absolute within the package
+		if target, ok := strings.CutPrefix(rel.Target, "/"); ok {
+			targets[rel.ID] = target
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+	var sheets []xlsxSheet
+	for _, s := range workbook.Sheets {
+		sheets = append(sheets, xlsxSheet{s.Name, targets[s.ID]})
+	}
+	return sheets, nil
+}
+
+// decodeXLSXPart decodes the XML part of the workbook
+func decodeXLSXPart(parts map[string]*zip.File, name string, v any) error {
+	file := parts[name]
+	if file == nil {
+		return fmt.Errorf("the workbook has no %s", name)
+	}
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := xml.NewDecoder(r).Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	return nil
+}
+
+// xlsxText is a shared or inline string, either plain text or rich text runs
+type xlsxText struct {
+	Text string   `xml:"t"`
+	Runs []string `xml:"r>t"`
+}
+
+// String returns the text without its formatting
+func (t xlsxText) String() string {
+	return t.Text + strings.Join(t.Runs, "")
+}
+
+// xlsxColumnIndex returns the zero-based column index of a cell reference such as C12
+func xlsxColumnIndex(ref string) (int, bool) {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A') + 1
+		letters++
+	}
+	return index - 1, letters > 0
+}